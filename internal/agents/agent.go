@@ -10,6 +10,16 @@ type Task struct {
 	ID           string
 	Spec         string
 	WorktreePath string
+
+	// Progress, if set, receives events while the agent works. Agents that
+	// cannot observe their own progress never call it.
+	Progress ProgressFunc
+
+	// SessionID, if set, is the session of an earlier attempt at the task
+	// and Resume holds only what is new since then. Agents that can resume
+	// a session continue it with Resume; others run the full Spec.
+	SessionID string
+	Resume    string
 }
 
 // TaskResult represents the outcome of an agent's work
@@ -19,9 +29,27 @@ type TaskResult struct {
 	Error     error
 	Duration  time.Duration
 	Artifacts []string
+	Usage     Usage
+	SessionID string // set by agents that can resume the run later
+
+	// Transcript is the agent's raw output, e.g. its stream-json, and
+	// Stderr what it wrote to standard error
+	Transcript string
+	Stderr     string
+}
+
+// Usage is what an agent run consumed. Agents that cannot tell leave it
+// zero.
+type Usage struct {
+	InputTokens  int // including cached prompt tokens
+	OutputTokens int
+	CostUSD      float64
+	Turns        int
 }
 
-// Agent defines the interface all coding agents must implement
+// Agent defines the interface all coding agents must implement. Execute
+// may return a result alongside an error, holding the usage and transcript
+// of the work done before it.
 type Agent interface {
 	Name() string
 	Execute(ctx context.Context, task *Task) (*TaskResult, error)
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os/exec"
 	"strings"
 	"time"
@@ -37,10 +38,27 @@ func (c *ClaudeCode) Name() string {
 }
 
 func (c *ClaudeCode) Execute(ctx context.Context, task *Task) (*TaskResult, error) {
+	if task.SessionID != "" && task.Resume != "" {
+		result, stderr, err := c.run(ctx, task, task.SessionID, task.Resume)
+		// A session that has expired or was made on another machine cannot
+		// be resumed; start over with the full spec instead
+		if err != nil || result.Success || !strings.Contains(stderr, "No conversation found") {
+			return result, err
+		}
+	}
+
+	result, _, err := c.run(ctx, task, "", task.Spec)
+	return result, err
+}
+
+// run runs Claude Code once with prompt, resuming sessionID if it is set,
+// and returns the result along with what the CLI wrote to stderr
+func (c *ClaudeCode) run(ctx context.Context, task *Task, sessionID, prompt string) (*TaskResult, string, error) {
 	start := time.Now()
 
 	args := []string{
 		"--print",
+		"--verbose",
 		"--output-format", "stream-json",
 	}
 
@@ -48,40 +66,34 @@ func (c *ClaudeCode) Execute(ctx context.Context, task *Task) (*TaskResult, erro
 		args = append(args, "--permission-mode", "read-only")
 	}
 
-	args = append(args, task.Spec)
+	if sessionID != "" {
+		args = append(args, "--resume", sessionID)
+	}
+
+	args = append(args, prompt)
 
 	cmd := exec.CommandContext(ctx, "claude", args...)
 	cmd.Dir = task.WorktreePath
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get stdout: %w", err)
+		return nil, "", fmt.Errorf("failed to get stdout: %w", err)
 	}
 
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get stderr: %w", err)
+		return nil, "", fmt.Errorf("failed to get stderr: %w", err)
 	}
 
 	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start claude: %w", err)
+		return nil, "", fmt.Errorf("failed to start claude: %w", err)
 	}
 
-	// Collect output
-	var output strings.Builder
-	scanner := bufio.NewScanner(stdout)
-	for scanner.Scan() {
-		line := scanner.Text()
-		var msg claudeStreamMessage
-		if err := json.Unmarshal([]byte(line), &msg); err != nil {
-			continue
-		}
-		if msg.Type == "assistant" && msg.Content != "" {
-			output.WriteString(msg.Content)
-		}
-	}
-	if scanErr := scanner.Err(); scanErr != nil {
-		return nil, fmt.Errorf("error reading stdout: %w", scanErr)
+	// Collect output, reporting progress as it streams in
+	var raw strings.Builder
+	stream, scanErr := parseClaudeStream(io.TeeReader(stdout, &raw), task.Progress)
+	if scanErr != nil {
+		return nil, "", fmt.Errorf("error reading stdout: %w", scanErr)
 	}
 
 	// Collect stderr
@@ -97,8 +109,12 @@ func (c *ClaudeCode) Execute(ctx context.Context, task *Task) (*TaskResult, erro
 	duration := time.Since(start)
 
 	result := &TaskResult{
-		Duration: duration,
-		Summary:  output.String(),
+		Duration:   duration,
+		Summary:    stream.Output,
+		Usage:      stream.Usage,
+		SessionID:  stream.SessionID,
+		Transcript: raw.String(),
+		Stderr:     errOutput.String(),
 	}
 
 	if err != nil {
@@ -108,13 +124,14 @@ func (c *ClaudeCode) Execute(ctx context.Context, task *Task) (*TaskResult, erro
 		result.Success = true
 	}
 
-	return result, nil
+	return result, errOutput.String(), nil
 }
 
 // Review runs Claude Code in review mode with a specific prompt
 func (c *ClaudeCode) Review(ctx context.Context, prompt string, workDir string) (string, error) {
 	args := []string{
 		"--print",
+		"--verbose",
 		"--output-format", "stream-json",
 		"--permission-mode", "read-only",
 		prompt,
@@ -137,19 +154,8 @@ func (c *ClaudeCode) Review(ctx context.Context, prompt string, workDir string)
 		return "", fmt.Errorf("failed to start claude: %w", err)
 	}
 
-	var output strings.Builder
-	scanner := bufio.NewScanner(stdout)
-	for scanner.Scan() {
-		line := scanner.Text()
-		var msg claudeStreamMessage
-		if err := json.Unmarshal([]byte(line), &msg); err != nil {
-			continue
-		}
-		if msg.Type == "assistant" && msg.Content != "" {
-			output.WriteString(msg.Content)
-		}
-	}
-	if scanErr := scanner.Err(); scanErr != nil {
+	stream, scanErr := parseClaudeStream(stdout, nil)
+	if scanErr != nil {
 		return "", fmt.Errorf("error reading stdout: %w", scanErr)
 	}
 
@@ -169,10 +175,137 @@ func (c *ClaudeCode) Review(ctx context.Context, prompt string, workDir string)
 		return "", fmt.Errorf("claude review failed: %w", err)
 	}
 
-	return output.String(), nil
+	return stream.Output, nil
 }
 
+// claudeStreamMessage is one line of Claude Code's stream-json output.
+// Assistant messages carry their content blocks in Message; older versions
+// put plain text in Content. The final result record carries the usage.
+// Every record names the session it belongs to.
 type claudeStreamMessage struct {
-	Type    string `json:"type"`
-	Content string `json:"content,omitempty"`
+	Type      string `json:"type"`
+	SessionID string `json:"session_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+	Message   *struct {
+		Content []claudeContentBlock `json:"content"`
+	} `json:"message,omitempty"`
+
+	NumTurns     int          `json:"num_turns,omitempty"`
+	TotalCostUSD float64      `json:"total_cost_usd,omitempty"`
+	CostUSD      float64      `json:"cost_usd,omitempty"` // older versions
+	Usage        *claudeUsage `json:"usage,omitempty"`
+}
+
+type claudeUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+}
+
+type claudeContentBlock struct {
+	Type  string         `json:"type"`
+	Text  string         `json:"text,omitempty"`
+	Name  string         `json:"name,omitempty"`
+	Input map[string]any `json:"input,omitempty"`
+}
+
+// maxStreamLine bounds one line of stream-json; tool results can hold
+// whole files
+const maxStreamLine = 16 * 1024 * 1024
+
+// claudeStream is what one run of Claude Code streamed back
+type claudeStream struct {
+	Output    string // the assistant's text
+	Usage     Usage
+	SessionID string // the session to resume to continue the run
+}
+
+// parseClaudeStream reads stream-json output, reporting each turn and tool
+// use to progress
+func parseClaudeStream(r io.Reader, progress ProgressFunc) (claudeStream, error) {
+	var stream claudeStream
+	var output strings.Builder
+	turn := 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLine)
+	for scanner.Scan() {
+		var msg claudeStreamMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+		if stream.SessionID == "" {
+			stream.SessionID = msg.SessionID
+		}
+		if msg.Type == "result" {
+			stream.Usage = msg.usage()
+			continue
+		}
+		if msg.Type != "assistant" {
+			continue
+		}
+
+		turn++
+		progress.report(ProgressEvent{Kind: ProgressTurn, Turn: turn})
+
+		if msg.Content != "" {
+			output.WriteString(msg.Content)
+		}
+		if msg.Message == nil {
+			continue
+		}
+		for _, block := range msg.Message.Content {
+			switch block.Type {
+			case "text":
+				output.WriteString(block.Text)
+			case "tool_use":
+				progress.report(toolProgress(block, turn))
+			}
+		}
+	}
+	if stream.Usage.Turns == 0 {
+		stream.Usage.Turns = turn
+	}
+	stream.Output = output.String()
+	return stream, scanner.Err()
+}
+
+// usage reads the usage from a result record
+func (m *claudeStreamMessage) usage() Usage {
+	u := Usage{Turns: m.NumTurns, CostUSD: m.TotalCostUSD}
+	if u.CostUSD == 0 {
+		u.CostUSD = m.CostUSD
+	}
+	if m.Usage != nil {
+		u.InputTokens = m.Usage.InputTokens + m.Usage.CacheCreationInputTokens + m.Usage.CacheReadInputTokens
+		u.OutputTokens = m.Usage.OutputTokens
+	}
+	return u
+}
+
+// toolProgress classifies a tool invocation
+func toolProgress(block claudeContentBlock, turn int) ProgressEvent {
+	event := ProgressEvent{Kind: ProgressToolUse, Tool: block.Name, Turn: turn}
+
+	input := func(keys ...string) string {
+		for _, k := range keys {
+			if v, ok := block.Input[k].(string); ok && v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	switch block.Name {
+	case "Edit", "MultiEdit", "Write", "NotebookEdit":
+		event.Kind = ProgressFileEdit
+		event.Detail = input("file_path", "notebook_path")
+	case "Bash":
+		event.Kind = ProgressCommand
+		event.Detail = input("command")
+	default:
+		event.Detail = input("file_path", "path", "pattern", "url", "description")
+	}
+	return event
 }
\ No newline at end of file
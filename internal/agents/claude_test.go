@@ -1,7 +1,10 @@
 package agents
 
 import (
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewClaudeCode(t *testing.T) {
@@ -62,6 +65,85 @@ func TestClaudeStreamMessage(t *testing.T) {
 	}
 }
 
+func TestParseClaudeStream(t *testing.T) {
+	stream := strings.Join([]string{
+		`{"type":"system","subtype":"init","session_id":"4f1c2d"}`,
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Looking. "},{"type":"tool_use","name":"Read","input":{"file_path":"main.go"}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","content":"package main"}]}}`,
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","name":"Edit","input":{"file_path":"main.go","old_string":"a","new_string":"b"}}]}}`,
+		`not json`,
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","name":"Bash","input":{"command":"go test ./..."}},{"type":"text","text":"Done."}]}}`,
+		`{"type":"assistant","content":"Legacy."}`,
+		`{"type":"result","num_turns":4,"result":"Done.","total_cost_usd":0.125,"usage":{"input_tokens":10,"cache_creation_input_tokens":200,"cache_read_input_tokens":3000,"output_tokens":450}}`,
+	}, "\n")
+
+	var events []ProgressEvent
+	got, err := parseClaudeStream(strings.NewReader(stream), func(e ProgressEvent) {
+		if e.Time.IsZero() {
+			t.Error("progress event without a time")
+		}
+		e.Time = time.Time{}
+		events = append(events, e)
+	})
+	if err != nil {
+		t.Fatalf("parseClaudeStream() error = %v", err)
+	}
+	if got.Output != "Looking. Done.Legacy." {
+		t.Errorf("Output = %q", got.Output)
+	}
+	if want := (Usage{InputTokens: 3210, OutputTokens: 450, CostUSD: 0.125, Turns: 4}); got.Usage != want {
+		t.Errorf("Usage = %+v, want %+v", got.Usage, want)
+	}
+	if got.SessionID != "4f1c2d" {
+		t.Errorf("SessionID = %q, want %q", got.SessionID, "4f1c2d")
+	}
+
+	want := []ProgressEvent{
+		{Kind: ProgressTurn, Turn: 1},
+		{Kind: ProgressToolUse, Tool: "Read", Detail: "main.go", Turn: 1},
+		{Kind: ProgressTurn, Turn: 2},
+		{Kind: ProgressFileEdit, Tool: "Edit", Detail: "main.go", Turn: 2},
+		{Kind: ProgressTurn, Turn: 3},
+		{Kind: ProgressCommand, Tool: "Bash", Detail: "go test ./...", Turn: 3},
+		{Kind: ProgressTurn, Turn: 4},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %+v\nwant %+v", events, want)
+	}
+}
+
+func TestParseClaudeStreamLongLine(t *testing.T) {
+	long := `{"type":"user","message":{"content":[{"type":"tool_result","content":"` + strings.Repeat("x", 1<<20) + `"}]}}`
+	stream := long + "\n" + `{"type":"assistant","message":{"content":[{"type":"text","text":"ok"}]}}`
+
+	got, err := parseClaudeStream(strings.NewReader(stream), nil)
+	if err != nil {
+		t.Fatalf("parseClaudeStream() error = %v", err)
+	}
+	if got.Output != "ok" {
+		t.Errorf("Output = %q", got.Output)
+	}
+	// Without a result record, turns are counted from the stream
+	if got.Usage.Turns != 1 {
+		t.Errorf("Usage.Turns = %d, want 1", got.Usage.Turns)
+	}
+	if got.SessionID != "" {
+		t.Errorf("SessionID = %q, want none", got.SessionID)
+	}
+}
+
+func TestParseClaudeStreamLegacyCost(t *testing.T) {
+	stream := `{"type":"result","num_turns":2,"cost_usd":0.5}`
+
+	got, err := parseClaudeStream(strings.NewReader(stream), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Usage.CostUSD != 0.5 || got.Usage.Turns != 2 {
+		t.Errorf("Usage = %+v", got.Usage)
+	}
+}
+
 // Note: Testing Execute and Review methods would require mocking the
 // claude CLI command. These functions are tested manually or via
 // integration tests with the actual claude CLI installed.
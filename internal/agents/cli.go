@@ -0,0 +1,296 @@
+package agents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ways a CLI agent receives its prompt
+const (
+	PromptArg   = "arg"   // substituted into the arguments
+	PromptStdin = "stdin" // written to standard input
+	PromptFile  = "file"  // written to a temporary file whose path is passed
+)
+
+// Output formats a CLI agent can produce
+const (
+	OutputPlain = "plain" // stdout is the summary
+	OutputJSONL = "jsonl" // one JSON object per line; text is extracted from each
+)
+
+// CLIConfig describes how to run a command-line coding agent.
+//
+// Args are templates: {prompt}, {prompt_file}, {worktree}, {model} and
+// {task_id} are replaced before the command runs. If no argument mentions
+// the prompt (or prompt file) it is appended as the last argument.
+type CLIConfig struct {
+	Name    string
+	Command string
+	Args    []string
+	Model   string
+	Env     map[string]string
+
+	Prompt string // arg (default), stdin or file
+
+	Output   string // plain (default) or jsonl
+	TextPath string // dotted path to the text in each JSON line, e.g. "message.content.0.text"
+
+	// The run succeeds if the command exits with one of SuccessExitCodes
+	// (default 0), SuccessPattern (if set) matches the output and
+	// FailurePattern (if set) does not
+	SuccessExitCodes []int
+	SuccessPattern   string
+	FailurePattern   string
+}
+
+// CLI is an agent driven entirely by configuration, so new command-line
+// tools can be used without code changes
+type CLI struct {
+	cfg            CLIConfig
+	successPattern *regexp.Regexp
+	failurePattern *regexp.Regexp
+}
+
+// NewCLI validates cfg and creates the agent
+func NewCLI(cfg CLIConfig) (*CLI, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("agent name is required")
+	}
+	if cfg.Command == "" {
+		return nil, fmt.Errorf("agent %s: command is required", cfg.Name)
+	}
+
+	switch cfg.Prompt {
+	case "":
+		cfg.Prompt = PromptArg
+	case PromptArg, PromptStdin, PromptFile:
+	default:
+		return nil, fmt.Errorf("agent %s: unknown prompt delivery %q", cfg.Name, cfg.Prompt)
+	}
+
+	switch cfg.Output {
+	case "":
+		cfg.Output = OutputPlain
+	case OutputPlain:
+	case OutputJSONL:
+		if cfg.TextPath == "" {
+			return nil, fmt.Errorf("agent %s: jsonl output needs a text path", cfg.Name)
+		}
+	default:
+		return nil, fmt.Errorf("agent %s: unknown output format %q", cfg.Name, cfg.Output)
+	}
+
+	if len(cfg.SuccessExitCodes) == 0 {
+		cfg.SuccessExitCodes = []int{0}
+	}
+
+	c := &CLI{cfg: cfg}
+	var err error
+	if cfg.SuccessPattern != "" {
+		if c.successPattern, err = regexp.Compile(cfg.SuccessPattern); err != nil {
+			return nil, fmt.Errorf("agent %s: invalid success pattern: %w", cfg.Name, err)
+		}
+	}
+	if cfg.FailurePattern != "" {
+		if c.failurePattern, err = regexp.Compile(cfg.FailurePattern); err != nil {
+			return nil, fmt.Errorf("agent %s: invalid failure pattern: %w", cfg.Name, err)
+		}
+	}
+
+	return c, nil
+}
+
+func (c *CLI) Name() string {
+	return c.cfg.Name
+}
+
+func (c *CLI) Execute(ctx context.Context, task *Task) (*TaskResult, error) {
+	start := time.Now()
+
+	vars := map[string]string{
+		"{prompt}":   task.Spec,
+		"{worktree}": task.WorktreePath,
+		"{model}":    c.cfg.Model,
+		"{task_id}":  task.ID,
+	}
+
+	if c.cfg.Prompt == PromptFile {
+		file, err := os.CreateTemp("", "foreman-prompt-*.md")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create prompt file: %w", err)
+		}
+		defer os.Remove(file.Name())
+
+		_, err = file.WriteString(task.Spec)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to write prompt file: %w", err)
+		}
+		vars["{prompt_file}"] = file.Name()
+	}
+
+	cmd := exec.CommandContext(ctx, c.cfg.Command, c.buildArgs(vars)...)
+	cmd.Dir = task.WorktreePath
+	if len(c.cfg.Env) > 0 {
+		cmd.Env = os.Environ()
+		for k, v := range c.cfg.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+	if c.cfg.Prompt == PromptStdin {
+		cmd.Stdin = strings.NewReader(task.Spec)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	runErr := cmd.Run()
+	if runErr != nil && cmd.ProcessState == nil {
+		return nil, fmt.Errorf("failed to start %s: %w", c.cfg.Command, runErr)
+	}
+
+	summary := c.parseOutput(stdout.String())
+	result := &TaskResult{
+		Duration:   time.Since(start),
+		Summary:    summary,
+		Transcript: stdout.String(),
+		Stderr:     stderr.String(),
+	}
+
+	if ctx.Err() != nil {
+		result.Error = fmt.Errorf("%s: %w", c.cfg.Name, ctx.Err())
+		return result, nil
+	}
+
+	if err := c.checkSuccess(cmd.ProcessState.ExitCode(), summary); err != nil {
+		result.Error = fmt.Errorf("%s: %w\nstderr: %s", c.cfg.Name, err, strings.TrimSpace(stderr.String()))
+		return result, nil
+	}
+
+	result.Success = true
+	return result, nil
+}
+
+// buildArgs expands the argument templates. The prompt is appended when no
+// argument refers to it.
+func (c *CLI) buildArgs(vars map[string]string) []string {
+	pairs := make([]string, 0, len(vars)*2)
+	for k, v := range vars {
+		pairs = append(pairs, k, v)
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	placeholder := ""
+	switch c.cfg.Prompt {
+	case PromptArg:
+		placeholder = "{prompt}"
+	case PromptFile:
+		placeholder = "{prompt_file}"
+	}
+
+	args := make([]string, 0, len(c.cfg.Args)+1)
+	referenced := false
+	for _, arg := range c.cfg.Args {
+		if placeholder != "" && strings.Contains(arg, placeholder) {
+			referenced = true
+		}
+		args = append(args, replacer.Replace(arg))
+	}
+
+	if placeholder != "" && !referenced {
+		args = append(args, vars[placeholder])
+	}
+	return args
+}
+
+// parseOutput extracts the agent's text from its stdout
+func (c *CLI) parseOutput(stdout string) string {
+	if c.cfg.Output != OutputJSONL {
+		return strings.TrimSpace(stdout)
+	}
+
+	var texts []string
+	for _, line := range strings.Split(stdout, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var v any
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			continue
+		}
+		if text, ok := lookupPath(v, c.cfg.TextPath); ok && text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// checkSuccess applies the configured success detection to a finished run
+func (c *CLI) checkSuccess(exitCode int, output string) error {
+	ok := false
+	for _, code := range c.cfg.SuccessExitCodes {
+		if exitCode == code {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return fmt.Errorf("exited with status %d", exitCode)
+	}
+
+	if c.failurePattern != nil && c.failurePattern.MatchString(output) {
+		return fmt.Errorf("output matches failure pattern %q", c.cfg.FailurePattern)
+	}
+	if c.successPattern != nil && !c.successPattern.MatchString(output) {
+		return fmt.Errorf("output does not match success pattern %q", c.cfg.SuccessPattern)
+	}
+	return nil
+}
+
+// lookupPath follows a dotted path through decoded JSON. Numeric segments
+// index arrays. Strings are returned as is, other values as JSON.
+func lookupPath(v any, path string) (string, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			next, ok := node[key]
+			if !ok {
+				return "", false
+			}
+			v = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return "", false
+			}
+			v = node[i]
+		default:
+			return "", false
+		}
+	}
+
+	switch value := v.(type) {
+	case string:
+		return value, true
+	case nil:
+		return "", false
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", false
+		}
+		return string(data), true
+	}
+}
@@ -0,0 +1,254 @@
+package agents
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewCLIValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     CLIConfig
+		wantErr bool
+	}{
+		{"minimal", CLIConfig{Name: "aider", Command: "aider"}, false},
+		{"missing name", CLIConfig{Command: "aider"}, true},
+		{"missing command", CLIConfig{Name: "aider"}, true},
+		{"unknown prompt delivery", CLIConfig{Name: "a", Command: "a", Prompt: "pipe"}, true},
+		{"unknown output", CLIConfig{Name: "a", Command: "a", Output: "xml"}, true},
+		{"jsonl without text path", CLIConfig{Name: "a", Command: "a", Output: OutputJSONL}, true},
+		{"jsonl", CLIConfig{Name: "a", Command: "a", Output: OutputJSONL, TextPath: "text"}, false},
+		{"bad pattern", CLIConfig{Name: "a", Command: "a", SuccessPattern: "("}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCLI(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewCLI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCLIBuildArgs(t *testing.T) {
+	vars := map[string]string{
+		"{prompt}":      "Add tests",
+		"{prompt_file}": "/tmp/prompt.md",
+		"{worktree}":    "/repo/wt",
+		"{model}":       "gpt-4o",
+		"{task_id}":     "T001",
+	}
+
+	tests := []struct {
+		name   string
+		prompt string
+		args   []string
+		want   []string
+	}{
+		{"placeholders", PromptArg, []string{"--model", "{model}", "--message", "{prompt}"}, []string{"--model", "gpt-4o", "--message", "Add tests"}},
+		{"prompt appended", PromptArg, []string{"--yes"}, []string{"--yes", "Add tests"}},
+		{"embedded placeholder", PromptArg, []string{"--dir={worktree}", "-p={prompt}"}, []string{"--dir=/repo/wt", "-p=Add tests"}},
+		{"prompt file appended", PromptFile, []string{"run"}, []string{"run", "/tmp/prompt.md"}},
+		{"prompt file placeholder", PromptFile, []string{"--file", "{prompt_file}", "{task_id}"}, []string{"--file", "/tmp/prompt.md", "T001"}},
+		{"stdin", PromptStdin, []string{"--model", "{model}"}, []string{"--model", "gpt-4o"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent, err := NewCLI(CLIConfig{Name: "a", Command: "a", Args: tt.args, Prompt: tt.prompt})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := agent.buildArgs(vars); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCLIParseOutput(t *testing.T) {
+	agent, err := NewCLI(CLIConfig{Name: "a", Command: "a", Output: OutputJSONL, TextPath: "message.content.0.text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := strings.Join([]string{
+		`{"type":"system"}`,
+		`not json`,
+		`{"message":{"content":[{"text":"First"}]}}`,
+		``,
+		`{"message":{"content":[{"text":"Second"}]}}`,
+	}, "\n")
+
+	if got := agent.parseOutput(stdout); got != "First\nSecond" {
+		t.Errorf("parseOutput() = %q", got)
+	}
+}
+
+func TestLookupPath(t *testing.T) {
+	v := map[string]any{
+		"result": "done",
+		"usage":  map[string]any{"tokens": float64(12)},
+		"items":  []any{"a", "b"},
+	}
+
+	tests := []struct {
+		path   string
+		want   string
+		wantOK bool
+	}{
+		{"result", "done", true},
+		{"usage.tokens", "12", true},
+		{"items.1", "b", true},
+		{"items.2", "", false},
+		{"items.x", "", false},
+		{"missing", "", false},
+		{"result.deeper", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := lookupPath(v, tt.path)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("lookupPath(%q) = %q, %v; want %q, %v", tt.path, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestCLIExecute(t *testing.T) {
+	dir, err := os.MkdirTemp("", "cli-agent-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	task := &Task{ID: "T001", Spec: "Add tests", WorktreePath: dir}
+
+	tests := []struct {
+		name        string
+		cfg         CLIConfig
+		wantSuccess bool
+		wantSummary string
+	}{
+		{
+			name:        "prompt as argument",
+			cfg:         CLIConfig{Args: []string{"-c", `echo "did: $1"`, "sh", "{prompt}"}},
+			wantSuccess: true,
+			wantSummary: "did: Add tests",
+		},
+		{
+			name:        "prompt on stdin",
+			cfg:         CLIConfig{Args: []string{"-c", "cat"}, Prompt: PromptStdin},
+			wantSuccess: true,
+			wantSummary: "Add tests",
+		},
+		{
+			name:        "prompt file",
+			cfg:         CLIConfig{Args: []string{"-c", `cat "$1"`, "sh"}, Prompt: PromptFile},
+			wantSuccess: true,
+			wantSummary: "Add tests",
+		},
+		{
+			name:        "runs in the worktree",
+			cfg:         CLIConfig{Args: []string{"-c", `test "$(pwd -P)" = "$(cd {worktree} && pwd -P)" && echo here`}, Prompt: PromptStdin},
+			wantSuccess: true,
+			wantSummary: "here",
+		},
+		{
+			name:        "environment",
+			cfg:         CLIConfig{Args: []string{"-c", `echo "$AGENT_MODE"`}, Prompt: PromptStdin, Env: map[string]string{"AGENT_MODE": "auto"}},
+			wantSuccess: true,
+			wantSummary: "auto",
+		},
+		{
+			name:        "json lines",
+			cfg:         CLIConfig{Args: []string{"-c", `echo '{"text":"one"}'; echo '{"text":"two"}'`}, Prompt: PromptStdin, Output: OutputJSONL, TextPath: "text"},
+			wantSuccess: true,
+			wantSummary: "one\ntwo",
+		},
+		{
+			name:        "non-zero exit",
+			cfg:         CLIConfig{Args: []string{"-c", "echo partial; exit 3"}, Prompt: PromptStdin},
+			wantSummary: "partial",
+		},
+		{
+			name:        "accepted exit code",
+			cfg:         CLIConfig{Args: []string{"-c", "exit 3"}, Prompt: PromptStdin, SuccessExitCodes: []int{0, 3}},
+			wantSuccess: true,
+		},
+		{
+			name:        "failure pattern",
+			cfg:         CLIConfig{Args: []string{"-c", "echo 'ERROR: no changes made'"}, Prompt: PromptStdin, FailurePattern: "^ERROR"},
+			wantSummary: "ERROR: no changes made",
+		},
+		{
+			name:        "success pattern missing",
+			cfg:         CLIConfig{Args: []string{"-c", "echo thinking"}, Prompt: PromptStdin, SuccessPattern: "Applied edit"},
+			wantSummary: "thinking",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Name = "test"
+			tt.cfg.Command = "sh"
+			agent, err := NewCLI(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := agent.Execute(context.Background(), task)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (error: %v)", result.Success, tt.wantSuccess, result.Error)
+			}
+			if !result.Success && result.Error == nil {
+				t.Error("failed result should carry an error")
+			}
+			if result.Summary != tt.wantSummary {
+				t.Errorf("Summary = %q, want %q", result.Summary, tt.wantSummary)
+			}
+		})
+	}
+}
+
+func TestCLIExecuteTranscript(t *testing.T) {
+	agent, err := NewCLI(CLIConfig{
+		Name:     "test",
+		Command:  "sh",
+		Args:     []string{"-c", `echo '{"text":"one"}'; echo 'warming up' >&2`},
+		Prompt:   PromptStdin,
+		Output:   OutputJSONL,
+		TextPath: "text",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := agent.Execute(context.Background(), &Task{Spec: "x", WorktreePath: os.TempDir()})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	// The transcript keeps the raw output the summary was extracted from
+	if result.Transcript != "{\"text\":\"one\"}\n" {
+		t.Errorf("Transcript = %q", result.Transcript)
+	}
+	if result.Stderr != "warming up\n" {
+		t.Errorf("Stderr = %q", result.Stderr)
+	}
+}
+
+func TestCLIExecuteMissingCommand(t *testing.T) {
+	agent, err := NewCLI(CLIConfig{Name: "ghost", Command: "foreman-no-such-agent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := agent.Execute(context.Background(), &Task{Spec: "x", WorktreePath: os.TempDir()}); err == nil {
+		t.Error("Execute() of a missing command should fail")
+	}
+}
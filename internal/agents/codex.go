@@ -37,8 +37,9 @@ func (c *Codex) Execute(ctx context.Context, task *Task) (*TaskResult, error) {
 	duration := time.Since(start)
 
 	result := &TaskResult{
-		Duration: duration,
-		Summary:  strings.TrimSpace(string(output)),
+		Duration:   duration,
+		Summary:    strings.TrimSpace(string(output)),
+		Transcript: string(output),
 	}
 
 	if err != nil {
@@ -0,0 +1,261 @@
+package agents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxTurns       = 50
+	defaultRequestTimeout = 10 * time.Minute
+
+	openAISystemPrompt = `You are a coding agent working in a git worktree. Use the tools to inspect and change files; paths are relative to the worktree root. Make the changes the task asks for, keep them focused, and verify them with the available commands where you can. When you are done, reply without calling a tool and summarise what you changed.`
+)
+
+// OpenAIConfig configures an agent backed by an OpenAI-compatible chat
+// completions API, such as vLLM, llama.cpp server or Ollama
+type OpenAIConfig struct {
+	Name    string
+	BaseURL string // up to and including the version, e.g. http://localhost:11434/v1
+	APIKey  string
+	Model   string
+
+	// MaxTurns bounds the number of model requests per task
+	MaxTurns int
+	// AllowedCommands lists the programs the model may run, e.g. "go" or
+	// "npm". Without any, the run_command tool is not offered.
+	AllowedCommands []string
+	CommandTimeout  time.Duration
+	RequestTimeout  time.Duration
+}
+
+// OpenAI is an agent that drives a chat model through its own tool loop.
+// The model reads, writes and lists files and runs allowed commands, all
+// confined to the task's worktree.
+type OpenAI struct {
+	cfg  OpenAIConfig
+	http *http.Client
+}
+
+// NewOpenAI validates cfg and creates the agent
+func NewOpenAI(cfg OpenAIConfig) (*OpenAI, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("agent name is required")
+	}
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("agent %s: base URL is required", cfg.Name)
+	}
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("agent %s: model is required", cfg.Name)
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.MaxTurns <= 0 {
+		cfg.MaxTurns = defaultMaxTurns
+	}
+	if cfg.CommandTimeout <= 0 {
+		cfg.CommandTimeout = defaultCommandTimeout
+	}
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = defaultRequestTimeout
+	}
+
+	return &OpenAI{
+		cfg:  cfg,
+		http: &http.Client{Timeout: cfg.RequestTimeout},
+	}, nil
+}
+
+func (a *OpenAI) Name() string {
+	return a.cfg.Name
+}
+
+func (a *OpenAI) Execute(ctx context.Context, task *Task) (*TaskResult, error) {
+	start := time.Now()
+
+	ws, err := newWorkspace(task.WorktreePath, a.cfg.AllowedCommands, a.cfg.CommandTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []chatMessage{
+		{Role: "system", Content: openAISystemPrompt},
+		{Role: "user", Content: task.Spec},
+	}
+	tools := ws.tools()
+	var usage Usage
+
+	for turn := 0; turn < a.cfg.MaxTurns; turn++ {
+		task.Progress.report(ProgressEvent{Kind: ProgressTurn, Turn: turn + 1})
+		reply, err := a.complete(ctx, messages, tools, &usage)
+		if err != nil {
+			// The turns before the error were paid for all the same
+			return &TaskResult{
+				Summary:    fmt.Sprintf("Stopped after %d turns: %v", turn, err),
+				Error:      err,
+				Duration:   time.Since(start),
+				Artifacts:  ws.written(),
+				Usage:      usage,
+				Transcript: chatTranscript(messages),
+			}, err
+		}
+		messages = append(messages, reply)
+
+		if len(reply.ToolCalls) == 0 {
+			return &TaskResult{
+				Success:    true,
+				Summary:    strings.TrimSpace(reply.Content),
+				Duration:   time.Since(start),
+				Artifacts:  ws.written(),
+				Usage:      usage,
+				Transcript: chatTranscript(messages),
+			}, nil
+		}
+
+		for _, call := range reply.ToolCalls {
+			task.Progress.report(callProgress(call, turn+1))
+			messages = append(messages, chatMessage{
+				Role:       "tool",
+				ToolCallID: call.ID,
+				Content:    ws.call(ctx, call.Function.Name, call.Function.Arguments),
+			})
+		}
+	}
+
+	return &TaskResult{
+		Summary:    fmt.Sprintf("Stopped after %d turns without finishing", a.cfg.MaxTurns),
+		Error:      fmt.Errorf("%s: exceeded %d turns", a.cfg.Name, a.cfg.MaxTurns),
+		Duration:   time.Since(start),
+		Artifacts:  ws.written(),
+		Usage:      usage,
+		Transcript: chatTranscript(messages),
+	}, nil
+}
+
+// chatTranscript renders the conversation as one JSON message per line
+func chatTranscript(messages []chatMessage) string {
+	var b strings.Builder
+	for _, m := range messages {
+		data, err := json.Marshal(m)
+		if err != nil {
+			continue
+		}
+		b.Write(data)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+type chatMessage struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []toolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+type toolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type chatTool struct {
+	Type     string       `json:"type"`
+	Function toolFunction `json:"function"`
+}
+
+type toolFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+type chatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Tools    []chatTool    `json:"tools,omitempty"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message      chatMessage `json:"message"`
+		FinishReason string      `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage,omitempty"`
+}
+
+// complete sends the conversation and returns the model's reply, adding
+// the request to usage
+func (a *OpenAI) complete(ctx context.Context, messages []chatMessage, tools []chatTool, usage *Usage) (chatMessage, error) {
+	data, err := json.Marshal(chatRequest{Model: a.cfg.Model, Messages: messages, Tools: tools})
+	if err != nil {
+		return chatMessage{}, fmt.Errorf("encoding request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.cfg.BaseURL+"/chat/completions", bytes.NewReader(data))
+	if err != nil {
+		return chatMessage{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+a.cfg.APIKey)
+	}
+
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return chatMessage{}, fmt.Errorf("%s: %w", a.cfg.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return chatMessage{}, fmt.Errorf("%s: %s: %s", a.cfg.Name, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	var out chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return chatMessage{}, fmt.Errorf("%s: decoding response: %w", a.cfg.Name, err)
+	}
+	usage.Turns++
+	if out.Usage != nil {
+		usage.InputTokens += out.Usage.PromptTokens
+		usage.OutputTokens += out.Usage.CompletionTokens
+	}
+	if len(out.Choices) == 0 {
+		return chatMessage{}, fmt.Errorf("%s: response has no choices", a.cfg.Name)
+	}
+
+	reply := out.Choices[0].Message
+	reply.Role = "assistant"
+	return reply, nil
+}
+
+// callProgress classifies a tool call
+func callProgress(call toolCall, turn int) ProgressEvent {
+	var args struct {
+		Path    string `json:"path"`
+		Command string `json:"command"`
+	}
+	json.Unmarshal([]byte(call.Function.Arguments), &args)
+
+	event := ProgressEvent{Kind: ProgressToolUse, Tool: call.Function.Name, Detail: args.Path, Turn: turn}
+	switch call.Function.Name {
+	case "write_file":
+		event.Kind = ProgressFileEdit
+	case "run_command":
+		event.Kind = ProgressCommand
+		event.Detail = args.Command
+	}
+	return event
+}
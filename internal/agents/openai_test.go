@@ -0,0 +1,248 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// chatStub replies to chat completion requests with a fixed script of
+// messages and records the requests it received
+type chatStub struct {
+	t        *testing.T
+	replies  []string
+	requests []chatRequest
+}
+
+func (s *chatStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/chat/completions" {
+		http.NotFound(w, r)
+		return
+	}
+	if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+		s.t.Errorf("Authorization = %q", got)
+	}
+
+	var req chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.t.Errorf("decoding request: %v", err)
+	}
+	s.requests = append(s.requests, req)
+
+	if len(s.requests) > len(s.replies) {
+		http.Error(w, "script exhausted", http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintf(w, `{"choices":[{"message":%s,"finish_reason":"stop"}],"usage":{"prompt_tokens":100,"completion_tokens":20}}`, s.replies[len(s.requests)-1])
+}
+
+func toolCallReply(id, name, arguments string) string {
+	args, _ := json.Marshal(arguments)
+	return fmt.Sprintf(`{"role":"assistant","content":null,"tool_calls":[{"id":%q,"type":"function","function":{"name":%q,"arguments":%s}}]}`, id, name, args)
+}
+
+func TestNewOpenAIValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     OpenAIConfig
+		wantErr bool
+	}{
+		{"minimal", OpenAIConfig{Name: "local", BaseURL: "http://localhost:11434/v1", Model: "qwen"}, false},
+		{"missing name", OpenAIConfig{BaseURL: "http://localhost/v1", Model: "qwen"}, true},
+		{"missing base URL", OpenAIConfig{Name: "local", Model: "qwen"}, true},
+		{"missing model", OpenAIConfig{Name: "local", BaseURL: "http://localhost/v1"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewOpenAI(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewOpenAI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpenAIExecute(t *testing.T) {
+	dir, err := os.MkdirTemp("", "openai-agent-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Demo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stub := &chatStub{t: t, replies: []string{
+		toolCallReply("call_1", "read_file", `{"path":"README.md"}`),
+		toolCallReply("call_2", "write_file", `{"path":"docs/usage.md","content":"Usage\n"}`),
+		toolCallReply("call_3", "read_file", `{"path":"../outside"}`),
+		`{"role":"assistant","content":"Added usage docs."}`,
+	}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	agent, err := NewOpenAI(OpenAIConfig{Name: "local", BaseURL: server.URL + "/v1/", APIKey: "secret", Model: "qwen"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var edits []string
+	progress := func(e ProgressEvent) {
+		if e.Kind == ProgressFileEdit {
+			edits = append(edits, e.Detail)
+		}
+	}
+
+	result, err := agent.Execute(context.Background(), &Task{ID: "T001", Spec: "Document usage", WorktreePath: dir, Progress: progress})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Execute() failed: %v", result.Error)
+	}
+	if result.Summary != "Added usage docs." {
+		t.Errorf("Summary = %q", result.Summary)
+	}
+	if want := []string{filepath.Join("docs", "usage.md")}; !reflect.DeepEqual(result.Artifacts, want) {
+		t.Errorf("Artifacts = %q, want %q", result.Artifacts, want)
+	}
+
+	if want := (Usage{InputTokens: 400, OutputTokens: 80, Turns: 4}); result.Usage != want {
+		t.Errorf("Usage = %+v, want %+v", result.Usage, want)
+	}
+
+	// The transcript holds the whole conversation, one message per line
+	if lines := strings.Split(strings.TrimSpace(result.Transcript), "\n"); len(lines) != 9 || !strings.Contains(lines[1], "Document usage") {
+		t.Errorf("Transcript has %d lines:\n%s", len(lines), result.Transcript)
+	}
+
+	if want := []string{"docs/usage.md"}; !reflect.DeepEqual(edits, want) {
+		t.Errorf("reported edits = %q, want %q", edits, want)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "docs", "usage.md"))
+	if err != nil || string(data) != "Usage\n" {
+		t.Errorf("written file = %q, %v", data, err)
+	}
+
+	if len(stub.requests) != 4 {
+		t.Fatalf("made %d requests, want 4", len(stub.requests))
+	}
+	first := stub.requests[0]
+	if first.Model != "qwen" {
+		t.Errorf("Model = %q", first.Model)
+	}
+	if len(first.Tools) != 3 {
+		t.Errorf("offered %d tools without allowed commands, want 3", len(first.Tools))
+	}
+	if msgs := first.Messages; len(msgs) != 2 || msgs[1].Role != "user" || msgs[1].Content != "Document usage" {
+		t.Errorf("first request messages = %+v", msgs)
+	}
+
+	// Each tool result is sent back in the next request
+	last := stub.requests[3].Messages
+	results := map[string]string{}
+	for _, m := range last {
+		if m.Role == "tool" {
+			results[m.ToolCallID] = m.Content
+		}
+	}
+	if results["call_1"] != "# Demo\n" {
+		t.Errorf("read_file result = %q", results["call_1"])
+	}
+	if results["call_3"] == "" || results["call_3"][:6] != "error:" {
+		t.Errorf("escaping read_file result = %q, want an error", results["call_3"])
+	}
+}
+
+func TestOpenAIExecuteMaxTurns(t *testing.T) {
+	dir, err := os.MkdirTemp("", "openai-agent-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	loop := toolCallReply("call", "list_dir", `{"path":"."}`)
+	stub := &chatStub{t: t, replies: []string{loop, loop, loop}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	agent, err := NewOpenAI(OpenAIConfig{Name: "local", BaseURL: server.URL + "/v1", APIKey: "secret", Model: "qwen", MaxTurns: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := agent.Execute(context.Background(), &Task{ID: "T001", Spec: "Loop", WorktreePath: dir})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Success || result.Error == nil {
+		t.Errorf("Execute() = %+v, want a failure", result)
+	}
+	if len(stub.requests) != 2 {
+		t.Errorf("made %d requests, want 2", len(stub.requests))
+	}
+}
+
+func TestOpenAIExecuteHTTPError(t *testing.T) {
+	dir, err := os.MkdirTemp("", "openai-agent-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	agent, err := NewOpenAI(OpenAIConfig{Name: "local", BaseURL: server.URL + "/v1", Model: "qwen"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := agent.Execute(context.Background(), &Task{ID: "T001", Spec: "x", WorktreePath: dir}); err == nil {
+		t.Error("Execute() should fail when the endpoint errors")
+	}
+}
+
+func TestOpenAIExecuteErrorKeepsUsage(t *testing.T) {
+	dir, err := os.MkdirTemp("", "openai-agent-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The second request exhausts the script and fails
+	stub := &chatStub{t: t, replies: []string{toolCallReply("call", "list_dir", `{"path":"."}`)}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	agent, err := NewOpenAI(OpenAIConfig{Name: "local", BaseURL: server.URL + "/v1", APIKey: "secret", Model: "qwen"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := agent.Execute(context.Background(), &Task{ID: "T001", Spec: "List", WorktreePath: dir})
+	if err == nil {
+		t.Fatal("Execute() should fail when the endpoint errors")
+	}
+	if result == nil {
+		t.Fatal("Execute() should return the work done before the error")
+	}
+	if want := (Usage{InputTokens: 100, OutputTokens: 20, Turns: 1}); result.Usage != want {
+		t.Errorf("Usage = %+v, want %+v", result.Usage, want)
+	}
+	if !strings.Contains(result.Transcript, `"list_dir"`) {
+		t.Errorf("Transcript = %q, want the first turn", result.Transcript)
+	}
+}
@@ -0,0 +1,35 @@
+package agents
+
+import "time"
+
+// Kinds of progress an agent reports while it works
+const (
+	ProgressTurn     = "turn"      // the model started another turn
+	ProgressToolUse  = "tool_use"  // a tool was invoked
+	ProgressFileEdit = "file_edit" // a file was created or changed
+	ProgressCommand  = "command"   // a shell command was run
+)
+
+// ProgressEvent describes one step of a running agent
+type ProgressEvent struct {
+	Kind   string
+	Tool   string // the tool invoked, e.g. "Edit" or "Bash"
+	Detail string // the file, command or other target of the tool
+	Turn   int    // turns so far
+	Time   time.Time
+}
+
+// ProgressFunc receives progress events. It is called from the agent's
+// goroutine and should return quickly.
+type ProgressFunc func(ProgressEvent)
+
+// report sends an event to fn, if there is one
+func (fn ProgressFunc) report(event ProgressEvent) {
+	if fn == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	fn(event)
+}
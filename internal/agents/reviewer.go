@@ -25,10 +25,13 @@ type ReviewerConfig struct {
 	Linters        []string
 }
 
+// DefaultTestCommand runs the tests when no test command is configured
+const DefaultTestCommand = "npm test"
+
 func NewReviewer(repoPath string, cfg ReviewerConfig) *Reviewer {
 	testCmd := cfg.TestCommand
 	if testCmd == "" {
-		testCmd = "npm test" // default
+		testCmd = DefaultTestCommand
 	}
 
 	coderabbit := tools.NewCodeRabbit()
@@ -75,7 +78,7 @@ func (r *Reviewer) Review(ctx context.Context, req *ReviewRequest) (*ReviewResul
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		out, err := r.runTests(ctx, req.WorktreePath)
+		out, err := r.RunTests(ctx, req.WorktreePath)
 		results <- toolResult{"tests", out, err}
 	}()
 
@@ -217,7 +220,8 @@ func (r *Reviewer) toolBasedReview(toolOutputs map[string]string) *ReviewResult
 	return result
 }
 
-func (r *Reviewer) runTests(ctx context.Context, workDir string) (string, error) {
+// RunTests runs the configured test command in workDir.
+func (r *Reviewer) RunTests(ctx context.Context, workDir string) (string, error) {
 	// Parse the test command (e.g., "npm test" -> ["npm", "test"])
 	parts := strings.Fields(r.testCommand)
 	if len(parts) == 0 {
@@ -0,0 +1,324 @@
+package agents
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ScriptAny is the script key whose steps apply to tasks without steps of
+// their own
+const ScriptAny = "*"
+
+// ScriptStep is what a scripted agent does on one attempt at a task. Edits
+// are applied in order: files, deletions, the patch, then the replay.
+type ScriptStep struct {
+	Files  map[string]string `yaml:"files"`  // path -> complete new content
+	Delete []string          `yaml:"delete"` // paths to remove
+	Patch  string            `yaml:"patch"`  // unified diff, applied with git apply
+	Replay string            `yaml:"replay"` // recorded Claude Code stream-json to replay
+
+	Summary string `yaml:"summary"`
+	Session string `yaml:"session"` // session ID reported, to exercise resuming
+
+	Error string        `yaml:"error"` // Execute fails with this error before editing anything
+	Fail  string        `yaml:"fail"`  // the attempt fails with this message after its edits
+	Delay time.Duration `yaml:"delay"` // wait after the edits, e.g. to outlast the task timeout
+	Hang  bool          `yaml:"hang"`  // wait until the task is cancelled or times out
+}
+
+// Script holds the steps of a scripted agent per task ID. Successive
+// attempts at a task take successive steps; the last step repeats.
+type Script map[string][]ScriptStep
+
+// LoadScript reads a script from a YAML (or JSON) file:
+//
+//	T001:
+//	  - fail: "tests do not compile"
+//	  - files:
+//	      internal/login.go: "package internal\n"
+//	    summary: Added login
+//
+// Replay paths are relative to the script file.
+func LoadScript(path string) (Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading script: %w", err)
+	}
+
+	var script Script
+	if err := yaml.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("parsing script %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	for _, steps := range script {
+		for i := range steps {
+			if steps[i].Replay != "" && !filepath.IsAbs(steps[i].Replay) {
+				steps[i].Replay = filepath.Join(dir, steps[i].Replay)
+			}
+		}
+	}
+	return script, nil
+}
+
+// Scripted is a deterministic agent that applies pre-recorded edits to the
+// worktree instead of running a model, so workflows can be tested offline
+type Scripted struct {
+	name   string
+	script Script
+
+	mu      sync.Mutex
+	prompts map[string][]string
+}
+
+func NewScripted(name string, script Script) *Scripted {
+	return &Scripted{
+		name:    name,
+		script:  script,
+		prompts: make(map[string][]string),
+	}
+}
+
+func (s *Scripted) Name() string {
+	return s.name
+}
+
+// Prompts returns the prompts the agent received for a task, one per
+// attempt: the resume prompt when a session was resumed, the spec otherwise
+func (s *Scripted) Prompts(taskID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.prompts[taskID]...)
+}
+
+// next records an attempt at task and returns its step
+func (s *Scripted) next(task *Task) (ScriptStep, error) {
+	prompt := task.Spec
+	if task.SessionID != "" && task.Resume != "" {
+		prompt = task.Resume
+	}
+
+	s.mu.Lock()
+	attempt := len(s.prompts[task.ID])
+	s.prompts[task.ID] = append(s.prompts[task.ID], prompt)
+	s.mu.Unlock()
+
+	steps, ok := s.script[task.ID]
+	if !ok {
+		steps = s.script[ScriptAny]
+	}
+	if len(steps) == 0 {
+		return ScriptStep{}, fmt.Errorf("%s: no script for task %s", s.name, task.ID)
+	}
+	if attempt >= len(steps) {
+		attempt = len(steps) - 1
+	}
+	return steps[attempt], nil
+}
+
+func (s *Scripted) Execute(ctx context.Context, task *Task) (*TaskResult, error) {
+	start := time.Now()
+
+	step, err := s.next(task)
+	if err != nil {
+		return nil, err
+	}
+	if step.Error != "" {
+		return nil, fmt.Errorf("%s", step.Error)
+	}
+
+	ws, err := newWorkspace(task.WorktreePath, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TaskResult{Summary: step.Summary, SessionID: step.Session}
+	if err := s.apply(ctx, ws, task, step, result); err != nil {
+		result.Error = fmt.Errorf("%s: %w", s.name, err)
+	}
+	result.Artifacts = ws.written()
+
+	if result.Error == nil {
+		switch {
+		case step.Hang:
+			<-ctx.Done()
+		case step.Delay > 0:
+			select {
+			case <-ctx.Done():
+			case <-time.After(step.Delay):
+			}
+		}
+		if ctx.Err() != nil {
+			result.Error = fmt.Errorf("%s: %w", s.name, ctx.Err())
+		} else if step.Fail != "" {
+			result.Error = fmt.Errorf("%s: %s", s.name, step.Fail)
+		}
+	}
+
+	result.Duration = time.Since(start)
+	result.Success = result.Error == nil
+	return result, nil
+}
+
+// apply makes the step's edits in the worktree
+func (s *Scripted) apply(ctx context.Context, ws *workspace, task *Task, step ScriptStep, result *TaskResult) error {
+	turn := 1
+	task.Progress.report(ProgressEvent{Kind: ProgressTurn, Turn: turn})
+
+	paths := make([]string, 0, len(step.Files))
+	for path := range step.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if _, err := ws.writeFile(path, step.Files[path]); err != nil {
+			return err
+		}
+		task.Progress.report(ProgressEvent{Kind: ProgressFileEdit, Tool: "write_file", Detail: path, Turn: turn})
+	}
+
+	for _, path := range step.Delete {
+		if err := ws.removeFile(path); err != nil {
+			return err
+		}
+		task.Progress.report(ProgressEvent{Kind: ProgressFileEdit, Tool: "delete", Detail: path, Turn: turn})
+	}
+
+	if step.Patch != "" {
+		if err := applyPatch(ctx, ws, step.Patch); err != nil {
+			return err
+		}
+		task.Progress.report(ProgressEvent{Kind: ProgressFileEdit, Tool: "patch", Turn: turn})
+	}
+
+	if step.Replay != "" {
+		return replaySession(ws, step.Replay, task.Progress, result)
+	}
+	return nil
+}
+
+// applyPatch applies a unified diff to the worktree and records the files
+// it touched
+func applyPatch(ctx context.Context, ws *workspace, patch string) error {
+	cmd := exec.CommandContext(ctx, "git", "apply", "--whitespace=nowarn", "-")
+	cmd.Dir = ws.root
+	cmd.Stdin = strings.NewReader(patch)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("patch does not apply: %s", strings.TrimSpace(string(out)))
+	}
+
+	// Both sides, so deleted and renamed files are recorded too
+	for _, line := range strings.Split(patch, "\n") {
+		for _, prefix := range []string{"--- a/", "+++ b/"} {
+			if path, ok := strings.CutPrefix(line, prefix); ok {
+				ws.markChanged(strings.TrimSpace(path))
+			}
+		}
+	}
+	return nil
+}
+
+// replaySession replays a recorded Claude Code session: the files it wrote
+// and edited are changed the same way in the worktree, and its text, usage,
+// session and progress are reported as if it had just run
+func replaySession(ws *workspace, path string, progress ProgressFunc, result *TaskResult) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading recorded session: %w", err)
+	}
+
+	stream, err := parseClaudeStream(bytes.NewReader(data), progress)
+	if err != nil {
+		return fmt.Errorf("reading recorded session: %w", err)
+	}
+	if result.Summary == "" {
+		result.Summary = stream.Output
+	}
+	if result.SessionID == "" {
+		result.SessionID = stream.SessionID
+	}
+	result.Usage = stream.Usage
+	result.Transcript = string(data)
+
+	// File paths in the recording are relative to the directory it was
+	// recorded in
+	var cwd string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLine)
+	for scanner.Scan() {
+		var msg struct {
+			claudeStreamMessage
+			Cwd string `json:"cwd"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+		if msg.Cwd != "" {
+			cwd = msg.Cwd
+		}
+		if msg.Type != "assistant" || msg.Message == nil {
+			continue
+		}
+		for _, block := range msg.Message.Content {
+			if block.Type != "tool_use" {
+				continue
+			}
+			if err := replayTool(ws, cwd, block); err != nil {
+				return fmt.Errorf("replaying %s: %w", block.Name, err)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// replayTool repeats one file-changing tool call; other tools are skipped
+func replayTool(ws *workspace, cwd string, block claudeContentBlock) error {
+	str := func(m map[string]any, key string) string {
+		v, _ := m[key].(string)
+		return v
+	}
+
+	path := str(block.Input, "file_path")
+	if filepath.IsAbs(path) && cwd != "" {
+		rel, err := filepath.Rel(cwd, path)
+		if err != nil {
+			return err
+		}
+		path = rel
+	}
+
+	switch block.Name {
+	case "Write":
+		_, err := ws.writeFile(path, str(block.Input, "content"))
+		return err
+
+	case "Edit":
+		return ws.editFile(path, []fileEdit{{
+			Old:        str(block.Input, "old_string"),
+			New:        str(block.Input, "new_string"),
+			ReplaceAll: block.Input["replace_all"] == true,
+		}})
+
+	case "MultiEdit":
+		raw, _ := block.Input["edits"].([]any)
+		edits := make([]fileEdit, 0, len(raw))
+		for _, e := range raw {
+			m, _ := e.(map[string]any)
+			edits = append(edits, fileEdit{Old: str(m, "old_string"), New: str(m, "new_string"), ReplaceAll: m["replace_all"] == true})
+		}
+		return ws.editFile(path, edits)
+	}
+	return nil
+}
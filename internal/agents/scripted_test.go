@@ -0,0 +1,242 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScriptedSteps(t *testing.T) {
+	dir, err := os.MkdirTemp("", "scripted-agent-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.WriteFile(filepath.Join(dir, "old.txt"), []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	agent := NewScripted("scripted", Script{
+		"T001": {
+			{Files: map[string]string{"half.go": "package half\n"}, Fail: "tests do not compile"},
+			{Files: map[string]string{"login.go": "package login\n"}, Delete: []string{"old.txt"}, Summary: "Added login"},
+		},
+		ScriptAny: {{Summary: "Nothing to do"}},
+	})
+
+	task := &Task{ID: "T001", Spec: "Add login", WorktreePath: dir}
+
+	// The first attempt leaves partial work behind and fails
+	result, err := agent.Execute(context.Background(), task)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Success || result.Error == nil || !strings.Contains(result.Error.Error(), "tests do not compile") {
+		t.Errorf("first attempt = %+v, want the scripted failure", result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "half.go")); err != nil {
+		t.Errorf("partial edit missing: %v", err)
+	}
+
+	// The next attempt takes the next step
+	result, err = agent.Execute(context.Background(), task)
+	if err != nil || !result.Success {
+		t.Fatalf("second attempt = %+v, %v", result, err)
+	}
+	if result.Summary != "Added login" {
+		t.Errorf("Summary = %q", result.Summary)
+	}
+	if want := []string{"login.go", "old.txt"}; !reflect.DeepEqual(result.Artifacts, want) {
+		t.Errorf("Artifacts = %v, want %v", result.Artifacts, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.txt")); !os.IsNotExist(err) {
+		t.Error("old.txt should be deleted")
+	}
+
+	// The last step repeats
+	result, err = agent.Execute(context.Background(), task)
+	if err != nil || !result.Success || result.Summary != "Added login" {
+		t.Errorf("third attempt = %+v, %v", result, err)
+	}
+
+	// Tasks without steps of their own use the default steps
+	other, err := agent.Execute(context.Background(), &Task{ID: "T002", Spec: "x", WorktreePath: dir})
+	if err != nil || other.Summary != "Nothing to do" {
+		t.Errorf("default step = %+v, %v", other, err)
+	}
+
+	if got := agent.Prompts("T001"); len(got) != 3 || got[0] != "Add login" {
+		t.Errorf("Prompts() = %q", got)
+	}
+}
+
+func TestScriptedFailures(t *testing.T) {
+	dir, err := os.MkdirTemp("", "scripted-agent-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	agent := NewScripted("scripted", Script{
+		"error":   {{Error: "claude: command not found"}},
+		"hang":    {{Files: map[string]string{"partial.go": "package partial\n"}, Summary: "Working on it", Hang: true}},
+		"escape":  {{Files: map[string]string{"../outside.txt": "x"}}},
+		"resumed": {{Session: "s1"}},
+	})
+
+	if _, err := agent.Execute(context.Background(), &Task{ID: "error", WorktreePath: dir}); err == nil {
+		t.Error("Execute() should return the scripted error")
+	}
+	if _, err := agent.Execute(context.Background(), &Task{ID: "unscripted", WorktreePath: dir}); err == nil {
+		t.Error("Execute() of an unscripted task should fail")
+	}
+
+	// A hanging step runs until the task times out, with partial output
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result, err := agent.Execute(ctx, &Task{ID: "hang", WorktreePath: dir})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Success || !errors.Is(result.Error, context.DeadlineExceeded) {
+		t.Errorf("hanging step = %+v, want a timeout", result)
+	}
+	if result.Summary != "Working on it" || len(result.Artifacts) != 1 {
+		t.Errorf("hanging step lost its partial output: %+v", result)
+	}
+
+	result, err = agent.Execute(context.Background(), &Task{ID: "escape", WorktreePath: dir})
+	if err != nil || result.Success {
+		t.Errorf("edit outside the worktree = %+v, %v; want a failed attempt", result, err)
+	}
+
+	result, _ = agent.Execute(context.Background(), &Task{ID: "resumed", Spec: "full spec", WorktreePath: dir, SessionID: "s1", Resume: "just the feedback"})
+	if result.SessionID != "s1" {
+		t.Errorf("SessionID = %q", result.SessionID)
+	}
+	if got := agent.Prompts("resumed"); len(got) != 1 || got[0] != "just the feedback" {
+		t.Errorf("Prompts() = %q, want the resume prompt", got)
+	}
+}
+
+func TestScriptedPatch(t *testing.T) {
+	dir, err := os.MkdirTemp("", "scripted-agent-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %s", out)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	patch := `--- a/main.go
++++ b/main.go
+@@ -1,3 +1,5 @@
+ package main
+ 
+-func main() {}
++import "fmt"
++
++func main() { fmt.Println("hi") }
+`
+	agent := NewScripted("scripted", Script{"T001": {{Patch: patch}}, "T002": {{Patch: patch}}})
+
+	result, err := agent.Execute(context.Background(), &Task{ID: "T001", WorktreePath: dir})
+	if err != nil || !result.Success {
+		t.Fatalf("Execute() = %+v, %v", result, err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "main.go"))
+	if !strings.Contains(string(data), `fmt.Println("hi")`) {
+		t.Errorf("patch not applied:\n%s", data)
+	}
+	if !reflect.DeepEqual(result.Artifacts, []string{"main.go"}) {
+		t.Errorf("Artifacts = %v", result.Artifacts)
+	}
+
+	// The same patch no longer applies
+	result, err = agent.Execute(context.Background(), &Task{ID: "T002", WorktreePath: dir})
+	if err != nil || result.Success {
+		t.Errorf("reapplied patch = %+v, %v; want a failed attempt", result, err)
+	}
+}
+
+func TestScriptedReplay(t *testing.T) {
+	dir, err := os.MkdirTemp("", "scripted-agent-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	worktree := filepath.Join(dir, "worktree")
+	if err := os.MkdirAll(worktree, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(worktree, "main.go"), []byte("package main\n\nvar a, b = 1, 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A session recorded in another worktree
+	recording := strings.Join([]string{
+		`{"type":"system","subtype":"init","cwd":"/home/dev/repo/.worktrees/task","session_id":"rec-1"}`,
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","name":"Read","input":{"file_path":"/home/dev/repo/.worktrees/task/main.go"}}]}}`,
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","name":"Edit","input":{"file_path":"/home/dev/repo/.worktrees/task/main.go","old_string":"1","new_string":"2","replace_all":true}}]}}`,
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","name":"MultiEdit","input":{"file_path":"main.go","edits":[{"old_string":"var a","new_string":"var x"},{"old_string":", b","new_string":", y"}]}}]}}`,
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","name":"Write","input":{"file_path":"/home/dev/repo/.worktrees/task/docs/notes.md","content":"Notes\n"}},{"type":"text","text":"Renamed the variables."}]}}`,
+		`{"type":"result","num_turns":4,"total_cost_usd":0.02,"usage":{"input_tokens":100,"output_tokens":10}}`,
+	}, "\n")
+	scriptPath := filepath.Join(dir, "script.yaml")
+	if err := os.WriteFile(filepath.Join(dir, "session.jsonl"), []byte(recording), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(scriptPath, []byte("T001:\n  - replay: session.jsonl\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	script, err := LoadScript(scriptPath)
+	if err != nil {
+		t.Fatalf("LoadScript() error = %v", err)
+	}
+	agent := NewScripted("scripted", script)
+
+	var edits int
+	result, err := agent.Execute(context.Background(), &Task{ID: "T001", WorktreePath: worktree, Progress: func(e ProgressEvent) {
+		if e.Kind == ProgressFileEdit {
+			edits++
+		}
+	}})
+	if err != nil || !result.Success {
+		t.Fatalf("Execute() = %+v, %v", result, err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(worktree, "main.go"))
+	if string(data) != "package main\n\nvar x, y = 2, 2\n" {
+		t.Errorf("main.go = %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(worktree, "docs", "notes.md")); string(data) != "Notes\n" {
+		t.Errorf("docs/notes.md = %q", data)
+	}
+
+	if result.Summary != "Renamed the variables." || result.SessionID != "rec-1" || result.Transcript != recording {
+		t.Errorf("result = %+v", result)
+	}
+	if want := (Usage{InputTokens: 100, OutputTokens: 10, CostUSD: 0.02, Turns: 4}); result.Usage != want {
+		t.Errorf("Usage = %+v, want %+v", result.Usage, want)
+	}
+	if edits != 3 {
+		t.Errorf("reported %d file edits, want 3", edits)
+	}
+	if want := []string{"docs/notes.md", "main.go"}; !reflect.DeepEqual(result.Artifacts, want) {
+		t.Errorf("Artifacts = %v, want %v", result.Artifacts, want)
+	}
+}
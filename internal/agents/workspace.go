@@ -0,0 +1,371 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCommandTimeout = 5 * time.Minute
+
+	// maxToolOutput bounds what a tool hands back to the model
+	maxToolOutput = 64 * 1024
+)
+
+// workspace implements the file and command tools of the OpenAI agent.
+// Every path is resolved inside root, and symlinks may not lead out of it.
+type workspace struct {
+	root           string
+	realRoot       string
+	allowed        map[string]bool
+	commandTimeout time.Duration
+
+	mu      sync.Mutex
+	changed map[string]bool
+}
+
+func newWorkspace(root string, allowedCommands []string, commandTimeout time.Duration) (*workspace, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, fmt.Errorf("worktree unavailable: %w", err)
+	}
+
+	allowed := make(map[string]bool)
+	for _, cmd := range allowedCommands {
+		allowed[cmd] = true
+	}
+
+	return &workspace{
+		root:           root,
+		realRoot:       realRoot,
+		allowed:        allowed,
+		commandTimeout: commandTimeout,
+		changed:        make(map[string]bool),
+	}, nil
+}
+
+// tools describes the available tools to the model
+func (w *workspace) tools() []chatTool {
+	pathParam := map[string]any{"type": "string", "description": "Path relative to the worktree root"}
+
+	tools := []chatTool{
+		{Type: "function", Function: toolFunction{
+			Name:        "read_file",
+			Description: "Read a file",
+			Parameters:  objectSchema(map[string]any{"path": pathParam}, "path"),
+		}},
+		{Type: "function", Function: toolFunction{
+			Name:        "write_file",
+			Description: "Create or overwrite a file with the given content",
+			Parameters: objectSchema(map[string]any{
+				"path":    pathParam,
+				"content": map[string]any{"type": "string", "description": "The complete new file content"},
+			}, "path", "content"),
+		}},
+		{Type: "function", Function: toolFunction{
+			Name:        "list_dir",
+			Description: "List a directory; subdirectories end with /",
+			Parameters:  objectSchema(map[string]any{"path": pathParam}, "path"),
+		}},
+	}
+
+	if len(w.allowed) > 0 {
+		names := make([]string, 0, len(w.allowed))
+		for name := range w.allowed {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		tools = append(tools, chatTool{Type: "function", Function: toolFunction{
+			Name:        "run_command",
+			Description: fmt.Sprintf("Run a command in the worktree root, without a shell. Allowed programs: %s", strings.Join(names, ", ")),
+			Parameters: objectSchema(map[string]any{
+				"command": map[string]any{"type": "string", "description": "The command line, e.g. \"go test ./...\""},
+			}, "command"),
+		}})
+	}
+
+	return tools
+}
+
+func objectSchema(properties map[string]any, required ...string) map[string]any {
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// call runs a tool and returns its output for the model. Failures are
+// reported to the model rather than ending the run, so it can correct
+// itself.
+func (w *workspace) call(ctx context.Context, name, arguments string) string {
+	var args struct {
+		Path    string `json:"path"`
+		Content string `json:"content"`
+		Command string `json:"command"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return fmt.Sprintf("error: invalid arguments: %v", err)
+	}
+
+	var out string
+	var err error
+	switch name {
+	case "read_file":
+		out, err = w.readFile(args.Path)
+	case "write_file":
+		out, err = w.writeFile(args.Path, args.Content)
+	case "list_dir":
+		out, err = w.listDir(args.Path)
+	case "run_command":
+		out, err = w.runCommand(ctx, args.Command)
+	default:
+		err = fmt.Errorf("unknown tool %q", name)
+	}
+
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return out
+}
+
+// resolve maps a model-supplied path to a path inside the worktree
+func (w *workspace) resolve(path string) (string, error) {
+	if filepath.IsAbs(path) {
+		rel, err := filepath.Rel(w.root, path)
+		if err != nil {
+			return "", fmt.Errorf("path %q is outside the worktree", path)
+		}
+		path = rel
+	}
+
+	full := filepath.Join(w.root, path)
+	if !within(w.root, full) {
+		return "", fmt.Errorf("path %q is outside the worktree", path)
+	}
+
+	real, err := resolveExisting(full)
+	if err != nil {
+		return "", err
+	}
+	if !within(w.realRoot, real) {
+		return "", fmt.Errorf("path %q leads outside the worktree", path)
+	}
+
+	// Git metadata is off limits
+	rel, _ := filepath.Rel(w.root, full)
+	if rel == ".git" || strings.HasPrefix(rel, ".git"+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q is inside .git", path)
+	}
+
+	return full, nil
+}
+
+// within reports whether path is root or below it
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolveExisting evaluates the symlinks of the longest existing prefix of
+// path and appends the rest
+func resolveExisting(path string) (string, error) {
+	var rest []string
+	for {
+		real, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{real}, rest...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		rest = append([]string{filepath.Base(path)}, rest...)
+		path = parent
+	}
+}
+
+func (w *workspace) readFile(path string) (string, error) {
+	full, err := w.resolve(path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return "", err
+	}
+	return truncateOutput(string(data)), nil
+}
+
+func (w *workspace) writeFile(path, content string) (string, error) {
+	full, err := w.resolve(path)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		return "", err
+	}
+
+	rel, _ := filepath.Rel(w.root, full)
+	w.markChanged(rel)
+
+	return fmt.Sprintf("wrote %d bytes to %s", len(content), rel), nil
+}
+
+// fileEdit replaces Old with New in a file, once or everywhere
+type fileEdit struct {
+	Old        string
+	New        string
+	ReplaceAll bool
+}
+
+// editFile applies edits to an existing file in order. Each Old must occur
+// in the file, and only once unless ReplaceAll is set.
+func (w *workspace) editFile(path string, edits []fileEdit) error {
+	full, err := w.resolve(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return err
+	}
+
+	content := string(data)
+	for _, e := range edits {
+		switch n := strings.Count(content, e.Old); {
+		case e.Old == "" || n == 0:
+			return fmt.Errorf("%s: text to replace not found", path)
+		case n > 1 && !e.ReplaceAll:
+			return fmt.Errorf("%s: text to replace occurs %d times", path, n)
+		}
+		if e.ReplaceAll {
+			content = strings.ReplaceAll(content, e.Old, e.New)
+		} else {
+			content = strings.Replace(content, e.Old, e.New, 1)
+		}
+	}
+
+	_, err = w.writeFile(path, content)
+	return err
+}
+
+// removeFile deletes a file; a file that is already gone is not an error
+func (w *workspace) removeFile(path string) error {
+	full, err := w.resolve(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(full); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	rel, _ := filepath.Rel(w.root, full)
+	w.markChanged(rel)
+	return nil
+}
+
+// markChanged records a file changed other than through writeFile
+func (w *workspace) markChanged(rel string) {
+	w.mu.Lock()
+	w.changed[rel] = true
+	w.mu.Unlock()
+}
+
+func (w *workspace) listDir(path string) (string, error) {
+	if path == "" {
+		path = "."
+	}
+	full, err := w.resolve(path)
+	if err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(full)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, e := range entries {
+		if e.Name() == ".git" {
+			continue
+		}
+		b.WriteString(e.Name())
+		if e.IsDir() {
+			b.WriteString("/")
+		}
+		b.WriteString("\n")
+	}
+	if b.Len() == 0 {
+		return "(empty)", nil
+	}
+	return truncateOutput(b.String()), nil
+}
+
+func (w *workspace) runCommand(ctx context.Context, command string) (string, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty command")
+	}
+	if !w.allowed[fields[0]] {
+		return "", fmt.Errorf("%s is not an allowed command", fields[0])
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.commandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, fields[0], fields[1:]...)
+	cmd.Dir = w.root
+	output, err := cmd.CombinedOutput()
+
+	status := "exit status 0"
+	if err != nil {
+		if ctx.Err() != nil {
+			status = fmt.Sprintf("timed out after %s", w.commandTimeout)
+		} else {
+			status = err.Error()
+		}
+	}
+	return truncateOutput(fmt.Sprintf("%s\n%s", status, output)), nil
+}
+
+// written returns the files the model wrote, sorted
+func (w *workspace) written() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	files := make([]string, 0, len(w.changed))
+	for f := range w.changed {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func truncateOutput(s string) string {
+	if len(s) <= maxToolOutput {
+		return s
+	}
+	return s[:maxToolOutput] + fmt.Sprintf("\n... (truncated, %d bytes total)", len(s))
+}
@@ -0,0 +1,107 @@
+package agents
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWorkspaceResolve(t *testing.T) {
+	dir, err := os.MkdirTemp("", "workspace-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "root")
+	outside := filepath.Join(dir, "outside")
+	for _, d := range []string{filepath.Join(root, "src"), filepath.Join(root, ".git"), outside} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "src"), filepath.Join(root, "inside")); err != nil {
+		t.Fatal(err)
+	}
+
+	ws, err := newWorkspace(root, nil, defaultCommandTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{"src/main.go", false},
+		{"new/dir/file.go", false},
+		{".", false},
+		{filepath.Join(root, "src/main.go"), false},
+		{"inside/main.go", false},
+		{"../outside/file", true},
+		{"src/../../outside", true},
+		{"/etc/passwd", true},
+		{"escape/file", true},
+		{"escape/new/file", true},
+		{".git/config", true},
+	}
+
+	for _, tt := range tests {
+		_, err := ws.resolve(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("resolve(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+		}
+	}
+}
+
+func TestWorkspaceTools(t *testing.T) {
+	dir, err := os.MkdirTemp("", "workspace-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ws, err := newWorkspace(dir, []string{"echo"}, defaultCommandTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if got := len(ws.tools()); got != 4 {
+		t.Errorf("offered %d tools with allowed commands, want 4", got)
+	}
+
+	if got := ws.call(ctx, "write_file", `{"path":"a/b.txt","content":"hello"}`); strings.HasPrefix(got, "error:") {
+		t.Fatalf("write_file = %q", got)
+	}
+	if got := ws.call(ctx, "read_file", `{"path":"a/b.txt"}`); got != "hello" {
+		t.Errorf("read_file = %q", got)
+	}
+	if got := ws.call(ctx, "list_dir", `{"path":""}`); got != "a/\n" {
+		t.Errorf("list_dir = %q", got)
+	}
+	if got := ws.call(ctx, "run_command", `{"command":"echo ok"}`); got != "exit status 0\nok\n" {
+		t.Errorf("run_command = %q", got)
+	}
+
+	for _, tt := range []struct{ name, args string }{
+		{"run_command", `{"command":"rm -rf /"}`},
+		{"run_command", `{"command":""}`},
+		{"read_file", `{"path":"missing"}`},
+		{"read_file", `not json`},
+		{"delete_file", `{"path":"a/b.txt"}`},
+	} {
+		if got := ws.call(ctx, tt.name, tt.args); !strings.HasPrefix(got, "error:") {
+			t.Errorf("%s(%s) = %q, want an error", tt.name, tt.args, got)
+		}
+	}
+
+	if got := ws.written(); len(got) != 1 || got[0] != filepath.Join("a", "b.txt") {
+		t.Errorf("written() = %q", got)
+	}
+}
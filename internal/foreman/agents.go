@@ -0,0 +1,91 @@
+package foreman
+
+import (
+	"fmt"
+
+	"github.com/bayological/foreman/internal/agents"
+)
+
+// Agent implementations selectable with an agent's type
+const (
+	AgentTypeClaudeCode = "claude-code"
+	AgentTypeCodex      = "codex"
+	AgentTypeCLI        = "cli"
+	AgentTypeOpenAI     = "openai"
+	AgentTypeScripted   = "scripted"
+)
+
+// newAgents creates the enabled agents of cfg, keyed by name
+func newAgents(cfg *Config) (map[string]agents.Agent, error) {
+	configured := make(map[string]agents.Agent)
+	for name, agentCfg := range cfg.Agents {
+		if !agentCfg.Enabled {
+			continue
+		}
+		agent, err := newAgent(name, agentCfg, cfg.Repo.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure agent %s: %w", name, err)
+		}
+		configured[name] = agent
+	}
+	return configured, nil
+}
+
+// agentType returns the implementation of the agent configured under name.
+// Without an explicit type, the built-in agents are picked by name and
+// anything else is a CLI agent.
+func agentType(name string, cfg AgentConfig) string {
+	if cfg.Type != "" {
+		return cfg.Type
+	}
+	switch name {
+	case AgentTypeClaudeCode, AgentTypeCodex:
+		return name
+	default:
+		return AgentTypeCLI
+	}
+}
+
+// newAgent creates the agent configured under name
+func newAgent(name string, cfg AgentConfig, repoPath string) (agents.Agent, error) {
+	switch kind := agentType(name, cfg); kind {
+	case AgentTypeClaudeCode:
+		return agents.NewClaudeCode(repoPath), nil
+	case AgentTypeCodex:
+		return agents.NewCodex(repoPath), nil
+	case AgentTypeCLI:
+		return agents.NewCLI(agents.CLIConfig{
+			Name:             name,
+			Command:          cfg.Command,
+			Args:             cfg.Args,
+			Model:            cfg.Model,
+			Env:              cfg.Env,
+			Prompt:           cfg.Prompt,
+			Output:           cfg.Output,
+			TextPath:         cfg.TextPath,
+			SuccessExitCodes: cfg.SuccessExitCodes,
+			SuccessPattern:   cfg.SuccessPattern,
+			FailurePattern:   cfg.FailurePattern,
+		})
+	case AgentTypeOpenAI:
+		return agents.NewOpenAI(agents.OpenAIConfig{
+			Name:            name,
+			BaseURL:         cfg.BaseURL,
+			APIKey:          cfg.APIKey,
+			Model:           cfg.Model,
+			MaxTurns:        cfg.MaxTurns,
+			AllowedCommands: cfg.AllowedCommands,
+		})
+	case AgentTypeScripted:
+		if cfg.Script == "" {
+			return nil, fmt.Errorf("scripted agent needs a script")
+		}
+		script, err := agents.LoadScript(cfg.Script)
+		if err != nil {
+			return nil, err
+		}
+		return agents.NewScripted(name, script), nil
+	default:
+		return nil, fmt.Errorf("unknown agent type %q", kind)
+	}
+}
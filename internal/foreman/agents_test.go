@@ -0,0 +1,109 @@
+package foreman
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNewAgent(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      AgentConfig
+		wantType string
+		wantName string
+		wantErr  bool
+	}{
+		{"claude-code", AgentConfig{}, "*agents.ClaudeCode", "claude-code", false},
+		{"codex", AgentConfig{}, "*agents.Codex", "codex", false},
+		{"aider", AgentConfig{Command: "aider", Args: []string{"--message", "{prompt}"}}, "*agents.CLI", "aider", false},
+		{"claude-opus", AgentConfig{Type: AgentTypeCLI, Command: "claude", Args: []string{"--model", "{model}"}}, "*agents.CLI", "claude-opus", false},
+		{"local", AgentConfig{Type: AgentTypeOpenAI, BaseURL: "http://localhost:11434/v1", Model: "qwen2.5-coder"}, "*agents.OpenAI", "local", false},
+		{"local", AgentConfig{Type: AgentTypeOpenAI, Model: "qwen2.5-coder"}, "", "", true},
+		{"aider", AgentConfig{}, "", "", true},
+		{"gemini", AgentConfig{Type: "rpc"}, "", "", true},
+		{"fake", AgentConfig{Type: AgentTypeScripted}, "", "", true},
+		{"fake", AgentConfig{Type: AgentTypeScripted, Script: "/nonexistent/script.yaml"}, "", "", true},
+	}
+
+	for _, tt := range tests {
+		agent, err := newAgent(tt.name, tt.cfg, "/repo")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("newAgent(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got := reflect.TypeOf(agent).String(); got != tt.wantType {
+			t.Errorf("newAgent(%q) = %s, want %s", tt.name, got, tt.wantType)
+		}
+		if agent.Name() != tt.wantName {
+			t.Errorf("newAgent(%q).Name() = %q, want %q", tt.name, agent.Name(), tt.wantName)
+		}
+	}
+}
+
+func TestNewScriptedAgent(t *testing.T) {
+	dir, err := os.MkdirTemp("", "scripted-agent-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "script.yaml")
+	if err := os.WriteFile(path, []byte("\"*\":\n  - summary: done\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	agent, err := newAgent("fake", AgentConfig{Type: AgentTypeScripted, Script: path}, "/repo")
+	if err != nil {
+		t.Fatalf("newAgent() error = %v", err)
+	}
+	if got := reflect.TypeOf(agent).String(); got != "*agents.Scripted" || agent.Name() != "fake" {
+		t.Errorf("newAgent() = %s named %q", got, agent.Name())
+	}
+}
+
+func TestLoadConfigAgents(t *testing.T) {
+	dir, err := os.MkdirTemp("", "foreman-config-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "foreman.yaml")
+	os.WriteFile(path, []byte(`
+agents:
+  claude-code:
+    enabled: true
+    timeout: 30m
+  gemini:
+    enabled: true
+    command: gemini
+    args: ["--model", "{model}", "--yolo"]
+    model: gemini-2.5-pro
+    prompt: stdin
+    output: jsonl
+    text_path: response
+    success_exit_codes: [0, 2]
+`), 0644)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if len(cfg.Agents) != 2 || !cfg.Agents["claude-code"].Enabled {
+		t.Fatalf("agents = %+v", cfg.Agents)
+	}
+	gemini := cfg.Agents["gemini"]
+	if gemini.Command != "gemini" || gemini.Prompt != "stdin" || gemini.TextPath != "response" || len(gemini.Args) != 3 || len(gemini.SuccessExitCodes) != 2 {
+		t.Errorf("gemini config = %+v", gemini)
+	}
+
+	if _, err := newAgent("gemini", gemini, "/repo"); err != nil {
+		t.Errorf("newAgent(gemini) error = %v", err)
+	}
+}
@@ -0,0 +1,490 @@
+package foreman
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bayological/foreman/internal/agents"
+	"github.com/bayological/foreman/internal/storage"
+)
+
+// Task metadata kept for competitions
+const (
+	// metaCompete lists the agents competing for a task, comma separated;
+	// an agent listed twice runs two samples
+	metaCompete = "compete"
+	// metaCandidates lists the candidates offered for a pick, best first,
+	// as comma separated agent=branch pairs
+	metaCandidates = "candidates"
+)
+
+// maxOffered is how many of the best candidates are offered for a pick
+const maxOffered = 2
+
+// candidate is one competing implementation of a task
+type candidate struct {
+	Index  int // position among the competitors, from 1
+	Agent  string
+	Branch string
+
+	Result    *agents.TaskResult
+	Review    *agents.ReviewResult
+	Err       error
+	Lines     int  // lines added and removed
+	TestsPass bool // the review's test run reported no failure
+}
+
+// ok reports whether the candidate produced reviewed work
+func (c *candidate) ok() bool {
+	return c.Err == nil && c.Result != nil && c.Result.Success && c.Review != nil
+}
+
+// label names the candidate on buttons: the agent, and which sample
+func (c *candidate) label() string {
+	return fmt.Sprintf("%s #%d", c.Agent, c.Index)
+}
+
+// competitors expands competing agents into one entry per run
+func competitors(names []string, samples int) []string {
+	if samples < 1 {
+		samples = 1
+	}
+	var runs []string
+	for _, name := range names {
+		for i := 0; i < samples; i++ {
+			runs = append(runs, name)
+		}
+	}
+	return runs
+}
+
+// competingAgents returns the runs of a feature task that competes, or nil
+func competingAgents(task *Task) []string {
+	task.mu.Lock()
+	runs := task.Metadata[metaCompete]
+	task.mu.Unlock()
+	if task.FeatureID == "" || runs == "" {
+		return nil
+	}
+	return strings.Split(runs, ",")
+}
+
+// candidateBranch is the branch of one competitor. It sits beside the
+// task branch: git cannot hold a branch and branches below it.
+func candidateBranch(task *Task, index int) string {
+	return fmt.Sprintf("%s-c%d", task.Branch, index)
+}
+
+// verdictRank orders review verdicts from best to worst
+func verdictRank(verdict agents.ReviewVerdict) int {
+	switch verdict {
+	case agents.VerdictApprove:
+		return 0
+	case agents.VerdictRequestChanges:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// rankCandidates orders candidates best first: reviewed work before
+// failures, then by verdict, passing tests and the smallest diff
+func rankCandidates(candidates []*candidate) []*candidate {
+	ranked := append([]*candidate(nil), candidates...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.ok() != b.ok() {
+			return a.ok()
+		}
+		if !a.ok() {
+			return a.Index < b.Index
+		}
+		if ra, rb := verdictRank(a.Review.Verdict), verdictRank(b.Review.Verdict); ra != rb {
+			return ra < rb
+		}
+		if a.TestsPass != b.TestsPass {
+			return a.TestsPass
+		}
+		if a.Lines != b.Lines {
+			return a.Lines < b.Lines
+		}
+		return a.Index < b.Index
+	})
+	return ranked
+}
+
+// testsPassed reports whether a review's test run found no failure
+func testsPassed(review *agents.ReviewResult) bool {
+	out := review.ToolOutputs["tests"]
+	return !strings.Contains(out, "FAILED") && !strings.HasPrefix(out, "ERROR:")
+}
+
+// competitionReport lists the ranked candidates
+func competitionReport(ranked []*candidate) string {
+	var b strings.Builder
+	b.WriteString("*Competition Results*\n\n")
+	for i, c := range ranked {
+		fmt.Fprintf(&b, "%d. `%s` ", i+1, c.label())
+		switch {
+		case c.Err != nil:
+			fmt.Fprintf(&b, "failed: %s\n", truncate(c.Err.Error(), 200))
+		case !c.ok():
+			summary := "no result"
+			if c.Result != nil && c.Result.Error != nil {
+				summary = c.Result.Error.Error()
+			} else if c.Result != nil {
+				summary = c.Result.Summary
+			}
+			fmt.Fprintf(&b, "failed: %s\n", truncate(summary, 200))
+		default:
+			tests := "tests pass"
+			if !c.TestsPass {
+				tests = "tests fail"
+			}
+			fmt.Fprintf(&b, "%s, %s, %d lines\n   Branch: `%s`\n", c.Review.Verdict, tests, c.Lines, c.Branch)
+			if summary := strings.TrimSpace(c.Review.Summary); summary != "" {
+				fmt.Fprintf(&b, "   %s\n", truncate(strings.ReplaceAll(summary, "\n", " "), 200))
+			}
+		}
+	}
+	return b.String()
+}
+
+// encodeCandidates and decodeCandidates keep the offered candidates in
+// task metadata, so a pick still works after a restart
+func encodeCandidates(offered []*candidate) string {
+	pairs := make([]string, len(offered))
+	for i, c := range offered {
+		pairs[i] = c.Agent + "=" + c.Branch
+	}
+	return strings.Join(pairs, ",")
+}
+
+func decodeCandidates(value string) []*candidate {
+	if value == "" {
+		return nil
+	}
+	var offered []*candidate
+	for _, pair := range strings.Split(value, ",") {
+		agentName, branch, _ := strings.Cut(pair, "=")
+		offered = append(offered, &candidate{Agent: agentName, Branch: branch})
+	}
+	return offered
+}
+
+// acquireCandidateSlot waits for a worker slot for one candidate. The slot
+// the task was leased with is shared through own; further candidates take
+// slots of their own, so a competition never runs more than MaxTasks
+// agents at once. It returns the release function, or false when ctx ends
+// first.
+func (f *Foreman) acquireCandidateSlot(ctx context.Context, own chan struct{}) (func(), bool) {
+	select {
+	case <-own:
+		return func() { own <- struct{}{} }, true
+	case f.sem <- struct{}{}:
+		return func() { <-f.sem }, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// runCompetition runs every competitor on task concurrently, each in its
+// own worktree and branch, reviews their work and offers the best for a
+// pick. Competitors get a single attempt each. Like executeTask, it reports
+// whether the task should run again.
+func (f *Foreman) runCompetition(ctx context.Context, task *Task, lease int, runs []string) bool {
+	taskCtx, cancel := context.WithTimeout(ctx, task.Timeout)
+	defer cancel()
+
+	f.trackTask(task, lease, cancel)
+	defer f.untrackTask(task, lease)
+
+	task.setStatus(StatusRunning)
+	f.saveTaskFeature(task)
+	f.recordTask(task, EventTaskAttempt, fmt.Sprintf("Competition between %s", strings.Join(runs, ", ")), "foreman")
+	f.telegram.Send(fmt.Sprintf("*Competition*\nTask: `%s`\nRunning %d candidates: %s", task.ID, len(runs), strings.Join(runs, ", ")))
+
+	own := make(chan struct{}, 1)
+	own <- struct{}{}
+
+	candidates := make([]*candidate, len(runs))
+	var wg sync.WaitGroup
+	for i, name := range runs {
+		c := &candidate{Index: i + 1, Agent: name, Branch: candidateBranch(task, i+1)}
+		candidates[i] = c
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, ok := f.acquireCandidateSlot(taskCtx, own)
+			if !ok {
+				c.Err = taskCtx.Err()
+				return
+			}
+			defer release()
+			f.runCandidate(taskCtx, task, c)
+		}()
+	}
+	wg.Wait()
+
+	// Shutting down: run the whole competition again after the restart
+	if ctx.Err() != nil {
+		f.discardCandidates(candidates)
+		task.setStatus(StatusPending)
+		return true
+	}
+
+	ranked := rankCandidates(candidates)
+	var offered, rest []*candidate
+	for _, c := range ranked {
+		if c.ok() && len(offered) < maxOffered {
+			offered = append(offered, c)
+		} else {
+			rest = append(rest, c)
+		}
+	}
+	f.discardCandidates(rest)
+	report := competitionReport(ranked)
+
+	if len(offered) == 0 {
+		f.markFailed(task, "Every candidate failed")
+		f.telegram.Escalate(task.ID, "Every candidate failed", report)
+		return false
+	}
+
+	task.mu.Lock()
+	task.Status = StatusApproval
+	task.Metadata[metaCandidates] = encodeCandidates(offered)
+	task.mu.Unlock()
+	if feature := f.getFeature(task.FeatureID); feature != nil {
+		f.transitionTask(feature, PhaseReviewing, fmt.Sprintf("Ranking candidates for task %s", task.ID), "foreman")
+		f.transitionTask(feature, PhaseAwaitingCodeApproval, fmt.Sprintf("Task %s awaiting a pick", task.ID), "foreman")
+		feature.setCurrentTask(task)
+	}
+	f.recordTask(task, EventTaskStatus, fmt.Sprintf("Competition finished, offering %d candidate(s)", len(offered)), "foreman")
+
+	labels := make([]string, len(offered))
+	for i, c := range offered {
+		labels[i] = c.label()
+	}
+	f.telegram.RequestCandidatePick(task.FeatureID, task.ID, report, labels)
+	return false
+}
+
+// runCandidate has one competitor implement task on the candidate's branch
+// and reviews the result
+func (f *Foreman) runCandidate(ctx context.Context, task *Task, c *candidate) {
+	// A competition interrupted by a restart may have left the branch
+	f.repo.DeleteBranch(c.Branch)
+	wt, err := f.repo.CreateWorktreeFrom(c.Branch, task.BaseBranch)
+	if err != nil {
+		c.Err = fmt.Errorf("worktree setup failed: %w", err)
+		return
+	}
+	defer f.repo.RemoveWorktree(c.Branch)
+
+	agent, ok := f.agents[c.Agent]
+	if !ok {
+		c.Err = fmt.Errorf("unknown agent: %s", c.Agent)
+		return
+	}
+
+	spec := task.Spec
+	if task.Context != "" {
+		spec = fmt.Sprintf("%s\n\n## Additional Context\n%s", task.Spec, task.Context)
+	}
+	c.Result, c.Err = agent.Execute(ctx, &agents.Task{
+		ID:           task.ID,
+		Spec:         spec,
+		WorktreePath: wt.Path,
+	})
+	transcript := f.beginTranscript(task)
+	saveAgentTranscript(transcript, fmt.Sprintf("candidate %d", c.Index), c.Agent, c.Result, c.Err)
+	if c.Result != nil {
+		f.recordUsage(task, c.Agent, c.Result.Usage)
+	}
+	if c.Err != nil || !c.Result.Success {
+		return
+	}
+
+	if err := f.repo.CommitAndPush(wt, fmt.Sprintf("Task %s: %s", task.ID, truncate(task.Spec, 50))); err != nil {
+		c.Err = fmt.Errorf("git push failed: %w", err)
+		return
+	}
+	if c.Lines, err = f.repo.DiffLines(c.Branch, f.baseBranch(task)); err != nil {
+		log.Printf("Warning: Failed to measure candidate %s: %v", c.Branch, err)
+	}
+
+	review, err := f.reviewer.Review(ctx, &agents.ReviewRequest{
+		Branch:       c.Branch,
+		BaseBranch:   f.baseBranch(task),
+		WorktreePath: wt.Path,
+		Spec:         task.Spec,
+	})
+	if err != nil {
+		saveTranscript(transcript, transcriptReview, fmt.Sprintf("Review failed: %v\n", err))
+		c.Err = fmt.Errorf("review failed: %w", err)
+		return
+	}
+	saveReviewTranscript(transcript, review)
+	c.Review = review
+	c.TestsPass = testsPassed(review)
+
+	f.recordEvent(storage.LogEvent{
+		Type:      EventReview,
+		FeatureID: task.FeatureID,
+		TaskID:    task.ID,
+		Actor:     "reviewer",
+		Message:   fmt.Sprintf("Candidate %s: %s", c.label(), review.Summary),
+		Verdict:   string(review.Verdict),
+	})
+}
+
+// discardCandidates deletes the branches of candidates
+func (f *Foreman) discardCandidates(candidates []*candidate) {
+	for _, c := range candidates {
+		f.repo.DeleteBranch(c.Branch)
+	}
+}
+
+// PickCandidate makes the chosen candidate's work the task's and approves
+// it; the other candidates are discarded. Candidates count from 1 in the
+// order they were offered.
+func (f *Foreman) PickCandidate(ctx context.Context, featureID, taskID string, n int) {
+	feature := f.getFeature(featureID)
+	var task *Task
+	if feature != nil {
+		task = feature.FindTask(taskID)
+	}
+	if task == nil {
+		f.telegram.Send(fmt.Sprintf("Task `%s` not found in feature `%s`", taskID, featureID))
+		return
+	}
+
+	task.mu.Lock()
+	offered := decodeCandidates(task.Metadata[metaCandidates])
+	task.mu.Unlock()
+	if n < 1 || n > len(offered) {
+		f.telegram.Send(fmt.Sprintf("Task `%s` has no candidate %d awaiting a pick", taskID, n))
+		return
+	}
+	winner := offered[n-1]
+
+	f.repo.RemoveWorktree(task.Branch)
+	if err := f.repo.ResetBranch(task.Branch, winner.Branch); err != nil {
+		f.telegram.Send(fmt.Sprintf("*Pick Failed*\nTask: `%s`\nError: %v", taskID, err))
+		return
+	}
+	f.discardCandidates(offered)
+
+	task.mu.Lock()
+	task.AgentName = winner.Agent
+	delete(task.Metadata, metaCandidates)
+	// Changes requested later go to the winner alone
+	delete(task.Metadata, metaCompete)
+	task.mu.Unlock()
+	clearSession(task)
+	f.markCompletedBy(task)
+	f.recordTask(task, EventTaskStatus, fmt.Sprintf("Picked the candidate of %s from %s", winner.Agent, winner.Branch), "user")
+	f.telegram.Send(fmt.Sprintf("Picked %s's implementation of `%s`", winner.Agent, taskID))
+
+	f.approveFeatureCode(ctx, featureID, taskID)
+}
+
+// parseCompeteArgs splits "[feature] <task> <agent>..." where the feature
+// is optional; "off" in place of the agents ends the competition
+func parseCompeteArgs(args string, isFeature func(string) bool) (featureID, taskID string, runs []string, err error) {
+	fields := strings.Fields(args)
+	if len(fields) > 2 && isFeature(fields[0]) {
+		featureID, fields = fields[0], fields[1:]
+	}
+	if len(fields) < 2 {
+		return "", "", nil, fmt.Errorf("expected a task and the competing agents")
+	}
+	return featureID, fields[0], fields[1:], nil
+}
+
+func (f *Foreman) handleCompete(args string) {
+	const usage = "Usage: /compete [feature] <task> <agent> <agent>...\nRepeat an agent to run it several times; /compete <task> off runs the task normally again.\nExample: /compete T-003 claude-code codex"
+
+	featureID, taskID, runs, err := parseCompeteArgs(args, func(id string) bool { return f.getFeature(id) != nil })
+	if err != nil {
+		f.telegram.Send(fmt.Sprintf("%s\n%s", err, usage))
+		return
+	}
+
+	if featureID == "" {
+		if featureID, err = f.findTaskFeature(taskID); err != nil {
+			f.telegram.Send(fmt.Sprintf("%s\n%s", err, usage))
+			return
+		}
+	}
+	var task *Task
+	if feature := f.getFeature(featureID); feature != nil {
+		task = feature.FindTask(taskID)
+	}
+	if task == nil {
+		f.telegram.Send(fmt.Sprintf("Task `%s` not found in any feature", taskID))
+		return
+	}
+	task.mu.Lock()
+	status := task.Status
+	task.mu.Unlock()
+	switch status {
+	case StatusComplete:
+		f.telegram.Send(fmt.Sprintf("Task `%s` is already complete", taskID))
+		return
+	case StatusRunning, StatusReview:
+		f.telegram.Send(fmt.Sprintf("Task `%s` is running; use /compete once it has finished", taskID))
+		return
+	}
+
+	if len(runs) == 1 && runs[0] == "off" {
+		task.mu.Lock()
+		delete(task.Metadata, metaCompete)
+		task.mu.Unlock()
+		f.recordTask(task, EventTaskStatus, "Competition ended", "user")
+		f.telegram.Send(fmt.Sprintf("Task `%s` runs with %s alone", taskID, task.AgentName))
+		return
+	}
+	if len(runs) < 2 {
+		f.telegram.Send(fmt.Sprintf("A competition needs two agents, or one agent twice\n%s", usage))
+		return
+	}
+	for _, name := range runs {
+		if _, ok := f.agents[name]; !ok {
+			f.telegram.Send(fmt.Sprintf("Unknown agent: %s\nAvailable: %v", name, f.getAgentNames()))
+			return
+		}
+	}
+
+	task.mu.Lock()
+	if task.Metadata == nil {
+		task.Metadata = make(map[string]string)
+	}
+	task.Metadata[metaCompete] = strings.Join(runs, ",")
+	task.mu.Unlock()
+	f.recordTask(task, EventTaskStatus, fmt.Sprintf("Competition between %s", strings.Join(runs, ", ")), "user")
+	f.telegram.Send(fmt.Sprintf("Task `%s` will be run by %s, and the best two offered for a pick", taskID, strings.Join(runs, ", ")))
+}
+
+func (f *Foreman) handlePickCandidate(data string) {
+	ref := strings.TrimPrefix(data, "pick_candidate:")
+	i := strings.LastIndex(ref, ":")
+	if i < 0 {
+		f.telegram.Send(fmt.Sprintf("Invalid candidate: %s", ref))
+		return
+	}
+	n, err := strconv.Atoi(ref[i+1:])
+	if err != nil {
+		f.telegram.Send(fmt.Sprintf("Invalid candidate: %s", ref))
+		return
+	}
+	featureID, taskID := parseFeatureTaskRef(ref[:i])
+	f.PickCandidate(context.Background(), featureID, taskID, n)
+}
@@ -0,0 +1,150 @@
+package foreman
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bayological/foreman/internal/agents"
+)
+
+func TestCompetitors(t *testing.T) {
+	tests := []struct {
+		names   []string
+		samples int
+		want    []string
+	}{
+		{[]string{"claude-code", "codex"}, 0, []string{"claude-code", "codex"}},
+		{[]string{"claude-code", "codex"}, 2, []string{"claude-code", "claude-code", "codex", "codex"}},
+		{[]string{"codex"}, 3, []string{"codex", "codex", "codex"}},
+		{nil, 2, nil},
+	}
+
+	for _, tc := range tests {
+		if got := competitors(tc.names, tc.samples); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("competitors(%v, %d) = %v, want %v", tc.names, tc.samples, got, tc.want)
+		}
+	}
+}
+
+func reviewed(index int, verdict agents.ReviewVerdict, testsPass bool, lines int) *candidate {
+	return &candidate{
+		Index:     index,
+		Agent:     "agent",
+		Result:    &agents.TaskResult{Success: true},
+		Review:    &agents.ReviewResult{Verdict: verdict},
+		TestsPass: testsPass,
+		Lines:     lines,
+	}
+}
+
+func TestRankCandidates(t *testing.T) {
+	candidates := []*candidate{
+		{Index: 1, Agent: "agent", Err: errors.New("crashed")},
+		reviewed(2, agents.VerdictRequestChanges, true, 10),
+		reviewed(3, agents.VerdictApprove, false, 10),
+		reviewed(4, agents.VerdictApprove, true, 300),
+		reviewed(5, agents.VerdictApprove, true, 40),
+		{Index: 6, Agent: "agent", Result: &agents.TaskResult{Summary: "gave up"}},
+		reviewed(7, agents.VerdictBlock, true, 1),
+	}
+
+	var order []int
+	for _, c := range rankCandidates(candidates) {
+		order = append(order, c.Index)
+	}
+	// Verdict first, then passing tests, then the smaller diff; failures last
+	if want := []int{5, 4, 3, 2, 7, 1, 6}; !reflect.DeepEqual(order, want) {
+		t.Errorf("ranking = %v, want %v", order, want)
+	}
+	if candidates[0].Index != 1 {
+		t.Error("rankCandidates should not reorder its argument")
+	}
+}
+
+func TestTestsPassed(t *testing.T) {
+	tests := []struct {
+		output string
+		want   bool
+	}{
+		{"ok  \tpkg\t0.1s", true},
+		{"--- FAILED: TestLogin", false},
+		{"ERROR: exit status 1", false},
+		{"", true},
+	}
+
+	for _, tc := range tests {
+		review := &agents.ReviewResult{ToolOutputs: map[string]string{"tests": tc.output}}
+		if got := testsPassed(review); got != tc.want {
+			t.Errorf("testsPassed(%q) = %v, want %v", tc.output, got, tc.want)
+		}
+	}
+}
+
+func TestCandidatesRoundTrip(t *testing.T) {
+	offered := []*candidate{
+		{Agent: "codex", Branch: "feature/1-login/T-001-c2"},
+		{Agent: "claude-code", Branch: "feature/1-login/T-001-c1"},
+	}
+
+	decoded := decodeCandidates(encodeCandidates(offered))
+	if len(decoded) != 2 {
+		t.Fatalf("decoded %d candidates, want 2", len(decoded))
+	}
+	for i, c := range decoded {
+		if c.Agent != offered[i].Agent || c.Branch != offered[i].Branch {
+			t.Errorf("candidate %d = %s on %s, want %s on %s", i+1, c.Agent, c.Branch, offered[i].Agent, offered[i].Branch)
+		}
+	}
+	if decodeCandidates("") != nil {
+		t.Error("no candidates should decode to nil")
+	}
+}
+
+func TestCompetitionReport(t *testing.T) {
+	winner := reviewed(2, agents.VerdictApprove, true, 12)
+	winner.Agent, winner.Branch = "codex", "feature/1/T-001-c2"
+	winner.Review.Summary = "All checks\npassed"
+	loser := &candidate{Index: 1, Agent: "claude-code", Err: errors.New("timed out")}
+
+	report := competitionReport([]*candidate{winner, loser})
+	for _, want := range []string{
+		"1. `codex #2` APPROVE, tests pass, 12 lines",
+		"Branch: `feature/1/T-001-c2`",
+		"All checks passed",
+		"2. `claude-code #1` failed: timed out",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestParseCompeteArgs(t *testing.T) {
+	isFeature := func(id string) bool { return id == "12345" }
+	tests := []struct {
+		input       string
+		wantFeature string
+		wantTask    string
+		wantRuns    []string
+		wantErr     bool
+	}{
+		{"T-001 claude-code codex", "", "T-001", []string{"claude-code", "codex"}, false},
+		{"12345 T-001 codex codex", "12345", "T-001", []string{"codex", "codex"}, false},
+		{"12345 T-001", "", "12345", []string{"T-001"}, false},
+		{"T-001 off", "", "T-001", []string{"off"}, false},
+		{"T-001", "", "", nil, true},
+	}
+
+	for _, tc := range tests {
+		feature, task, runs, err := parseCompeteArgs(tc.input, isFeature)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parseCompeteArgs(%q) error = %v", tc.input, err)
+			continue
+		}
+		if feature != tc.wantFeature || task != tc.wantTask || !reflect.DeepEqual(runs, tc.wantRuns) {
+			t.Errorf("parseCompeteArgs(%q) = %q, %q, %v", tc.input, feature, task, runs)
+		}
+	}
+}
@@ -3,8 +3,10 @@ package foreman
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
+	"github.com/bayological/foreman/internal/storage"
 	"gopkg.in/yaml.v3"
 )
 
@@ -15,12 +17,50 @@ type Config struct {
 	Review           ReviewConfig      `yaml:"review"`
 	Concurrency      ConcurrencyConfig `yaml:"concurrency"`
 	Storage          StorageConfig     `yaml:"storage"`
+	Forge            ForgeConfig       `yaml:"forge"`
+	Budget           BudgetConfig      `yaml:"budget"`
+	Routing          []RouteConfig     `yaml:"routing"`
 	DefaultAgent     string            `yaml:"default_agent"`
 	DefaultTechStack string            `yaml:"default_tech_stack"`
 }
 
+// StorageConfig sets where Foreman keeps its state. The file backend locks
+// features.json so a second Foreman cannot share it; the lock works on Unix
+// and Windows only, and elsewhere a warning is logged instead.
 type StorageConfig struct {
-	Path string `yaml:"path"` // Path to features.json file
+	Backend  string `yaml:"backend"`   // file (default) or sqlite
+	Path     string `yaml:"path"`      // Path to features.json or the SQLite database
+	Backups  int    `yaml:"backups"`   // Backups of features.json to keep; -1 disables them
+	EventLog string `yaml:"event_log"` // Append-only event log; defaults to events.jsonl next to path
+
+	// Agent and review tool output per task attempt; defaults to the
+	// transcripts directory next to path. Retention defaults to 30 days;
+	// a negative retention keeps transcripts forever.
+	Transcripts         string        `yaml:"transcripts"`
+	TranscriptRetention time.Duration `yaml:"transcript_retention"`
+}
+
+// EventLogPath returns the path of the event log, or "" when storage is not
+// configured
+func (c StorageConfig) EventLogPath() string {
+	if c.EventLog != "" || c.Path == "" {
+		return c.EventLog
+	}
+	return filepath.Join(filepath.Dir(c.Path), "events.jsonl")
+}
+
+// TranscriptsPath returns the transcript directory, or "" when storage is
+// not configured
+func (c StorageConfig) TranscriptsPath() string {
+	if c.Transcripts != "" || c.Path == "" {
+		return c.Transcripts
+	}
+	return filepath.Join(filepath.Dir(c.Path), "transcripts")
+}
+
+// Options returns the options for opening the configured storage
+func (c StorageConfig) Options() storage.Options {
+	return storage.Options{Backend: c.Backend, Path: c.Path, Backups: c.Backups}
 }
 
 type RepoConfig struct {
@@ -29,20 +69,83 @@ type RepoConfig struct {
 	MainBranch string `yaml:"main_branch"`
 }
 
+// ForgeConfig selects the code hosting service pull requests are opened on.
+// URL and project are derived from the repo remote when left empty.
+type ForgeConfig struct {
+	Type    string `yaml:"type"` // github, gitlab or gitea
+	URL     string `yaml:"url"`
+	APIURL  string `yaml:"api_url"`
+	Project string `yaml:"project"`
+	Token   string `yaml:"token"`
+}
+
+// BudgetConfig limits what agents may spend, in US dollars. Zero means no
+// limit. A task that would start over budget is held until the budget is
+// approved in Telegram, which raises it by the same amount again.
+type BudgetConfig struct {
+	PerFeature float64 `yaml:"per_feature"`
+	Daily      float64 `yaml:"daily"`
+}
+
 type TelegramConfig struct {
 	Token  string `yaml:"token"`
 	ChatID int64  `yaml:"chat_id"`
 }
 
+// AgentConfig configures one coding agent. The claude-code and codex names
+// use the built-in adapters; any other agent runs the command described by
+// the CLI fields.
 type AgentConfig struct {
 	Enabled  bool          `yaml:"enabled"`
 	Timeout  time.Duration `yaml:"timeout"`
 	Priority int           `yaml:"priority"`
+	Type     string        `yaml:"type"` // claude-code, codex, cli, openai or scripted; defaults from the agent name
+
+	// CLI agents
+	Command          string            `yaml:"command"`
+	Args             []string          `yaml:"args"` // {prompt}, {prompt_file}, {worktree}, {model}, {task_id}
+	Model            string            `yaml:"model"`
+	Env              map[string]string `yaml:"env"`
+	Prompt           string            `yaml:"prompt"`    // arg (default), stdin or file
+	Output           string            `yaml:"output"`    // plain (default) or jsonl
+	TextPath         string            `yaml:"text_path"` // where the text is in each JSON line
+	SuccessExitCodes []int             `yaml:"success_exit_codes"`
+	SuccessPattern   string            `yaml:"success_pattern"`
+	FailurePattern   string            `yaml:"failure_pattern"`
+
+	// OpenAI-compatible agents; Model names the served model
+	BaseURL         string   `yaml:"base_url"` // e.g. http://localhost:11434/v1
+	APIKey          string   `yaml:"api_key"`
+	MaxTurns        int      `yaml:"max_turns"`
+	AllowedCommands []string `yaml:"allowed_commands"` // programs the model may run
+
+	// Scripted agents replay pre-recorded edits, for offline testing
+	Script string `yaml:"script"`
 }
 
-type AgentsConfig struct {
-	ClaudeCode AgentConfig `yaml:"claude-code"`
-	Codex      AgentConfig `yaml:"codex"`
+// AgentsConfig holds the agent configurations, keyed by agent name
+type AgentsConfig map[string]AgentConfig
+
+// RouteConfig sends the tasks it matches to an agent, or has several
+// agents compete for them. Every condition set must hold; a condition
+// listing several values holds when any of them does. Words match
+// case-insensitively and whole, so "go" does not match "mongodb". A route
+// without conditions matches every task.
+type RouteConfig struct {
+	Agent   string        `yaml:"agent"`   // defaults to the first competing agent
+	Timeout time.Duration `yaml:"timeout"` // defaults to concurrency.task_timeout
+
+	// Competing agents each implement the task in their own worktree, and
+	// the best two are offered for a pick. Samples runs each of them
+	// several times.
+	Compete []string `yaml:"compete"`
+	Samples int      `yaml:"samples"`
+
+	IsTest    *bool    `yaml:"is_test"`
+	UserStory []string `yaml:"user_story"` // words in the user story or phase heading
+	Files     []string `yaml:"files"`      // globs over the task's file paths: *.tsx, web/**
+	Keywords  []string `yaml:"keywords"`   // words in the task title
+	TechStack []string `yaml:"tech_stack"` // words in the feature's tech stack
 }
 
 type ReviewConfig struct {
@@ -92,6 +195,9 @@ func LoadConfig(path string) (*Config, error) {
 	if cfg.Review.MaxRetries == 0 {
 		cfg.Review.MaxRetries = 2
 	}
+	if cfg.Storage.TranscriptRetention == 0 {
+		cfg.Storage.TranscriptRetention = storage.DefaultTranscriptRetention
+	}
 	if cfg.DefaultAgent == "" {
 		cfg.DefaultAgent = "claude-code"
 	}
@@ -0,0 +1,357 @@
+package foreman
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bayological/foreman/internal/agents"
+	"github.com/bayological/foreman/internal/storage"
+)
+
+// Budget scopes; a scope is the kind followed by the feature ID or the day
+const (
+	budgetFeature = "feature"
+	budgetDaily   = "daily"
+)
+
+// usageTotals sums the usage of several agent runs
+type usageTotals struct {
+	Runs         int
+	InputTokens  int
+	OutputTokens int
+	Turns        int
+	CostUSD      float64
+}
+
+func (t *usageTotals) add(u *storage.UsageState) {
+	t.Runs++
+	t.InputTokens += u.InputTokens
+	t.OutputTokens += u.OutputTokens
+	t.Turns += u.Turns
+	t.CostUSD += u.CostUSD
+}
+
+// sumUsage totals runs grouped by key. Runs with an empty key are skipped.
+func sumUsage(runs []*storage.UsageState, key func(*storage.UsageState) string) map[string]*usageTotals {
+	totals := make(map[string]*usageTotals)
+	for _, u := range runs {
+		k := key(u)
+		if k == "" {
+			continue
+		}
+		if totals[k] == nil {
+			totals[k] = &usageTotals{}
+		}
+		totals[k].add(u)
+	}
+	return totals
+}
+
+// recordUsage persists what one agent run on a task consumed
+func (f *Foreman) recordUsage(task *Task, agentName string, usage agents.Usage) {
+	if f.usage == nil || usage == (agents.Usage{}) {
+		return
+	}
+
+	err := f.usage.SaveUsage(&storage.UsageState{
+		FeatureID:    task.FeatureID,
+		TaskID:       task.ID,
+		Agent:        agentName,
+		Attempt:      task.Attempt,
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		CostUSD:      usage.CostUSD,
+		Turns:        usage.Turns,
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to save usage of task %s: %v", task.ID, err)
+	}
+}
+
+// loadUsage returns the runs recorded since the given time
+func (f *Foreman) loadUsage(since time.Time) []*storage.UsageState {
+	if f.usage == nil {
+		return nil
+	}
+	runs, err := f.usage.LoadUsage(since)
+	if err != nil {
+		log.Printf("Warning: Failed to load usage: %v", err)
+	}
+	return runs
+}
+
+// loadBudgetApprovals returns the approvals to go over each budget, which
+// outlive a restart
+func (f *Foreman) loadBudgetApprovals() map[string]int {
+	approvals, err := f.usage.LoadBudgetApprovals()
+	if err != nil {
+		log.Printf("Warning: Failed to load budget approvals: %v", err)
+	}
+	if approvals == nil {
+		approvals = make(map[string]int)
+	}
+	return approvals
+}
+
+// startOfDay returns local midnight of t's day; daily budgets reset then
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// budgetLimit is a scope's budget, raised by its base amount once for each
+// approval to go over it
+func (f *Foreman) budgetLimit(scope string, base float64) float64 {
+	f.budgetMu.Lock()
+	defer f.budgetMu.Unlock()
+	return base * float64(1+f.budgetApprovals[scope])
+}
+
+// exceededBudget returns the scope of the first budget that task may not
+// spend from, with what was spent and the limit. It returns "" when the
+// task may run.
+func (f *Foreman) exceededBudget(task *Task, now time.Time) (scope string, spent, limit float64) {
+	budget := f.cfg.Budget
+	if budget.PerFeature <= 0 && budget.Daily <= 0 {
+		return "", 0, 0
+	}
+
+	if budget.PerFeature > 0 && task.FeatureID != "" {
+		scope := budgetFeature + ":" + task.FeatureID
+		limit := f.budgetLimit(scope, budget.PerFeature)
+		spent := 0.0
+		if totals := sumUsage(f.loadUsage(time.Time{}), func(u *storage.UsageState) string { return u.FeatureID })[task.FeatureID]; totals != nil {
+			spent = totals.CostUSD
+		}
+		if spent >= limit {
+			return scope, spent, limit
+		}
+	}
+
+	if budget.Daily > 0 {
+		day := startOfDay(now)
+		scope := budgetDaily + ":" + day.Format("2006-01-02")
+		limit := f.budgetLimit(scope, budget.Daily)
+		spent := 0.0
+		for _, u := range f.loadUsage(day) {
+			spent += u.CostUSD
+		}
+		if spent >= limit {
+			return scope, spent, limit
+		}
+	}
+
+	return "", 0, 0
+}
+
+// holdForBudget keeps a task from starting while it would run over a
+// budget. Held tasks wait for the budget to be approved; the first task
+// held under a scope asks for that approval. It reports whether the task
+// was held.
+func (f *Foreman) holdForBudget(task *Task) bool {
+	scope, spent, limit := f.exceededBudget(task, time.Now())
+	if scope == "" {
+		return false
+	}
+
+	f.budgetMu.Lock()
+	if f.budgetHeld == nil {
+		f.budgetHeld = make(map[string][]*Task)
+	}
+	f.budgetHeld[scope] = append(f.budgetHeld[scope], task)
+	first := len(f.budgetHeld[scope]) == 1
+	f.budgetMu.Unlock()
+
+	task.setStatus(StatusPending)
+	f.recordTask(task, EventTaskStatus, fmt.Sprintf("Held: %s budget of %s reached", scope, formatCost(limit)), "foreman")
+
+	if first {
+		kind, id, _ := strings.Cut(scope, ":")
+		what := fmt.Sprintf("Today's spending is %s of the %s daily budget.", formatCost(spent), formatCost(limit))
+		raise := f.cfg.Budget.Daily
+		if kind == budgetFeature {
+			what = fmt.Sprintf("Feature `%s` has spent %s of its %s budget.", id, formatCost(spent), formatCost(limit))
+			raise = f.cfg.Budget.PerFeature
+		}
+		f.telegram.RequestBudgetApproval(scope, fmt.Sprintf("%s\nTask `%s` is on hold until the budget is approved.", what, task.ID), formatCost(raise))
+	}
+	return true
+}
+
+// handleApproveBudget raises a budget by its configured amount and queues
+// the tasks held under it again
+func (f *Foreman) handleApproveBudget(data string) {
+	scope := strings.TrimPrefix(data, "approve_budget:")
+
+	// Only the first press of the button raises the budget
+	f.budgetMu.Lock()
+	held := f.budgetHeld[scope]
+	if len(held) == 0 {
+		f.budgetMu.Unlock()
+		f.telegram.Send(fmt.Sprintf("No tasks are held by budget `%s`", scope))
+		return
+	}
+	if f.budgetApprovals == nil {
+		f.budgetApprovals = make(map[string]int)
+	}
+	f.budgetApprovals[scope]++
+	delete(f.budgetHeld, scope)
+	f.budgetMu.Unlock()
+
+	if f.usage != nil {
+		if err := f.usage.ApproveBudget(scope); err != nil {
+			log.Printf("Warning: Failed to save approval of budget %s: %v", scope, err)
+		}
+	}
+
+	f.recordEvent(storage.LogEvent{
+		Type:    EventApproval,
+		Actor:   "user",
+		Message: "Budget raised",
+		Data:    map[string]string{"stage": "budget", "scope": scope},
+	})
+
+	for _, task := range held {
+		f.enqueue(task)
+	}
+	f.telegram.Send(fmt.Sprintf("Budget `%s` raised. Resuming %d task(s).", scope, len(held)))
+}
+
+// handleStopBudget fails the tasks held under a budget
+func (f *Foreman) handleStopBudget(data string) {
+	scope := strings.TrimPrefix(data, "stop_budget:")
+
+	f.budgetMu.Lock()
+	held := f.budgetHeld[scope]
+	delete(f.budgetHeld, scope)
+	f.budgetMu.Unlock()
+
+	for _, task := range held {
+		f.failTask(task, fmt.Errorf("stopped: %s budget exceeded", scope))
+		f.saveTaskFeature(task)
+	}
+	if len(held) == 0 {
+		f.telegram.Send(fmt.Sprintf("No tasks are held by budget `%s`", scope))
+	}
+}
+
+func (f *Foreman) handleCosts(args string) {
+	if featureID := strings.TrimSpace(args); featureID != "" {
+		f.telegram.Send(f.featureCosts(featureID))
+		return
+	}
+	f.telegram.Send(f.costSummary(time.Now()))
+}
+
+// costSummary reports today's spending and the totals per feature and agent
+func (f *Foreman) costSummary(now time.Time) string {
+	runs := f.loadUsage(time.Time{})
+	if len(runs) == 0 {
+		return "No agent usage recorded yet"
+	}
+
+	var b strings.Builder
+	b.WriteString("*Costs*\n\n")
+
+	var today, all usageTotals
+	day := startOfDay(now)
+	for _, u := range runs {
+		all.add(u)
+		if !u.CreatedAt.Before(day) {
+			today.add(u)
+		}
+	}
+
+	fmt.Fprintf(&b, "Today: %s", formatCost(today.CostUSD))
+	if daily := f.cfg.Budget.Daily; daily > 0 {
+		fmt.Fprintf(&b, " of %s", formatCost(f.budgetLimit(budgetDaily+":"+day.Format("2006-01-02"), daily)))
+	}
+	fmt.Fprintf(&b, " (%d runs)\nAll time: %s\n", today.Runs, describeTotals(&all))
+
+	byFeature := sumUsage(runs, func(u *storage.UsageState) string { return u.FeatureID })
+	if len(byFeature) > 0 {
+		b.WriteString("\n*By feature:*\n")
+		for _, id := range sortedKeys(byFeature) {
+			name := ""
+			if feature := f.getFeature(id); feature != nil {
+				name = " " + feature.Name
+			}
+			fmt.Fprintf(&b, "  - `%s`%s: %s", id, name, describeTotals(byFeature[id]))
+			if per := f.cfg.Budget.PerFeature; per > 0 {
+				fmt.Fprintf(&b, " of %s", formatCost(f.budgetLimit(budgetFeature+":"+id, per)))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	byAgent := sumUsage(runs, func(u *storage.UsageState) string { return u.Agent })
+	b.WriteString("\n*By agent:*\n")
+	for _, name := range sortedKeys(byAgent) {
+		fmt.Fprintf(&b, "  - %s: %s\n", name, describeTotals(byAgent[name]))
+	}
+
+	return b.String()
+}
+
+// featureCosts reports a feature's spending per task
+func (f *Foreman) featureCosts(featureID string) string {
+	var runs []*storage.UsageState
+	for _, u := range f.loadUsage(time.Time{}) {
+		if u.FeatureID == featureID {
+			runs = append(runs, u)
+		}
+	}
+	if len(runs) == 0 {
+		return fmt.Sprintf("No agent usage recorded for feature `%s`", featureID)
+	}
+
+	var total usageTotals
+	for _, u := range runs {
+		total.add(u)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*Costs for* `%s`\n\nTotal: %s", featureID, describeTotals(&total))
+	if per := f.cfg.Budget.PerFeature; per > 0 {
+		fmt.Fprintf(&b, " of %s", formatCost(f.budgetLimit(budgetFeature+":"+featureID, per)))
+	}
+	b.WriteString("\n\n*By task:*\n")
+
+	byTask := sumUsage(runs, func(u *storage.UsageState) string { return u.TaskID })
+	for _, id := range sortedKeys(byTask) {
+		fmt.Fprintf(&b, "  - `%s`: %s, %d turns\n", id, describeTotals(byTask[id]), byTask[id].Turns)
+	}
+	return b.String()
+}
+
+func describeTotals(t *usageTotals) string {
+	return fmt.Sprintf("%s, %s in / %s out tokens (%d runs)",
+		formatCost(t.CostUSD), formatTokens(t.InputTokens), formatTokens(t.OutputTokens), t.Runs)
+}
+
+func formatCost(usd float64) string {
+	return fmt.Sprintf("$%.2f", usd)
+}
+
+// formatTokens abbreviates token counts: 950, 12.3k, 1.2M
+func formatTokens(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+func sortedKeys(m map[string]*usageTotals) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,161 @@
+package foreman
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bayological/foreman/internal/agents"
+	"github.com/bayological/foreman/internal/storage"
+)
+
+func newCostsForeman(budget BudgetConfig) *Foreman {
+	return &Foreman{
+		cfg:      &Config{Budget: budget},
+		features: make(map[string]*Feature),
+		usage:    storage.NewMemory(),
+	}
+}
+
+func TestRecordUsage(t *testing.T) {
+	f := newCostsForeman(BudgetConfig{})
+	task := &Task{ID: "T001", FeatureID: "f1", Attempt: 1}
+
+	f.recordUsage(task, "claude-code", agents.Usage{InputTokens: 1200, OutputTokens: 300, CostUSD: 0.4, Turns: 5})
+	// Agents that report nothing leave no record
+	f.recordUsage(task, "aider", agents.Usage{})
+
+	runs := f.loadUsage(time.Time{})
+	if len(runs) != 1 {
+		t.Fatalf("expected 1 run, got %d", len(runs))
+	}
+	u := runs[0]
+	if u.FeatureID != "f1" || u.TaskID != "T001" || u.Agent != "claude-code" || u.Attempt != 1 || u.CostUSD != 0.4 || u.Turns != 5 {
+		t.Errorf("run = %+v", u)
+	}
+}
+
+func TestExceededBudget(t *testing.T) {
+	now := time.Now()
+	yesterday := now.Add(-24 * time.Hour)
+
+	f := newCostsForeman(BudgetConfig{PerFeature: 1, Daily: 2})
+	for _, u := range []*storage.UsageState{
+		{FeatureID: "f1", TaskID: "T001", Agent: "claude-code", CostUSD: 0.75, CreatedAt: yesterday},
+		{FeatureID: "f1", TaskID: "T002", Agent: "claude-code", CostUSD: 0.5},
+		{FeatureID: "f2", TaskID: "T001", Agent: "claude-code", CostUSD: 1.25},
+		{TaskID: "42", Agent: "codex", CostUSD: 0.2},
+	} {
+		f.usage.SaveUsage(u)
+	}
+
+	day := budgetDaily + ":" + startOfDay(now).Format("2006-01-02")
+	tests := []struct {
+		name      string
+		task      *Task
+		wantScope string
+		wantSpent float64
+	}{
+		// Feature budgets count every day's spending
+		{"feature over budget", &Task{ID: "T003", FeatureID: "f1"}, "feature:f1", 1.25},
+		{"standalone within daily budget", &Task{ID: "43"}, "", 0},
+	}
+	for _, tt := range tests {
+		scope, spent, _ := f.exceededBudget(tt.task, now)
+		if scope != tt.wantScope || spent != tt.wantSpent {
+			t.Errorf("%s: exceededBudget() = %q, %v; want %q, %v", tt.name, scope, spent, tt.wantScope, tt.wantSpent)
+		}
+	}
+
+	// Approving a budget raises it by the configured amount
+	f.budgetApprovals = map[string]int{"feature:f1": 1}
+	if scope, _, _ := f.exceededBudget(&Task{ID: "T003", FeatureID: "f1"}, now); scope != "" {
+		t.Errorf("approved feature budget still exceeded: %q", scope)
+	}
+
+	// Today's spending (0.5 + 1.25 + 0.2) plus another run hits the daily budget
+	f.usage.SaveUsage(&storage.UsageState{TaskID: "44", Agent: "codex", CostUSD: 0.05})
+	scope, spent, limit := f.exceededBudget(&Task{ID: "45"}, now)
+	if scope != day || spent < 2 || limit != 2 {
+		t.Errorf("exceededBudget() = %q, %v, %v; want %q over 2", scope, spent, limit, day)
+	}
+
+	// Without budgets nothing is held
+	f.cfg.Budget = BudgetConfig{}
+	if scope, _, _ := f.exceededBudget(&Task{ID: "45"}, now); scope != "" {
+		t.Errorf("exceededBudget() without budgets = %q", scope)
+	}
+}
+
+func TestBudgetApprovalsOutliveRestart(t *testing.T) {
+	f := newCostsForeman(BudgetConfig{PerFeature: 1})
+	f.usage.SaveUsage(&storage.UsageState{FeatureID: "f1", TaskID: "T001", Agent: "claude-code", CostUSD: 1.5})
+	if err := f.usage.ApproveBudget("feature:f1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restarted Foreman reads the approval back from storage
+	restarted := newCostsForeman(f.cfg.Budget)
+	restarted.usage = f.usage
+	restarted.budgetApprovals = restarted.loadBudgetApprovals()
+	if scope, _, limit := restarted.exceededBudget(&Task{ID: "T002", FeatureID: "f1"}, time.Now()); scope != "" {
+		t.Errorf("approved feature budget exceeded after a restart: %q, limit %v", scope, limit)
+	}
+}
+
+func TestCostSummary(t *testing.T) {
+	f := newCostsForeman(BudgetConfig{PerFeature: 10})
+	if got := f.costSummary(time.Now()); got != "No agent usage recorded yet" {
+		t.Errorf("empty summary = %q", got)
+	}
+
+	feature := NewFeature("f1", "Auth", "Login")
+	f.features[feature.ID] = feature
+	for _, u := range []*storage.UsageState{
+		{FeatureID: "f1", TaskID: "T001", Agent: "claude-code", InputTokens: 1_500_000, OutputTokens: 20_000, CostUSD: 3.5, Turns: 12},
+		{FeatureID: "f1", TaskID: "T002", Agent: "local", InputTokens: 900, OutputTokens: 100, Turns: 2},
+		{TaskID: "42", Agent: "claude-code", CostUSD: 0.5, CreatedAt: time.Now().Add(-48 * time.Hour)},
+	} {
+		f.usage.SaveUsage(u)
+	}
+
+	summary := f.costSummary(time.Now())
+	for _, want := range []string{
+		"Today: $3.50 (2 runs)",
+		"All time: $4.00, 1.5M in / 20.1k out tokens (3 runs)",
+		"`f1` Auth: $3.50, 1.5M in / 20.1k out tokens (2 runs) of $10.00",
+		"claude-code: $4.00",
+		"local: $0.00, 900 in / 100 out tokens (1 runs)",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary lacks %q:\n%s", want, summary)
+		}
+	}
+
+	costs := f.featureCosts("f1")
+	for _, want := range []string{"Total: $3.50", "`T001`: $3.50, 1.5M in / 20.0k out tokens (1 runs), 12 turns", "`T002`"} {
+		if !strings.Contains(costs, want) {
+			t.Errorf("feature costs lack %q:\n%s", want, costs)
+		}
+	}
+	if got := f.featureCosts("f2"); !strings.Contains(got, "No agent usage") {
+		t.Errorf("featureCosts() of unknown feature = %q", got)
+	}
+}
+
+func TestFormatTokens(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "0"},
+		{950, "950"},
+		{12_345, "12.3k"},
+		{1_200_000, "1.2M"},
+	}
+	for _, tt := range tests {
+		if got := formatTokens(tt.n); got != tt.want {
+			t.Errorf("formatTokens(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package foreman
+
+import (
+	"context"
+
+	"github.com/bayological/foreman/internal/agents"
+	"github.com/bayological/foreman/internal/forge"
+	"github.com/bayological/foreman/internal/speckit"
+	"github.com/bayological/foreman/internal/telegram"
+)
+
+// Chat is the conversation Foreman is driven through. It delivers commands,
+// button presses and plain replies to the registered handlers, and carries
+// Foreman's messages and approval requests back to the user. The Telegram
+// bot is the production implementation.
+type Chat interface {
+	RegisterCommand(name string, handler telegram.CommandHandler)
+	RegisterCallback(prefix string, handler telegram.CallbackHandler)
+	RegisterMessageHandler(handler telegram.MessageHandler)
+	Listen(ctx context.Context)
+
+	Send(message string) error
+	SendTracked(message string) (int, error)
+	Edit(messageID int, message string) error
+	SendDocument(name string, data []byte, caption string) error
+
+	RequestApproval(taskID, summary, prURL string) error
+	Escalate(taskID, reason, details string) error
+	RequestPhaseApproval(featureID, phase, summary, extra string) error
+	RequestCodeApproval(featureID, taskID, summary, extra string) error
+	RequestResolutionApproval(featureID, taskID, summary, extra string) error
+	RequestCandidatePick(featureID, taskID, summary string, candidates []string) error
+	RequestBudgetApproval(scope, summary, raise string) error
+}
+
+// SpecRunner runs the SpecKit commands that write a feature's spec, plan
+// and tasks, and finds the feature directories they write to
+type SpecRunner interface {
+	Initialize(ctx context.Context, workDir string) error
+	Constitution(ctx context.Context, principles string) (*speckit.CommandResult, error)
+	Specify(ctx context.Context, description string, ws *speckit.Workspace) (*speckit.CommandResult, error)
+	Clarify(ctx context.Context, ws *speckit.Workspace) (*speckit.CommandResult, error)
+	Plan(ctx context.Context, techStack string, ws *speckit.Workspace) (*speckit.CommandResult, error)
+	Tasks(ctx context.Context, ws *speckit.Workspace) (*speckit.CommandResult, error)
+
+	FeatureDirIn(workDir, name string) string
+	ListFeatureDirs(workDir string) []string
+	DetectFeatureDir(workDir string, before []string, output string) (string, error)
+}
+
+var (
+	_ Chat       = (*telegram.Bot)(nil)
+	_ SpecRunner = (*speckit.SpecKit)(nil)
+)
+
+// Deps are the collaborators Foreman works through. New builds them from
+// the config; NewWithDeps takes them ready-made, e.g. fakes in tests.
+type Deps struct {
+	Chat    Chat
+	SpecKit SpecRunner
+	Agents  map[string]agents.Agent // keyed by agent name
+
+	// Forge opens pull requests. When nil it is derived from the config and
+	// the repo remote, as New does.
+	Forge forge.Forge
+
+	// Preflight checks the tools Foreman relies on, at startup and for
+	// /doctor. When nil it is Preflight, which probes the host.
+	Preflight func(ctx context.Context, cfg *Config) PreflightReport
+}
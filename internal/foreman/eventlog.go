@@ -0,0 +1,169 @@
+package foreman
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/bayological/foreman/internal/storage"
+)
+
+// Event log entry types
+const (
+	EventFeatureCreated = "feature_created"
+	EventTransition     = "transition"
+	EventSpecDir        = "spec_dir"
+	EventTasksGenerated = "tasks_generated"
+	EventApproval       = "approval"
+	EventRejection      = "rejection"
+	EventFeedback       = "feedback"
+	EventAnswers        = "answers"
+	EventTaskAttempt    = "task_attempt"
+	EventTaskStatus     = "task_status"
+	EventReview         = "review"
+	EventPullRequest    = "pull_request"
+)
+
+// recordEvent appends an event to the event log, if one is configured.
+// Failing to record an event never stops the workflow.
+func (f *Foreman) recordEvent(event storage.LogEvent) {
+	if f.events == nil {
+		return
+	}
+	if err := f.events.Append(event); err != nil {
+		log.Printf("Warning: Failed to record %s event: %v", event.Type, err)
+	}
+}
+
+// recordTask records a task event along with the task as it is now
+func (f *Foreman) recordTask(task *Task, eventType, message, actor string) {
+	state := taskToState(task)
+	f.recordEvent(storage.LogEvent{
+		Type:      eventType,
+		FeatureID: task.FeatureID,
+		TaskID:    task.ID,
+		Actor:     actor,
+		Message:   message,
+		Task:      &state,
+	})
+}
+
+// recordStage records an approval, rejection or feedback on one stage of a
+// feature: spec, plan, tasks or code
+func (f *Foreman) recordStage(eventType, featureID, stage, message string) {
+	f.recordEvent(storage.LogEvent{
+		Type:      eventType,
+		FeatureID: featureID,
+		Actor:     "user",
+		Message:   message,
+		Data:      map[string]string{"stage": stage},
+	})
+}
+
+// transition moves a feature to another phase and records the transition
+func (f *Foreman) transition(feature *Feature, to Phase, message, actor string) error {
+	from := feature.GetPhase()
+	if err := feature.Transition(to, message, actor); err != nil {
+		return err
+	}
+
+	f.recordEvent(storage.LogEvent{
+		Type:      EventTransition,
+		FeatureID: feature.ID,
+		Actor:     actor,
+		Message:   message,
+		FromPhase: string(from),
+		ToPhase:   string(to),
+	})
+	return nil
+}
+
+// transitionTask moves a feature for one of its tasks. A move the phase
+// does not allow, e.g. once the feature was cancelled, is logged: the task
+// carries on regardless.
+func (f *Foreman) transitionTask(feature *Feature, to Phase, message, actor string) {
+	if err := f.transition(feature, to, message, actor); err != nil {
+		log.Printf("Warning: Feature %s: %v", feature.ID, err)
+	}
+}
+
+// ReplayFeature rebuilds a feature from its events in the event log. The
+// log must hold the feature's creation.
+func ReplayFeature(events []storage.LogEvent, featureID string) (*Feature, error) {
+	var feature *Feature
+
+	for _, e := range storage.FeatureEvents(events, featureID) {
+		if feature == nil {
+			if e.Type != EventFeatureCreated || e.Feature == nil {
+				return nil, fmt.Errorf("event log for feature %s does not start with its creation", featureID)
+			}
+			feature = stateToFeature(e.Feature, 0)
+			continue
+		}
+
+		switch e.Type {
+		case EventTransition:
+			feature.Phase = Phase(e.ToPhase)
+			feature.Events = append(feature.Events, WorkflowEvent{
+				Timestamp: e.Timestamp,
+				FromPhase: Phase(e.FromPhase),
+				ToPhase:   Phase(e.ToPhase),
+				Message:   e.Message,
+				Actor:     e.Actor,
+			})
+
+		case EventSpecDir:
+			feature.SpecDir = e.Message
+
+		case EventTasksGenerated:
+			feature.Tasks = nil
+			for _, ts := range e.Tasks {
+				feature.Tasks = append(feature.Tasks, stateToTask(ts, 0))
+			}
+			feature.TaskIndex = 0
+			feature.CurrentTask = nil
+
+		case EventFeedback:
+			// Feedback on a stage re-runs it with the feedback as constraints.
+			// Code feedback lands in the task, which carries its own snapshot.
+			if e.Data["stage"] != "code" {
+				feature.Constraints = e.Message
+			}
+
+		case EventAnswers:
+			for id, answer := range e.Data {
+				feature.Answers[id] = answer
+			}
+
+		case EventPullRequest:
+			if n, err := strconv.Atoi(e.Data["number"]); err == nil {
+				feature.PRNumber = n
+			}
+			feature.PRURL = e.Data["url"]
+		}
+
+		if e.Task != nil {
+			task := stateToTask(*e.Task, 0)
+			replaced := false
+			for i, t := range feature.Tasks {
+				if t.ID == task.ID {
+					feature.Tasks[i] = task
+					replaced = true
+				}
+			}
+			if !replaced {
+				feature.Tasks = append(feature.Tasks, task)
+			}
+			if e.Type == EventReview && task.Status == StatusApproval {
+				feature.CurrentTask = task
+			}
+		}
+
+		feature.UpdatedAt = e.Timestamp
+	}
+
+	if feature == nil {
+		return nil, fmt.Errorf("no events for feature %s", featureID)
+	}
+	return feature, nil
+}
@@ -0,0 +1,104 @@
+package foreman
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bayological/foreman/internal/storage"
+)
+
+func TestReplayFeature(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "foreman-eventlog-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	f := newQueueForeman(storage.NewMemory())
+	f.events, err = storage.OpenEventLog(filepath.Join(tmpDir, "events.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.events.Close()
+
+	feature := NewFeature("feat-1", "Auth", "Login with email")
+	f.recordEvent(storage.LogEvent{Type: EventFeatureCreated, FeatureID: feature.ID, Feature: f.featureToState(feature)})
+
+	// Spec rejected once, then the rest of the workflow up to a failed task
+	f.transition(feature, PhaseSpecifying, "Starting specification", "foreman")
+	feature.SetSpecDir("004-auth")
+	f.recordEvent(storage.LogEvent{Type: EventSpecDir, FeatureID: feature.ID, Message: "004-auth"})
+	f.transition(feature, PhaseAwaitingSpecApproval, "Spec created", "foreman")
+	f.recordStage(EventRejection, feature.ID, "spec", "")
+	f.recordStage(EventFeedback, feature.ID, "spec", "Use OAuth")
+	feature.Constraints = "Use OAuth"
+	f.transition(feature, PhaseSpecifying, "Re-running specification", "foreman")
+	f.transition(feature, PhaseAwaitingSpecApproval, "Spec created", "foreman")
+	f.recordStage(EventApproval, feature.ID, "spec", "")
+	f.transition(feature, PhaseClarifying, "Running clarification", "foreman")
+	feature.Answers["Q1"] = "Google only"
+	f.recordEvent(storage.LogEvent{Type: EventAnswers, FeatureID: feature.ID, Data: map[string]string{"Q1": "Google only"}})
+
+	tasks := []*Task{
+		{ID: "T001", Spec: "Login form", FeatureID: feature.ID, Status: StatusPending, Branch: feature.TaskBranch("T001")},
+		{ID: "T002", Spec: "Session API", FeatureID: feature.ID, Status: StatusPending, Branch: feature.TaskBranch("T002"), Dependencies: []string{"T001"}},
+	}
+	feature.SetTasks(tasks)
+	f.recordEvent(storage.LogEvent{Type: EventTasksGenerated, FeatureID: feature.ID, Tasks: []storage.TaskState{taskToState(tasks[0]), taskToState(tasks[1])}})
+
+	tasks[0].Status = StatusRunning
+	f.recordTask(tasks[0], EventTaskAttempt, "Attempt 1", "foreman")
+	tasks[0].Attempt++
+	tasks[0].AddContext("Review Feedback (attempt 1):\nAdd tests")
+	tasks[0].Status = StatusPending
+	reviewed := taskToState(tasks[0])
+	f.recordEvent(storage.LogEvent{Type: EventReview, FeatureID: feature.ID, TaskID: "T001", Verdict: "request_changes", Task: &reviewed})
+	tasks[0].Status = StatusFailed
+	f.recordTask(tasks[0], EventTaskStatus, "agent failed", "foreman")
+
+	// Events of other features are ignored
+	f.recordEvent(storage.LogEvent{Type: EventTransition, FeatureID: "other", ToPhase: string(PhaseComplete)})
+
+	events, err := f.events.Events()
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := ReplayFeature(events, feature.ID)
+	if err != nil {
+		t.Fatalf("ReplayFeature() error = %v", err)
+	}
+
+	if replayed.Phase != feature.Phase || replayed.SpecDir != "004-auth" || replayed.Constraints != "Use OAuth" {
+		t.Errorf("replayed feature = phase %s, spec dir %q, constraints %q", replayed.Phase, replayed.SpecDir, replayed.Constraints)
+	}
+	if replayed.Answers["Q1"] != "Google only" {
+		t.Errorf("replayed answers = %v", replayed.Answers)
+	}
+	if len(replayed.Events) != len(feature.Events) {
+		t.Errorf("replayed %d workflow events, want %d", len(replayed.Events), len(feature.Events))
+	}
+	if len(replayed.Tasks) != 2 {
+		t.Fatalf("replayed %d tasks, want 2", len(replayed.Tasks))
+	}
+
+	task := replayed.FindTask("T001")
+	if task.Status != StatusFailed || task.Attempt != 1 || task.Context != tasks[0].Context {
+		t.Errorf("replayed task = %+v", task)
+	}
+	if other := replayed.FindTask("T002"); other.Status != StatusPending || len(other.Dependencies) != 1 {
+		t.Errorf("replayed untouched task = %+v", other)
+	}
+}
+
+func TestReplayFeatureNeedsCreation(t *testing.T) {
+	events := []storage.LogEvent{
+		{Type: EventTransition, FeatureID: "feat-1", ToPhase: string(PhaseSpecifying)},
+	}
+	if _, err := ReplayFeature(events, "feat-1"); err == nil {
+		t.Error("ReplayFeature() should fail without a creation event")
+	}
+	if _, err := ReplayFeature(nil, "feat-1"); err == nil {
+		t.Error("ReplayFeature() should fail without events")
+	}
+}
@@ -0,0 +1,131 @@
+package foreman
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Task metadata kept by the fallback chain
+const (
+	// metaTriedAgents lists the agents a task fell back from, comma separated
+	metaTriedAgents = "tried_agents"
+	// metaCompletedBy names the agent whose attempt succeeded
+	metaCompletedBy = "completed_by"
+)
+
+// rateLimitPattern matches the ways agents report being rate limited or
+// out of quota
+var rateLimitPattern = regexp.MustCompile(`(?i)rate[ _-]?limit|too many requests|\b429\b|usage limit|quota exceeded|exceeded your current quota|overloaded`)
+
+// isRateLimited reports whether an agent's error or output says it was
+// rate limited
+func isRateLimited(texts ...string) bool {
+	for _, text := range texts {
+		if rateLimitPattern.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// agentsByPriority returns the configured agents in fallback order: lowest
+// priority first, agents without a priority last, ties by name
+func (f *Foreman) agentsByPriority() []string {
+	names := f.getAgentNames()
+	rank := func(name string) int {
+		if p := f.cfg.Agents[name].Priority; p > 0 {
+			return p
+		}
+		return int(^uint(0) >> 1)
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		ri, rj := rank(names[i]), rank(names[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// triedAgents returns the agents task has already fallen back from
+func triedAgents(task *Task) []string {
+	if task.Metadata[metaTriedAgents] == "" {
+		return nil
+	}
+	return strings.Split(task.Metadata[metaTriedAgents], ",")
+}
+
+// nextAgent returns the agent task falls back to, or "" when every agent
+// has been tried
+func (f *Foreman) nextAgent(task *Task) string {
+	tried := map[string]bool{task.AgentName: true}
+	for _, name := range triedAgents(task) {
+		tried[name] = true
+	}
+	for _, name := range f.agentsByPriority() {
+		if !tried[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+// switchAgent hands task to the next agent in the fallback chain, carrying
+// what went wrong forward as context. The new agent starts with a fresh set
+// of retries. It returns the new agent, or "" when none is left.
+func (f *Foreman) switchAgent(task *Task, reason, previous string) string {
+	next := f.nextAgent(task)
+	if next == "" {
+		return ""
+	}
+
+	from := task.AgentName
+	task.mu.Lock()
+	if task.Metadata == nil {
+		task.Metadata = make(map[string]string)
+	}
+	task.Metadata[metaTriedAgents] = strings.Join(append(triedAgents(task), from), ",")
+	task.AgentName = next
+	task.Attempt = 0
+	task.mu.Unlock()
+	// The new agent cannot continue the old one's session
+	clearSession(task)
+	task.AddContext(fmt.Sprintf("A previous attempt by %s %s:\n%s", from, reason, previous))
+
+	f.recordTask(task, EventTaskStatus, fmt.Sprintf("Falling back from %s to %s: %s %s", from, next, from, reason), "foreman")
+	return next
+}
+
+// fallBackOrFail hands task to the next agent, reporting that it should run
+// again, or fails it with err when every agent has been tried
+func (f *Foreman) fallBackOrFail(task *Task, reason, previous string, err error) bool {
+	from := task.AgentName
+	next := f.switchAgent(task, reason, previous)
+	if next == "" {
+		f.failTask(task, err)
+		return false
+	}
+
+	f.telegram.Send(fmt.Sprintf(
+		"*Agent Fallback*\nTask: `%s`\n%s %s, handing over to %s",
+		task.ID, from, reason, next,
+	))
+	return true
+}
+
+// markCompletedBy records the agent whose attempt succeeded
+func (f *Foreman) markCompletedBy(task *Task) {
+	task.mu.Lock()
+	if task.Metadata == nil {
+		task.Metadata = make(map[string]string)
+	}
+	task.Metadata[metaCompletedBy] = task.AgentName
+	task.mu.Unlock()
+
+	if tried := triedAgents(task); len(tried) > 0 {
+		f.recordTask(task, EventTaskStatus, fmt.Sprintf("Completed by %s after %s", task.AgentName, strings.Join(tried, ", ")), "foreman")
+	}
+}
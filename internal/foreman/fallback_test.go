@@ -0,0 +1,124 @@
+package foreman
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bayological/foreman/internal/agents"
+)
+
+func newFallbackForeman() *Foreman {
+	return &Foreman{
+		cfg: &Config{Agents: AgentsConfig{
+			"claude-code": {Enabled: true, Priority: 1},
+			"codex":       {Enabled: true, Priority: 2},
+			"aider":       {Enabled: true},
+			"gemini":      {Enabled: true, Priority: 2},
+		}},
+		agents: map[string]agents.Agent{
+			"claude-code": agents.NewClaudeCode("/repo"),
+			"codex":       agents.NewCodex("/repo"),
+			"aider":       nil,
+			"gemini":      nil,
+		},
+		features: make(map[string]*Feature),
+	}
+}
+
+func TestAgentsByPriority(t *testing.T) {
+	f := newFallbackForeman()
+
+	want := []string{"claude-code", "codex", "gemini", "aider"}
+	if got := f.agentsByPriority(); !reflect.DeepEqual(got, want) {
+		t.Errorf("agentsByPriority() = %v, want %v", got, want)
+	}
+}
+
+func TestSwitchAgent(t *testing.T) {
+	f := newFallbackForeman()
+	task := &Task{ID: "T001", AgentName: "codex", Attempt: 2, Metadata: map[string]string{}}
+	saveSession(task, "codex-session")
+
+	// Falls back in priority order, skipping the agent that failed
+	var chain []string
+	for {
+		next := f.switchAgent(task, "was rate limited", "429 Too Many Requests")
+		if next == "" {
+			break
+		}
+		chain = append(chain, next)
+		if task.Attempt != 0 {
+			t.Errorf("attempt after switching to %s = %d, want 0", next, task.Attempt)
+		}
+	}
+
+	if want := []string{"claude-code", "gemini", "aider"}; !reflect.DeepEqual(chain, want) {
+		t.Errorf("fallback chain = %v, want %v", chain, want)
+	}
+	if task.AgentName != "aider" {
+		t.Errorf("AgentName = %q, want the last agent tried", task.AgentName)
+	}
+	if got := task.Metadata[metaTriedAgents]; got != "codex,claude-code,gemini" {
+		t.Errorf("tried agents = %q", got)
+	}
+	if task.Metadata[metaSessionID] != "" {
+		t.Error("the failed agent's session should not carry over to the next agent")
+	}
+	if !strings.Contains(task.Context, "A previous attempt by codex was rate limited:\n429 Too Many Requests") {
+		t.Errorf("context does not carry the failed attempt forward:\n%s", task.Context)
+	}
+
+	f.markCompletedBy(task)
+	if got := task.Metadata[metaCompletedBy]; got != "aider" {
+		t.Errorf("completed by = %q, want aider", got)
+	}
+}
+
+func TestSwitchAgentWithoutMetadata(t *testing.T) {
+	f := newFallbackForeman()
+	task := &Task{ID: "T001", AgentName: "aider"}
+
+	if next := f.switchAgent(task, "timed out", ""); next != "claude-code" {
+		t.Errorf("switchAgent() = %q, want claude-code", next)
+	}
+	if got := triedAgents(task); !reflect.DeepEqual(got, []string{"aider"}) {
+		t.Errorf("triedAgents() = %v", got)
+	}
+}
+
+func TestReassignTaskEndsCompetition(t *testing.T) {
+	f := newFallbackForeman()
+	task := &Task{ID: "T001", FeatureID: "f1", AgentName: "codex", Attempt: 1, Metadata: map[string]string{
+		metaCompete:    "codex,claude-code",
+		metaReassignTo: "claude-code",
+	}}
+
+	f.applyReassignment(task)
+	if task.AgentName != "claude-code" || task.Attempt != 0 {
+		t.Errorf("after reassignment agent = %s, attempt = %d", task.AgentName, task.Attempt)
+	}
+	if runs := competingAgents(task); len(runs) > 1 {
+		t.Errorf("the reassigned task still competes between %v", runs)
+	}
+}
+
+func TestIsRateLimited(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"Error: 429 Too Many Requests", true},
+		{"API Error: rate_limit_error", true},
+		{"Claude AI usage limit reached|1718000000", true},
+		{"You exceeded your current quota, please check your plan", true},
+		{"Overloaded", true},
+		{"exit status 1", false},
+		{"Processed 4290 files", false},
+	}
+	for _, tt := range tests {
+		if got := isRateLimited(tt.text); got != tt.want {
+			t.Errorf("isRateLimited(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
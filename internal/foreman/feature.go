@@ -14,6 +14,10 @@ type Feature struct {
 	Name        string
 	Description string
 	Branch      string
+	PRNumber    int
+	PRURL       string
+	// SpecDir names the feature's directory under .specify/specs
+	SpecDir string
 
 	Phase       Phase
 	CurrentTask *Task
@@ -33,6 +37,8 @@ type Feature struct {
 	TechStack   string
 	Constraints string
 
+	scheduler *Scheduler
+
 	mu sync.RWMutex
 }
 
@@ -50,6 +56,12 @@ func NewFeature(id, name, description string) *Feature {
 	}
 }
 
+// TaskBranch returns the branch a task of this feature works on. Task
+// branches fork from the feature branch and are merged back on approval.
+func (f *Feature) TaskBranch(taskID string) string {
+	return fmt.Sprintf("feature/%s/%s", f.ID, taskID)
+}
+
 func (f *Feature) Transition(to Phase, message, actor string) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -79,6 +91,19 @@ func (f *Feature) GetPhase() Phase {
 	return f.Phase
 }
 
+func (f *Feature) SetSpecDir(dir string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.SpecDir = dir
+	f.UpdatedAt = time.Now()
+}
+
+func (f *Feature) GetSpecDir() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.SpecDir
+}
+
 func (f *Feature) SetSpec(spec *speckit.Spec) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -101,6 +126,42 @@ func (f *Feature) SetTasks(tasks []*Task) {
 	f.UpdatedAt = time.Now()
 }
 
+func (f *Feature) setCurrentTask(task *Task) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.CurrentTask = task
+}
+
+func (f *Feature) getCurrentTask() *Task {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.CurrentTask
+}
+
+func (f *Feature) setScheduler(s *Scheduler) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.scheduler = s
+}
+
+func (f *Feature) getScheduler() *Scheduler {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.scheduler
+}
+
+// FindTask returns the feature's task with the given ID, or nil.
+func (f *Feature) FindTask(id string) *Task {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, t := range f.Tasks {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
 func (f *Feature) NextTask() *Task {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -146,6 +207,9 @@ func (f *Feature) StatusReport() string {
 	report := fmt.Sprintf("*Feature: %s*\n", f.Name)
 	report += fmt.Sprintf("ID: `%s`\n", f.ID)
 	report += fmt.Sprintf("Branch: `%s`\n", f.Branch)
+	if f.SpecDir != "" {
+		report += fmt.Sprintf("Spec: `.specify/specs/%s`\n", f.SpecDir)
+	}
 	report += fmt.Sprintf("Phase: %s\n", f.Phase.String())
 
 	if len(f.Tasks) > 0 {
@@ -1,6 +1,7 @@
 package foreman
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -215,3 +216,15 @@ func TestSanitizeBranchName(t *testing.T) {
 		})
 	}
 }
+
+func TestFeatureTaskBranch(t *testing.T) {
+	feature := NewFeature("123", "User Auth", "desc")
+
+	branch := feature.TaskBranch("T-001")
+	if branch != "feature/123/T-001" {
+		t.Errorf("TaskBranch() = %q, want %q", branch, "feature/123/T-001")
+	}
+	if strings.HasPrefix(branch, feature.Branch+"/") {
+		t.Error("task branches must not nest under the feature branch ref")
+	}
+}
@@ -2,12 +2,17 @@ package foreman
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/bayological/foreman/internal/agents"
+	"github.com/bayological/foreman/internal/forge"
 	"github.com/bayological/foreman/internal/git"
 	"github.com/bayological/foreman/internal/speckit"
 	"github.com/bayological/foreman/internal/storage"
@@ -20,22 +25,61 @@ type Foreman struct {
 	repo     *git.Repo
 	agents   map[string]agents.Agent
 	reviewer *agents.Reviewer
-	telegram *telegram.Bot
-	speckit  *speckit.SpecKit
-	storage  *storage.FileStorage
+	telegram Chat
+	speckit  SpecRunner
+	storage  storage.Storage // nil when storage is not configured
+	forge    forge.Forge     // nil when no forge could be configured
 
-	taskQueue chan *Task
+	// events is the append-only event log; nil when storage is not configured
+	events *storage.EventLog
+
+	// queue persists tasks waiting for a worker; it is in-memory when
+	// storage is not configured. queueWake signals new work.
+	queue     storage.Queue
+	queueWake chan struct{}
+
+	// usage records what agent runs consumed; in-memory when storage is
+	// not configured
+	usage storage.UsageLog
+
+	// transcripts keeps the raw output of agent runs and review tools; nil
+	// when storage is not configured
+	transcripts *storage.Transcripts
+
+	// preflight checks the tools Foreman relies on
+	preflight func(ctx context.Context, cfg *Config) PreflightReport
+
+	// Budget approvals per scope, persisted with usage, and the tasks held
+	// waiting for one
+	budgetApprovals map[string]int
+	budgetHeld      map[string][]*Task
+	budgetMu        sync.Mutex
+
+	// Tasks assigned directly rather than through a feature
+	standalone   map[string]*Task
+	standaloneMu sync.Mutex
 
 	features   map[string]*Feature
 	featuresMu sync.RWMutex
 
-	active map[string]context.CancelFunc
+	// Running tasks, keyed like their queue entries
+	active map[storage.QueueKey]activeTask
 	mu     sync.RWMutex
 	sem    chan struct{}
 
 	// Pending feedback tracking
 	pendingFeedback   *PendingFeedback
 	pendingFeedbackMu sync.RWMutex
+
+	// Merge conflict resolutions, keyed by feature and task ID, and the
+	// agents resolving them
+	resolutions   map[string]*conflictResolution
+	resolutionsMu sync.Mutex
+	resolving     sync.WaitGroup
+
+	// runCtx is Run's context, for work handlers start that must stop with
+	// Foreman; nil before Run
+	runCtx context.Context
 }
 
 // PendingFeedback tracks when we're waiting for feedback text from the user
@@ -46,44 +90,96 @@ type PendingFeedback struct {
 }
 
 func New(cfg *Config) (*Foreman, error) {
-	repo, err := git.NewRepo(cfg.Repo.Path, cfg.Repo.Remote, cfg.Repo.MainBranch)
+	tg, err := telegram.NewBot(cfg.Telegram.Token, cfg.Telegram.ChatID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open repo: %w", err)
+		return nil, fmt.Errorf("failed to create telegram bot: %w", err)
 	}
 
-	tg, err := telegram.NewBot(cfg.Telegram.Token, cfg.Telegram.ChatID)
+	configured, err := newAgents(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create telegram bot: %w", err)
+		return nil, err
+	}
+
+	return NewWithDeps(cfg, Deps{
+		Chat:    tg,
+		SpecKit: speckit.New(cfg.Repo.Path),
+		Agents:  configured,
+	})
+}
+
+// NewWithDeps creates a Foreman for the repo in cfg that talks to the user,
+// runs SpecKit and implements tasks through deps instead of building them
+// from the config
+func NewWithDeps(cfg *Config, deps Deps) (*Foreman, error) {
+	if err := validateRoutes(cfg.Routing, deps.Agents); err != nil {
+		return nil, fmt.Errorf("invalid routing: %w", err)
+	}
+
+	repo, err := git.NewRepo(cfg.Repo.Path, cfg.Repo.Remote, cfg.Repo.MainBranch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repo: %w", err)
 	}
 
 	// Initialize storage if configured
-	var store *storage.FileStorage
+	var store storage.Storage
 	if cfg.Storage.Path != "" {
-		store, err = storage.New(cfg.Storage.Path)
+		store, err = storage.Open(cfg.Storage.Options())
 		if err != nil {
 			return nil, fmt.Errorf("failed to initialize storage: %w", err)
 		}
 	}
 
 	f := &Foreman{
-		cfg:       cfg,
-		repo:      repo,
-		telegram:  tg,
-		speckit:   speckit.New(cfg.Repo.Path),
-		storage:   store,
-		taskQueue: make(chan *Task, 100),
-		features:  make(map[string]*Feature),
-		active:    make(map[string]context.CancelFunc),
-		sem:       make(chan struct{}, cfg.Concurrency.MaxTasks),
-		agents:    make(map[string]agents.Agent),
+		cfg:        cfg,
+		repo:       repo,
+		telegram:   deps.Chat,
+		speckit:    deps.SpecKit,
+		storage:    store,
+		queue:      store,
+		usage:      store,
+		queueWake:  make(chan struct{}, 1),
+		standalone: make(map[string]*Task),
+		features:   make(map[string]*Feature),
+		active:     make(map[storage.QueueKey]activeTask),
+		sem:        make(chan struct{}, cfg.Concurrency.MaxTasks),
+		agents:     deps.Agents,
+		preflight:  deps.Preflight,
+
+		resolutions: make(map[string]*conflictResolution),
+	}
+	if f.agents == nil {
+		f.agents = make(map[string]agents.Agent)
+	}
+	if f.preflight == nil {
+		f.preflight = Preflight
+	}
+	if store == nil {
+		memory := storage.NewMemory()
+		f.queue = memory
+		f.usage = memory
+	}
+	f.budgetApprovals = f.loadBudgetApprovals()
+
+	if path := cfg.Storage.EventLogPath(); path != "" {
+		f.events, err = storage.OpenEventLog(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open event log: %w", err)
+		}
 	}
 
-	// Initialize agents based on config
-	if cfg.Agents.ClaudeCode.Enabled {
-		f.agents["claude-code"] = agents.NewClaudeCode(cfg.Repo.Path)
+	if path := cfg.Storage.TranscriptsPath(); path != "" {
+		f.transcripts, err = storage.OpenTranscripts(path, cfg.Storage.TranscriptRetention)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open transcripts: %w", err)
+		}
 	}
-	if cfg.Agents.Codex.Enabled {
-		f.agents["codex"] = agents.NewCodex(cfg.Repo.Path)
+
+	f.forge = deps.Forge
+	if f.forge == nil {
+		f.forge, err = newForge(cfg.Forge, repo)
+		if err != nil {
+			log.Printf("Warning: Pull requests disabled: %v", err)
+		}
 	}
 
 	// Initialize reviewer
@@ -105,23 +201,40 @@ func New(cfg *Config) (*Foreman, error) {
 }
 
 func (f *Foreman) Run(ctx context.Context) error {
+	f.runCtx = ctx
 	f.telegram.Send("Foreman starting up...")
 
-	if err := f.speckit.Initialize(ctx); err != nil {
-		log.Printf("Warning: SpecKit init failed: %v", err)
-	}
-
 	// Register command handlers
 	f.registerHandlers()
 
+	// Nothing can be running yet, so whatever claims to be was interrupted
+	if report := f.reconcile(); !report.empty() {
+		f.telegram.Send(report.String())
+	}
+
+	// Report missing tools now rather than when a task first needs them
+	preflight := f.preflight(ctx, f.cfg)
+	log.Printf("Preflight checks: %s", preflight.Summary())
+	f.telegram.Send(preflight.String())
+
 	// Start Telegram listener
 	go f.telegram.Listen(ctx)
-	go f.taskProcessor(ctx)
+	processed := make(chan struct{})
+	go func() {
+		defer close(processed)
+		f.taskProcessor(ctx)
+	}()
+	go f.pruneTranscripts(ctx)
 
 	f.telegram.Send("Ready! Use /newfeature to start a new feature.")
 
 	<-ctx.Done()
 
+	// Cancelled tasks and resolutions record where they stopped before
+	// storage is closed
+	<-processed
+	f.resolving.Wait()
+
 	// Graceful shutdown: save all features
 	f.shutdown()
 
@@ -134,9 +247,9 @@ func (f *Foreman) shutdown() {
 
 	// Cancel all active tasks
 	f.mu.Lock()
-	for id, cancel := range f.active {
-		log.Printf("Cancelling task %s", id)
-		cancel()
+	for key, run := range f.active {
+		log.Printf("Cancelling task %s", key)
+		run.cancel()
 	}
 	f.mu.Unlock()
 
@@ -150,25 +263,62 @@ func (f *Foreman) shutdown() {
 
 	log.Printf("Saved %d features to storage", count)
 	f.telegram.Send(fmt.Sprintf("Foreman shutting down. Saved %d features.", count))
+
+	if f.storage != nil {
+		if err := f.storage.Close(); err != nil {
+			log.Printf("Warning: Failed to close storage: %v", err)
+		}
+	}
+	if f.events != nil {
+		if err := f.events.Close(); err != nil {
+			log.Printf("Warning: Failed to close event log: %v", err)
+		}
+	}
 }
 
+// taskProcessor leases queued tasks whenever a worker slot is free. Leases
+// are only taken with a slot in hand, so queued tasks stay queued (and
+// persisted) until they can actually run. Once ctx is done it returns when
+// its workers have finished.
 func (f *Foreman) taskProcessor(ctx context.Context) {
+	ticker := time.NewTicker(leaseRenewal)
+	defer ticker.Stop()
+
+	var workers sync.WaitGroup
+	defer workers.Wait()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case task := <-f.taskQueue:
-			f.sem <- struct{}{}
-			go func(t *Task) {
+		case f.sem <- struct{}{}:
+		}
+
+		task, lease := f.leaseTask()
+		if task != nil {
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
 				defer func() { <-f.sem }()
-				f.executeTask(ctx, t)
-			}(task)
+				f.runQueuedTask(ctx, task, lease)
+			}()
+			continue
+		}
+		<-f.sem
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-f.queueWake:
+		case <-ticker.C:
+			f.expireLeases()
 		}
 	}
 }
 
+// Assign queues a standalone task
 func (f *Foreman) Assign(task *Task) {
-	f.taskQueue <- task
+	f.enqueue(task)
 }
 
 // Feature workflow methods
@@ -182,6 +332,12 @@ func (f *Foreman) StartFeature(ctx context.Context, name, description string) (*
 	f.featuresMu.Unlock()
 
 	f.saveFeatureToStorage(feature)
+	f.recordEvent(storage.LogEvent{
+		Type:      EventFeatureCreated,
+		FeatureID: id,
+		Actor:     "user",
+		Feature:   f.featureToState(feature),
+	})
 
 	f.telegram.Send(fmt.Sprintf(
 		"*New Feature Started*\n\nID: `%s`\nName: %s\nBranch: `%s`",
@@ -194,10 +350,19 @@ func (f *Foreman) StartFeature(ctx context.Context, name, description string) (*
 }
 
 func (f *Foreman) runSpecificationPhase(ctx context.Context, feature *Feature) {
-	feature.Transition(PhaseSpecifying, "Starting specification", "foreman")
+	f.transition(feature, PhaseSpecifying, "Starting specification", "foreman")
 	f.telegram.Send(fmt.Sprintf("Creating specification for `%s`...", feature.ID))
 
-	result, err := f.speckit.Specify(ctx, feature.Description, feature.Branch)
+	wt, ws, err := f.specWorkspace(ctx, feature)
+	if err != nil {
+		f.handlePhaseError(feature, err)
+		return
+	}
+	// Specify creates a new spec directory, even when re-run after feedback
+	ws.Feature = ""
+	before := f.speckit.ListFeatureDirs(wt.Path)
+
+	result, err := f.speckit.Specify(ctx, feature.Description, ws)
 	if err != nil {
 		f.handlePhaseError(feature, err)
 		return
@@ -208,15 +373,28 @@ func (f *Foreman) runSpecificationPhase(ctx context.Context, feature *Feature) {
 		return
 	}
 
-	featureDir := f.speckit.GetLatestFeatureDir()
-	spec, err := speckit.ParseSpec(featureDir)
+	specDir, err := f.speckit.DetectFeatureDir(wt.Path, before, result.Output)
+	if err != nil {
+		f.handlePhaseError(feature, err)
+		return
+	}
+	feature.SetSpecDir(specDir)
+	f.saveFeatureToStorage(feature)
+	f.recordEvent(storage.LogEvent{Type: EventSpecDir, FeatureID: feature.ID, Actor: "foreman", Message: specDir})
+
+	if err := f.commitSpecArtifacts(feature, wt, "Add specification"); err != nil {
+		f.handlePhaseError(feature, err)
+		return
+	}
+
+	spec, err := speckit.ParseSpec(f.speckit.FeatureDirIn(wt.Path, specDir))
 	if err != nil {
 		log.Printf("Warning: Could not parse spec: %v", err)
 	} else {
 		feature.SetSpec(spec)
 	}
 
-	feature.Transition(PhaseAwaitingSpecApproval, "Spec created, awaiting approval", "foreman")
+	f.transition(feature, PhaseAwaitingSpecApproval, "Spec created, awaiting approval", "foreman")
 	f.requestSpecApproval(feature)
 }
 
@@ -248,20 +426,32 @@ func (f *Foreman) ApproveSpec(ctx context.Context, featureID string) {
 		return
 	}
 
+	f.recordStage(EventApproval, featureID, "spec", "")
 	f.telegram.Send(fmt.Sprintf("Spec approved for `%s`. Starting clarification...", featureID))
 
 	go f.runClarificationPhase(ctx, feature)
 }
 
 func (f *Foreman) runClarificationPhase(ctx context.Context, feature *Feature) {
-	feature.Transition(PhaseClarifying, "Running clarification", "foreman")
+	f.transition(feature, PhaseClarifying, "Running clarification", "foreman")
+
+	wt, ws, err := f.specWorkspace(ctx, feature)
+	if err != nil {
+		f.handlePhaseError(feature, err)
+		return
+	}
 
-	result, err := f.speckit.Clarify(ctx)
+	result, err := f.speckit.Clarify(ctx, ws)
 	if err != nil {
 		f.handlePhaseError(feature, err)
 		return
 	}
 
+	if err := f.commitSpecArtifacts(feature, wt, "Clarify specification"); err != nil {
+		f.handlePhaseError(feature, err)
+		return
+	}
+
 	questions := speckit.ParseClarifications(result.Output)
 	feature.PendingQuestions = questions
 
@@ -294,6 +484,7 @@ func (f *Foreman) AnswerClarification(ctx context.Context, featureID string, ans
 	for k, v := range answers {
 		feature.Answers[k] = v
 	}
+	f.recordEvent(storage.LogEvent{Type: EventAnswers, FeatureID: featureID, Actor: "user", Data: answers})
 
 	allAnswered := true
 	for _, q := range feature.PendingQuestions {
@@ -310,7 +501,7 @@ func (f *Foreman) AnswerClarification(ctx context.Context, featureID string, ans
 }
 
 func (f *Foreman) runPlanningPhase(ctx context.Context, feature *Feature) {
-	feature.Transition(PhasePlanning, "Creating implementation plan", "foreman")
+	f.transition(feature, PhasePlanning, "Creating implementation plan", "foreman")
 	f.telegram.Send(fmt.Sprintf("Creating implementation plan for `%s`...", feature.ID))
 
 	techStack := feature.TechStack
@@ -318,7 +509,13 @@ func (f *Foreman) runPlanningPhase(ctx context.Context, feature *Feature) {
 		techStack = f.cfg.DefaultTechStack
 	}
 
-	result, err := f.speckit.Plan(ctx, techStack)
+	wt, ws, err := f.specWorkspace(ctx, feature)
+	if err != nil {
+		f.handlePhaseError(feature, err)
+		return
+	}
+
+	result, err := f.speckit.Plan(ctx, techStack, ws)
 	if err != nil {
 		f.handlePhaseError(feature, err)
 		return
@@ -329,15 +526,19 @@ func (f *Foreman) runPlanningPhase(ctx context.Context, feature *Feature) {
 		return
 	}
 
-	featureDir := f.speckit.GetLatestFeatureDir()
-	plan, err := speckit.ParsePlan(featureDir)
+	if err := f.commitSpecArtifacts(feature, wt, "Add implementation plan"); err != nil {
+		f.handlePhaseError(feature, err)
+		return
+	}
+
+	plan, err := speckit.ParsePlan(f.speckit.FeatureDirIn(wt.Path, ws.Feature))
 	if err != nil {
 		log.Printf("Warning: Could not parse plan: %v", err)
 	} else {
 		feature.SetPlan(plan)
 	}
 
-	feature.Transition(PhaseAwaitingPlanApproval, "Plan created, awaiting approval", "foreman")
+	f.transition(feature, PhaseAwaitingPlanApproval, "Plan created, awaiting approval", "foreman")
 	f.requestPlanApproval(feature)
 }
 
@@ -369,15 +570,22 @@ func (f *Foreman) ApprovePlan(ctx context.Context, featureID string) {
 		return
 	}
 
+	f.recordStage(EventApproval, featureID, "plan", "")
 	f.telegram.Send(fmt.Sprintf("Plan approved for `%s`. Generating tasks...", featureID))
 
 	go f.runTaskingPhase(ctx, feature)
 }
 
 func (f *Foreman) runTaskingPhase(ctx context.Context, feature *Feature) {
-	feature.Transition(PhaseTasking, "Generating tasks", "foreman")
+	f.transition(feature, PhaseTasking, "Generating tasks", "foreman")
 
-	result, err := f.speckit.Tasks(ctx)
+	wt, ws, err := f.specWorkspace(ctx, feature)
+	if err != nil {
+		f.handlePhaseError(feature, err)
+		return
+	}
+
+	result, err := f.speckit.Tasks(ctx, ws)
 	if err != nil {
 		f.handlePhaseError(feature, err)
 		return
@@ -388,32 +596,89 @@ func (f *Foreman) runTaskingPhase(ctx context.Context, feature *Feature) {
 		return
 	}
 
-	featureDir := f.speckit.GetLatestFeatureDir()
-	taskItems, err := speckit.ParseTasks(featureDir)
+	if err := f.commitSpecArtifacts(feature, wt, "Add task breakdown"); err != nil {
+		f.handlePhaseError(feature, err)
+		return
+	}
+
+	taskItems, err := speckit.ParseTasks(f.speckit.FeatureDirIn(wt.Path, ws.Feature))
 	if err != nil {
 		f.handlePhaseError(feature, err)
 		return
 	}
 
+	deps := inferDependencies(taskItems)
+
 	var tasks []*Task
 	for _, item := range taskItems {
-		task := NewTask(item.Title, f.cfg.DefaultAgent, f.cfg.Concurrency.TaskTimeout)
+		agentName, timeout, compete := f.routeTask(feature, item)
+		task := NewTask(item.Title, agentName, timeout)
+		if len(compete) > 0 {
+			task.Metadata[metaCompete] = strings.Join(compete, ",")
+		}
 		task.ID = item.ID
 		task.Spec = item.Title
 		task.FeatureID = feature.ID
-		task.Branch = feature.Branch
+		task.Branch = feature.TaskBranch(item.ID)
+		task.BaseBranch = feature.Branch
 		task.IsParallel = item.IsParallel
+		task.Dependencies = deps[item.ID]
+		task.FilePaths = item.FilePaths
 		task.Metadata["user_story"] = item.UserStoryRef
 		task.Metadata["is_test"] = fmt.Sprintf("%v", item.IsTest)
 		tasks = append(tasks, task)
 	}
 
+	// Reject unusable task graphs before they reach human approval
+	if _, err := NewScheduler(tasks); err != nil {
+		f.handlePhaseError(feature, fmt.Errorf("invalid task dependencies: %w", err))
+		return
+	}
+
 	feature.SetTasks(tasks)
 
-	feature.Transition(PhaseAwaitingTaskApproval, "Tasks generated, awaiting approval", "foreman")
+	var states []storage.TaskState
+	for _, task := range tasks {
+		states = append(states, taskToState(task))
+	}
+	f.recordEvent(storage.LogEvent{Type: EventTasksGenerated, FeatureID: feature.ID, Actor: "foreman", Tasks: states})
+
+	f.transition(feature, PhaseAwaitingTaskApproval, "Tasks generated, awaiting approval", "foreman")
 	f.requestTaskApproval(feature, taskItems)
 }
 
+// specWorkspace returns the feature's own worktree on its branch, where the
+// SpecKit phases run without touching the main checkout. Once specified, the
+// workspace is bound to the feature's spec directory.
+func (f *Foreman) specWorkspace(ctx context.Context, feature *Feature) (*git.Worktree, *speckit.Workspace, error) {
+	wt, err := f.repo.EnsureWorktree(feature.Branch, "")
+	if err != nil {
+		return nil, nil, fmt.Errorf("feature worktree setup failed: %w", err)
+	}
+
+	if err := f.speckit.Initialize(ctx, wt.Path); err != nil {
+		return nil, nil, err
+	}
+
+	ws := &speckit.Workspace{Dir: wt.Path, Feature: feature.GetSpecDir()}
+	if ws.Feature == "" && feature.GetPhase() != PhaseSpecifying {
+		return nil, nil, fmt.Errorf("feature %s has no spec directory; re-run the specification", feature.ID)
+	}
+	return wt, ws, nil
+}
+
+// commitSpecArtifacts commits what a SpecKit phase generated to the feature
+// branch and pushes it.
+func (f *Foreman) commitSpecArtifacts(feature *Feature, wt *git.Worktree, message string) error {
+	if err := f.repo.CommitWorktree(wt, fmt.Sprintf("%s: %s", message, feature.Name)); err != nil {
+		return fmt.Errorf("failed to commit spec artifacts: %w", err)
+	}
+	if err := f.repo.PushBranch(feature.Branch); err != nil {
+		log.Printf("Warning: Failed to push %s: %v", feature.Branch, err)
+	}
+	return nil
+}
+
 func (f *Foreman) requestTaskApproval(feature *Feature, taskItems []speckit.TaskItem) {
 	summary := fmt.Sprintf("*%d Tasks Generated*\n\n", len(taskItems))
 
@@ -435,7 +700,15 @@ func (f *Foreman) requestTaskApproval(feature *Feature, taskItems []speckit.Task
 			if len(title) > 40 {
 				title = title[:37] + "..."
 			}
-			summary += fmt.Sprintf("  - `%s` %s%s\n", t.ID, title, parallel)
+			agent := ""
+			if task := feature.FindTask(t.ID); task != nil {
+				if runs := competingAgents(task); len(runs) > 0 {
+					agent = " → " + strings.Join(runs, " vs ")
+				} else if task.AgentName != f.cfg.DefaultAgent {
+					agent = " → " + task.AgentName
+				}
+			}
+			summary += fmt.Sprintf("  - `%s` %s%s%s\n", t.ID, title, parallel, agent)
 		}
 		summary += "\n"
 	}
@@ -460,64 +733,97 @@ func (f *Foreman) ApproveTasks(ctx context.Context, featureID string) {
 		return
 	}
 
+	f.recordStage(EventApproval, featureID, "tasks", "")
 	f.telegram.Send(fmt.Sprintf("Tasks approved for `%s`. Starting implementation...", featureID))
 
 	go f.runImplementationPhase(ctx, feature)
 }
 
 func (f *Foreman) runImplementationPhase(ctx context.Context, feature *Feature) {
-	feature.Transition(PhaseImplementing, "Starting implementation", "foreman")
+	f.transition(feature, PhaseImplementing, "Starting implementation", "foreman")
 
 	f.telegram.Send(fmt.Sprintf(
 		"*Implementation Started*\n\nFeature: `%s`\nTasks: %d\n\nProgress updates will follow...",
 		feature.ID, len(feature.Tasks),
 	))
 
-	// Queue all parallel tasks first
+	// Anything not complete, awaiting approval or actually running is
+	// restarted, so resuming an interrupted feature picks up where it left off
 	for _, task := range feature.Tasks {
-		if task.IsParallel {
-			task.Status = StatusPending
-			f.taskQueue <- task
+		switch task.Status {
+		case StatusRunning, StatusReview, StatusFailed:
+			if !f.isTaskActive(task) {
+				task.setStatus(StatusPending)
+			}
 		}
 	}
 
-	// Queue the first sequential task (non-parallel)
-	// Subsequent sequential tasks will be queued one at a time via approveFeatureCode
-	for _, task := range feature.Tasks {
-		if !task.IsParallel && task.Status == StatusPending {
-			feature.CurrentTask = task
-			f.taskQueue <- task
-			break
-		}
+	sched, err := NewScheduler(feature.Tasks)
+	if err != nil {
+		f.handlePhaseError(feature, fmt.Errorf("invalid task dependencies: %w", err))
+		return
 	}
+	feature.setScheduler(sched)
+
+	// Subsequent tasks are released by approveFeatureCode as their
+	// dependencies complete
+	f.dispatchReadyTasks(feature)
 }
 
-func (f *Foreman) completeFeature(feature *Feature) {
-	feature.Transition(PhaseComplete, "All tasks completed", "foreman")
-	f.saveFeatureToStorage(feature)
+// dispatchReadyTasks queues every task of the feature whose dependencies
+// are complete and whose files are not being touched by a task in flight.
+// It returns the number of tasks queued.
+func (f *Foreman) dispatchReadyTasks(feature *Feature) int {
+	sched := feature.getScheduler()
+	if sched == nil {
+		return 0
+	}
 
-	// Try to create a PR
-	prURL := ""
-	prBody := f.buildPRBody(feature)
-	pr, err := f.repo.CreatePullRequest(feature.Branch, feature.Name, prBody)
+	ready := sched.Ready()
+	for _, task := range ready {
+		task.setStatus(StatusPending)
+		f.enqueue(task)
+	}
+	return len(ready)
+}
+
+// ensureScheduler returns the feature's scheduler, rebuilding it from the
+// current task statuses if the feature was loaded from storage.
+func (f *Foreman) ensureScheduler(feature *Feature) (*Scheduler, error) {
+	if sched := feature.getScheduler(); sched != nil {
+		return sched, nil
+	}
+	sched, err := NewScheduler(feature.Tasks)
 	if err != nil {
-		log.Printf("Failed to create PR for feature %s: %v", feature.ID, err)
-		// Try to get existing PR URL
-		if url, urlErr := f.repo.GetPullRequestURL(feature.Branch); urlErr == nil {
-			prURL = url
-		}
-	} else {
-		prURL = pr.URL
+		return nil, err
 	}
+	feature.setScheduler(sched)
+	return sched, nil
+}
+
+func (f *Foreman) completeFeature(feature *Feature) {
+	if err := f.transition(feature, PhaseComplete, "All tasks completed", "foreman"); err != nil {
+		log.Printf("Warning: Feature %s cannot complete: %v", feature.ID, err)
+		f.telegram.Send(fmt.Sprintf("Every task of feature `%s` is approved, but the feature cannot complete: %s", feature.ID, err))
+		return
+	}
+	f.saveFeatureToStorage(feature)
+	f.repo.RemoveWorktree(feature.Branch)
 
 	msg := fmt.Sprintf(
 		"*Feature Complete!*\n\nFeature: `%s`\nName: %s\nBranch: `%s`",
 		feature.ID, feature.Name, feature.Branch,
 	)
-	if prURL != "" {
-		msg += fmt.Sprintf("\n\n[View Pull Request](%s)", prURL)
+
+	pr, err := f.openPullRequest(context.Background(), feature)
+	if err != nil {
+		log.Printf("Failed to open PR for feature %s: %v", feature.ID, err)
+		msg += fmt.Sprintf("\n\nCould not open a pull request: %s", validation.SanitizeErrorMessage(err))
+		if url := f.compareURL(feature.Branch); url != "" {
+			msg += fmt.Sprintf("\n[Compare Changes](%s)", url)
+		}
 	} else {
-		msg += "\n\nReady for final review and merge."
+		msg += fmt.Sprintf("\n\n[View Pull Request](%s)", pr.URL)
 	}
 
 	f.telegram.Send(msg)
@@ -551,7 +857,7 @@ func (f *Foreman) buildPRBody(feature *Feature) string {
 }
 
 func (f *Foreman) handlePhaseError(feature *Feature, err error) {
-	feature.Transition(PhaseFailed, err.Error(), "foreman")
+	f.transition(feature, PhaseFailed, err.Error(), "foreman")
 	f.saveFeatureToStorage(feature)
 	f.telegram.Send(fmt.Sprintf("*Phase Failed*\n\nFeature: `%s`\nError: %v", feature.ID, err))
 }
@@ -578,34 +884,55 @@ func generateID() string {
 
 // Task execution methods
 
-func (f *Foreman) executeTask(ctx context.Context, task *Task) {
+// executeTask runs one attempt at task under lease. It reports whether the
+// task should run again; the caller queues it once this attempt is done with
+// it, so that no other worker picks it up meanwhile.
+func (f *Foreman) executeTask(ctx context.Context, task *Task, lease int) (requeue bool) {
+	f.applyReassignment(task)
+	if runs := competingAgents(task); len(runs) > 1 {
+		return f.runCompetition(ctx, task, lease, runs)
+	}
+
 	taskCtx, cancel := context.WithTimeout(ctx, task.Timeout)
 	defer cancel()
 
-	f.trackTask(task.ID, cancel)
-	defer f.untrackTask(task.ID)
+	f.trackTask(task, lease, cancel)
+	defer f.untrackTask(task, lease)
 
-	task.Status = StatusRunning
-	f.telegram.Send(fmt.Sprintf(
-		"*Task Started*\nID: `%s`\nAgent: %s\nBranch: `%s`",
-		task.ID, task.AgentName, task.Branch,
-	))
+	task.setStatus(StatusRunning)
+	f.saveTaskFeature(task)
+	f.recordTask(task, EventTaskAttempt, fmt.Sprintf("Attempt %d with %s", task.Attempt+1, task.AgentName), "foreman")
+	progress := startProgress(f.telegram, task, progressInterval)
+	defer progress.finish(false)
 
-	// Setup worktree
-	wt, err := f.repo.CreateWorktree(task.Branch)
+	// Setup worktree; feature tasks fork from the feature branch. A retry
+	// that resumes the agent's session continues in the worktree it left.
+	sessionID, resume := resumeSession(task)
+	createWorktree := f.repo.CreateWorktreeFrom
+	if sessionID != "" {
+		createWorktree = f.repo.EnsureWorktree
+	}
+	wt, err := createWorktree(task.Branch, task.BaseBranch)
 	if err != nil {
 		f.failTask(task, fmt.Errorf("worktree setup failed: %w", err))
-		return
+		return false
 	}
-	defer f.repo.RemoveWorktree(task.Branch)
+	defer func() {
+		// A run that took the task over since works in the same worktree
+		if !keepsWorktree(task) && f.holdsTask(task, lease) {
+			f.repo.RemoveWorktree(task.Branch)
+		}
+	}()
 
+	task.mu.Lock()
 	task.WorktreePath = wt.Path
+	task.mu.Unlock()
 
 	// Get agent
 	agent, ok := f.agents[task.AgentName]
 	if !ok {
 		f.failTask(task, fmt.Errorf("unknown agent: %s", task.AgentName))
-		return
+		return false
 	}
 
 	// Build full prompt with context
@@ -619,73 +946,116 @@ func (f *Foreman) executeTask(ctx context.Context, task *Task) {
 		ID:           task.ID,
 		Spec:         fullSpec,
 		WorktreePath: task.WorktreePath,
+		Progress:     progress.update,
+		SessionID:    sessionID,
+		Resume:       resume,
 	})
+	progress.finish(err == nil && result.Success)
+	transcript := f.beginTranscript(task)
+	saveAgentTranscript(transcript, "task", task.AgentName, result, err)
+	if result != nil {
+		f.recordUsage(task, task.AgentName, result.Usage)
+		saveSession(task, result.SessionID)
+	}
+
+	// A timed out agent is not retried; the next agent gets a chance instead
+	timedOut := errors.Is(taskCtx.Err(), context.DeadlineExceeded)
 
 	if err != nil {
-		f.handleExecutionError(task, err)
-		return
+		return f.handleExecutionError(task, err, timedOut)
 	}
 
 	if !result.Success {
-		f.handleAgentFailure(task, result)
-		return
+		return f.handleAgentFailure(task, result, timedOut)
 	}
+	f.markCompletedBy(task)
 
 	// Commit and push
 	if err := f.repo.CommitAndPush(wt, fmt.Sprintf("Task %s: %s", task.ID, truncate(task.Spec, 50))); err != nil {
 		f.failTask(task, fmt.Errorf("git push failed: %w", err))
-		return
+		return false
 	}
 
 	// Review
-	task.Status = StatusReview
+	task.setStatus(StatusReview)
 	if task.FeatureID != "" {
 		if feature := f.getFeature(task.FeatureID); feature != nil {
-			feature.Transition(PhaseReviewing, fmt.Sprintf("Reviewing task %s", task.ID), "foreman")
+			f.transitionTask(feature, PhaseReviewing, fmt.Sprintf("Reviewing task %s", task.ID), "foreman")
 		}
 	}
 	f.telegram.Send(fmt.Sprintf("Reviewing `%s`...", task.ID))
 
 	review, err := f.reviewer.Review(taskCtx, &agents.ReviewRequest{
 		Branch:       task.Branch,
-		BaseBranch:   f.cfg.Repo.MainBranch,
+		BaseBranch:   f.baseBranch(task),
 		WorktreePath: wt.Path,
 		Spec:         task.Spec,
 	})
 
 	if err != nil {
+		saveTranscript(transcript, transcriptReview, fmt.Sprintf("Review failed: %v\n", err))
 		f.failTask(task, fmt.Errorf("review failed: %w", err))
-		return
+		return false
 	}
+	saveReviewTranscript(transcript, review)
 
-	f.handleReview(task, result, review)
+	return f.handleReview(task, result, review)
 }
 
-func (f *Foreman) handleReview(task *Task, result *agents.TaskResult, review *agents.ReviewResult) {
+// handleReview acts on the review of an attempt, reporting whether the task
+// should run again with the reviewer's feedback
+func (f *Foreman) handleReview(task *Task, result *agents.TaskResult, review *agents.ReviewResult) bool {
+	f.saveReview(task, review)
+
+	// Settle the task and record it before handing it to the user or back
+	// to the workers, who may change it from then on
+	retry := false
 	switch review.Verdict {
-	case agents.VerdictApprove:
-		task.Status = StatusApproval
+	case agents.VerdictApprove, agents.VerdictBlock:
+		task.setStatus(StatusApproval)
+	case agents.VerdictRequestChanges:
+		if task.Attempt < f.cfg.Review.MaxRetries {
+			retry = true
+			task.mu.Lock()
+			task.Attempt++
+			task.Status = StatusPending
+			task.mu.Unlock()
+			task.AddContext(fmt.Sprintf("Review Feedback (attempt %d):\n%s", task.Attempt, review.Summary))
+		} else {
+			task.setStatus(StatusApproval)
+		}
+	}
+
+	state := taskToState(task)
+	f.recordEvent(storage.LogEvent{
+		Type:      EventReview,
+		FeatureID: task.FeatureID,
+		TaskID:    task.ID,
+		Actor:     "reviewer",
+		Message:   review.Summary,
+		Verdict:   string(review.Verdict),
+		Task:      &state,
+	})
 
+	switch review.Verdict {
+	case agents.VerdictApprove:
 		// Check if this task belongs to a feature
 		if task.FeatureID != "" {
 			if feature := f.getFeature(task.FeatureID); feature != nil {
-				feature.Transition(PhaseAwaitingCodeApproval, fmt.Sprintf("Task %s awaiting approval", task.ID), "foreman")
+				f.transitionTask(feature, PhaseAwaitingCodeApproval, fmt.Sprintf("Task %s awaiting approval", task.ID), "foreman")
+				feature.setCurrentTask(task)
 			}
-			f.telegram.RequestPhaseApproval(task.FeatureID, "code", review.Summary, fmt.Sprintf("Task: `%s`", task.ID))
+			f.telegram.RequestCodeApproval(task.FeatureID, task.ID, review.Summary, fmt.Sprintf("Branch: `%s`", task.Branch))
 		} else {
-			f.telegram.RequestApproval(task.ID, review.Summary, task.PRURL("https://github.com/owner/repo"))
+			f.telegram.RequestApproval(task.ID, review.Summary, f.compareURL(task.Branch))
 		}
 
 	case agents.VerdictRequestChanges:
-		if task.Attempt < f.cfg.Review.MaxRetries {
+		if retry {
 			f.telegram.Send(fmt.Sprintf(
 				"*Changes Requested* - Attempt %d/%d\n\n%s",
-				task.Attempt+1, f.cfg.Review.MaxRetries, review.Summary,
+				task.Attempt, f.cfg.Review.MaxRetries, review.Summary,
 			))
-			task.Attempt++
-			task.AddContext(fmt.Sprintf("Review Feedback (attempt %d):\n%s", task.Attempt, review.Summary))
-			task.Status = StatusPending
-			f.taskQueue <- task
 		} else {
 			f.escalate(task, review, "Max retries exceeded")
 		}
@@ -693,75 +1063,162 @@ func (f *Foreman) handleReview(task *Task, result *agents.TaskResult, review *ag
 	case agents.VerdictBlock:
 		f.escalate(task, review, "Blocking issues found")
 	}
+	return retry
 }
 
-func (f *Foreman) handleExecutionError(task *Task, err error) {
-	if task.Attempt < f.cfg.Review.MaxRetries {
+// handleExecutionError retries the agent, or falls back to the next agent
+// once it has failed too often, timed out or been rate limited. It reports
+// whether the task should run again.
+func (f *Foreman) handleExecutionError(task *Task, err error, timedOut bool) bool {
+	var reason string
+	switch {
+	case timedOut:
+		reason = "timed out"
+	case isRateLimited(err.Error()):
+		reason = "was rate limited"
+	case task.Attempt < f.cfg.Review.MaxRetries:
 		f.telegram.Send(fmt.Sprintf(
 			"*Execution Error* - Retrying (%d/%d)\nError: %s",
 			task.Attempt+1, f.cfg.Review.MaxRetries, validation.SanitizeErrorMessage(err),
 		))
+		task.mu.Lock()
 		task.Attempt++
+		task.mu.Unlock()
 		task.AddContext(fmt.Sprintf("Previous attempt failed with error: %v", err))
-		f.taskQueue <- task
-	} else {
-		f.failTask(task, err)
+		f.recordTask(task, EventTaskStatus, fmt.Sprintf("Retrying after error: %v", err), "foreman")
+		return true
+	default:
+		reason = fmt.Sprintf("failed %d times", task.Attempt+1)
 	}
+
+	return f.fallBackOrFail(task, reason, fmt.Sprintf("Error: %v", err), err)
 }
 
-func (f *Foreman) handleAgentFailure(task *Task, result *agents.TaskResult) {
-	if task.Attempt < f.cfg.Review.MaxRetries {
+// handleAgentFailure retries the agent, or falls back to the next agent
+// once it has failed too often, timed out or been rate limited. It reports
+// whether the task should run again.
+func (f *Foreman) handleAgentFailure(task *Task, result *agents.TaskResult, timedOut bool) bool {
+	resultErr := ""
+	if result.Error != nil {
+		resultErr = result.Error.Error()
+	}
+
+	var reason string
+	switch {
+	case timedOut:
+		reason = "timed out"
+	case isRateLimited(result.Summary, resultErr):
+		reason = "was rate limited"
+	case task.Attempt < f.cfg.Review.MaxRetries:
 		f.telegram.Send(fmt.Sprintf(
 			"*Agent Failed* - Retrying (%d/%d)\n%s",
 			task.Attempt+1, f.cfg.Review.MaxRetries, result.Summary,
 		))
+		task.mu.Lock()
 		task.Attempt++
+		task.mu.Unlock()
 		task.AddContext(fmt.Sprintf("Previous attempt failed:\n%s", result.Summary))
-		f.taskQueue <- task
-	} else {
-		f.failTask(task, fmt.Errorf("agent failed: %s", result.Summary))
+		f.recordTask(task, EventTaskStatus, fmt.Sprintf("Retrying after agent failure: %s", result.Summary), "foreman")
+		return true
+	default:
+		reason = fmt.Sprintf("failed %d times", task.Attempt+1)
 	}
+
+	return f.fallBackOrFail(task, reason, result.Summary, fmt.Errorf("agent failed: %s", result.Summary))
 }
 
 func (f *Foreman) failTask(task *Task, err error) {
-	task.Status = StatusFailed
+	f.markFailed(task, err.Error())
 	f.telegram.Send(fmt.Sprintf("*Task Failed*\nID: `%s`\nError: %s", task.ID, validation.SanitizeErrorMessage(err)))
 }
 
+// markFailed records task as failed, for reason, and takes it out of its
+// feature's schedule; telling the user is left to the caller
+func (f *Foreman) markFailed(task *Task, reason string) {
+	task.mu.Lock()
+	task.Status = StatusFailed
+	task.mu.Unlock()
+	f.endSession(task)
+	f.recordTask(task, EventTaskStatus, reason, "foreman")
+	if feature := f.getFeature(task.FeatureID); feature != nil {
+		if sched := feature.getScheduler(); sched != nil {
+			sched.Fail(task.ID)
+		}
+	}
+}
+
+// escalate asks the user to step in on a task already waiting for approval
 func (f *Foreman) escalate(task *Task, review *agents.ReviewResult, reason string) {
-	task.Status = StatusApproval
 	f.telegram.Escalate(task.ID, reason, review.Summary)
 }
 
-func (f *Foreman) trackTask(id string, cancel context.CancelFunc) {
+// activeTask is a running task: the lease its worker holds, and how to
+// cancel the run
+type activeTask struct {
+	lease  int
+	cancel context.CancelFunc
+}
+
+func (f *Foreman) trackTask(task *Task, lease int, cancel context.CancelFunc) {
 	f.mu.Lock()
-	f.active[id] = cancel
+	f.active[queueKey(task)] = activeTask{lease: lease, cancel: cancel}
 	f.mu.Unlock()
 }
 
-func (f *Foreman) untrackTask(id string) {
+// untrackTask forgets the run under lease, unless another run has taken the
+// task over since
+func (f *Foreman) untrackTask(task *Task, lease int) {
 	f.mu.Lock()
-	delete(f.active, id)
-	f.mu.Unlock()
+	defer f.mu.Unlock()
+	if f.active[queueKey(task)].lease == lease {
+		delete(f.active, queueKey(task))
+	}
+}
+
+// holdsTask reports whether the run under lease is still the task's latest
+func (f *Foreman) holdsTask(task *Task, lease int) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	run, ok := f.active[queueKey(task)]
+	return ok && run.lease == lease
 }
 
-func (f *Foreman) cancelTask(id string) bool {
+func (f *Foreman) cancelTask(key storage.QueueKey) bool {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	if cancel, ok := f.active[id]; ok {
-		cancel()
+	if run, ok := f.active[key]; ok {
+		run.cancel()
 		return true
 	}
 	return false
 }
 
+// cancelFeatureTasks cancels every running task of a feature
+func (f *Foreman) cancelFeatureTasks(featureID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for key, run := range f.active {
+		if key.FeatureID == featureID {
+			run.cancel()
+		}
+	}
+}
+
+func (f *Foreman) isTaskActive(task *Task) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	_, ok := f.active[queueKey(task)]
+	return ok
+}
+
 func (f *Foreman) getActiveTaskIDs() []string {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 	ids := make([]string, 0, len(f.active))
-	for id := range f.active {
-		ids = append(ids, id)
+	for key := range f.active {
+		ids = append(ids, key.String())
 	}
+	slices.Sort(ids)
 	return ids
 }
 
@@ -777,44 +1234,106 @@ func (f *Foreman) approveTask(taskID string) error {
 	return f.repo.MergeBranch(fmt.Sprintf("task/%s", taskID))
 }
 
-func (f *Foreman) approveFeatureCode(ctx context.Context, featureID string) {
+func (f *Foreman) approveFeatureCode(ctx context.Context, featureID, taskID string) {
 	feature := f.getFeature(featureID)
 	if feature == nil {
 		f.telegram.Send(fmt.Sprintf("Feature `%s` not found", featureID))
 		return
 	}
 
-	// Mark current task as complete
-	if feature.CurrentTask != nil {
-		feature.CurrentTask.Status = StatusComplete
+	// Older approval buttons only carry the feature ID
+	task := feature.getCurrentTask()
+	if taskID != "" {
+		task = feature.FindTask(taskID)
+	}
+	if task == nil {
+		f.telegram.Send(fmt.Sprintf("No task awaiting approval in feature `%s`", featureID))
+		return
 	}
 
-	// Check if all tasks are complete
-	allComplete := true
-	for _, task := range feature.Tasks {
-		if task.Status != StatusComplete {
-			allComplete = false
-			break
+	if task.BaseBranch != "" {
+		if err := f.integrateTask(task); err != nil {
+			return
 		}
 	}
 
-	if allComplete {
+	f.finishTaskApproval(feature, task)
+}
+
+// finishTaskApproval marks a merged task complete and moves the feature on:
+// either to completion or to the next tasks the scheduler releases.
+func (f *Foreman) finishTaskApproval(feature *Feature, task *Task) {
+	featureID := feature.ID
+	task.setStatus(StatusComplete)
+	clearSession(task)
+	f.recordTask(task, EventApproval, "", "user")
+
+	sched, err := f.ensureScheduler(feature)
+	if err != nil {
+		f.handlePhaseError(feature, fmt.Errorf("invalid task dependencies: %w", err))
+		return
+	}
+	sched.Complete(task.ID)
+
+	if sched.Done() {
 		f.completeFeature(feature)
 		return
 	}
 
-	// Find the next sequential (non-parallel) task that isn't complete
-	for _, task := range feature.Tasks {
-		if !task.IsParallel && task.Status == StatusPending {
-			feature.CurrentTask = task
-			f.telegram.Send(fmt.Sprintf("Starting next task `%s` for feature `%s`...", task.ID, featureID))
-			f.taskQueue <- task
-			return
-		}
+	f.transitionTask(feature, PhaseImplementing, fmt.Sprintf("Task %s approved", task.ID), "user")
+	f.saveFeatureToStorage(feature)
+
+	if n := f.dispatchReadyTasks(feature); n > 0 {
+		f.telegram.Send(fmt.Sprintf("Task `%s` approved. Starting %d more task(s) for feature `%s`...", task.ID, n, featureID))
+		return
 	}
 
-	// If we get here, there are still parallel tasks running
-	f.telegram.Send(fmt.Sprintf("Waiting for remaining parallel tasks in feature `%s`...", featureID))
+	if sched.InFlight() > 0 {
+		f.telegram.Send(fmt.Sprintf("Task `%s` approved. Waiting for %d in-flight task(s) in feature `%s`...", task.ID, sched.InFlight(), featureID))
+		return
+	}
+
+	f.telegram.Send(fmt.Sprintf("Task `%s` approved, but no remaining task in feature `%s` can start: a prerequisite has failed. Use the retry buttons to continue.", task.ID, featureID))
+}
+
+// integrateTask merges an approved task branch into its base branch and
+// reports the outcome. The task stays awaiting approval if the merge fails;
+// on conflict an agent is asked to resolve it.
+func (f *Foreman) integrateTask(task *Task) error {
+	err := f.repo.IntegrateBranch(task.BaseBranch, task.Branch)
+
+	var conflict *git.MergeConflictError
+	if errors.As(err, &conflict) {
+		f.handleMergeConflict(task, task.BaseBranch, conflict)
+		return err
+	}
+	if err != nil {
+		f.telegram.Send(fmt.Sprintf("*Merge Failed*\nTask: `%s`\nError: %s", task.ID, validation.SanitizeErrorMessage(err)))
+		return err
+	}
+
+	if err := f.repo.PushBranch(task.BaseBranch); err != nil {
+		log.Printf("Warning: Failed to push %s: %v", task.BaseBranch, err)
+	}
+	f.repo.DeleteBranch(task.Branch)
+
+	return nil
+}
+
+// baseBranch returns the branch a task's changes are reviewed and merged against.
+func (f *Foreman) baseBranch(task *Task) string {
+	if task.BaseBranch != "" {
+		return task.BaseBranch
+	}
+	return f.cfg.Repo.MainBranch
+}
+
+func formatFileList(files []string) string {
+	var b strings.Builder
+	for _, file := range files {
+		fmt.Fprintf(&b, "  - `%s`\n", file)
+	}
+	return b.String()
 }
 
 // Pending feedback management
@@ -852,6 +1371,10 @@ func (f *Foreman) handleFeedbackMessage(text string) {
 
 	ctx := context.Background()
 
+	if feedback.Phase != "code" {
+		f.recordStage(EventFeedback, feature.ID, feedback.Phase, text)
+	}
+
 	switch feedback.Phase {
 	case "spec":
 		f.telegram.Send(fmt.Sprintf("Feedback received for spec. Re-running specification for `%s`...", feedback.FeatureID))
@@ -875,9 +1398,12 @@ func (f *Foreman) handleFeedbackMessage(text string) {
 				if task.ID == feedback.TaskID {
 					f.telegram.Send(fmt.Sprintf("Feedback received for task `%s`. Re-queuing with feedback...", feedback.TaskID))
 					task.AddContext(fmt.Sprintf("User Feedback:\n%s", text))
+					task.mu.Lock()
 					task.Attempt = 0
 					task.Status = StatusPending
-					f.taskQueue <- task
+					task.mu.Unlock()
+					f.recordTask(task, EventFeedback, text, "user")
+					f.enqueue(task)
 					return
 				}
 			}
@@ -931,6 +1457,34 @@ func (f *Foreman) saveFeatureToStorage(feature *Feature) {
 	}
 }
 
+// saveReview records a review result so a task's review history survives
+// restarts
+func (f *Foreman) saveReview(task *Task, review *agents.ReviewResult) {
+	if f.storage == nil {
+		return
+	}
+
+	err := f.storage.SaveReview(&storage.ReviewState{
+		FeatureID:      task.FeatureID,
+		TaskID:         task.ID,
+		Attempt:        task.Attempt,
+		Verdict:        string(review.Verdict),
+		Summary:        review.Summary,
+		BlockingIssues: review.BlockingIssues,
+		Suggestions:    review.Suggestions,
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to save review of task %s: %v", task.ID, err)
+	}
+}
+
+// saveTaskFeature persists the feature a task belongs to, if any
+func (f *Foreman) saveTaskFeature(task *Task) {
+	if feature := f.getFeature(task.FeatureID); feature != nil {
+		f.saveFeatureToStorage(feature)
+	}
+}
+
 func (f *Foreman) featureToState(feature *Feature) *storage.FeatureState {
 	feature.mu.RLock()
 	defer feature.mu.RUnlock()
@@ -940,7 +1494,13 @@ func (f *Foreman) featureToState(feature *Feature) *storage.FeatureState {
 		Name:        feature.Name,
 		Description: feature.Description,
 		Branch:      feature.Branch,
+		PRNumber:    feature.PRNumber,
+		PRURL:       feature.PRURL,
+		SpecDir:     feature.SpecDir,
 		Phase:       string(feature.Phase),
+		Spec:        specToState(feature.Spec),
+		Plan:        planToState(feature.Plan),
+		TaskIndex:   feature.TaskIndex,
 		TechStack:   feature.TechStack,
 		Constraints: feature.Constraints,
 		CreatedAt:   feature.CreatedAt,
@@ -948,29 +1508,127 @@ func (f *Foreman) featureToState(feature *Feature) *storage.FeatureState {
 		Answers:     feature.Answers,
 	}
 
+	if feature.CurrentTask != nil {
+		state.CurrentTask = feature.CurrentTask.ID
+	}
+
+	for _, q := range feature.PendingQuestions {
+		state.PendingQuestions = append(state.PendingQuestions, storage.QuestionState{
+			ID:       q.ID,
+			Question: q.Question,
+			Context:  q.Context,
+			Answered: q.Answered,
+			Answer:   q.Answer,
+		})
+	}
+
+	for _, e := range feature.Events {
+		state.Events = append(state.Events, storage.EventState{
+			Timestamp: e.Timestamp,
+			FromPhase: string(e.FromPhase),
+			ToPhase:   string(e.ToPhase),
+			Message:   e.Message,
+			Actor:     e.Actor,
+		})
+	}
+
 	for _, task := range feature.Tasks {
-		state.Tasks = append(state.Tasks, storage.TaskState{
-			ID:         task.ID,
-			Spec:       task.Spec,
-			Status:     string(task.Status),
-			Branch:     task.Branch,
-			AgentName:  task.AgentName,
-			IsParallel: task.IsParallel,
-			Attempt:    task.Attempt,
-			FeatureID:  task.FeatureID,
+		state.Tasks = append(state.Tasks, taskToState(task))
+	}
+
+	return state
+}
+
+// taskToState snapshots task; the state shares nothing with it, so it can be
+// saved while the task keeps changing
+func taskToState(task *Task) storage.TaskState {
+	task.mu.Lock()
+	defer task.mu.Unlock()
+
+	return storage.TaskState{
+		ID:           task.ID,
+		Spec:         task.Spec,
+		Context:      task.Context,
+		Status:       string(task.Status),
+		Branch:       task.Branch,
+		BaseBranch:   task.BaseBranch,
+		WorktreePath: task.WorktreePath,
+		AgentName:    task.AgentName,
+		Timeout:      task.Timeout,
+		IsParallel:   task.IsParallel,
+		Attempt:      task.Attempt,
+		CreatedAt:    task.CreatedAt,
+		FeatureID:    task.FeatureID,
+		Dependencies: slices.Clone(task.Dependencies),
+		FilePaths:    slices.Clone(task.FilePaths),
+		Metadata:     maps.Clone(task.Metadata),
+	}
+}
+
+func specToState(spec *speckit.Spec) *storage.SpecState {
+	if spec == nil {
+		return nil
+	}
+
+	state := &storage.SpecState{
+		Title:        spec.Title,
+		Description:  spec.Description,
+		Requirements: spec.Requirements,
+		RawContent:   spec.RawContent,
+		FilePath:     spec.FilePath,
+	}
+	for _, story := range spec.UserStories {
+		state.UserStories = append(state.UserStories, storage.UserStoryState{
+			ID:          story.ID,
+			Title:       story.Title,
+			Description: story.Description,
+			Acceptance:  story.Acceptance,
 		})
 	}
+	return state
+}
 
+func planToState(plan *speckit.Plan) *storage.PlanState {
+	if plan == nil {
+		return nil
+	}
+
+	state := &storage.PlanState{
+		Overview:     plan.Overview,
+		TechStack:    plan.TechStack,
+		Architecture: plan.Architecture,
+		RawContent:   plan.RawContent,
+		FilePath:     plan.FilePath,
+	}
+	for _, phase := range plan.Phases {
+		state.Phases = append(state.Phases, storage.PlanPhaseState{
+			Name:        phase.Name,
+			Description: phase.Description,
+			Steps:       phase.Steps,
+		})
+	}
 	return state
 }
 
 func (f *Foreman) featureStateToFeature(state *storage.FeatureState) *Feature {
+	return stateToFeature(state, f.cfg.Concurrency.TaskTimeout)
+}
+
+// stateToFeature restores a feature. Tasks saved without a timeout get
+// taskTimeout.
+func stateToFeature(state *storage.FeatureState, taskTimeout time.Duration) *Feature {
 	feature := &Feature{
 		ID:          state.ID,
 		Name:        state.Name,
 		Description: state.Description,
 		Branch:      state.Branch,
+		PRNumber:    state.PRNumber,
+		PRURL:       state.PRURL,
+		SpecDir:     state.SpecDir,
 		Phase:       Phase(state.Phase),
+		Spec:        stateToSpec(state.Spec),
+		Plan:        stateToPlan(state.Plan),
+		TaskIndex:   state.TaskIndex,
 		TechStack:   state.TechStack,
 		Constraints: state.Constraints,
 		CreatedAt:   state.CreatedAt,
@@ -983,21 +1641,114 @@ func (f *Foreman) featureStateToFeature(state *storage.FeatureState) *Feature {
 		feature.Answers = make(map[string]string)
 	}
 
+	for _, q := range state.PendingQuestions {
+		feature.PendingQuestions = append(feature.PendingQuestions, speckit.Question{
+			ID:       q.ID,
+			Question: q.Question,
+			Context:  q.Context,
+			Answered: q.Answered,
+			Answer:   q.Answer,
+		})
+	}
+
+	for _, e := range state.Events {
+		feature.Events = append(feature.Events, WorkflowEvent{
+			Timestamp: e.Timestamp,
+			FromPhase: Phase(e.FromPhase),
+			ToPhase:   Phase(e.ToPhase),
+			Message:   e.Message,
+			Actor:     e.Actor,
+		})
+	}
+
 	for _, ts := range state.Tasks {
-		task := &Task{
-			ID:         ts.ID,
-			Spec:       ts.Spec,
-			Status:     TaskStatus(ts.Status),
-			Branch:     ts.Branch,
-			AgentName:  ts.AgentName,
-			IsParallel: ts.IsParallel,
-			Attempt:    ts.Attempt,
-			FeatureID:  ts.FeatureID,
-			Timeout:    f.cfg.Concurrency.TaskTimeout,
-			Metadata:   make(map[string]string),
-		}
+		task := stateToTask(ts, taskTimeout)
 		feature.Tasks = append(feature.Tasks, task)
+		if task.ID == state.CurrentTask {
+			feature.CurrentTask = task
+		}
 	}
 
 	return feature
 }
+
+func (f *Foreman) taskFromState(ts storage.TaskState) *Task {
+	return stateToTask(ts, f.cfg.Concurrency.TaskTimeout)
+}
+
+// stateToTask restores a task, defaulting its timeout to taskTimeout
+func stateToTask(ts storage.TaskState, taskTimeout time.Duration) *Task {
+	task := &Task{
+		ID:           ts.ID,
+		Spec:         ts.Spec,
+		Context:      ts.Context,
+		Status:       TaskStatus(ts.Status),
+		Branch:       ts.Branch,
+		BaseBranch:   ts.BaseBranch,
+		WorktreePath: ts.WorktreePath,
+		AgentName:    ts.AgentName,
+		Timeout:      ts.Timeout,
+		IsParallel:   ts.IsParallel,
+		Attempt:      ts.Attempt,
+		CreatedAt:    ts.CreatedAt,
+		FeatureID:    ts.FeatureID,
+		Dependencies: slices.Clone(ts.Dependencies),
+		FilePaths:    slices.Clone(ts.FilePaths),
+		Metadata:     maps.Clone(ts.Metadata),
+	}
+
+	// Tasks saved before timeouts were persisted use the configured one
+	if task.Timeout == 0 {
+		task.Timeout = taskTimeout
+	}
+	if task.Metadata == nil {
+		task.Metadata = make(map[string]string)
+	}
+
+	return task
+}
+
+func stateToSpec(state *storage.SpecState) *speckit.Spec {
+	if state == nil {
+		return nil
+	}
+
+	spec := &speckit.Spec{
+		Title:        state.Title,
+		Description:  state.Description,
+		Requirements: state.Requirements,
+		RawContent:   state.RawContent,
+		FilePath:     state.FilePath,
+	}
+	for _, story := range state.UserStories {
+		spec.UserStories = append(spec.UserStories, speckit.UserStory{
+			ID:          story.ID,
+			Title:       story.Title,
+			Description: story.Description,
+			Acceptance:  story.Acceptance,
+		})
+	}
+	return spec
+}
+
+func stateToPlan(state *storage.PlanState) *speckit.Plan {
+	if state == nil {
+		return nil
+	}
+
+	plan := &speckit.Plan{
+		Overview:     state.Overview,
+		TechStack:    state.TechStack,
+		Architecture: state.Architecture,
+		RawContent:   state.RawContent,
+		FilePath:     state.FilePath,
+	}
+	for _, phase := range state.Phases {
+		plan.Phases = append(plan.Phases, speckit.PlanPhase{
+			Name:        phase.Name,
+			Description: phase.Description,
+			Steps:       phase.Steps,
+		})
+	}
+	return plan
+}
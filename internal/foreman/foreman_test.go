@@ -2,10 +2,14 @@ package foreman
 
 import (
 	"context"
+	"encoding/json"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/bayological/foreman/internal/agents"
+	"github.com/bayological/foreman/internal/speckit"
+	"github.com/bayological/foreman/internal/storage"
 )
 
 func TestNewForeman(t *testing.T) {
@@ -161,14 +165,15 @@ func TestGetFeatures(t *testing.T) {
 
 func TestTaskTracking(t *testing.T) {
 	f := &Foreman{
-		active: make(map[string]context.CancelFunc),
+		active: make(map[storage.QueueKey]activeTask),
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	// Test tracking
-	f.trackTask("task-1", cancel)
+	task1 := &Task{ID: "task-1"}
+	f.trackTask(task1, 1, cancel)
 	ids := f.getActiveTaskIDs()
 	if len(ids) != 1 {
 		t.Errorf("expected 1 active task, got %d", len(ids))
@@ -178,7 +183,7 @@ func TestTaskTracking(t *testing.T) {
 	}
 
 	// Test cancel
-	cancelled := f.cancelTask("task-1")
+	cancelled := f.cancelTask(queueKey(task1))
 	if !cancelled {
 		t.Error("expected cancel to return true")
 	}
@@ -192,8 +197,9 @@ func TestTaskTracking(t *testing.T) {
 	}
 
 	// Test untracking
-	f.trackTask("task-2", func() {})
-	f.untrackTask("task-2")
+	task2 := &Task{ID: "task-2"}
+	f.trackTask(task2, 1, func() {})
+	f.untrackTask(task2, 1)
 	ids = f.getActiveTaskIDs()
 	// Note: task-1 was only cancelled, not removed
 	if len(ids) != 1 {
@@ -201,12 +207,38 @@ func TestTaskTracking(t *testing.T) {
 	}
 
 	// Cancel non-existent task
-	cancelled = f.cancelTask("nonexistent")
+	cancelled = f.cancelTask(storage.QueueKey{TaskID: "nonexistent"})
 	if cancelled {
 		t.Error("expected cancel to return false for non-existent task")
 	}
 }
 
+func TestTaskTrackingByLease(t *testing.T) {
+	f := &Foreman{
+		active: make(map[storage.QueueKey]activeTask),
+	}
+
+	// A retry leased while the first run is still winding down
+	task := &Task{ID: "T001", FeatureID: "f1"}
+	f.trackTask(task, 1, func() {})
+	f.trackTask(task, 2, func() {})
+
+	if f.holdsTask(task, 1) || !f.holdsTask(task, 2) {
+		t.Error("the latest lease should hold the task")
+	}
+	f.untrackTask(task, 1)
+	if !f.isTaskActive(task) {
+		t.Error("the first run finishing must not untrack the retry")
+	}
+	if f.isTaskActive(&Task{ID: "T001", FeatureID: "f2"}) {
+		t.Error("the same task ID in another feature is a different task")
+	}
+	f.untrackTask(task, 2)
+	if f.isTaskActive(task) {
+		t.Error("the retry finishing should untrack the task")
+	}
+}
+
 func TestGetAgentNames(t *testing.T) {
 	f := &Foreman{
 		agents: make(map[string]agents.Agent),
@@ -257,3 +289,190 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+func TestBaseBranch(t *testing.T) {
+	f := &Foreman{cfg: &Config{Repo: RepoConfig{MainBranch: "main"}}}
+
+	if got := f.baseBranch(&Task{}); got != "main" {
+		t.Errorf("baseBranch() for standalone task = %q, want main", got)
+	}
+	if got := f.baseBranch(&Task{BaseBranch: "feature/1-x"}); got != "feature/1-x" {
+		t.Errorf("baseBranch() for feature task = %q, want feature/1-x", got)
+	}
+}
+
+func TestFeatureStateKeepsSpecDir(t *testing.T) {
+	f := &Foreman{cfg: &Config{}}
+	feature := NewFeature("1", "Auth", "Login")
+	feature.SetSpecDir("004-auth")
+
+	state := f.featureToState(feature)
+	if state.SpecDir != "004-auth" {
+		t.Errorf("state.SpecDir = %q, want 004-auth", state.SpecDir)
+	}
+
+	restored := f.featureStateToFeature(state)
+	if restored.GetSpecDir() != "004-auth" {
+		t.Errorf("restored SpecDir = %q, want 004-auth", restored.GetSpecDir())
+	}
+}
+
+func fullFeature() *Feature {
+	created := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	task1 := &Task{
+		ID:           "T001",
+		Spec:         "Add the login form",
+		Context:      "Review Feedback (attempt 1):\nValidate the email field",
+		Branch:       "feature/1-auth/T001",
+		BaseBranch:   "feature/1-auth",
+		WorktreePath: "/repo/.worktrees/feature/1-auth/T001",
+		AgentName:    "claude-code",
+		Timeout:      45 * time.Minute,
+		Attempt:      1,
+		Status:       StatusApproval,
+		CreatedAt:    created,
+		FeatureID:    "1",
+		FilePaths:    []string{"web/login.tsx"},
+		Metadata:     map[string]string{"user_story": "US1"},
+	}
+	task2 := &Task{
+		ID:           "T002",
+		Spec:         "Add the session API",
+		Branch:       "feature/1-auth/T002",
+		BaseBranch:   "feature/1-auth",
+		AgentName:    "codex",
+		Timeout:      30 * time.Minute,
+		Status:       StatusPending,
+		CreatedAt:    created,
+		FeatureID:    "1",
+		IsParallel:   true,
+		Dependencies: []string{"T001"},
+		Metadata:     map[string]string{},
+	}
+
+	return &Feature{
+		ID:          "1",
+		Name:        "Auth",
+		Description: "Login with email",
+		Branch:      "feature/1-auth",
+		PRNumber:    7,
+		PRURL:       "https://github.com/o/r/pull/7",
+		SpecDir:     "004-auth",
+		Phase:       PhaseAwaitingCodeApproval,
+		CurrentTask: task1,
+		Spec: &speckit.Spec{
+			Title:       "Auth",
+			Description: "Users log in",
+			UserStories: []speckit.UserStory{
+				{ID: "US1", Title: "Log in", Description: "As a user", Acceptance: []string{"Sees dashboard"}},
+			},
+			Requirements: []string{"FR-001: Email login"},
+			RawContent:   "# Auth\n",
+			FilePath:     ".specify/specs/004-auth/spec.md",
+		},
+		Plan: &speckit.Plan{
+			Overview:     "Session cookies",
+			TechStack:    []string{"Go", "React"},
+			Architecture: "Handlers and a session store",
+			Phases:       []speckit.PlanPhase{{Name: "Setup", Description: "Scaffolding", Steps: []string{"Add routes"}}},
+			RawContent:   "# Plan\n",
+			FilePath:     ".specify/specs/004-auth/plan.md",
+		},
+		Tasks:     []*Task{task1, task2},
+		TaskIndex: 1,
+		PendingQuestions: []speckit.Question{
+			{ID: "Q1", Question: "SSO?", Context: "Enterprise users", Answered: true, Answer: "No"},
+		},
+		Answers: map[string]string{"Q1": "No"},
+		Events: []WorkflowEvent{
+			{Timestamp: created, FromPhase: PhaseIdle, ToPhase: PhaseSpecifying, Message: "Starting", Actor: "foreman"},
+			{Timestamp: created.Add(time.Hour), FromPhase: PhaseReviewing, ToPhase: PhaseAwaitingCodeApproval, Message: "Task T001 awaiting approval", Actor: "foreman"},
+		},
+		CreatedAt:   created,
+		UpdatedAt:   created.Add(2 * time.Hour),
+		TechStack:   "Go",
+		Constraints: "No new dependencies",
+	}
+}
+
+func TestFeatureStateRoundTrip(t *testing.T) {
+	f := &Foreman{cfg: &Config{Concurrency: ConcurrencyConfig{TaskTimeout: time.Minute}}}
+	feature := fullFeature()
+
+	restored := f.featureStateToFeature(f.featureToState(feature))
+	if !reflect.DeepEqual(restored, feature) {
+		t.Errorf("round trip lost state:\n got %+v\nwant %+v", restored, feature)
+	}
+	if restored.CurrentTask != restored.Tasks[0] {
+		t.Error("CurrentTask should point at the restored task, not a copy")
+	}
+}
+
+func TestFeatureStateRoundTripThroughJSON(t *testing.T) {
+	f := &Foreman{cfg: &Config{Concurrency: ConcurrencyConfig{TaskTimeout: time.Minute}}}
+	feature := fullFeature()
+
+	data, err := json.Marshal(f.featureToState(feature))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var state storage.FeatureState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	restored := f.featureStateToFeature(&state)
+	if !reflect.DeepEqual(restored, feature) {
+		t.Errorf("JSON round trip lost state:\n got %+v\nwant %+v", restored, feature)
+	}
+}
+
+func TestFeatureStateDefaults(t *testing.T) {
+	f := &Foreman{cfg: &Config{Concurrency: ConcurrencyConfig{TaskTimeout: time.Minute}}}
+
+	// States saved before tasks carried timeouts and metadata
+	restored := f.featureStateToFeature(&storage.FeatureState{
+		ID:    "1",
+		Tasks: []storage.TaskState{{ID: "T001"}},
+	})
+
+	task := restored.Tasks[0]
+	if task.Timeout != time.Minute {
+		t.Errorf("Timeout = %v, want the configured %v", task.Timeout, time.Minute)
+	}
+	if task.Metadata == nil || restored.Answers == nil || restored.Events == nil {
+		t.Error("expected maps and slices to be initialised")
+	}
+	if restored.CurrentTask != nil {
+		t.Errorf("CurrentTask = %v, want nil", restored.CurrentTask)
+	}
+}
+
+// TestFullFeatureCoversEveryField keeps the round-trip tests honest: a field
+// added to Feature or Task must be set in fullFeature, and so persisted.
+func TestFullFeatureCoversEveryField(t *testing.T) {
+	feature := fullFeature()
+	assertSet := func(values ...reflect.Value) {
+		typ := values[0].Type()
+		for i := 0; i < typ.NumField(); i++ {
+			if !typ.Field(i).IsExported() {
+				continue
+			}
+			set := false
+			for _, v := range values {
+				set = set || !v.Field(i).IsZero()
+			}
+			if !set {
+				t.Errorf("fullFeature leaves %s.%s unset", typ.Name(), typ.Field(i).Name)
+			}
+		}
+	}
+
+	assertSet(reflect.ValueOf(feature).Elem())
+	var tasks []reflect.Value
+	for _, task := range feature.Tasks {
+		tasks = append(tasks, reflect.ValueOf(task).Elem())
+	}
+	assertSet(tasks...)
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/bayological/foreman/internal/storage"
 )
 
 func (f *Foreman) registerHandlers() {
@@ -13,6 +15,7 @@ func (f *Foreman) registerHandlers() {
 	f.telegram.RegisterCommand("features", f.handleListFeatures)
 	f.telegram.RegisterCommand("feature", f.handleFeatureStatus)
 	f.telegram.RegisterCommand("resume", f.handleResume)
+	f.telegram.RegisterCommand("pr", f.handlePullRequest)
 
 	// Phase-specific commands
 	f.telegram.RegisterCommand("techstack", f.handleSetTechStack)
@@ -22,11 +25,16 @@ func (f *Foreman) registerHandlers() {
 	// Legacy task commands (still supported)
 	f.telegram.RegisterCommand("assign", f.handleAssign)
 	f.telegram.RegisterCommand("cancel", f.handleCancel)
+	f.telegram.RegisterCommand("reassign", f.handleReassign)
+	f.telegram.RegisterCommand("compete", f.handleCompete)
 
 	// General commands
 	f.telegram.RegisterCommand("agents", f.handleAgents)
 	f.telegram.RegisterCommand("help", f.handleHelp)
 	f.telegram.RegisterCommand("status", f.handleStatus)
+	f.telegram.RegisterCommand("costs", f.handleCosts)
+	f.telegram.RegisterCommand("logs", f.handleLogs)
+	f.telegram.RegisterCommand("doctor", f.handleDoctor)
 
 	// Legacy approval callbacks
 	f.telegram.RegisterCallback("approve", f.handleApprove)
@@ -44,6 +52,11 @@ func (f *Foreman) registerHandlers() {
 	f.telegram.RegisterCallback("reject_code", f.handleRejectCode)
 	f.telegram.RegisterCallback("request_changes", f.handleRequestChanges)
 	f.telegram.RegisterCallback("retry", f.handleRetry)
+	f.telegram.RegisterCallback("pick_candidate", f.handlePickCandidate)
+	f.telegram.RegisterCallback("approve_resolution", f.handleApproveResolution)
+	f.telegram.RegisterCallback("reject_resolution", f.handleRejectResolution)
+	f.telegram.RegisterCallback("approve_budget", f.handleApproveBudget)
+	f.telegram.RegisterCallback("stop_budget", f.handleStopBudget)
 
 	// Register message handler for feedback text
 	f.telegram.RegisterMessageHandler(f.handleFeedbackMessage)
@@ -103,6 +116,27 @@ func (f *Foreman) handleFeatureStatus(args string) {
 	f.telegram.Send(feature.StatusReport())
 }
 
+func (f *Foreman) handlePullRequest(args string) {
+	featureID := strings.TrimSpace(args)
+	if featureID == "" {
+		f.telegram.Send("Usage: /pr <feature_id>")
+		return
+	}
+
+	feature := f.getFeature(featureID)
+	if feature == nil {
+		f.telegram.Send(fmt.Sprintf("Feature `%s` not found", featureID))
+		return
+	}
+
+	report, err := f.pullRequestReport(context.Background(), feature)
+	if err != nil {
+		f.telegram.Send(fmt.Sprintf("Cannot show pull request: %v", err))
+		return
+	}
+	f.telegram.Send(report)
+}
+
 func (f *Foreman) handleResume(args string) {
 	featureID := strings.TrimSpace(args)
 	if featureID == "" {
@@ -159,7 +193,7 @@ func (f *Foreman) handleResume(args string) {
 
 	case PhaseFailed:
 		f.telegram.Send(fmt.Sprintf("Feature `%s` has failed. Restarting from specification...", featureID))
-		feature.Phase = PhaseIdle
+		f.transition(feature, PhaseIdle, "Restarting after failure", "user")
 		go f.runSpecificationPhase(ctx, feature)
 
 	default:
@@ -251,6 +285,7 @@ func (f *Foreman) handleApproveSpec(data string) {
 func (f *Foreman) handleRejectSpec(data string) {
 	featureID := strings.TrimPrefix(data, "reject_spec:")
 	f.setPendingFeedback(featureID, "spec", "")
+	f.recordStage(EventRejection, featureID, "spec", "")
 	f.telegram.Send(fmt.Sprintf("Spec rejected for `%s`. Please type your feedback:", featureID))
 }
 
@@ -263,6 +298,7 @@ func (f *Foreman) handleApprovePlan(data string) {
 func (f *Foreman) handleRejectPlan(data string) {
 	featureID := strings.TrimPrefix(data, "reject_plan:")
 	f.setPendingFeedback(featureID, "plan", "")
+	f.recordStage(EventRejection, featureID, "plan", "")
 	f.telegram.Send(fmt.Sprintf("Plan rejected for `%s`. Please type your feedback:", featureID))
 }
 
@@ -275,33 +311,75 @@ func (f *Foreman) handleApproveTasks(data string) {
 func (f *Foreman) handleRejectTasks(data string) {
 	featureID := strings.TrimPrefix(data, "reject_tasks:")
 	f.setPendingFeedback(featureID, "tasks", "")
+	f.recordStage(EventRejection, featureID, "tasks", "")
 	f.telegram.Send(fmt.Sprintf("Tasks rejected for `%s`. Please type your feedback:", featureID))
 }
 
 func (f *Foreman) handleApproveCode(data string) {
-	featureID := strings.TrimPrefix(data, "approve_code:")
+	featureID, taskID := parseFeatureTaskRef(strings.TrimPrefix(data, "approve_code:"))
 	ctx := context.Background()
-	f.approveFeatureCode(ctx, featureID)
+	f.approveFeatureCode(ctx, featureID, taskID)
 }
 
 func (f *Foreman) handleRejectCode(data string) {
-	featureID := strings.TrimPrefix(data, "reject_code:")
+	featureID, taskID := parseFeatureTaskRef(strings.TrimPrefix(data, "reject_code:"))
 	feature := f.getFeature(featureID)
-	if feature != nil && feature.CurrentTask != nil {
-		f.cancelTask(feature.CurrentTask.ID)
+	if taskID == "" && feature != nil {
+		if current := feature.getCurrentTask(); current != nil {
+			taskID = current.ID
+		}
+	}
+	if taskID != "" {
+		f.cancelTask(storage.QueueKey{FeatureID: featureID, TaskID: taskID})
+	}
+	f.recordEvent(storage.LogEvent{Type: EventRejection, FeatureID: featureID, TaskID: taskID, Actor: "user", Data: map[string]string{"stage": "code"}})
+	if feature != nil {
+		if task := feature.FindTask(taskID); task != nil {
+			task.mu.Lock()
+			candidates := task.Metadata[metaCandidates]
+			delete(task.Metadata, metaCandidates)
+			task.mu.Unlock()
+			if candidates != "" {
+				f.discardCandidates(decodeCandidates(candidates))
+			}
+			// The feature cannot finish without the task; a retry runs it again
+			f.markFailed(task, "Code rejected")
+		}
 	}
 	f.telegram.Send(fmt.Sprintf("Code rejected for `%s`. Task cancelled.", featureID))
 }
 
 func (f *Foreman) handleRequestChanges(data string) {
-	featureID := strings.TrimPrefix(data, "request_changes:")
+	featureID, taskID := parseFeatureTaskRef(strings.TrimPrefix(data, "request_changes:"))
 	feature := f.getFeature(featureID)
-	var taskID string
-	if feature != nil && feature.CurrentTask != nil {
-		taskID = feature.CurrentTask.ID
+	if taskID == "" && feature != nil {
+		if current := feature.getCurrentTask(); current != nil {
+			taskID = current.ID
+		}
 	}
 	f.setPendingFeedback(featureID, "code", taskID)
-	f.telegram.Send(fmt.Sprintf("Please type your requested changes for feature `%s`:", featureID))
+	f.telegram.Send(fmt.Sprintf("Please type your requested changes for task `%s` in feature `%s`:", taskID, featureID))
+}
+
+// parseFeatureTaskRef splits "<feature_id>:<task_id>" callback data. The task
+// ID is empty for buttons that only reference a feature.
+func parseFeatureTaskRef(ref string) (featureID, taskID string) {
+	parts := strings.SplitN(ref, ":", 2)
+	featureID = parts[0]
+	if len(parts) > 1 {
+		taskID = parts[1]
+	}
+	return featureID, taskID
+}
+
+func (f *Foreman) handleApproveResolution(data string) {
+	featureID, taskID := parseFeatureTaskRef(strings.TrimPrefix(data, "approve_resolution:"))
+	f.ApproveResolution(featureID, taskID)
+}
+
+func (f *Foreman) handleRejectResolution(data string) {
+	featureID, taskID := parseFeatureTaskRef(strings.TrimPrefix(data, "reject_resolution:"))
+	f.RejectResolution(featureID, taskID)
 }
 
 func (f *Foreman) handleRetry(data string) {
@@ -311,10 +389,12 @@ func (f *Foreman) handleRetry(data string) {
 	// Find the task and re-queue it
 	f.featuresMu.RLock()
 	var targetTask *Task
+	var targetFeature *Feature
 	for _, feature := range f.features {
 		for _, task := range feature.Tasks {
 			if task.ID == taskID {
 				targetTask = task
+				targetFeature = feature
 				break
 			}
 		}
@@ -325,9 +405,17 @@ func (f *Foreman) handleRetry(data string) {
 	f.featuresMu.RUnlock()
 
 	if targetTask != nil {
+		if sched := targetFeature.getScheduler(); sched != nil {
+			sched.Redispatch(targetTask.ID)
+		}
+		targetTask.mu.Lock()
 		targetTask.Attempt = 0
 		targetTask.Status = StatusPending
-		f.taskQueue <- targetTask
+		targetTask.mu.Unlock()
+		f.recordTask(targetTask, EventTaskStatus, "Retry requested", "user")
+		if !f.enqueue(targetTask) {
+			f.telegram.Send(fmt.Sprintf("Task `%s` is already queued", taskID))
+		}
 	} else {
 		f.telegram.Send(fmt.Sprintf("Task `%s` not found", taskID))
 	}
@@ -337,16 +425,12 @@ func (f *Foreman) handleRetry(data string) {
 
 func (f *Foreman) handleApprove(data string) {
 	taskID := strings.TrimPrefix(data, "approve:")
-	if err := f.approveTask(taskID); err != nil {
-		f.telegram.Send(fmt.Sprintf("Merge failed for `%s`: %v", taskID, err))
-		return
-	}
-	f.telegram.Send(fmt.Sprintf("Task `%s` merged successfully", taskID))
+	f.mergeLegacyTask(taskID)
 }
 
 func (f *Foreman) handleReject(data string) {
 	taskID := strings.TrimPrefix(data, "reject:")
-	if f.cancelTask(taskID) {
+	if f.cancelTask(storage.QueueKey{TaskID: taskID}) {
 		f.telegram.Send(fmt.Sprintf("Task `%s` rejected and cancelled", taskID))
 	} else {
 		f.repo.DeleteBranch(fmt.Sprintf("task/%s", taskID))
@@ -415,14 +499,36 @@ func (f *Foreman) handleAssign(args string) {
 }
 
 func (f *Foreman) handleCancel(args string) {
-	id := strings.TrimSpace(args)
-	if id == "" {
-		f.telegram.Send("Usage: /cancel <task_id or feature_id>")
+	const usage = "Usage: /cancel <feature_id>, or /cancel [feature_id] <task_id>"
+
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 {
+		f.telegram.Send(usage)
+		return
+	}
+	if len(fields) == 2 {
+		key := storage.QueueKey{FeatureID: fields[0], TaskID: fields[1]}
+		if f.cancelTask(key) {
+			f.telegram.Send(fmt.Sprintf("Cancelled task `%s`", key))
+		} else {
+			f.telegram.Send(fmt.Sprintf("Task `%s` not found or not running", key))
+		}
 		return
 	}
+	id := fields[0]
 
-	// Try cancelling as task first
-	if f.cancelTask(id) {
+	// Try cancelling as task first: standalone, then in the feature it
+	// belongs to
+	if f.cancelTask(storage.QueueKey{TaskID: id}) {
+		f.telegram.Send(fmt.Sprintf("Cancelled task `%s`", id))
+		return
+	}
+	featureID, err := f.findTaskFeature(id)
+	if err != nil {
+		f.telegram.Send(fmt.Sprintf("%s\n%s", err, usage))
+		return
+	}
+	if featureID != "" && f.cancelTask(storage.QueueKey{FeatureID: featureID, TaskID: id}) {
 		f.telegram.Send(fmt.Sprintf("Cancelled task `%s`", id))
 		return
 	}
@@ -430,10 +536,11 @@ func (f *Foreman) handleCancel(args string) {
 	// Try cancelling as feature
 	feature := f.getFeature(id)
 	if feature != nil {
-		feature.Transition(PhaseFailed, "Cancelled by user", "user")
-		if feature.CurrentTask != nil {
-			f.cancelTask(feature.CurrentTask.ID)
+		if err := f.transition(feature, PhaseFailed, "Cancelled by user", "user"); err != nil {
+			f.telegram.Send(fmt.Sprintf("Cannot cancel feature `%s`: %s", id, err))
+			return
 		}
+		f.cancelFeatureTasks(id)
 		f.telegram.Send(fmt.Sprintf("Cancelled feature `%s`", id))
 		return
 	}
@@ -463,6 +570,7 @@ func (f *Foreman) handleHelp(args string) {
 /features - List all features
 /feature <id> - Show feature status
 /resume <id> - Resume interrupted feature
+/pr <id> - Show pull request checks and reviews
 /techstack <id> <stack> - Set tech stack
 /answer <id> Q1: ans1, Q2: ans2 - Answer clarifications
 /constitution <principles> - Set project principles
@@ -470,8 +578,13 @@ func (f *Foreman) handleHelp(args string) {
 *Other Commands:*
 /assign <agent> <spec> - Create task directly
 /cancel <id> - Cancel task or feature
+/reassign [feature] <task> <agent> - Hand a task to another agent
+/compete [feature] <task> <agent> <agent>... - Have agents compete for a task
 /status - Show all active work
+/costs [feature_id] - Show agent token usage and costs
+/logs [feature_id] <task_id> [attempt] - Show a task's agent and review logs
 /agents - List available agents
+/doctor - Check agents, tools and the repo are ready
 /help - Show this message
 
 *Workflow Phases:*
@@ -311,3 +311,23 @@ func splitOnComma(s string) []string {
 	parts = append(parts, current)
 	return parts
 }
+
+func TestParseFeatureTaskRef(t *testing.T) {
+	tests := []struct {
+		input       string
+		wantFeature string
+		wantTask    string
+	}{
+		{"12345:T-001", "12345", "T-001"},
+		{"12345", "12345", ""},
+		{"12345:", "12345", ""},
+	}
+
+	for _, tc := range tests {
+		featureID, taskID := parseFeatureTaskRef(tc.input)
+		if featureID != tc.wantFeature || taskID != tc.wantTask {
+			t.Errorf("parseFeatureTaskRef(%q) = (%q, %q), want (%q, %q)",
+				tc.input, featureID, taskID, tc.wantFeature, tc.wantTask)
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package foreman
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bayological/foreman/internal/agents"
+	"github.com/bayological/foreman/internal/tools"
+)
+
+// Preflight check outcomes
+const (
+	CheckPass = "pass"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+)
+
+// preflightTimeout bounds each check, so an unreachable remote or a hung
+// binary cannot hold up startup
+const preflightTimeout = 15 * time.Second
+
+// CheckResult is the outcome of one preflight check
+type CheckResult struct {
+	Name   string
+	Status string // pass, warn or fail
+	Detail string
+}
+
+// PreflightReport holds the results of a preflight run in a fixed order
+type PreflightReport []CheckResult
+
+// Count returns how many checks ended with status
+func (r PreflightReport) Count(status string) int {
+	n := 0
+	for _, c := range r {
+		if c.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed reports whether any check failed
+func (r PreflightReport) Failed() bool {
+	return r.Count(CheckFail) > 0
+}
+
+// Summary counts the results, e.g. "9 pass, 1 warn, 0 fail"
+func (r PreflightReport) Summary() string {
+	return fmt.Sprintf("%d pass, %d warn, %d fail", r.Count(CheckPass), r.Count(CheckWarn), r.Count(CheckFail))
+}
+
+// Table renders one aligned line per check, for a terminal
+func (r PreflightReport) Table() string {
+	width := 0
+	for _, c := range r {
+		width = max(width, len(c.Name))
+	}
+
+	var b strings.Builder
+	for _, c := range r {
+		fmt.Fprintf(&b, "%-4s  %-*s  %s\n", strings.ToUpper(c.Status), width, c.Name, c.Detail)
+	}
+	return b.String()
+}
+
+// String renders the report for Telegram
+func (r PreflightReport) String() string {
+	return fmt.Sprintf("*Preflight Checks* (%s)\n```\n%s```", r.Summary(), strings.ReplaceAll(r.Table(), "```", "'''"))
+}
+
+// prober runs the commands and requests the checks are made of; tests
+// replace it to check without touching the system
+type prober struct {
+	lookPath func(file string) (string, error)
+	run      func(ctx context.Context, dir, name string, args ...string) (string, error)
+	getenv   func(key string) string
+	exists   func(path string) bool
+	get      func(ctx context.Context, url, token string) (int, error)
+}
+
+var systemProber = prober{
+	lookPath: exec.LookPath,
+	run:      runProbe,
+	getenv:   os.Getenv,
+	exists: func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	},
+	get: httpStatus,
+}
+
+// runProbe runs a command with its output combined. Git is kept from
+// prompting for credentials, which would hang the check.
+func runProbe(ctx context.Context, dir, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if os.Getenv("GIT_SSH_COMMAND") == "" {
+		cmd.Env = append(cmd.Env, "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+	}
+	output, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
+
+// httpStatus requests url and returns the response status
+func httpStatus(ctx context.Context, url, token string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// Preflight checks that the agents and tools cfg relies on are installed
+// and logged in, that the remote answers and that worktrees can be
+// created, so a missing tool shows up before a task needs it
+func Preflight(ctx context.Context, cfg *Config) PreflightReport {
+	return systemProber.preflight(ctx, cfg)
+}
+
+type preflightCheck struct {
+	name string
+	run  func(ctx context.Context) (status, detail string)
+}
+
+// preflight runs the checks for cfg concurrently
+func (p prober) preflight(ctx context.Context, cfg *Config) PreflightReport {
+	checks := p.checks(cfg)
+	report := make(PreflightReport, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check preflightCheck) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, preflightTimeout)
+			defer cancel()
+			status, detail := check.run(ctx)
+			report[i] = CheckResult{Name: check.name, Status: status, Detail: detail}
+		}(i, check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// checks lists what cfg needs: git and the repo, the enabled agents,
+// SpecKit, the review tools and gh
+func (p prober) checks(cfg *Config) []preflightCheck {
+	repo := cfg.Repo
+	checks := []preflightCheck{
+		{"git", func(ctx context.Context) (string, string) { return p.binary(ctx, "git", CheckFail) }},
+		{"remote " + repo.Remote, func(ctx context.Context) (string, string) { return p.remote(ctx, repo) }},
+		{".worktrees", func(context.Context) (string, string) {
+			return checkWritable(filepath.Join(repo.Path, ".worktrees"))
+		}},
+	}
+
+	names := make([]string, 0, len(cfg.Agents))
+	for name, agentCfg := range cfg.Agents {
+		if agentCfg.Enabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	claudeChecked := false
+	for _, name := range names {
+		agentCfg := cfg.Agents[name]
+		kind := agentType(name, agentCfg)
+		claudeChecked = claudeChecked || kind == AgentTypeClaudeCode
+		checks = append(checks, preflightCheck{"agent " + name, func(ctx context.Context) (string, string) {
+			return p.agent(ctx, kind, agentCfg)
+		}})
+	}
+	// SpecKit and the LLM reviewer run Claude Code whichever agents
+	// implement the tasks
+	if !claudeChecked {
+		checks = append(checks, preflightCheck{"claude", p.claude})
+	}
+	checks = append(checks, preflightCheck{"specify", func(ctx context.Context) (string, string) {
+		return p.binary(ctx, "specify", CheckFail)
+	}})
+
+	// Review skips a missing linter or CodeRabbit, but a missing test
+	// runner blocks every task
+	review := cfg.Review.Tools
+	if review.CodeRabbit {
+		checks = append(checks, preflightCheck{"coderabbit", func(ctx context.Context) (string, string) {
+			return p.binary(ctx, "coderabbit", CheckWarn)
+		}})
+	}
+	for _, linter := range tools.NewLinter(review.Linters...).Names() {
+		linter, command := linter, tools.LinterCommand(linter)
+		checks = append(checks, preflightCheck{"linter " + linter, func(ctx context.Context) (string, string) {
+			status, detail := p.binary(ctx, command, CheckWarn)
+			if status == CheckPass && command != linter {
+				detail = fmt.Sprintf("%s (%s)", command, detail)
+			}
+			return status, detail
+		}})
+	}
+	testCommand := review.TestCommand
+	if testCommand == "" {
+		testCommand = agents.DefaultTestCommand
+	}
+	checks = append(checks, preflightCheck{"test_command", func(ctx context.Context) (string, string) {
+		return p.testCommand(ctx, testCommand)
+	}})
+
+	return append(checks, preflightCheck{"gh", p.gh})
+}
+
+// binary checks that name is on the PATH and reports its version. A
+// missing binary ends the check with the missing status.
+func (p prober) binary(ctx context.Context, name, missing string) (string, string) {
+	path, err := p.lookPath(name)
+	if err != nil {
+		return missing, name + " not found on PATH"
+	}
+	return CheckPass, p.version(ctx, path)
+}
+
+// version returns the first line of path --version, or path itself when
+// that prints nothing useful
+func (p prober) version(ctx context.Context, path string) string {
+	output, err := p.run(ctx, "", path, "--version")
+	line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	if err != nil || line == "" {
+		return path
+	}
+	return truncate(strings.TrimSpace(line), 60)
+}
+
+func (p prober) agent(ctx context.Context, kind string, cfg AgentConfig) (string, string) {
+	switch kind {
+	case AgentTypeClaudeCode:
+		return p.claude(ctx)
+	case AgentTypeCodex:
+		return p.codex(ctx)
+	case AgentTypeCLI:
+		if cfg.Command == "" {
+			return CheckFail, "no command configured"
+		}
+		return p.binary(ctx, cfg.Command, CheckFail)
+	case AgentTypeOpenAI:
+		return p.openAI(ctx, cfg)
+	case AgentTypeScripted:
+		switch {
+		case cfg.Script == "":
+			return CheckWarn, "no script configured"
+		case !p.exists(cfg.Script):
+			return CheckFail, "script not found: " + cfg.Script
+		}
+		return CheckPass, cfg.Script
+	}
+	return CheckFail, fmt.Sprintf("unknown agent type %q", kind)
+}
+
+// claude checks the Claude Code CLI. It is logged in with an API key or
+// token in the environment, or with credentials stored under the home
+// directory.
+func (p prober) claude(ctx context.Context) (string, string) {
+	status, detail := p.binary(ctx, "claude", CheckFail)
+	if status != CheckPass {
+		return status, detail
+	}
+	if p.getenv("ANTHROPIC_API_KEY") != "" || p.getenv("CLAUDE_CODE_OAUTH_TOKEN") != "" {
+		return CheckPass, detail + ", key set"
+	}
+	if home := p.getenv("HOME"); home != "" {
+		for _, path := range []string{filepath.Join(home, ".claude", ".credentials.json"), filepath.Join(home, ".claude.json")} {
+			if p.exists(path) {
+				return CheckPass, detail + ", logged in"
+			}
+		}
+	}
+	return CheckWarn, detail + ", no login found: run claude and /login, or set ANTHROPIC_API_KEY"
+}
+
+func (p prober) codex(ctx context.Context) (string, string) {
+	status, detail := p.binary(ctx, "codex", CheckFail)
+	if status != CheckPass {
+		return status, detail
+	}
+	if p.getenv("OPENAI_API_KEY") != "" {
+		return CheckPass, detail + ", key set"
+	}
+	if _, err := p.run(ctx, "", "codex", "login", "status"); err != nil {
+		return CheckWarn, detail + ", not logged in: run codex login"
+	}
+	return CheckPass, detail + ", logged in"
+}
+
+// openAI checks that the agent's server answers and accepts its key
+func (p prober) openAI(ctx context.Context, cfg AgentConfig) (string, string) {
+	if cfg.BaseURL == "" {
+		return CheckFail, "no base_url configured"
+	}
+	base := strings.TrimSuffix(cfg.BaseURL, "/")
+	code, err := p.get(ctx, base+"/models", cfg.APIKey)
+	switch {
+	case err != nil:
+		return CheckFail, "unreachable: " + err.Error()
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return CheckFail, fmt.Sprintf("%s rejected the API key (HTTP %d)", base, code)
+	case code >= 300:
+		return CheckWarn, fmt.Sprintf("%s answered HTTP %d", base, code)
+	}
+	return CheckPass, strings.TrimSpace(base + " " + cfg.Model)
+}
+
+// gh is optional: pull requests are opened through the forge API, but
+// agents may call gh to read issues and pull requests
+func (p prober) gh(ctx context.Context) (string, string) {
+	status, detail := p.binary(ctx, "gh", CheckWarn)
+	if status != CheckPass {
+		return status, detail
+	}
+	if _, err := p.run(ctx, "", "gh", "auth", "status"); err != nil {
+		return CheckWarn, detail + ", not logged in: run gh auth login"
+	}
+	return CheckPass, detail + ", logged in"
+}
+
+// remote checks that the remote answers and has the main branch
+func (p prober) remote(ctx context.Context, repo RepoConfig) (string, string) {
+	remoteURL, err := p.run(ctx, repo.Path, "git", "remote", "get-url", repo.Remote)
+	if err != nil {
+		return CheckFail, fmt.Sprintf("remote %s is not configured", repo.Remote)
+	}
+	remoteURL = redactURL(remoteURL)
+
+	output, err := p.run(ctx, repo.Path, "git", "ls-remote", "--heads", repo.Remote, repo.MainBranch)
+	if err != nil {
+		reason, _, _ := strings.Cut(output, "\n")
+		if reason == "" {
+			reason = err.Error()
+		}
+		return CheckFail, fmt.Sprintf("%s unreachable: %s", remoteURL, truncate(reason, 100))
+	}
+	if output == "" {
+		return CheckWarn, fmt.Sprintf("%s has no %s branch", remoteURL, repo.MainBranch)
+	}
+	return CheckPass, remoteURL
+}
+
+// redactURL drops credentials embedded in a remote URL
+func redactURL(remote string) string {
+	u, err := url.Parse(remote)
+	if err != nil || u.User == nil {
+		return remote
+	}
+	u.User = nil
+	return u.String()
+}
+
+// testCommand checks the program the review's test command starts
+func (p prober) testCommand(ctx context.Context, command string) (string, string) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return CheckFail, "empty test command"
+	}
+	status, detail := p.binary(ctx, fields[0], CheckFail)
+	if status != CheckPass {
+		return status, detail
+	}
+	return CheckPass, fmt.Sprintf("%s (%s)", command, detail)
+}
+
+// checkWritable checks that files can be created in dir, as worktrees are
+func checkWritable(dir string) (string, string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return CheckFail, err.Error()
+	}
+	file, err := os.CreateTemp(dir, ".preflight-*")
+	if err != nil {
+		return CheckFail, "not writable: " + err.Error()
+	}
+	file.Close()
+	os.Remove(file.Name())
+	return CheckPass, dir + " is writable"
+}
+
+func (f *Foreman) handleDoctor(args string) {
+	f.telegram.Send("Running preflight checks...")
+	f.telegram.Send(f.preflight(context.Background(), f.cfg).String())
+}
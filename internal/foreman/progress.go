@@ -0,0 +1,177 @@
+package foreman
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bayological/foreman/internal/agents"
+)
+
+const (
+	// progressInterval is the least time between edits of a progress
+	// message, keeping well inside Telegram's rate limits
+	progressInterval = 5 * time.Second
+
+	// progressLines is how many recent activities a progress message shows
+	progressLines = 5
+)
+
+// progressMessenger sends and edits the live progress message of a task
+type progressMessenger interface {
+	SendTracked(message string) (int, error)
+	Edit(messageID int, message string) error
+}
+
+// taskProgress keeps a single Telegram message per task up to date with
+// what its agent is doing
+type taskProgress struct {
+	messenger progressMessenger
+	taskID    string
+	agent     string
+	branch    string
+	started   time.Time
+	interval  time.Duration
+
+	mu        sync.Mutex
+	messageID int
+	turn      int
+	tools     int
+	recent    []string
+	dirty     bool
+
+	stop     chan struct{}
+	done     chan struct{}
+	finished sync.Once
+}
+
+// startProgress sends the task's progress message and keeps it updated
+// until finish is called
+func startProgress(messenger progressMessenger, task *Task, interval time.Duration) *taskProgress {
+	p := &taskProgress{
+		messenger: messenger,
+		taskID:    task.ID,
+		agent:     task.AgentName,
+		branch:    task.Branch,
+		started:   time.Now(),
+		interval:  interval,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	id, err := messenger.SendTracked(p.render("⚙️ *Task Started*"))
+	if err == nil {
+		p.messageID = id
+	}
+
+	go p.run()
+	return p
+}
+
+// update records an agent progress event; it is an agents.ProgressFunc
+func (p *taskProgress) update(event agents.ProgressEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if event.Turn > p.turn {
+		p.turn = event.Turn
+	}
+	if event.Kind == agents.ProgressTurn {
+		p.dirty = true
+		return
+	}
+
+	p.tools++
+	p.recent = append(p.recent, describeProgress(event))
+	if len(p.recent) > progressLines {
+		p.recent = p.recent[len(p.recent)-progressLines:]
+	}
+	p.dirty = true
+}
+
+// run edits the message at most once per interval while there is news
+func (p *taskProgress) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			dirty := p.dirty
+			p.dirty = false
+			p.mu.Unlock()
+
+			if dirty {
+				p.edit("⚙️ *Task Running*")
+			}
+		}
+	}
+}
+
+// finish stops the updates and leaves the message with a final status.
+// Only the first call has an effect.
+func (p *taskProgress) finish(success bool) {
+	p.finished.Do(func() {
+		close(p.stop)
+		<-p.done
+
+		if success {
+			p.edit("✅ *Agent Finished*")
+		} else {
+			p.edit("❌ *Agent Failed*")
+		}
+	})
+}
+
+func (p *taskProgress) edit(header string) {
+	if p.messageID == 0 {
+		return
+	}
+	p.messenger.Edit(p.messageID, p.render(header))
+}
+
+// render formats the progress message under header
+func (p *taskProgress) render(header string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\nID: `%s`\nAgent: %s\nBranch: `%s`\n", header, p.taskID, p.agent, p.branch)
+	fmt.Fprintf(&b, "Elapsed: %s", time.Since(p.started).Round(time.Second))
+	if p.turn > 0 {
+		fmt.Fprintf(&b, " · Turn %d · %d tool calls", p.turn, p.tools)
+	}
+
+	if len(p.recent) > 0 {
+		b.WriteString("\n\n*Latest:*")
+		for _, line := range p.recent {
+			b.WriteString("\n")
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
+
+// describeProgress renders one activity line
+func describeProgress(event agents.ProgressEvent) string {
+	// Backticks in the detail would break the Markdown code span
+	detail := truncate(strings.ReplaceAll(strings.TrimSpace(event.Detail), "`", "'"), 80)
+
+	switch event.Kind {
+	case agents.ProgressFileEdit:
+		return fmt.Sprintf("✏️ Edited `%s`", detail)
+	case agents.ProgressCommand:
+		return fmt.Sprintf("▶️ Ran `%s`", detail)
+	}
+	tool := strings.ReplaceAll(event.Tool, "_", "\\_")
+	if detail == "" {
+		return fmt.Sprintf("🔧 %s", tool)
+	}
+	return fmt.Sprintf("🔧 %s `%s`", tool, detail)
+}
@@ -0,0 +1,106 @@
+package foreman
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bayological/foreman/internal/agents"
+)
+
+// recordingMessenger keeps the messages sent and edited
+type recordingMessenger struct {
+	mu    sync.Mutex
+	sent  []string
+	edits []string
+}
+
+func (m *recordingMessenger) SendTracked(message string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, message)
+	return len(m.sent), nil
+}
+
+func (m *recordingMessenger) Edit(messageID int, message string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.edits = append(m.edits, message)
+	return nil
+}
+
+func (m *recordingMessenger) editCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.edits)
+}
+
+func TestTaskProgress(t *testing.T) {
+	messenger := &recordingMessenger{}
+	task := &Task{ID: "T001", AgentName: "claude-code", Branch: "feature/1/T001"}
+
+	p := startProgress(messenger, task, 10*time.Millisecond)
+	if len(messenger.sent) != 1 || !strings.Contains(messenger.sent[0], "Task Started") {
+		t.Fatalf("sent = %q", messenger.sent)
+	}
+
+	p.update(agents.ProgressEvent{Kind: agents.ProgressTurn, Turn: 1})
+	p.update(agents.ProgressEvent{Kind: agents.ProgressToolUse, Tool: "mcp_search", Turn: 1})
+	for i := 0; i < progressLines; i++ {
+		p.update(agents.ProgressEvent{Kind: agents.ProgressFileEdit, Tool: "Edit", Detail: "main.go", Turn: 2})
+	}
+	p.update(agents.ProgressEvent{Kind: agents.ProgressCommand, Tool: "Bash", Detail: "echo `date`", Turn: 3})
+
+	deadline := time.Now().Add(time.Second)
+	for messenger.editCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if messenger.editCount() == 0 {
+		t.Fatal("progress message was never edited")
+	}
+
+	p.finish(true)
+	p.finish(false)
+
+	edits := messenger.edits
+	final := edits[len(edits)-1]
+	if !strings.Contains(final, "Agent Finished") || strings.Contains(final, "Agent Failed") {
+		t.Errorf("final message = %q", final)
+	}
+	if !strings.Contains(final, "Turn 3 · 7 tool calls") {
+		t.Errorf("final message lacks counts: %q", final)
+	}
+	if strings.Contains(final, "mcp") {
+		t.Errorf("final message should only keep the latest %d activities: %q", progressLines, final)
+	}
+	if !strings.Contains(final, "▶️ Ran `echo 'date'`") {
+		t.Errorf("final message lacks the command: %q", final)
+	}
+
+	// No edits after finishing
+	count := messenger.editCount()
+	p.update(agents.ProgressEvent{Kind: agents.ProgressTurn, Turn: 4})
+	time.Sleep(30 * time.Millisecond)
+	if messenger.editCount() != count {
+		t.Error("progress message edited after finish")
+	}
+}
+
+func TestDescribeProgress(t *testing.T) {
+	tests := []struct {
+		event agents.ProgressEvent
+		want  string
+	}{
+		{agents.ProgressEvent{Kind: agents.ProgressFileEdit, Tool: "Write", Detail: "a.go"}, "✏️ Edited `a.go`"},
+		{agents.ProgressEvent{Kind: agents.ProgressCommand, Tool: "Bash", Detail: "go test ./..."}, "▶️ Ran `go test ./...`"},
+		{agents.ProgressEvent{Kind: agents.ProgressToolUse, Tool: "Grep", Detail: "TODO"}, "🔧 Grep `TODO`"},
+		{agents.ProgressEvent{Kind: agents.ProgressToolUse, Tool: "todo_write"}, "🔧 todo\\_write"},
+	}
+
+	for _, tt := range tests {
+		if got := describeProgress(tt.event); got != tt.want {
+			t.Errorf("describeProgress(%+v) = %q, want %q", tt.event, got, tt.want)
+		}
+	}
+}
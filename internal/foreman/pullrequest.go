@@ -0,0 +1,129 @@
+package foreman
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bayological/foreman/internal/forge"
+	"github.com/bayological/foreman/internal/git"
+	"github.com/bayological/foreman/internal/storage"
+)
+
+// newForge builds the configured forge, filling in the URL and project from
+// the repo's remote when they are not set.
+func newForge(cfg ForgeConfig, repo *git.Repo) (forge.Forge, error) {
+	if cfg.URL == "" || cfg.Project == "" {
+		remote, err := repo.RemoteURL()
+		if err != nil {
+			return nil, err
+		}
+		webURL, project, err := forge.ParseRemote(remote)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.URL == "" {
+			cfg.URL = webURL
+		}
+		if cfg.Project == "" {
+			cfg.Project = project
+		}
+	}
+
+	return forge.New(forge.Config{
+		Type:    cfg.Type,
+		URL:     cfg.URL,
+		APIURL:  cfg.APIURL,
+		Project: cfg.Project,
+		Token:   cfg.Token,
+	})
+}
+
+// openPullRequest opens the feature's pull request against main, or updates
+// the description of the one already open.
+func (f *Foreman) openPullRequest(ctx context.Context, feature *Feature) (*forge.PullRequest, error) {
+	if f.forge == nil {
+		return nil, fmt.Errorf("no forge configured")
+	}
+
+	opts := forge.PullRequestOptions{
+		Head:  feature.Branch,
+		Base:  f.cfg.Repo.MainBranch,
+		Title: feature.Name,
+		Body:  f.buildPRBody(feature),
+	}
+
+	pr, err := f.forge.FindPullRequest(ctx, feature.Branch)
+	switch {
+	case err == nil:
+		pr, err = f.forge.UpdatePullRequest(ctx, pr.Number, forge.PullRequestOptions{Title: opts.Title, Body: opts.Body})
+	case errors.Is(err, forge.ErrNotFound):
+		pr, err = f.forge.CreatePullRequest(ctx, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	feature.mu.Lock()
+	feature.PRNumber = pr.Number
+	feature.PRURL = pr.URL
+	feature.mu.Unlock()
+	f.saveFeatureToStorage(feature)
+	f.recordEvent(storage.LogEvent{
+		Type:      EventPullRequest,
+		FeatureID: feature.ID,
+		Actor:     "foreman",
+		Data:      map[string]string{"number": strconv.Itoa(pr.Number), "url": pr.URL},
+	})
+
+	return pr, nil
+}
+
+// compareURL links to the changes on branch relative to main, or returns ""
+// without a forge.
+func (f *Foreman) compareURL(branch string) string {
+	if f.forge == nil {
+		return ""
+	}
+	return f.forge.CompareURL(f.cfg.Repo.MainBranch, branch)
+}
+
+// pullRequestReport summarises a feature's pull request: CI checks and
+// review comments.
+func (f *Foreman) pullRequestReport(ctx context.Context, feature *Feature) (string, error) {
+	if f.forge == nil {
+		return "", fmt.Errorf("no forge configured")
+	}
+	if feature.PRNumber == 0 {
+		return "", fmt.Errorf("feature `%s` has no pull request yet", feature.ID)
+	}
+
+	status, err := f.forge.CheckStatus(ctx, feature.PRNumber)
+	if err != nil {
+		return "", err
+	}
+	comments, err := f.forge.ReviewComments(ctx, feature.PRNumber)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*Pull Request* [#%d](%s)\n\n", feature.PRNumber, feature.PRURL)
+	fmt.Fprintf(&b, "Checks: %s\n", status.State)
+	for _, check := range status.Checks {
+		fmt.Fprintf(&b, "  - %s: %s\n", check.Name, check.State)
+	}
+
+	fmt.Fprintf(&b, "\nReview comments: %d\n", len(comments))
+	for _, c := range comments {
+		location := ""
+		if c.Path != "" {
+			location = fmt.Sprintf(" on `%s:%d`", c.Path, c.Line)
+		}
+		fmt.Fprintf(&b, "  - %s%s: %s\n", c.Author, location, truncate(c.Body, 200))
+	}
+
+	return b.String(), nil
+}
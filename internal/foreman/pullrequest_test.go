@@ -0,0 +1,86 @@
+package foreman
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bayological/foreman/internal/forge"
+)
+
+func TestOpenPullRequest(t *testing.T) {
+	mem := forge.NewMemory("http://forge.test/acme/app")
+	f := &Foreman{
+		cfg:   &Config{Repo: RepoConfig{MainBranch: "main"}},
+		forge: mem,
+	}
+	feature := NewFeature("1", "User Auth", "Login and signup")
+
+	pr, err := f.openPullRequest(context.Background(), feature)
+	if err != nil {
+		t.Fatalf("openPullRequest() error = %v", err)
+	}
+	if pr.Base != "main" || pr.Head != feature.Branch || pr.Title != "User Auth" {
+		t.Errorf("unexpected pull request %+v", pr)
+	}
+	if feature.PRNumber != pr.Number || feature.PRURL != pr.URL {
+		t.Errorf("feature PR = #%d %s, want #%d %s", feature.PRNumber, feature.PRURL, pr.Number, pr.URL)
+	}
+
+	// A second call updates the open pull request instead of failing
+	feature.Description = "Login, signup and password reset"
+	again, err := f.openPullRequest(context.Background(), feature)
+	if err != nil {
+		t.Fatalf("second openPullRequest() error = %v", err)
+	}
+	if again.Number != pr.Number {
+		t.Errorf("expected existing PR #%d to be updated, got #%d", pr.Number, again.Number)
+	}
+	if !strings.Contains(again.Body, "password reset") {
+		t.Errorf("expected updated body, got %q", again.Body)
+	}
+	if n := len(mem.PullRequests()); n != 1 {
+		t.Errorf("expected 1 pull request, got %d", n)
+	}
+}
+
+func TestOpenPullRequestWithoutForge(t *testing.T) {
+	f := &Foreman{cfg: &Config{Repo: RepoConfig{MainBranch: "main"}}}
+
+	if _, err := f.openPullRequest(context.Background(), NewFeature("1", "X", "Y")); err == nil {
+		t.Error("expected error without a forge")
+	}
+	if got := f.compareURL("task/abc"); got != "" {
+		t.Errorf("compareURL() without forge = %q, want empty", got)
+	}
+}
+
+func TestPullRequestReport(t *testing.T) {
+	mem := forge.NewMemory("http://forge.test/acme/app")
+	f := &Foreman{
+		cfg:   &Config{Repo: RepoConfig{MainBranch: "main"}},
+		forge: mem,
+	}
+	feature := NewFeature("1", "User Auth", "Login")
+
+	if _, err := f.pullRequestReport(context.Background(), feature); err == nil {
+		t.Error("expected error before a pull request is opened")
+	}
+
+	pr, err := f.openPullRequest(context.Background(), feature)
+	if err != nil {
+		t.Fatalf("openPullRequest() error = %v", err)
+	}
+	mem.SetChecks(pr.Number, forge.Check{Name: "ci", State: forge.CheckFailure})
+	mem.AddReviewComment(pr.Number, forge.ReviewComment{Author: "alice", Body: "handle errors", Path: "auth.go", Line: 10})
+
+	report, err := f.pullRequestReport(context.Background(), feature)
+	if err != nil {
+		t.Fatalf("pullRequestReport() error = %v", err)
+	}
+	for _, want := range []string{"Checks: failure", "ci: failure", "alice on `auth.go:10`: handle errors"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package foreman
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/bayological/foreman/internal/storage"
+)
+
+const (
+	// leaseDuration is how long a worker may hold a task without renewing
+	// its lease before the task is handed to another worker
+	leaseDuration = 5 * time.Minute
+
+	// leaseRenewal is how often running tasks renew their lease and expired
+	// leases are collected
+	leaseRenewal = time.Minute
+)
+
+// enqueue adds a task to the persistent queue and wakes the task processor.
+// It reports false if the task was already waiting to run.
+func (f *Foreman) enqueue(task *Task) bool {
+	entry := storage.QueueEntry{
+		TaskID:    task.ID,
+		FeatureID: task.FeatureID,
+	}
+	if task.FeatureID == "" {
+		state := taskToState(task)
+		entry.Task = &state
+
+		f.standaloneMu.Lock()
+		f.standalone[task.ID] = task
+		f.standaloneMu.Unlock()
+	}
+
+	queued, err := f.queue.Enqueue(entry)
+	if err != nil {
+		log.Printf("Warning: Failed to persist queued task %s: %v", task.ID, err)
+	}
+	if queued {
+		f.wakeQueue()
+	}
+	return queued
+}
+
+// wakeQueue tells the task processor there is work, without blocking if it
+// has already been told.
+func (f *Foreman) wakeQueue() {
+	select {
+	case f.queueWake <- struct{}{}:
+	default:
+	}
+}
+
+// queueKey returns the key of task's queue entry
+func queueKey(task *Task) storage.QueueKey {
+	return storage.QueueKey{FeatureID: task.FeatureID, TaskID: task.ID}
+}
+
+// requeueInterrupted puts tasks that were leased or running when Foreman
+// last stopped back on the queue. It returns their keys.
+func (f *Foreman) requeueInterrupted() []storage.QueueKey {
+	keys, err := f.queue.RequeueLeased()
+	if err != nil {
+		log.Printf("Warning: Failed to requeue interrupted tasks: %v", err)
+	}
+	return keys
+}
+
+// leaseTask claims the next queued task. Entries whose task no longer exists
+// are marked done and skipped. It returns nil when nothing is queued.
+func (f *Foreman) leaseTask() (*Task, int) {
+	for {
+		entry, err := f.queue.Lease(leaseDuration)
+		if err != nil {
+			log.Printf("Warning: Failed to lease task: %v", err)
+		}
+		if entry == nil {
+			return nil, 0
+		}
+
+		if task := f.queuedTask(entry); task != nil {
+			return task, entry.Lease
+		}
+
+		log.Printf("Dropping queued task %s: task no longer exists", entry.Key())
+		if err := f.queue.MarkDone(entry.Key(), entry.Lease); err != nil {
+			log.Printf("Warning: Failed to drop queued task %s: %v", entry.Key(), err)
+		}
+	}
+}
+
+// queuedTask finds the task a queue entry refers to, restoring standalone
+// tasks from the entry after a restart.
+func (f *Foreman) queuedTask(entry *storage.QueueEntry) *Task {
+	if entry.FeatureID != "" {
+		feature := f.getFeature(entry.FeatureID)
+		if feature == nil {
+			return nil
+		}
+		return feature.FindTask(entry.TaskID)
+	}
+
+	f.standaloneMu.Lock()
+	defer f.standaloneMu.Unlock()
+
+	if task, ok := f.standalone[entry.TaskID]; ok {
+		return task
+	}
+	if entry.Task == nil {
+		return nil
+	}
+	task := f.taskFromState(*entry.Task)
+	f.standalone[task.ID] = task
+	return task
+}
+
+// runQueuedTask executes a leased task, renewing the lease while it runs.
+// Tasks over budget are held instead, leaving the queue until approved.
+func (f *Foreman) runQueuedTask(ctx context.Context, task *Task, lease int) {
+	if f.holdForBudget(task) {
+		if err := f.queue.MarkDone(queueKey(task), lease); err != nil {
+			log.Printf("Warning: Failed to mark task %s held: %v", task.ID, err)
+		}
+		f.saveTaskFeature(task)
+		return
+	}
+
+	if err := f.queue.MarkRunning(queueKey(task), lease, leaseDuration); err != nil {
+		log.Printf("Warning: Failed to mark task %s running: %v", task.ID, err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(leaseRenewal)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := f.queue.RenewLease(queueKey(task), lease, leaseDuration); err != nil {
+					log.Printf("Warning: Failed to renew lease on task %s: %v", task.ID, err)
+				}
+			}
+		}
+	}()
+
+	requeue := f.executeTask(ctx, task, lease)
+	close(done)
+
+	// Persist where the task ended up so a restart can reconcile it
+	f.saveTaskFeature(task)
+
+	// A task the user queued again meanwhile, e.g. with feedback, is left
+	// queued by MarkDone
+	if err := f.queue.MarkDone(queueKey(task), lease); err != nil {
+		log.Printf("Warning: Failed to mark task %s done: %v", task.ID, err)
+	}
+	if requeue {
+		f.enqueue(task)
+	}
+}
+
+// expireLeases requeues tasks whose workers stopped renewing their lease.
+func (f *Foreman) expireLeases() {
+	keys, err := f.queue.ExpireLeases(time.Now())
+	if err != nil {
+		log.Printf("Warning: Failed to expire task leases: %v", err)
+	}
+	for _, key := range keys {
+		log.Printf("Lease on task %s expired, requeued", key)
+	}
+	if len(keys) > 0 {
+		f.wakeQueue()
+	}
+}
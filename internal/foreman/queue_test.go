@@ -0,0 +1,92 @@
+package foreman
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bayological/foreman/internal/storage"
+)
+
+func newQueueForeman(queue *storage.FileStorage) *Foreman {
+	return &Foreman{
+		cfg:        &Config{Concurrency: ConcurrencyConfig{TaskTimeout: time.Minute}},
+		features:   make(map[string]*Feature),
+		queue:      queue,
+		queueWake:  make(chan struct{}, 1),
+		standalone: make(map[string]*Task),
+	}
+}
+
+func TestEnqueueAndLeaseFeatureTask(t *testing.T) {
+	f := newQueueForeman(storage.NewMemory())
+
+	feature := NewFeature("feat-1", "Test", "Test feature")
+	task := &Task{ID: "T001", FeatureID: feature.ID}
+	feature.Tasks = []*Task{task}
+	f.features[feature.ID] = feature
+
+	if !f.enqueue(task) {
+		t.Fatal("expected task to be queued")
+	}
+	if f.enqueue(task) {
+		t.Error("expected duplicate enqueue to be a no-op")
+	}
+
+	select {
+	case <-f.queueWake:
+	default:
+		t.Error("expected enqueue to wake the task processor")
+	}
+
+	leased, lease := f.leaseTask()
+	if leased != task {
+		t.Fatalf("leaseTask() = %v, want the feature's task", leased)
+	}
+	if lease == 0 {
+		t.Error("expected a lease number")
+	}
+
+	if next, _ := f.leaseTask(); next != nil {
+		t.Errorf("expected empty queue, got %s", next.ID)
+	}
+}
+
+func TestStandaloneTaskSurvivesRestart(t *testing.T) {
+	queue := storage.NewMemory()
+	f := newQueueForeman(queue)
+
+	task := NewTask("Add logging", "claude-code", time.Minute)
+	f.Assign(task)
+	f.leaseTask()
+
+	// A fresh Foreman sharing the same storage knows nothing of the task
+	restarted := newQueueForeman(queue)
+	keys := restarted.requeueInterrupted()
+	if len(keys) != 1 || keys[0] != queueKey(task) {
+		t.Fatalf("requeueInterrupted() = %v, want [%s]", keys, task.ID)
+	}
+
+	leased, _ := restarted.leaseTask()
+	if leased == nil {
+		t.Fatal("expected the interrupted task to be leased again")
+	}
+	if leased.Spec != "Add logging" || leased.AgentName != "claude-code" || leased.Branch != task.Branch {
+		t.Errorf("restored task = %+v", leased)
+	}
+}
+
+func TestLeaseSkipsMissingTasks(t *testing.T) {
+	queue := storage.NewMemory()
+	f := newQueueForeman(queue)
+
+	queue.Enqueue(storage.QueueEntry{TaskID: "T001", FeatureID: "deleted"})
+
+	if task, _ := f.leaseTask(); task != nil {
+		t.Errorf("expected no task, got %s", task.ID)
+	}
+
+	entries, _ := queue.QueueEntries()
+	if len(entries) != 1 || entries[0].State != storage.QueueDone {
+		t.Errorf("expected the orphaned entry to be marked done, got %+v", entries)
+	}
+}
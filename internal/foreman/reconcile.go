@@ -0,0 +1,203 @@
+package foreman
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bayological/foreman/internal/git"
+)
+
+// taskRecovery is what reconciliation does with a persisted task
+type taskRecovery int
+
+const (
+	recoveryKeep   taskRecovery = iota // state matches git, leave it
+	recoveryReset                      // nothing usable survived, run from scratch
+	recoveryResume                     // committed work survived, continue on top of it
+)
+
+// taskGitState is what git knows about a task's work
+type taskGitState struct {
+	branchExists  bool
+	commitsAhead  int
+	worktreeDirty bool // uncommitted changes were left in its worktree
+}
+
+// recoveryFor decides how to recover a task with the given persisted status.
+// Running and reviewing tasks cannot have survived a restart, so they run
+// again, continuing from their branch if it has commits of its own. Tasks
+// awaiting approval only need their branch.
+func recoveryFor(status TaskStatus, state taskGitState) taskRecovery {
+	switch status {
+	case StatusRunning, StatusReview:
+		if state.branchExists && (state.commitsAhead > 0 || state.worktreeDirty) {
+			return recoveryResume
+		}
+		return recoveryReset
+	case StatusApproval:
+		if !state.branchExists {
+			return recoveryReset
+		}
+	}
+	return recoveryKeep
+}
+
+// recoveryReport summarises a reconciliation pass
+type recoveryReport struct {
+	requeued  []string // queue entries interrupted mid-run
+	reset     []string // tasks restarted from scratch
+	resumed   []string // tasks continuing from committed work
+	removed   []string // stale worktrees
+	dispatch  []string // features whose ready tasks were queued
+	attention []string // features interrupted mid-phase
+}
+
+func (r *recoveryReport) empty() bool {
+	return len(r.requeued)+len(r.reset)+len(r.resumed)+len(r.removed)+len(r.dispatch)+len(r.attention) == 0
+}
+
+func (r *recoveryReport) String() string {
+	var b strings.Builder
+	b.WriteString("*Recovery Summary*\n")
+
+	section := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n%s (%d):\n", title, len(items))
+		for _, item := range items {
+			fmt.Fprintf(&b, "  - %s\n", item)
+		}
+	}
+	section("Re-queued interrupted tasks", r.requeued)
+	section("Restarted tasks", r.reset)
+	section("Resumed tasks", r.resumed)
+	section("Removed stale worktrees", r.removed)
+	section("Resumed features", r.dispatch)
+	section("Interrupted features (use /resume)", r.attention)
+
+	return b.String()
+}
+
+// reconcile brings persisted features and tasks back in line with the git
+// state after a restart, and clears out worktrees nothing is using. It must
+// run before the task processor starts, while no task can be running.
+func (f *Foreman) reconcile() *recoveryReport {
+	report := &recoveryReport{}
+
+	for _, key := range f.requeueInterrupted() {
+		report.requeued = append(report.requeued, fmt.Sprintf("`%s`", key))
+	}
+
+	if err := f.repo.PruneWorktrees(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	for _, feature := range f.getFeatures() {
+		f.reconcileFeature(feature, report)
+	}
+
+	f.removeStaleWorktrees(report)
+
+	return report
+}
+
+func (f *Foreman) reconcileFeature(feature *Feature, report *recoveryReport) {
+	changed := false
+	for _, task := range feature.Tasks {
+		state := f.taskGitState(task)
+		switch recoveryFor(task.Status, state) {
+		case recoveryReset:
+			log.Printf("Restarting interrupted task %s (status %s)", task.ID, task.Status)
+			task.Status = StatusPending
+			report.reset = append(report.reset, fmt.Sprintf("`%s` (%s)", task.ID, feature.ID))
+			changed = true
+
+		case recoveryResume:
+			if state.worktreeDirty {
+				// Keep the agent's uncommitted work before the worktree goes
+				wt := f.repo.WorktreeFor(task.Branch)
+				if err := f.repo.CommitWorktree(wt, fmt.Sprintf("Task %s: interrupted work in progress", task.ID)); err != nil {
+					log.Printf("Warning: Failed to save interrupted work on %s: %v", task.Branch, err)
+				}
+			}
+			log.Printf("Resuming interrupted task %s from %s", task.ID, task.Branch)
+			task.Status = StatusPending
+			task.AddContext("A previous attempt at this task was interrupted. Its work is already committed on this branch: review it and finish the task.")
+			report.resumed = append(report.resumed, fmt.Sprintf("`%s` (%s)", task.ID, feature.ID))
+			changed = true
+		}
+	}
+
+	switch feature.GetPhase() {
+	case PhaseImplementing, PhaseReviewing, PhaseAwaitingCodeApproval:
+		// Statuses may have changed, so the scheduler is rebuilt from them
+		sched, err := NewScheduler(feature.Tasks)
+		if err != nil {
+			log.Printf("Warning: Cannot schedule tasks of feature %s: %v", feature.ID, err)
+			break
+		}
+		feature.setScheduler(sched)
+		if n := f.dispatchReadyTasks(feature); n > 0 {
+			report.dispatch = append(report.dispatch, fmt.Sprintf("`%s` (%d tasks queued)", feature.ID, n))
+		}
+
+	case PhaseSpecifying, PhasePlanning, PhaseTasking:
+		report.attention = append(report.attention, fmt.Sprintf("`%s` was %s", feature.ID, feature.GetPhase()))
+	}
+
+	if changed {
+		f.saveFeatureToStorage(feature)
+	}
+}
+
+func (f *Foreman) taskGitState(task *Task) taskGitState {
+	var state taskGitState
+	if task.Branch == "" || !f.repo.BranchExists(task.Branch) {
+		return state
+	}
+	state.branchExists = true
+
+	if ahead, err := f.repo.CommitsAhead(task.Branch, f.baseBranch(task)); err == nil {
+		state.commitsAhead = ahead
+	}
+	if wt := f.repo.WorktreeFor(task.Branch); wt != nil {
+		if dirty, err := git.HasChanges(wt.Path); err == nil {
+			state.worktreeDirty = dirty
+		}
+	}
+	return state
+}
+
+// removeStaleWorktrees removes every managed worktree except the spec
+// worktrees of unfinished features. Task and conflict-resolution worktrees
+// are recreated when their work runs again.
+func (f *Foreman) removeStaleWorktrees(report *recoveryReport) {
+	worktrees, err := f.repo.ListWorktrees()
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+
+	keep := make(map[string]bool)
+	for _, feature := range f.getFeatures() {
+		switch feature.GetPhase() {
+		case PhaseComplete, PhaseFailed:
+		default:
+			keep[feature.Branch] = true
+		}
+	}
+
+	for _, wt := range worktrees {
+		if wt.Branch != "" && keep[wt.Branch] {
+			continue
+		}
+		f.repo.RemoveWorktreeAt(wt.Path)
+		name := wt.Branch
+		if name == "" {
+			name = wt.Path
+		}
+		report.removed = append(report.removed, fmt.Sprintf("`%s`", name))
+	}
+}
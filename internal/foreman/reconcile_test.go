@@ -0,0 +1,154 @@
+package foreman
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bayological/foreman/internal/git"
+	"github.com/bayological/foreman/internal/storage"
+)
+
+func TestRecoveryFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		status TaskStatus
+		state  taskGitState
+		want   taskRecovery
+	}{
+		{"running without branch", StatusRunning, taskGitState{}, recoveryReset},
+		{"running with empty branch", StatusRunning, taskGitState{branchExists: true}, recoveryReset},
+		{"running with commits", StatusRunning, taskGitState{branchExists: true, commitsAhead: 2}, recoveryResume},
+		{"running with dirty worktree", StatusRunning, taskGitState{branchExists: true, worktreeDirty: true}, recoveryResume},
+		{"review with commits", StatusReview, taskGitState{branchExists: true, commitsAhead: 1}, recoveryResume},
+		{"approval with branch", StatusApproval, taskGitState{branchExists: true, commitsAhead: 1}, recoveryKeep},
+		{"approval without branch", StatusApproval, taskGitState{}, recoveryReset},
+		{"pending", StatusPending, taskGitState{}, recoveryKeep},
+		{"complete", StatusComplete, taskGitState{}, recoveryKeep},
+		{"failed", StatusFailed, taskGitState{branchExists: true}, recoveryKeep},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recoveryFor(tt.status, tt.state); got != tt.want {
+				t.Errorf("recoveryFor(%s, %+v) = %d, want %d", tt.status, tt.state, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecoveryReport(t *testing.T) {
+	report := &recoveryReport{}
+	if !report.empty() {
+		t.Error("expected new report to be empty")
+	}
+
+	report.reset = append(report.reset, "`T001` (feat-1)")
+	report.removed = append(report.removed, "`feature/feat-1/T001`")
+	if report.empty() {
+		t.Error("expected report with entries not to be empty")
+	}
+
+	msg := report.String()
+	for _, want := range []string{"Restarted tasks (1)", "`T001` (feat-1)", "Removed stale worktrees (1)"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("report missing %q:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "Resumed tasks") {
+		t.Errorf("report should omit empty sections:\n%s", msg)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	dir, err := os.MkdirTemp("", "foreman-reconcile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	gitCmd := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %s: %v", args, out, err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	gitCmd(dir, "init")
+	gitCmd(dir, "config", "user.email", "test@test.com")
+	gitCmd(dir, "config", "user.name", "Test User")
+	gitCmd(dir, "config", "commit.gpgsign", "false")
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("test"), 0644)
+	gitCmd(dir, "add", ".")
+	gitCmd(dir, "commit", "-m", "Initial commit")
+	mainBranch := gitCmd(dir, "rev-parse", "--abbrev-ref", "HEAD")
+
+	repo, err := git.NewRepo(dir, "origin", mainBranch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := newQueueForeman(storage.NewMemory())
+	f.cfg.Repo.MainBranch = mainBranch
+	f.repo = repo
+
+	feature := NewFeature("feat-1", "Demo", "Demo feature")
+	feature.Phase = PhaseImplementing
+	gitCmd(dir, "branch", feature.Branch)
+
+	// T001 crashed before committing anything, T002 left uncommitted work
+	// in its worktree, T003 waits for approval of a branch that is gone
+	fresh := &Task{ID: "T001", FeatureID: feature.ID, Status: StatusRunning, Branch: feature.TaskBranch("T001"), BaseBranch: feature.Branch}
+	dirty := &Task{ID: "T002", FeatureID: feature.ID, Status: StatusRunning, Branch: feature.TaskBranch("T002"), BaseBranch: feature.Branch, IsParallel: true}
+	lost := &Task{ID: "T003", FeatureID: feature.ID, Status: StatusApproval, Branch: feature.TaskBranch("T003"), BaseBranch: feature.Branch, IsParallel: true}
+	feature.Tasks = []*Task{fresh, dirty, lost}
+	f.features[feature.ID] = feature
+
+	if _, err := repo.CreateWorktreeFrom(fresh.Branch, feature.Branch); err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.CreateWorktreeFrom(dirty.Branch, feature.Branch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(wt.Path, "work.go"), []byte("package work"), 0644)
+
+	report := f.reconcile()
+
+	for _, task := range feature.Tasks {
+		if task.Status != StatusPending {
+			t.Errorf("task %s status = %s, want %s", task.ID, task.Status, StatusPending)
+		}
+	}
+	if len(report.reset) != 2 || len(report.resumed) != 1 || !strings.Contains(report.resumed[0], "T002") {
+		t.Errorf("unexpected report: reset=%v resumed=%v", report.reset, report.resumed)
+	}
+	if !strings.Contains(dirty.Context, "interrupted") {
+		t.Errorf("resumed task should be told about its earlier work, got %q", dirty.Context)
+	}
+
+	// The uncommitted work was saved on the task branch
+	if ahead, err := repo.CommitsAhead(dirty.Branch, feature.Branch); err != nil || ahead != 1 {
+		t.Errorf("CommitsAhead(%s) = %d, %v; want 1", dirty.Branch, ahead, err)
+	}
+
+	// Task worktrees are gone, and the tasks are queued again
+	if worktrees, _ := repo.ListWorktrees(); len(worktrees) != 0 {
+		t.Errorf("expected stale worktrees to be removed, got %+v", worktrees)
+	}
+	queued := 0
+	entries, _ := f.queue.QueueEntries()
+	for _, entry := range entries {
+		if entry.State == storage.QueueQueued {
+			queued++
+		}
+	}
+	if queued != 3 {
+		t.Errorf("expected 3 queued tasks, got %d", queued)
+	}
+}
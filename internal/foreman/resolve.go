@@ -0,0 +1,340 @@
+package foreman
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bayological/foreman/internal/agents"
+	"github.com/bayological/foreman/internal/git"
+	"github.com/bayological/foreman/internal/validation"
+)
+
+// maxConflictFileSize caps how much of each conflicted file goes into the
+// resolution prompt.
+const maxConflictFileSize = 20000
+
+// conflictResolution is a merge conflict being resolved by an agent. Once
+// the agent is done it waits here for the user to commit or discard it.
+type conflictResolution struct {
+	task     *Task
+	conflict *git.Conflict // nil while the agent is still working
+}
+
+func resolutionKey(featureID, taskID string) string {
+	return featureID + ":" + taskID
+}
+
+// startResolution claims the task for conflict resolution. It returns false
+// if a resolution for the task is already in progress.
+func (f *Foreman) startResolution(task *Task) bool {
+	f.resolutionsMu.Lock()
+	defer f.resolutionsMu.Unlock()
+	key := resolutionKey(task.FeatureID, task.ID)
+	if _, ok := f.resolutions[key]; ok {
+		return false
+	}
+	f.resolutions[key] = &conflictResolution{task: task}
+	return true
+}
+
+func (f *Foreman) setResolution(task *Task, conflict *git.Conflict) {
+	f.resolutionsMu.Lock()
+	defer f.resolutionsMu.Unlock()
+	f.resolutions[resolutionKey(task.FeatureID, task.ID)] = &conflictResolution{task: task, conflict: conflict}
+}
+
+// takeResolution removes and returns a resolution that is ready for a decision.
+func (f *Foreman) takeResolution(featureID, taskID string) *conflictResolution {
+	f.resolutionsMu.Lock()
+	defer f.resolutionsMu.Unlock()
+	key := resolutionKey(featureID, taskID)
+	res, ok := f.resolutions[key]
+	if !ok || res.conflict == nil {
+		return nil
+	}
+	delete(f.resolutions, key)
+	return res
+}
+
+func (f *Foreman) dropResolution(task *Task) {
+	f.resolutionsMu.Lock()
+	defer f.resolutionsMu.Unlock()
+	delete(f.resolutions, resolutionKey(task.FeatureID, task.ID))
+}
+
+// handleMergeConflict starts agent-driven resolution of a conflicting merge
+// of task's branch into target.
+func (f *Foreman) handleMergeConflict(task *Task, target string, conflict *git.MergeConflictError) {
+	if !f.startResolution(task) {
+		f.telegram.Send(fmt.Sprintf("Conflict resolution for `%s` is already in progress", task.ID))
+		return
+	}
+
+	f.telegram.Send(fmt.Sprintf(
+		"*Merge Conflict*\n\nTask `%s` conflicts with `%s` in:\n%s\nAsking %s to resolve it...",
+		task.ID, target, formatFileList(conflict.Files), f.resolverName(task),
+	))
+
+	// Like a task, the resolution stops when Foreman does
+	ctx := f.runCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	f.resolving.Add(1)
+	go func() {
+		defer f.resolving.Done()
+		f.resolveConflict(ctx, task, target)
+	}()
+}
+
+// noResolution tells the user there is no resolution to decide on for a
+// task. Resolutions are kept in memory only, so a restart loses them.
+func (f *Foreman) noResolution(taskID string) {
+	f.telegram.Send(fmt.Sprintf(
+		"No conflict resolution awaiting approval for `%s`. It may still be running, or it was lost when Foreman restarted: approve the task again to resolve the conflict anew.",
+		taskID,
+	))
+}
+
+// resolveConflict recreates the conflicted merge in its own worktree, lets an
+// agent resolve it, runs the tests and asks the user to approve the result.
+func (f *Foreman) resolveConflict(ctx context.Context, task *Task, target string) {
+	abandon := func(reason string, c *git.Conflict) {
+		if c != nil {
+			f.repo.AbortMerge(c)
+		}
+		f.dropResolution(task)
+		f.telegram.Send(fmt.Sprintf(
+			"*Conflict Resolution Failed*\nTask: `%s`\n%s\n\nResolve the conflict on `%s` by hand and approve again.",
+			task.ID, reason, task.Branch,
+		))
+	}
+
+	// The resolving agent takes a worker slot like a task, so it counts
+	// towards max_tasks
+	select {
+	case f.sem <- struct{}{}:
+		defer func() { <-f.sem }()
+	case <-ctx.Done():
+		abandon(validation.SanitizeErrorMessage(ctx.Err()), nil)
+		return
+	}
+
+	c, err := f.repo.PrepareConflict(target, task.Branch)
+	if err != nil {
+		abandon(validation.SanitizeErrorMessage(err), nil)
+		return
+	}
+
+	agentName := f.resolverName(task)
+	agent, ok := f.agents[agentName]
+	if !ok {
+		abandon(fmt.Sprintf("Unknown agent: %s", agentName), c)
+		return
+	}
+
+	timeout := task.Timeout
+	if timeout == 0 {
+		timeout = f.cfg.Concurrency.TaskTimeout
+	}
+	resolveCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	transcript := f.beginTranscript(task)
+
+	if len(c.Files) > 0 {
+		result, err := agent.Execute(resolveCtx, &agents.Task{
+			ID:           task.ID + "-merge",
+			Spec:         f.buildResolutionPrompt(task, c),
+			WorktreePath: c.Worktree.Path,
+		})
+		saveAgentTranscript(transcript, "conflict resolution", agentName, result, err)
+		if result != nil {
+			f.recordUsage(task, agentName, result.Usage)
+		}
+		if err != nil {
+			abandon(fmt.Sprintf("Agent error: %s", validation.SanitizeErrorMessage(err)), c)
+			return
+		}
+		if !result.Success {
+			abandon(fmt.Sprintf("Agent failed: %s", truncate(result.Summary, 500)), c)
+			return
+		}
+	}
+
+	if unresolved := c.UnresolvedFiles(); len(unresolved) > 0 {
+		abandon(fmt.Sprintf("Conflict markers remain in:\n%s", formatFileList(unresolved)), c)
+		return
+	}
+
+	testStatus := "✅ Tests passed"
+	out, err := f.reviewer.RunTests(resolveCtx, c.Worktree.Path)
+	saveTranscript(transcript, "review-tests.log", out)
+	if err != nil {
+		testStatus = fmt.Sprintf("❌ Tests failed:\n```\n%s\n```", truncate(out, 1000))
+	}
+
+	f.setResolution(task, c)
+
+	f.telegram.RequestResolutionApproval(
+		task.FeatureID, task.ID,
+		fmt.Sprintf("%s resolved conflicts merging `%s` into `%s`:\n%s", agentName, task.Branch, target, formatFileList(c.Files)),
+		testStatus,
+	)
+}
+
+// resolverName picks the agent that resolves conflicts for task.
+func (f *Foreman) resolverName(task *Task) string {
+	if _, ok := f.agents[task.AgentName]; ok {
+		return task.AgentName
+	}
+	return f.cfg.DefaultAgent
+}
+
+// buildResolutionPrompt describes the conflict to the agent: the conflicted
+// files with their markers, the spec of the incoming task and the specs of
+// the tasks already merged that touched the same files.
+func (f *Foreman) buildResolutionPrompt(task *Task, c *git.Conflict) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Resolve the merge conflicts from merging branch `%s` into `%s`.\n\n", c.Source, c.Target)
+	b.WriteString("Edit each conflicted file so that it keeps the intent of both sides and contains no conflict markers ")
+	b.WriteString("(`<<<<<<<`, `=======`, `>>>>>>>`). Do not commit and do not change unrelated files.\n\n")
+
+	b.WriteString("## Incoming Task\n")
+	if task.Spec != "" {
+		fmt.Fprintf(&b, "%s: %s\n\n", task.ID, task.Spec)
+	} else {
+		fmt.Fprintf(&b, "%s (no spec available)\n\n", task.ID)
+	}
+
+	if merged := f.mergedTasks(task, c.Files); len(merged) > 0 {
+		fmt.Fprintf(&b, "## Already Merged into %s\n", c.Target)
+		for _, t := range merged {
+			fmt.Fprintf(&b, "%s: %s\n", t.ID, t.Spec)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Conflicted Files\n")
+	for _, file := range c.Files {
+		data, err := os.ReadFile(filepath.Join(c.Worktree.Path, file))
+		if err != nil {
+			fmt.Fprintf(&b, "\n### %s\n(unreadable: %v)\n", file, err)
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s\n```\n%s\n```\n", file, truncate(string(data), maxConflictFileSize))
+	}
+
+	return b.String()
+}
+
+// mergedTasks returns the completed tasks of task's feature that the incoming
+// changes most likely conflict with: those declaring one of the conflicted
+// files, or every completed task if none declares them.
+func (f *Foreman) mergedTasks(task *Task, files []string) []*Task {
+	feature := f.getFeature(task.FeatureID)
+	if feature == nil {
+		return nil
+	}
+
+	var complete, touching []*Task
+	for _, t := range feature.Tasks {
+		if t.ID == task.ID || t.Status != StatusComplete {
+			continue
+		}
+		complete = append(complete, t)
+		for _, path := range t.FilePaths {
+			if touchesAny(path, files) {
+				touching = append(touching, t)
+				break
+			}
+		}
+	}
+
+	if len(touching) > 0 {
+		return touching
+	}
+	return complete
+}
+
+func touchesAny(path string, files []string) bool {
+	for _, file := range files {
+		if pathsOverlap(path, file) {
+			return true
+		}
+	}
+	return false
+}
+
+// ApproveResolution commits an approved conflict resolution and carries on
+// with the task as if its merge had succeeded.
+func (f *Foreman) ApproveResolution(featureID, taskID string) {
+	res := f.takeResolution(featureID, taskID)
+	if res == nil {
+		f.noResolution(taskID)
+		return
+	}
+
+	task, c := res.task, res.conflict
+	if err := f.repo.CompleteMerge(c, fmt.Sprintf("Merge %s into %s", c.Source, c.Target)); err != nil {
+		f.repo.AbortMerge(c)
+		f.telegram.Send(fmt.Sprintf(
+			"*Merge Failed*\nTask: `%s`\nError: %s\n\nApprove the task again to retry.",
+			task.ID, validation.SanitizeErrorMessage(err),
+		))
+		return
+	}
+
+	if err := f.repo.PushBranch(c.Target); err != nil {
+		log.Printf("Warning: Failed to push %s: %v", c.Target, err)
+	}
+	f.repo.DeleteBranch(task.Branch)
+
+	feature := f.getFeature(featureID)
+	if feature == nil {
+		f.telegram.Send(fmt.Sprintf("Task `%s` merged successfully", task.ID))
+		return
+	}
+
+	f.finishTaskApproval(feature, task)
+}
+
+// RejectResolution discards a proposed conflict resolution and leaves the
+// task unmerged.
+func (f *Foreman) RejectResolution(featureID, taskID string) {
+	res := f.takeResolution(featureID, taskID)
+	if res == nil {
+		f.noResolution(taskID)
+		return
+	}
+
+	f.repo.AbortMerge(res.conflict)
+	f.telegram.Send(fmt.Sprintf(
+		"Resolution discarded. Task `%s` is still unmerged; resolve the conflict on `%s` and approve again.",
+		taskID, res.task.Branch,
+	))
+}
+
+// mergeLegacyTask merges a standalone task into main, resolving conflicts
+// through an agent if needed.
+func (f *Foreman) mergeLegacyTask(taskID string) {
+	err := f.approveTask(taskID)
+
+	var conflict *git.MergeConflictError
+	if errors.As(err, &conflict) {
+		task := &Task{ID: taskID, Branch: fmt.Sprintf("task/%s", taskID)}
+		f.handleMergeConflict(task, conflict.Target, conflict)
+		return
+	}
+	if err != nil {
+		f.telegram.Send(fmt.Sprintf("Merge failed for `%s`: %v", taskID, err))
+		return
+	}
+	f.telegram.Send(fmt.Sprintf("Task `%s` merged successfully", taskID))
+}
@@ -0,0 +1,115 @@
+package foreman
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bayological/foreman/internal/git"
+)
+
+func TestMergedTasks(t *testing.T) {
+	feature := NewFeature("1", "Demo", "Demo feature")
+	feature.Tasks = []*Task{
+		{ID: "T-001", FeatureID: "1", Status: StatusComplete, FilePaths: []string{"src/models"}},
+		{ID: "T-002", FeatureID: "1", Status: StatusComplete, FilePaths: []string{"src/api/handler.go"}},
+		{ID: "T-003", FeatureID: "1", Status: StatusApproval, FilePaths: []string{"src/models/user.go"}},
+		{ID: "T-004", FeatureID: "1", Status: StatusPending, FilePaths: []string{"src/models/user.go"}},
+	}
+	f := &Foreman{features: map[string]*Feature{"1": feature}}
+	incoming := feature.Tasks[2]
+
+	var ids []string
+	for _, task := range f.mergedTasks(incoming, []string{"src/models/user.go"}) {
+		ids = append(ids, task.ID)
+	}
+	if got := strings.Join(ids, ","); got != "T-001" {
+		t.Errorf("mergedTasks() = %s, want T-001", got)
+	}
+
+	// No completed task declares the file: fall back to all completed tasks
+	ids = nil
+	for _, task := range f.mergedTasks(incoming, []string{"README.md"}) {
+		ids = append(ids, task.ID)
+	}
+	if got := strings.Join(ids, ","); got != "T-001,T-002" {
+		t.Errorf("mergedTasks() fallback = %s, want T-001,T-002", got)
+	}
+
+	if got := f.mergedTasks(&Task{ID: "legacy"}, []string{"a.go"}); got != nil {
+		t.Errorf("mergedTasks() for standalone task = %v, want nil", got)
+	}
+}
+
+func TestBuildResolutionPrompt(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "foreman-resolve-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	markers := "<<<<<<< HEAD\nfrom T-001\n=======\nfrom T-002\n>>>>>>> feature/1/T-002\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "user.go"), []byte(markers), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	feature := NewFeature("1", "Demo", "Demo feature")
+	feature.Tasks = []*Task{
+		{ID: "T-001", FeatureID: "1", Spec: "Add the user model", Status: StatusComplete, FilePaths: []string{"user.go"}},
+		{ID: "T-002", FeatureID: "1", Spec: "Add user validation", Status: StatusApproval, FilePaths: []string{"user.go"}},
+	}
+	f := &Foreman{features: map[string]*Feature{"1": feature}}
+
+	prompt := f.buildResolutionPrompt(feature.Tasks[1], &git.Conflict{
+		Target:   "feature/1-demo",
+		Source:   "feature/1/T-002",
+		Files:    []string{"user.go"},
+		Worktree: &git.Worktree{Path: tmpDir, Branch: "feature/1-demo"},
+	})
+
+	for _, want := range []string{
+		"feature/1/T-002",
+		"T-002: Add user validation",
+		"T-001: Add the user model",
+		"### user.go",
+		"=======\nfrom T-002",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestResolutionTracking(t *testing.T) {
+	f := &Foreman{resolutions: make(map[string]*conflictResolution)}
+	task := &Task{ID: "T-001", FeatureID: "1"}
+
+	if !f.startResolution(task) {
+		t.Fatal("expected first startResolution to succeed")
+	}
+	if f.startResolution(task) {
+		t.Error("expected second startResolution to be refused while in progress")
+	}
+
+	// Same task ID in another feature is independent
+	if !f.startResolution(&Task{ID: "T-001", FeatureID: "2"}) {
+		t.Error("expected startResolution for another feature to succeed")
+	}
+
+	if res := f.takeResolution("1", "T-001"); res != nil {
+		t.Error("takeResolution should not return a resolution the agent is still working on")
+	}
+
+	f.setResolution(task, &git.Conflict{Target: "feature/1-demo"})
+	res := f.takeResolution("1", "T-001")
+	if res == nil || res.task != task {
+		t.Fatal("expected ready resolution to be returned")
+	}
+	if f.takeResolution("1", "T-001") != nil {
+		t.Error("takeResolution should remove the resolution")
+	}
+	if !f.startResolution(task) {
+		t.Error("expected startResolution to succeed after the resolution was taken")
+	}
+}
@@ -0,0 +1,258 @@
+package foreman
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bayological/foreman/internal/agents"
+	"github.com/bayological/foreman/internal/speckit"
+)
+
+// metaReassignTo names the agent a running task moves to once its current
+// attempt ends
+const metaReassignTo = "reassign_to"
+
+// validateRoutes checks that every route names a configured agent and that
+// its file globs are well formed
+func validateRoutes(routes []RouteConfig, configured map[string]agents.Agent) error {
+	for i, route := range routes {
+		if route.Agent == "" && len(route.Compete) == 0 {
+			return fmt.Errorf("route %d: no agent", i+1)
+		}
+		for _, name := range append([]string{route.Agent}, route.Compete...) {
+			if _, ok := configured[name]; name != "" && !ok {
+				return fmt.Errorf("route %d: unknown agent %q", i+1, name)
+			}
+		}
+		if len(route.Compete) > 0 && len(competitors(route.Compete, route.Samples)) < 2 {
+			return fmt.Errorf("route %d: a competition needs two agents or two samples", i+1)
+		}
+		for _, glob := range route.Files {
+			if _, err := path.Match(glob, ""); err != nil {
+				return fmt.Errorf("route %d: invalid file glob %q: %w", i+1, glob, err)
+			}
+		}
+	}
+	return nil
+}
+
+// matchRoute returns the index of the first route matching item in a
+// feature built on techStack, or -1 when none does
+func matchRoute(routes []RouteConfig, item speckit.TaskItem, techStack string) int {
+	for i, route := range routes {
+		if route.matches(item, techStack) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r RouteConfig) matches(item speckit.TaskItem, techStack string) bool {
+	if r.IsTest != nil && *r.IsTest != item.IsTest {
+		return false
+	}
+	if len(r.UserStory) > 0 && !containsAnyWord(item.UserStoryRef, r.UserStory) {
+		return false
+	}
+	if len(r.Keywords) > 0 && !containsAnyWord(item.Title, r.Keywords) {
+		return false
+	}
+	if len(r.TechStack) > 0 && !containsAnyWord(techStack, r.TechStack) {
+		return false
+	}
+	if len(r.Files) > 0 && !anyPathMatches(item.FilePaths, r.Files) {
+		return false
+	}
+	return true
+}
+
+// containsAnyWord reports whether text contains any of words as a whole
+// word or phrase, ignoring case
+func containsAnyWord(text string, words []string) bool {
+	for _, word := range words {
+		pattern := `(?i)(^|[^a-z0-9])` + regexp.QuoteMeta(strings.TrimSpace(word)) + `($|[^a-z0-9])`
+		if regexp.MustCompile(pattern).MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyPathMatches reports whether any of paths matches any of globs. A glob
+// ending in /** matches everything below its directory, and a glob without
+// a slash also matches the file name alone, so *.tsx matches web/App.tsx.
+func anyPathMatches(paths, globs []string) bool {
+	for _, p := range paths {
+		p = strings.TrimPrefix(path.Clean(p), "./")
+		for _, glob := range globs {
+			if matchGlob(glob, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchGlob(glob, p string) bool {
+	if dir, ok := strings.CutSuffix(glob, "/**"); ok {
+		return p == dir || strings.HasPrefix(p, dir+"/") || matchDirGlob(dir, p)
+	}
+	if ok, _ := path.Match(glob, p); ok {
+		return true
+	}
+	if !strings.Contains(glob, "/") {
+		ok, _ := path.Match(glob, path.Base(p))
+		return ok
+	}
+	return false
+}
+
+// matchDirGlob reports whether a parent directory of p matches dir, for
+// globs like src/*/components/**
+func matchDirGlob(dir, p string) bool {
+	for parent := path.Dir(p); parent != "." && parent != "/"; parent = path.Dir(parent) {
+		if ok, _ := path.Match(dir, parent); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// featureTechStack returns the tech stack routes match against: what the
+// plan lists and what was set for the feature, or the default
+func (f *Foreman) featureTechStack(feature *Feature) string {
+	stack := feature.TechStack
+	if stack == "" {
+		stack = f.cfg.DefaultTechStack
+	}
+	if feature.Plan != nil {
+		stack = strings.TrimSpace(stack + "\n" + strings.Join(feature.Plan.TechStack, "\n"))
+	}
+	return stack
+}
+
+// routeTask picks the agent and timeout for a generated task from the
+// routing rules, falling back to the default agent and task timeout. It
+// also returns the agents that compete for the task, if any.
+func (f *Foreman) routeTask(feature *Feature, item speckit.TaskItem) (string, time.Duration, []string) {
+	agentName, timeout := f.cfg.DefaultAgent, f.cfg.Concurrency.TaskTimeout
+
+	i := matchRoute(f.cfg.Routing, item, f.featureTechStack(feature))
+	if i < 0 {
+		return agentName, timeout, nil
+	}
+	route := f.cfg.Routing[i]
+	if route.Timeout > 0 {
+		timeout = route.Timeout
+	}
+	agentName = route.Agent
+	if agentName == "" {
+		agentName = route.Compete[0]
+	}
+	return agentName, timeout, competitors(route.Compete, route.Samples)
+}
+
+// reassignTask hands task to another agent from its next attempt on, ending
+// any competition for it. Like a fallback, the new agent starts with a fresh
+// set of retries.
+func (f *Foreman) reassignTask(task *Task, agentName string) {
+	task.mu.Lock()
+	from := task.AgentName
+	task.AgentName = agentName
+	task.Attempt = 0
+	delete(task.Metadata, metaReassignTo)
+	delete(task.Metadata, metaCompete)
+	task.mu.Unlock()
+	// The new agent cannot continue the old one's session
+	clearSession(task)
+	f.recordTask(task, EventTaskStatus, fmt.Sprintf("Reassigned from %s to %s", from, agentName), "user")
+}
+
+// applyReassignment moves task to the agent it was reassigned to while an
+// earlier attempt was running
+func (f *Foreman) applyReassignment(task *Task) {
+	task.mu.Lock()
+	next := task.Metadata[metaReassignTo]
+	task.mu.Unlock()
+	if next != "" {
+		f.reassignTask(task, next)
+	}
+}
+
+// parseReassignArgs splits "[feature] <task> <agent>"
+func parseReassignArgs(args string) (featureID, taskID, agentName string, err error) {
+	fields := strings.Fields(args)
+	switch len(fields) {
+	case 2:
+		return "", fields[0], fields[1], nil
+	case 3:
+		return fields[0], fields[1], fields[2], nil
+	}
+	return "", "", "", fmt.Errorf("expected a task and an agent")
+}
+
+func (f *Foreman) handleReassign(args string) {
+	const usage = "Usage: /reassign [feature] <task> <agent>\nExample: /reassign T-003 codex"
+
+	featureID, taskID, agentName, err := parseReassignArgs(args)
+	if err != nil {
+		f.telegram.Send(fmt.Sprintf("%s\n%s", err, usage))
+		return
+	}
+	if _, ok := f.agents[agentName]; !ok {
+		f.telegram.Send(fmt.Sprintf("Unknown agent: %s\nAvailable: %v", agentName, f.getAgentNames()))
+		return
+	}
+
+	if featureID == "" {
+		if featureID, err = f.findTaskFeature(taskID); err != nil {
+			f.telegram.Send(fmt.Sprintf("%s\n%s", err, usage))
+			return
+		}
+	}
+	var task *Task
+	if feature := f.getFeature(featureID); feature != nil {
+		task = feature.FindTask(taskID)
+	}
+	if task == nil {
+		f.telegram.Send(fmt.Sprintf("Task `%s` not found", taskID))
+		return
+	}
+
+	// The worker running the task reads the reassignment when the attempt
+	// ends, so it is made under the task's lock
+	task.mu.Lock()
+	status, current := task.Status, task.AgentName
+	running := status == StatusRunning || status == StatusReview
+	// A task several agents compete for is not yet anyone's alone
+	same := current == agentName && task.Metadata[metaCompete] == ""
+	if running && same {
+		delete(task.Metadata, metaReassignTo)
+	} else if running {
+		// Switching mid-attempt would credit the old agent's work and
+		// session to the new one
+		if task.Metadata == nil {
+			task.Metadata = make(map[string]string)
+		}
+		task.Metadata[metaReassignTo] = agentName
+	}
+	task.mu.Unlock()
+
+	switch {
+	case status == StatusComplete:
+		f.telegram.Send(fmt.Sprintf("Task `%s` is already complete", taskID))
+	case running && same:
+		f.telegram.Send(fmt.Sprintf("Task `%s` stays with %s", taskID, agentName))
+	case running:
+		f.recordTask(task, EventTaskStatus, fmt.Sprintf("Reassignment to %s requested", agentName), "user")
+		f.telegram.Send(fmt.Sprintf("Task `%s` is running with %s; %s takes over from the next attempt", taskID, current, agentName))
+	case same:
+		f.telegram.Send(fmt.Sprintf("Task `%s` is already assigned to %s", taskID, agentName))
+	default:
+		f.reassignTask(task, agentName)
+		f.telegram.Send(fmt.Sprintf("Task `%s` reassigned from %s to %s", taskID, current, agentName))
+	}
+}
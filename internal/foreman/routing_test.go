@@ -0,0 +1,115 @@
+package foreman
+
+import (
+	"testing"
+
+	"github.com/bayological/foreman/internal/agents"
+	"github.com/bayological/foreman/internal/speckit"
+)
+
+func TestMatchRoute(t *testing.T) {
+	yes := true
+	routes := []RouteConfig{
+		{Agent: "tester", IsTest: &yes},
+		{Agent: "codex", Files: []string{"web/**", "*.tsx"}},
+		{Agent: "codex", Keywords: []string{"frontend", "UI"}},
+		{Agent: "claude-code", TechStack: []string{"go"}, UserStory: []string{"API"}},
+	}
+
+	tests := []struct {
+		name      string
+		item      speckit.TaskItem
+		techStack string
+		want      int
+	}{
+		{"test task", speckit.TaskItem{Title: "Add login tests", IsTest: true, FilePaths: []string{"web/login.tsx"}}, "", 0},
+		{"file under directory", speckit.TaskItem{Title: "Add page", FilePaths: []string{"web/pages/login.ts"}}, "", 1},
+		{"file name glob", speckit.TaskItem{Title: "Add page", FilePaths: []string{"src/App.tsx"}}, "", 1},
+		{"keyword", speckit.TaskItem{Title: "Wire the ui state"}, "", 2},
+		{"keyword inside a word", speckit.TaskItem{Title: "Build the handler"}, "", -1},
+		{"tech stack and story", speckit.TaskItem{Title: "Add handler", UserStoryRef: "Public API"}, "- Go 1.21\n- PostgreSQL", 3},
+		{"tech stack without story", speckit.TaskItem{Title: "Add handler", UserStoryRef: "Setup"}, "Go", -1},
+		{"tech stack inside a word", speckit.TaskItem{Title: "Add handler", UserStoryRef: "API"}, "MongoDB", -1},
+	}
+
+	for _, tc := range tests {
+		if got := matchRoute(routes, tc.item, tc.techStack); got != tc.want {
+			t.Errorf("%s: matchRoute() = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+
+	if got := matchRoute([]RouteConfig{{Agent: "codex"}}, speckit.TaskItem{Title: "Anything"}, ""); got != 0 {
+		t.Errorf("a route without conditions should match every task, got %d", got)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		glob, path string
+		want       bool
+	}{
+		{"web/**", "web/app.ts", true},
+		{"web/**", "web/pages/app.ts", true},
+		{"web/**", "website/app.ts", false},
+		{"src/*/components/**", "src/admin/components/table/row.tsx", true},
+		{"*.go", "internal/api/handler.go", true},
+		{"internal/*.go", "internal/api/handler.go", false},
+		{"internal/*/*.go", "internal/api/handler.go", true},
+	}
+
+	for _, tc := range tests {
+		if got := matchGlob(tc.glob, tc.path); got != tc.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tc.glob, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestValidateRoutes(t *testing.T) {
+	configured := map[string]agents.Agent{"codex": nil}
+
+	if err := validateRoutes([]RouteConfig{{Agent: "codex", Files: []string{"web/**"}}}, configured); err != nil {
+		t.Errorf("validateRoutes() = %v", err)
+	}
+	if err := validateRoutes([]RouteConfig{{Agent: "gemini"}}, configured); err == nil {
+		t.Error("a route to an agent that is not configured should be rejected")
+	}
+	if err := validateRoutes([]RouteConfig{{Agent: "codex", Files: []string{"web/[a"}}}, configured); err == nil {
+		t.Error("a malformed glob should be rejected")
+	}
+	if err := validateRoutes([]RouteConfig{{Compete: []string{"codex"}, Samples: 2}}, configured); err != nil {
+		t.Errorf("two samples of one agent should compete: %v", err)
+	}
+	if err := validateRoutes([]RouteConfig{{Compete: []string{"codex"}}}, configured); err == nil {
+		t.Error("a competition with a single run should be rejected")
+	}
+	if err := validateRoutes([]RouteConfig{{Compete: []string{"codex", "gemini"}}}, configured); err == nil {
+		t.Error("a competing agent that is not configured should be rejected")
+	}
+	if err := validateRoutes([]RouteConfig{{Keywords: []string{"ui"}}}, configured); err == nil {
+		t.Error("a route without an agent should be rejected")
+	}
+}
+
+func TestParseReassignArgs(t *testing.T) {
+	tests := []struct {
+		input                            string
+		wantFeature, wantTask, wantAgent string
+		wantErr                          bool
+	}{
+		{"T-001 codex", "", "T-001", "codex", false},
+		{"12345 T-001 codex", "12345", "T-001", "codex", false},
+		{"T-001", "", "", "", true},
+		{"", "", "", "", true},
+	}
+
+	for _, tc := range tests {
+		feature, task, agent, err := parseReassignArgs(tc.input)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parseReassignArgs(%q) error = %v", tc.input, err)
+			continue
+		}
+		if feature != tc.wantFeature || task != tc.wantTask || agent != tc.wantAgent {
+			t.Errorf("parseReassignArgs(%q) = %q, %q, %q", tc.input, feature, task, agent)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package foreman
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/bayological/foreman/internal/speckit"
+)
+
+type schedState int
+
+const (
+	schedWaiting schedState = iota
+	schedDispatched
+	schedDone
+	schedFailed
+)
+
+// Scheduler releases a feature's tasks in dependency order. A task becomes
+// ready once all of its dependencies are complete and none of the tasks
+// currently in flight touch an overlapping file path.
+type Scheduler struct {
+	order []string
+	tasks map[string]*Task
+	deps  map[string][]string
+	state map[string]schedState
+
+	mu sync.Mutex
+}
+
+// NewScheduler builds a dependency graph from the tasks' Dependencies.
+// Each task's current status seeds its scheduling state, so completed tasks
+// count as done and running or approval-pending tasks as in flight.
+// References to unknown tasks and dependency cycles are reported as errors.
+func NewScheduler(tasks []*Task) (*Scheduler, error) {
+	s := &Scheduler{
+		tasks: make(map[string]*Task),
+		deps:  make(map[string][]string),
+		state: make(map[string]schedState),
+	}
+
+	for _, task := range tasks {
+		if _, dup := s.tasks[task.ID]; dup {
+			return nil, fmt.Errorf("duplicate task ID %s", task.ID)
+		}
+		s.order = append(s.order, task.ID)
+		s.tasks[task.ID] = task
+		s.state[task.ID] = initialSchedState(task.Status)
+	}
+
+	for _, task := range tasks {
+		for _, dep := range task.Dependencies {
+			if _, ok := s.tasks[dep]; !ok {
+				return nil, fmt.Errorf("task %s depends on unknown task %s", task.ID, dep)
+			}
+		}
+		s.deps[task.ID] = task.Dependencies
+	}
+
+	if cycle := s.findCycle(); len(cycle) > 0 {
+		return nil, fmt.Errorf("dependency cycle detected: %s", strings.Join(cycle, " -> "))
+	}
+
+	return s, nil
+}
+
+func initialSchedState(status TaskStatus) schedState {
+	switch status {
+	case StatusPending, "":
+		return schedWaiting
+	case StatusComplete:
+		return schedDone
+	case StatusFailed:
+		return schedFailed
+	default:
+		return schedDispatched
+	}
+}
+
+// findCycle returns the task IDs forming a cycle, or nil if the graph is acyclic.
+func (s *Scheduler) findCycle() []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	marks := make(map[string]int)
+	var stack []string
+	var cycle []string
+
+	var visit func(id string) bool
+	visit = func(id string) bool {
+		marks[id] = visiting
+		stack = append(stack, id)
+
+		for _, dep := range s.deps[id] {
+			switch marks[dep] {
+			case visiting:
+				for i, sid := range stack {
+					if sid == dep {
+						cycle = append(append([]string{}, stack[i:]...), dep)
+						break
+					}
+				}
+				return true
+			case unvisited:
+				if visit(dep) {
+					return true
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		marks[id] = visited
+		return false
+	}
+
+	for _, id := range s.order {
+		if marks[id] == unvisited && visit(id) {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
+// Ready returns the tasks that can start now and marks them as dispatched.
+func (s *Scheduler) Ready() []*Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var inFlight []*Task
+	for _, id := range s.order {
+		if s.state[id] == schedDispatched {
+			inFlight = append(inFlight, s.tasks[id])
+		}
+	}
+
+	var ready []*Task
+	for _, id := range s.order {
+		if s.state[id] != schedWaiting || !s.depsDone(id) {
+			continue
+		}
+
+		task := s.tasks[id]
+		if conflictsWith(task, inFlight) {
+			continue
+		}
+
+		s.state[id] = schedDispatched
+		inFlight = append(inFlight, task)
+		ready = append(ready, task)
+	}
+
+	return ready
+}
+
+func (s *Scheduler) depsDone(id string) bool {
+	for _, dep := range s.deps[id] {
+		if s.state[dep] != schedDone {
+			return false
+		}
+	}
+	return true
+}
+
+// Complete marks a task as done, unblocking its dependents.
+func (s *Scheduler) Complete(id string) {
+	s.setState(id, schedDone)
+}
+
+// Fail marks a task as failed. Its file paths are released but its
+// dependents stay blocked.
+func (s *Scheduler) Fail(id string) {
+	s.setState(id, schedFailed)
+}
+
+// Redispatch marks a previously failed task as in flight again, e.g. when
+// a human retries it.
+func (s *Scheduler) Redispatch(id string) {
+	s.setState(id, schedDispatched)
+}
+
+func (s *Scheduler) setState(id string, state schedState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.state[id]; ok {
+		s.state[id] = state
+	}
+}
+
+// Done reports whether every task has completed.
+func (s *Scheduler) Done() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, state := range s.state {
+		if state != schedDone {
+			return false
+		}
+	}
+	return true
+}
+
+// InFlight returns the number of dispatched tasks that have not yet
+// completed or failed.
+func (s *Scheduler) InFlight() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, state := range s.state {
+		if state == schedDispatched {
+			count++
+		}
+	}
+	return count
+}
+
+// conflictsWith reports whether task touches a file path that overlaps with
+// any of the given tasks.
+func conflictsWith(task *Task, others []*Task) bool {
+	for _, other := range others {
+		for _, a := range task.FilePaths {
+			for _, b := range other.FilePaths {
+				if pathsOverlap(a, b) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// pathsOverlap reports whether two paths are the same or one contains the other.
+func pathsOverlap(a, b string) bool {
+	a = filepath.Clean(a)
+	b = filepath.Clean(b)
+	if a == b {
+		return true
+	}
+	sep := string(filepath.Separator)
+	return strings.HasPrefix(a, b+sep) || strings.HasPrefix(b, a+sep)
+}
+
+// inferDependencies combines explicit task references with the ordering
+// implied by tasks.md: every phase waits for the whole previous phase, a
+// sequential task waits for everything before it in its phase, and a [P]
+// task only waits for the last sequential task before it.
+func inferDependencies(items []speckit.TaskItem) map[string][]string {
+	deps := make(map[string][]string)
+
+	var barrier, section, parallelSince []string
+	lastSeq := ""
+	currentStory := ""
+
+	for i, item := range items {
+		if i == 0 || item.UserStoryRef != currentStory {
+			if len(section) > 0 {
+				barrier = section
+			}
+			section = nil
+			parallelSince = nil
+			lastSeq = ""
+			currentStory = item.UserStoryRef
+		}
+
+		var implied []string
+		if lastSeq != "" {
+			implied = []string{lastSeq}
+		} else {
+			implied = barrier
+		}
+
+		if item.IsParallel {
+			parallelSince = append(parallelSince, item.ID)
+		} else {
+			implied = append(append([]string{}, implied...), parallelSince...)
+			parallelSince = nil
+			lastSeq = item.ID
+		}
+
+		deps[item.ID] = mergeIDs(item.Dependencies, implied)
+		section = append(section, item.ID)
+	}
+
+	return deps
+}
+
+// mergeIDs returns the union of a and b, preserving order and dropping duplicates.
+func mergeIDs(a, b []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, id := range append(append([]string{}, a...), b...) {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -0,0 +1,206 @@
+package foreman
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bayological/foreman/internal/speckit"
+)
+
+func newSchedTask(id string, deps []string, files ...string) *Task {
+	return &Task{
+		ID:           id,
+		Status:       StatusPending,
+		Dependencies: deps,
+		FilePaths:    files,
+	}
+}
+
+func readyIDs(s *Scheduler) []string {
+	var ids []string
+	for _, t := range s.Ready() {
+		ids = append(ids, t.ID)
+	}
+	return ids
+}
+
+func TestSchedulerReleasesInDependencyOrder(t *testing.T) {
+	tasks := []*Task{
+		newSchedTask("T-001", nil),
+		newSchedTask("T-002", nil),
+		newSchedTask("T-003", []string{"T-001", "T-002"}),
+		newSchedTask("T-004", []string{"T-003"}),
+	}
+
+	s, err := NewScheduler(tasks)
+	if err != nil {
+		t.Fatalf("NewScheduler() error = %v", err)
+	}
+
+	if got := strings.Join(readyIDs(s), ","); got != "T-001,T-002" {
+		t.Errorf("first Ready() = %s, want T-001,T-002", got)
+	}
+	if got := readyIDs(s); len(got) != 0 {
+		t.Errorf("Ready() should not re-release dispatched tasks, got %v", got)
+	}
+
+	s.Complete("T-001")
+	if got := readyIDs(s); len(got) != 0 {
+		t.Errorf("T-003 released before T-002 completed: %v", got)
+	}
+
+	s.Complete("T-002")
+	if got := strings.Join(readyIDs(s), ","); got != "T-003" {
+		t.Errorf("Ready() = %s, want T-003", got)
+	}
+
+	s.Complete("T-003")
+	s.Ready()
+	s.Complete("T-004")
+	if !s.Done() {
+		t.Error("expected scheduler to be done")
+	}
+}
+
+func TestSchedulerFileOverlap(t *testing.T) {
+	tasks := []*Task{
+		newSchedTask("T-001", nil, "src/models/user.go"),
+		newSchedTask("T-002", nil, "src/models"),
+		newSchedTask("T-003", nil, "src/api/handler.go"),
+	}
+
+	s, err := NewScheduler(tasks)
+	if err != nil {
+		t.Fatalf("NewScheduler() error = %v", err)
+	}
+
+	if got := strings.Join(readyIDs(s), ","); got != "T-001,T-003" {
+		t.Errorf("Ready() = %s, want T-001,T-003", got)
+	}
+
+	s.Complete("T-001")
+	if got := strings.Join(readyIDs(s), ","); got != "T-002" {
+		t.Errorf("Ready() = %s, want T-002", got)
+	}
+}
+
+func TestSchedulerFailBlocksDependents(t *testing.T) {
+	tasks := []*Task{
+		newSchedTask("T-001", nil, "a.go"),
+		newSchedTask("T-002", []string{"T-001"}),
+		newSchedTask("T-003", nil, "a.go"),
+	}
+
+	s, err := NewScheduler(tasks)
+	if err != nil {
+		t.Fatalf("NewScheduler() error = %v", err)
+	}
+
+	s.Ready()
+	s.Fail("T-001")
+
+	// File lock released, dependent still blocked
+	if got := strings.Join(readyIDs(s), ","); got != "T-003" {
+		t.Errorf("Ready() = %s, want T-003", got)
+	}
+	if s.InFlight() != 1 {
+		t.Errorf("InFlight() = %d, want 1", s.InFlight())
+	}
+
+	s.Redispatch("T-001")
+	s.Complete("T-001")
+	if got := strings.Join(readyIDs(s), ","); got != "T-002" {
+		t.Errorf("Ready() = %s, want T-002", got)
+	}
+}
+
+func TestSchedulerSeedsFromStatus(t *testing.T) {
+	done := newSchedTask("T-001", nil)
+	done.Status = StatusComplete
+	awaiting := newSchedTask("T-002", nil, "x.go")
+	awaiting.Status = StatusApproval
+
+	s, err := NewScheduler([]*Task{
+		done,
+		awaiting,
+		newSchedTask("T-003", []string{"T-001"}, "x.go"),
+		newSchedTask("T-004", []string{"T-001"}),
+	})
+	if err != nil {
+		t.Fatalf("NewScheduler() error = %v", err)
+	}
+
+	if got := strings.Join(readyIDs(s), ","); got != "T-004" {
+		t.Errorf("Ready() = %s, want T-004", got)
+	}
+}
+
+func TestSchedulerDetectsCycles(t *testing.T) {
+	_, err := NewScheduler([]*Task{
+		newSchedTask("T-001", []string{"T-003"}),
+		newSchedTask("T-002", []string{"T-001"}),
+		newSchedTask("T-003", []string{"T-002"}),
+	})
+	if err == nil {
+		t.Fatal("expected cycle error")
+	}
+	if !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("error = %v, want cycle error", err)
+	}
+}
+
+func TestSchedulerUnknownDependency(t *testing.T) {
+	_, err := NewScheduler([]*Task{newSchedTask("T-001", []string{"T-999"})})
+	if err == nil {
+		t.Fatal("expected unknown dependency error")
+	}
+}
+
+func TestPathsOverlap(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"src/a.go", "src/a.go", true},
+		{"src", "src/a.go", true},
+		{"src/a.go", "./src/a.go", true},
+		{"src/a.go", "src/ab.go", false},
+		{"src/a", "src/ab/c.go", false},
+	}
+
+	for _, tc := range tests {
+		if got := pathsOverlap(tc.a, tc.b); got != tc.want {
+			t.Errorf("pathsOverlap(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestInferDependencies(t *testing.T) {
+	items := []speckit.TaskItem{
+		{ID: "T-001", UserStoryRef: "Setup"},
+		{ID: "T-002", UserStoryRef: "Setup", IsParallel: true},
+		{ID: "T-003", UserStoryRef: "Setup", IsParallel: true},
+		{ID: "T-004", UserStoryRef: "Setup"},
+		{ID: "T-005", UserStoryRef: "Core", IsParallel: true},
+		{ID: "T-006", UserStoryRef: "Core", IsParallel: true, Dependencies: []string{"T-002"}},
+		{ID: "T-007", UserStoryRef: "Core"},
+	}
+
+	deps := inferDependencies(items)
+
+	want := map[string]string{
+		"T-001": "",
+		"T-002": "T-001",
+		"T-003": "T-001",
+		"T-004": "T-001,T-002,T-003",
+		"T-005": "T-001,T-002,T-003,T-004",
+		"T-006": "T-002,T-001,T-003,T-004",
+		"T-007": "T-001,T-002,T-003,T-004,T-005,T-006",
+	}
+
+	for id, w := range want {
+		if got := strings.Join(deps[id], ","); got != w {
+			t.Errorf("deps[%s] = %s, want %s", id, got, w)
+		}
+	}
+}
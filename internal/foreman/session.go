@@ -0,0 +1,89 @@
+package foreman
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Task metadata kept for resuming an agent's session on retry
+const (
+	// metaSessionID is the session of the agent's last attempt
+	metaSessionID = "session_id"
+	// metaSessionAgent is the agent the session belongs to
+	metaSessionAgent = "session_agent"
+	// metaSessionContext is the length of the task context the session has
+	// already seen; anything added after it is new to the agent
+	metaSessionContext = "session_context"
+)
+
+// saveSession remembers the session an agent ran task in, so the next
+// attempt can continue it
+func saveSession(task *Task, sessionID string) {
+	if sessionID == "" {
+		return
+	}
+	task.mu.Lock()
+	defer task.mu.Unlock()
+	if task.Metadata == nil {
+		task.Metadata = make(map[string]string)
+	}
+	task.Metadata[metaSessionID] = sessionID
+	task.Metadata[metaSessionAgent] = task.AgentName
+	task.Metadata[metaSessionContext] = strconv.Itoa(len(task.Context))
+}
+
+// clearSession forgets task's session; the next attempt starts from scratch
+func clearSession(task *Task) {
+	task.mu.Lock()
+	defer task.mu.Unlock()
+	delete(task.Metadata, metaSessionID)
+	delete(task.Metadata, metaSessionAgent)
+	delete(task.Metadata, metaSessionContext)
+}
+
+// hasSession reports whether task holds a session of its current agent
+func hasSession(task *Task) bool {
+	return task.Metadata[metaSessionID] != "" && task.Metadata[metaSessionAgent] == task.AgentName
+}
+
+// resumeSession returns the session to continue and the prompt to continue
+// it with: the context added since the session last ran. It returns empty
+// strings when the task should start from scratch.
+func resumeSession(task *Task) (sessionID, prompt string) {
+	if !hasSession(task) {
+		return "", ""
+	}
+	seen, err := strconv.Atoi(task.Metadata[metaSessionContext])
+	if err != nil || seen < 0 || seen > len(task.Context) {
+		return "", ""
+	}
+	prompt = strings.TrimSpace(strings.TrimPrefix(task.Context[seen:], "\n\n---\n"))
+	if prompt == "" {
+		return "", ""
+	}
+	return task.Metadata[metaSessionID], prompt
+}
+
+// keepsWorktree reports whether task's worktree should outlive the current
+// attempt: it holds a session and is queued to run, or already running,
+// again. The worker asks once the task may already be in a handler's hands.
+func keepsWorktree(task *Task) bool {
+	task.mu.Lock()
+	defer task.mu.Unlock()
+	if !hasSession(task) {
+		return false
+	}
+	return task.Status == StatusPending || task.Status == StatusRunning
+}
+
+// endSession forgets task's session and removes the worktree kept for it
+func (f *Foreman) endSession(task *Task) {
+	task.mu.Lock()
+	sessionID := task.Metadata[metaSessionID]
+	task.mu.Unlock()
+	if sessionID == "" {
+		return
+	}
+	clearSession(task)
+	f.repo.RemoveWorktree(task.Branch)
+}
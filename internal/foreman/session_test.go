@@ -0,0 +1,88 @@
+package foreman
+
+import "testing"
+
+func TestResumeSession(t *testing.T) {
+	task := &Task{ID: "T001", AgentName: "claude-code", Spec: "Add login"}
+	task.AddContext("Use the existing session store")
+
+	if id, _ := resumeSession(task); id != "" {
+		t.Errorf("resumeSession() without a session = %q", id)
+	}
+
+	saveSession(task, "abc123")
+	if id, prompt := resumeSession(task); id != "" || prompt != "" {
+		t.Errorf("resumeSession() with nothing new = %q, %q; want a fresh start", id, prompt)
+	}
+
+	task.AddContext("Review Feedback (attempt 1):\nMissing tests")
+	id, prompt := resumeSession(task)
+	if id != "abc123" {
+		t.Errorf("session = %q, want abc123", id)
+	}
+	if prompt != "Review Feedback (attempt 1):\nMissing tests" {
+		t.Errorf("prompt = %q, want only the new feedback", prompt)
+	}
+
+	// Feedback piles up until the agent runs again
+	task.AddContext("User Feedback:\nAlso handle logout")
+	if _, prompt := resumeSession(task); prompt != "Review Feedback (attempt 1):\nMissing tests\n\n---\nUser Feedback:\nAlso handle logout" {
+		t.Errorf("prompt = %q", prompt)
+	}
+
+	saveSession(task, "def456")
+	task.AddContext("Review Feedback (attempt 2):\nName the handler")
+	if id, prompt := resumeSession(task); id != "def456" || prompt != "Review Feedback (attempt 2):\nName the handler" {
+		t.Errorf("resumeSession() after another run = %q, %q", id, prompt)
+	}
+}
+
+func TestResumeSessionStartsOver(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(task *Task)
+	}{
+		{"another agent", func(task *Task) { task.AgentName = "codex" }},
+		{"context shrank", func(task *Task) { task.Context = "short" }},
+		{"bad offset", func(task *Task) { task.Metadata[metaSessionContext] = "x" }},
+		{"cleared", clearSession},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &Task{ID: "T001", AgentName: "claude-code", Context: "Earlier context"}
+			saveSession(task, "abc123")
+			task.AddContext("New feedback")
+			tt.modify(task)
+
+			if id, prompt := resumeSession(task); id != "" || prompt != "" {
+				t.Errorf("resumeSession() = %q, %q; want a fresh start", id, prompt)
+			}
+		})
+	}
+}
+
+func TestKeepsWorktree(t *testing.T) {
+	tests := []struct {
+		status  TaskStatus
+		session bool
+		want    bool
+	}{
+		{StatusPending, true, true},
+		{StatusRunning, true, true},
+		{StatusPending, false, false},
+		{StatusApproval, true, false},
+		{StatusFailed, true, false},
+		{StatusComplete, true, false},
+	}
+
+	for _, tt := range tests {
+		task := &Task{ID: "T001", AgentName: "claude-code", Status: tt.status}
+		if tt.session {
+			saveSession(task, "abc123")
+		}
+		if got := keepsWorktree(task); got != tt.want {
+			t.Errorf("keepsWorktree(%s, session %v) = %v, want %v", tt.status, tt.session, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package foreman
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -23,6 +24,7 @@ type Task struct {
 	Spec         string
 	Context      string
 	Branch       string
+	BaseBranch   string
 	WorktreePath string
 	AgentName    string
 	Timeout      time.Duration
@@ -31,7 +33,13 @@ type Task struct {
 	CreatedAt    time.Time
 	FeatureID    string
 	IsParallel   bool
+	Dependencies []string
+	FilePaths    []string
 	Metadata     map[string]string
+
+	// mu guards the fields the worker running a task and the handlers
+	// answering the user about it both touch
+	mu sync.Mutex
 }
 
 func NewTask(spec string, agentName string, timeout time.Duration) *Task {
@@ -49,13 +57,19 @@ func NewTask(spec string, agentName string, timeout time.Duration) *Task {
 	}
 }
 
-func (t *Task) PRURL(repoURL string) string {
-	return fmt.Sprintf("%s/compare/main...%s", repoURL, t.Branch)
-}
-
 func (t *Task) AddContext(ctx string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	if t.Context != "" {
 		t.Context += "\n\n---\n"
 	}
 	t.Context += ctx
+}
+
+// setStatus moves the task on; the other tasks of its feature may be saved
+// by another worker meanwhile
+func (t *Task) setStatus(status TaskStatus) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Status = status
 }
\ No newline at end of file
@@ -37,18 +37,6 @@ func TestNewTask(t *testing.T) {
 	}
 }
 
-func TestTaskPRURL(t *testing.T) {
-	task := NewTask("Test", "agent", time.Hour)
-	task.Branch = "task/abc123"
-
-	url := task.PRURL("https://github.com/owner/repo")
-
-	expected := "https://github.com/owner/repo/compare/main...task/abc123"
-	if url != expected {
-		t.Errorf("PRURL() = %q, want %q", url, expected)
-	}
-}
-
 func TestTaskAddContext(t *testing.T) {
 	task := NewTask("Test", "agent", time.Hour)
 
@@ -104,6 +92,29 @@ func TestTaskMetadata(t *testing.T) {
 	}
 }
 
+func TestTaskStateIsACopy(t *testing.T) {
+	task := NewTask("Test", "agent", time.Hour)
+	task.Dependencies = []string{"T001"}
+	task.FilePaths = []string{"main.go"}
+	task.Metadata["key"] = "value"
+
+	state := taskToState(task)
+	task.Dependencies[0] = "T002"
+	task.FilePaths[0] = "other.go"
+	task.Metadata["key"] = "changed"
+
+	if state.Dependencies[0] != "T001" || state.FilePaths[0] != "main.go" || state.Metadata["key"] != "value" {
+		t.Errorf("state changed with the task: %+v", state)
+	}
+
+	restored := stateToTask(state, time.Hour)
+	restored.Metadata["key"] = "restored"
+	restored.Dependencies[0] = "T003"
+	if state.Metadata["key"] != "value" || state.Dependencies[0] != "T001" {
+		t.Errorf("state changed with the restored task: %+v", state)
+	}
+}
+
 func TestTaskUniqueIDs(t *testing.T) {
 	ids := make(map[string]bool)
 
@@ -0,0 +1,264 @@
+package foreman
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bayological/foreman/internal/agents"
+	"github.com/bayological/foreman/internal/storage"
+)
+
+const (
+	// transcriptPruneInterval is how often transcripts past their retention
+	// period are removed
+	transcriptPruneInterval = time.Hour
+
+	// maxLogMessage is the longest log sent as a message; longer logs are
+	// sent as a document. Telegram allows 4096 characters.
+	maxLogMessage = 3500
+)
+
+// Files recorded for each attempt
+const (
+	transcriptInfo   = "info.txt"
+	transcriptAgent  = "agent.log"
+	transcriptStderr = "stderr.log"
+	transcriptReview = "review.md"
+)
+
+// beginTranscript starts recording an attempt at task. It returns nil when
+// transcripts are not kept or the attempt cannot be recorded.
+func (f *Foreman) beginTranscript(task *Task) *storage.TranscriptAttempt {
+	if f.transcripts == nil {
+		return nil
+	}
+	attempt, err := f.transcripts.Begin(task.FeatureID, task.ID)
+	if err != nil {
+		log.Printf("Warning: Failed to record transcript of task %s: %v", task.ID, err)
+		return nil
+	}
+	return attempt
+}
+
+// saveTranscript writes one file of an attempt
+func saveTranscript(attempt *storage.TranscriptAttempt, name, content string) {
+	if attempt == nil {
+		return
+	}
+	if err := attempt.Save(name, content); err != nil {
+		log.Printf("Warning: Failed to save transcript %s: %v", name, err)
+	}
+}
+
+// saveAgentTranscript records an agent run: what ran, its raw output and
+// stderr, and the error it ended with
+func saveAgentTranscript(attempt *storage.TranscriptAttempt, run, agentName string, result *agents.TaskResult, err error) {
+	if attempt == nil {
+		return
+	}
+
+	var info strings.Builder
+	fmt.Fprintf(&info, "Run: %s\nAgent: %s\nFinished: %s\n", run, agentName, time.Now().Format(time.RFC3339))
+	switch {
+	case err != nil:
+		fmt.Fprintf(&info, "Result: error\n\n%v\n", err)
+	case result.Success:
+		fmt.Fprintf(&info, "Duration: %s\nResult: success\n\n%s\n", result.Duration.Round(time.Second), result.Summary)
+	default:
+		fmt.Fprintf(&info, "Duration: %s\nResult: failed\n\n%v\n", result.Duration.Round(time.Second), result.Error)
+	}
+	saveTranscript(attempt, transcriptInfo, info.String())
+
+	if result != nil {
+		saveTranscript(attempt, transcriptAgent, result.Transcript)
+		saveTranscript(attempt, transcriptStderr, result.Stderr)
+	}
+}
+
+// saveReviewTranscript records the full output of every review tool and the
+// review itself
+func saveReviewTranscript(attempt *storage.TranscriptAttempt, review *agents.ReviewResult) {
+	for tool, output := range review.ToolOutputs {
+		saveTranscript(attempt, "review-"+tool+".log", output)
+	}
+	saveTranscript(attempt, transcriptReview, fmt.Sprintf("Verdict: %s\n\n%s\n", review.Verdict, review.Summary))
+}
+
+// pruneTranscripts removes transcripts past their retention period now and
+// then periodically until ctx is done
+func (f *Foreman) pruneTranscripts(ctx context.Context) {
+	if f.transcripts == nil {
+		return
+	}
+
+	ticker := time.NewTicker(transcriptPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		if removed, err := f.transcripts.Prune(time.Now()); err != nil {
+			log.Printf("Warning: Failed to prune transcripts: %v", err)
+		} else if removed > 0 {
+			log.Printf("Pruned %d task transcripts", removed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// findTaskFeature returns the feature a task ID belongs to, or "" for a
+// standalone or unknown task. SpecKit numbers every feature's tasks from
+// T001, so an ID found in several features is an error: the caller has to
+// name the feature.
+func (f *Foreman) findTaskFeature(taskID string) (string, error) {
+	f.featuresMu.RLock()
+	defer f.featuresMu.RUnlock()
+	var found []string
+	for _, feature := range f.features {
+		if feature.FindTask(taskID) != nil {
+			found = append(found, feature.ID)
+		}
+	}
+	if len(found) > 1 {
+		sort.Strings(found)
+		return "", fmt.Errorf("task %s is in features %s; name the feature first", taskID, strings.Join(found, ", "))
+	}
+	if len(found) == 0 {
+		return "", nil
+	}
+	return found[0], nil
+}
+
+// taskLogs renders the transcript of one attempt of a task; attempt 0 means
+// the latest. It returns the attempt shown and the attempts recorded.
+func (f *Foreman) taskLogs(featureID, taskID string, attempt int) (string, int, []int, error) {
+	attempts, err := f.transcripts.Attempts(featureID, taskID)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	if len(attempts) == 0 {
+		return "", 0, nil, fmt.Errorf("no transcripts recorded for task %s", taskID)
+	}
+	if attempt == 0 {
+		attempt = attempts[len(attempts)-1]
+	}
+	if i := sort.SearchInts(attempts, attempt); i == len(attempts) || attempts[i] != attempt {
+		return "", 0, attempts, fmt.Errorf("task %s has no attempt %d", taskID, attempt)
+	}
+
+	files, err := f.transcripts.Files(featureID, taskID, attempt)
+	if err != nil {
+		return "", 0, attempts, err
+	}
+
+	// The summary comes first, the raw agent output last
+	order := func(name string) int {
+		switch name {
+		case transcriptInfo:
+			return 0
+		case transcriptReview:
+			return 1
+		case transcriptAgent:
+			return 3
+		}
+		return 2
+	}
+	sort.SliceStable(files, func(i, j int) bool { return order(files[i].Name) < order(files[j].Name) })
+
+	var b strings.Builder
+	for _, file := range files {
+		data, err := f.transcripts.Read(featureID, taskID, attempt, file.Name)
+		if err != nil {
+			return "", 0, attempts, err
+		}
+		fmt.Fprintf(&b, "===== %s =====\n%s", file.Name, data)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), attempt, attempts, nil
+}
+
+// parseLogsArgs reads "[feature] <task> [attempt]"; isFeature tells
+// whether a word names a feature
+func parseLogsArgs(args string, isFeature func(string) bool) (featureID, taskID string, attempt int, err error) {
+	fields := strings.Fields(args)
+	if len(fields) > 1 && isFeature(fields[0]) {
+		featureID, fields = fields[0], fields[1:]
+	}
+	if len(fields) == 0 || len(fields) > 2 {
+		return "", "", 0, fmt.Errorf("expected a task and at most an attempt")
+	}
+	taskID = fields[0]
+	if len(fields) == 2 {
+		attempt, err = strconv.Atoi(fields[1])
+		if err != nil || attempt < 1 {
+			return "", "", 0, fmt.Errorf("invalid attempt %q", fields[1])
+		}
+	}
+	return featureID, taskID, attempt, nil
+}
+
+func (f *Foreman) handleLogs(args string) {
+	const usage = "Usage: /logs [feature] <task> [attempt]\nExample: /logs T001 2"
+
+	if f.transcripts == nil {
+		f.telegram.Send("Transcripts are not kept: storage is not configured")
+		return
+	}
+
+	if strings.TrimSpace(args) == "" {
+		f.telegram.Send(usage)
+		return
+	}
+
+	featureID, taskID, attempt, err := parseLogsArgs(args, func(id string) bool { return f.getFeature(id) != nil })
+	if err != nil {
+		f.telegram.Send(fmt.Sprintf("%s\n%s", err, usage))
+		return
+	}
+	if featureID == "" {
+		if featureID, err = f.findTaskFeature(taskID); err != nil {
+			f.telegram.Send(fmt.Sprintf("%s\n%s", err, usage))
+			return
+		}
+	}
+
+	text, shown, attempts, err := f.taskLogs(featureID, taskID, attempt)
+	if err != nil {
+		msg := err.Error()
+		if len(attempts) > 0 {
+			msg += fmt.Sprintf("\nRecorded attempts: %s", joinInts(attempts))
+		}
+		f.telegram.Send(msg)
+		return
+	}
+
+	caption := fmt.Sprintf("Logs for `%s`, attempt %d of %d", taskID, shown, len(attempts))
+	if len(text) <= maxLogMessage {
+		// Triple backticks would end the code block early
+		f.telegram.Send(fmt.Sprintf("%s\n```\n%s```", caption, strings.ReplaceAll(text, "```", "'''")))
+		return
+	}
+	name := fmt.Sprintf("%s-attempt-%d.log", taskID, shown)
+	if err := f.telegram.SendDocument(name, []byte(text), caption); err != nil {
+		f.telegram.Send(fmt.Sprintf("Failed to send logs for `%s`: %s", taskID, err))
+	}
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ", ")
+}
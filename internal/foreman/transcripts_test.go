@@ -0,0 +1,173 @@
+package foreman
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bayological/foreman/internal/agents"
+	"github.com/bayological/foreman/internal/storage"
+)
+
+func newTranscriptForeman(t *testing.T) *Foreman {
+	dir, err := os.MkdirTemp("", "transcripts-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	transcripts, err := storage.OpenTranscripts(dir, storage.DefaultTranscriptRetention)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Foreman{
+		cfg:         &Config{},
+		features:    make(map[string]*Feature),
+		transcripts: transcripts,
+	}
+}
+
+func TestTaskLogs(t *testing.T) {
+	f := newTranscriptForeman(t)
+	task := &Task{ID: "T001", FeatureID: "f1", AgentName: "claude-code"}
+
+	first := f.beginTranscript(task)
+	saveAgentTranscript(first, "task", "claude-code", nil, errors.New("claude exited with error"))
+
+	second := f.beginTranscript(task)
+	saveAgentTranscript(second, "task", "claude-code", &agents.TaskResult{
+		Success:    true,
+		Summary:    "Added login",
+		Duration:   90 * time.Second,
+		Transcript: `{"type":"assistant"}` + "\n",
+		Stderr:     "warning: slow network",
+	}, nil)
+	saveReviewTranscript(second, &agents.ReviewResult{
+		Verdict:     agents.VerdictApprove,
+		Summary:     "All checks passed",
+		ToolOutputs: map[string]string{"tests": "ok  \tpkg\t0.1s", "lint": ""},
+	})
+
+	text, shown, attempts, err := f.taskLogs("f1", "T001", 0)
+	if err != nil {
+		t.Fatalf("taskLogs() error = %v", err)
+	}
+	if shown != 2 || len(attempts) != 2 {
+		t.Errorf("showed attempt %d of %v, want the latest of 2", shown, attempts)
+	}
+
+	// Summary first, raw agent output last; empty tool output is not recorded
+	var sections []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "===== ") {
+			sections = append(sections, strings.Trim(line, "= "))
+		}
+	}
+	want := []string{"info.txt", "review.md", "review-tests.log", "stderr.log", "agent.log"}
+	if strings.Join(sections, ",") != strings.Join(want, ",") {
+		t.Errorf("sections = %v, want %v", sections, want)
+	}
+	for _, s := range []string{"Agent: claude-code", "Duration: 1m30s", "Result: success", "Verdict: APPROVE", "warning: slow network", `{"type":"assistant"}`} {
+		if !strings.Contains(text, s) {
+			t.Errorf("logs missing %q:\n%s", s, text)
+		}
+	}
+
+	text, _, _, err = f.taskLogs("f1", "T001", 1)
+	if err != nil || !strings.Contains(text, "Result: error") || strings.Contains(text, "agent.log") {
+		t.Errorf("taskLogs() of attempt 1 = %q, %v", text, err)
+	}
+
+	if _, _, attempts, err := f.taskLogs("f1", "T001", 3); err == nil || len(attempts) != 2 {
+		t.Errorf("taskLogs() of a missing attempt = %v, %v", attempts, err)
+	}
+	if _, _, _, err := f.taskLogs("f1", "T002", 0); err == nil {
+		t.Error("taskLogs() of a task without transcripts should fail")
+	}
+}
+
+func TestBeginTranscriptWithoutStorage(t *testing.T) {
+	f := &Foreman{cfg: &Config{}}
+	attempt := f.beginTranscript(&Task{ID: "T001"})
+	if attempt != nil {
+		t.Fatal("beginTranscript() without storage should record nothing")
+	}
+	// Saving to a missing attempt is a no-op
+	saveAgentTranscript(attempt, "task", "claude-code", &agents.TaskResult{Success: true}, nil)
+	saveReviewTranscript(attempt, &agents.ReviewResult{ToolOutputs: map[string]string{"tests": "ok"}})
+}
+
+func TestParseLogsArgs(t *testing.T) {
+	isFeature := func(id string) bool { return id == "f1" }
+
+	tests := []struct {
+		args        string
+		wantFeature string
+		wantTask    string
+		wantAttempt int
+		wantErr     bool
+	}{
+		{"T001", "", "T001", 0, false},
+		{"T001 2", "", "T001", 2, false},
+		{"f1 T001", "f1", "T001", 0, false},
+		{"f1 T001 3", "f1", "T001", 3, false},
+		{"f1", "", "f1", 0, false},
+		{"T001 latest", "", "", 0, true},
+		{"T001 0", "", "", 0, true},
+		{"T001 1 2", "", "", 0, true},
+		{"", "", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		feature, task, attempt, err := parseLogsArgs(tt.args, isFeature)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseLogsArgs(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if feature != tt.wantFeature || task != tt.wantTask || attempt != tt.wantAttempt {
+			t.Errorf("parseLogsArgs(%q) = %q, %q, %d", tt.args, feature, task, attempt)
+		}
+	}
+}
+
+func TestFindTaskFeature(t *testing.T) {
+	f := newTranscriptForeman(t)
+	for _, id := range []string{"f1", "f2"} {
+		feature := NewFeature(id, "Test", "Test feature")
+		feature.Tasks = []*Task{{ID: "T001", FeatureID: id}}
+		if id == "f2" {
+			feature.Tasks = append(feature.Tasks, &Task{ID: "T002", FeatureID: id})
+		}
+		f.features[id] = feature
+	}
+
+	if got, err := f.findTaskFeature("T002"); got != "f2" || err != nil {
+		t.Errorf("findTaskFeature(T002) = %q, %v; want f2", got, err)
+	}
+	if got, err := f.findTaskFeature("T009"); got != "" || err != nil {
+		t.Errorf("findTaskFeature(T009) = %q, %v; want no feature", got, err)
+	}
+	// SpecKit task IDs repeat across features
+	_, err := f.findTaskFeature("T001")
+	if err == nil || !strings.Contains(err.Error(), "f1, f2") {
+		t.Errorf("findTaskFeature(T001) error = %v, want one naming both features", err)
+	}
+}
+
+func TestTranscriptsPath(t *testing.T) {
+	tests := []struct {
+		cfg  StorageConfig
+		want string
+	}{
+		{StorageConfig{}, ""},
+		{StorageConfig{Path: "/data/features.json"}, "/data/transcripts"},
+		{StorageConfig{Path: "/data/features.json", Transcripts: "/logs"}, "/logs"},
+	}
+	for _, tt := range tests {
+		if got := tt.cfg.TranscriptsPath(); got != tt.want {
+			t.Errorf("TranscriptsPath(%+v) = %q, want %q", tt.cfg, got, tt.want)
+		}
+	}
+}
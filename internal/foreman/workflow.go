@@ -32,9 +32,12 @@ var validTransitions = map[Phase][]Phase{
 	PhaseAwaitingPlanApproval: {PhaseTasking, PhasePlanning, PhaseFailed},
 	PhaseTasking:              {PhaseAwaitingTaskApproval, PhaseFailed},
 	PhaseAwaitingTaskApproval: {PhaseImplementing, PhaseTasking, PhaseFailed},
-	PhaseImplementing:         {PhaseReviewing, PhaseFailed},
-	PhaseReviewing:            {PhaseAwaitingCodeApproval, PhaseImplementing, PhaseFailed},
-	PhaseAwaitingCodeApproval: {PhaseImplementing, PhaseComplete, PhaseFailed},
+	// Tasks run in parallel but share the feature's phase, which follows
+	// whichever task moved last: the implementation phases move freely
+	// between each other, and to completion once the last task is approved
+	PhaseImplementing:         {PhaseImplementing, PhaseReviewing, PhaseAwaitingCodeApproval, PhaseComplete, PhaseFailed},
+	PhaseReviewing:            {PhaseImplementing, PhaseReviewing, PhaseAwaitingCodeApproval, PhaseComplete, PhaseFailed},
+	PhaseAwaitingCodeApproval: {PhaseImplementing, PhaseReviewing, PhaseAwaitingCodeApproval, PhaseComplete, PhaseFailed},
 	PhaseComplete:             {PhaseIdle},
 	PhaseFailed:               {PhaseIdle},
 }
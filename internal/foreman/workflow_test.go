@@ -33,7 +33,10 @@ func TestCanTransition(t *testing.T) {
 		{"idle to planning (skip)", PhaseIdle, PhasePlanning, false},
 		{"specifying to complete (skip)", PhaseSpecifying, PhaseComplete, false},
 		{"complete to specifying", PhaseComplete, PhaseSpecifying, false},
-		{"implementing to complete (skip review)", PhaseImplementing, PhaseComplete, false},
+		{"implementing to complete (last approval while others were in flight)", PhaseImplementing, PhaseComplete, true},
+		{"awaiting code to reviewing (parallel task)", PhaseAwaitingCodeApproval, PhaseReviewing, true},
+		{"reviewing to reviewing (parallel task)", PhaseReviewing, PhaseReviewing, true},
+		{"implementing to specifying", PhaseImplementing, PhaseSpecifying, false},
 		{"unknown phase", Phase("unknown"), PhaseIdle, false},
 	}
 
@@ -0,0 +1,209 @@
+// Package forge talks to the code hosting service a repository lives on
+// (GitHub, GitLab, Gitea) to manage pull requests.
+package forge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when a pull request does not exist.
+var ErrNotFound = errors.New("pull request not found")
+
+// Forge manages pull requests on a code hosting service. GitLab merge
+// requests are treated as pull requests.
+type Forge interface {
+	// CreatePullRequest opens a pull request from opts.Head into opts.Base.
+	CreatePullRequest(ctx context.Context, opts PullRequestOptions) (*PullRequest, error)
+	// UpdatePullRequest changes the title, body or base of a pull request.
+	// Empty fields are left unchanged.
+	UpdatePullRequest(ctx context.Context, number int, opts PullRequestOptions) (*PullRequest, error)
+	// FindPullRequest returns the open pull request for head, or ErrNotFound.
+	FindPullRequest(ctx context.Context, head string) (*PullRequest, error)
+	// ReviewComments returns the review comments left on a pull request.
+	ReviewComments(ctx context.Context, number int) ([]ReviewComment, error)
+	// CheckStatus returns the combined CI status of a pull request's head.
+	CheckStatus(ctx context.Context, number int) (*CheckStatus, error)
+	// ClosePullRequest closes a pull request without merging it.
+	ClosePullRequest(ctx context.Context, number int) error
+	// CompareURL returns the web URL comparing head against base.
+	CompareURL(base, head string) string
+}
+
+// PullRequestOptions describes a pull request to create or update.
+type PullRequestOptions struct {
+	Head  string
+	Base  string
+	Title string
+	Body  string
+}
+
+// PullRequest is a pull request as reported by the forge.
+type PullRequest struct {
+	Number int
+	URL    string
+	Title  string
+	Body   string
+	Head   string
+	Base   string
+	State  string // "open", "closed" or "merged"
+}
+
+// ReviewComment is a comment left by a reviewer. Path and Line are empty for
+// comments that are not attached to a line of code.
+type ReviewComment struct {
+	Author    string
+	Body      string
+	Path      string
+	Line      int
+	CreatedAt time.Time
+}
+
+type CheckState string
+
+const (
+	CheckPending CheckState = "pending"
+	CheckSuccess CheckState = "success"
+	CheckFailure CheckState = "failure"
+	CheckNone    CheckState = "none" // no checks configured
+)
+
+// Check is a single CI job or status on a commit.
+type Check struct {
+	Name  string
+	State CheckState
+	URL   string
+}
+
+// CheckStatus is the combined state of all checks on a pull request.
+type CheckStatus struct {
+	State  CheckState
+	Checks []Check
+}
+
+// combineChecks derives the overall state: any failure fails, then any
+// pending check keeps it pending.
+func combineChecks(checks []Check) *CheckStatus {
+	status := &CheckStatus{State: CheckNone, Checks: checks}
+	if len(checks) == 0 {
+		return status
+	}
+
+	status.State = CheckSuccess
+	for _, c := range checks {
+		switch c.State {
+		case CheckFailure:
+			status.State = CheckFailure
+			return status
+		case CheckPending:
+			status.State = CheckPending
+		}
+	}
+	return status
+}
+
+// Config selects and configures a forge.
+type Config struct {
+	Type    string // "github", "gitlab", "gitea" or "memory"
+	URL     string // web URL of the forge, e.g. https://gitlab.example.com
+	APIURL  string // API base URL; derived from URL when empty
+	Project string // "owner/repo", or "group/subgroup/project" on GitLab
+	Token   string
+}
+
+// tokenEnv is consulted when no token is configured.
+var tokenEnv = map[string]string{
+	"github": "GITHUB_TOKEN",
+	"gitlab": "GITLAB_TOKEN",
+	"gitea":  "GITEA_TOKEN",
+}
+
+// New creates the forge described by cfg.
+func New(cfg Config) (Forge, error) {
+	if cfg.Type == "" {
+		cfg.Type = DetectType(cfg.URL)
+	}
+	if cfg.Type == "" {
+		return nil, fmt.Errorf("cannot infer forge type from %q; set forge.type", cfg.URL)
+	}
+	if cfg.Type == "memory" {
+		return NewMemory(cfg.URL), nil
+	}
+
+	if cfg.URL == "" || cfg.Project == "" {
+		return nil, fmt.Errorf("forge URL and project are required")
+	}
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv(tokenEnv[cfg.Type])
+	}
+
+	switch cfg.Type {
+	case "github":
+		return NewGitHub(cfg), nil
+	case "gitlab":
+		return NewGitLab(cfg), nil
+	case "gitea":
+		return NewGitea(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown forge type: %s", cfg.Type)
+	}
+}
+
+// DetectType guesses the forge type from its host name.
+func DetectType(webURL string) string {
+	u, err := url.Parse(webURL)
+	if err != nil {
+		return ""
+	}
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case strings.Contains(host, "github"):
+		return "github"
+	case strings.Contains(host, "gitlab"):
+		return "gitlab"
+	case strings.Contains(host, "gitea"), strings.Contains(host, "codeberg"):
+		return "gitea"
+	}
+	return ""
+}
+
+// ParseRemote splits a git remote URL into the forge's web URL and the
+// project path. Both https and scp-style ssh remotes are understood:
+//
+//	https://gitlab.example.com/group/project.git
+//	git@github.com:owner/repo.git
+func ParseRemote(remote string) (webURL, project string, err error) {
+	remote = strings.TrimSpace(remote)
+
+	var host, path string
+	switch {
+	case strings.Contains(remote, "://"):
+		u, perr := url.Parse(remote)
+		if perr != nil {
+			return "", "", fmt.Errorf("invalid remote URL: %w", perr)
+		}
+		host, path = u.Hostname(), u.Path
+	case strings.Contains(remote, ":"):
+		// scp-like syntax: [user@]host:path
+		hostPart, pathPart, _ := strings.Cut(remote, ":")
+		if i := strings.LastIndex(hostPart, "@"); i >= 0 {
+			hostPart = hostPart[i+1:]
+		}
+		host, path = hostPart, pathPart
+	default:
+		return "", "", fmt.Errorf("unrecognised remote URL: %s", remote)
+	}
+
+	project = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || !strings.Contains(project, "/") {
+		return "", "", fmt.Errorf("unrecognised remote URL: %s", remote)
+	}
+
+	return "https://" + host, project, nil
+}
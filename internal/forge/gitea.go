@@ -0,0 +1,198 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Gitea implements Forge against the Gitea (and Forgejo) REST API.
+type Gitea struct {
+	webURL  string
+	project string
+	api     *client
+}
+
+// NewGitea creates a Gitea forge. The API URL defaults to <url>/api/v1.
+func NewGitea(cfg Config) *Gitea {
+	apiURL := cfg.APIURL
+	if apiURL == "" {
+		apiURL = cfg.URL + "/api/v1"
+	}
+
+	token := cfg.Token
+	return &Gitea{
+		webURL:  cfg.URL,
+		project: cfg.Project,
+		api: newClient(strings.TrimSuffix(apiURL, "/"), func(req *http.Request) {
+			if token != "" {
+				req.Header.Set("Authorization", "token "+token)
+			}
+		}),
+	}
+}
+
+type giteaPR struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	State   string `json:"state"`
+	Merged  bool   `json:"merged"`
+	Head    struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+func (p *giteaPR) toPullRequest() *PullRequest {
+	state := p.State
+	if p.Merged {
+		state = "merged"
+	}
+	return &PullRequest{
+		Number: p.Number,
+		URL:    p.HTMLURL,
+		Title:  p.Title,
+		Body:   p.Body,
+		Head:   p.Head.Ref,
+		Base:   p.Base.Ref,
+		State:  state,
+	}
+}
+
+func (g *Gitea) repoPath(format string, args ...interface{}) string {
+	return "/repos/" + g.project + fmt.Sprintf(format, args...)
+}
+
+func (g *Gitea) CreatePullRequest(ctx context.Context, opts PullRequestOptions) (*PullRequest, error) {
+	var pr giteaPR
+	err := g.api.do(ctx, http.MethodPost, g.repoPath("/pulls"), map[string]string{
+		"title": opts.Title,
+		"body":  opts.Body,
+		"head":  opts.Head,
+		"base":  opts.Base,
+	}, &pr)
+	if err != nil {
+		return nil, fmt.Errorf("creating pull request: %w", err)
+	}
+	return pr.toPullRequest(), nil
+}
+
+func (g *Gitea) UpdatePullRequest(ctx context.Context, number int, opts PullRequestOptions) (*PullRequest, error) {
+	var pr giteaPR
+	if err := g.api.do(ctx, http.MethodPatch, g.repoPath("/pulls/%d", number), updateFields(opts, "body", "base"), &pr); err != nil {
+		return nil, fmt.Errorf("updating pull request #%d: %w", number, err)
+	}
+	return pr.toPullRequest(), nil
+}
+
+// FindPullRequest pages through the open pull requests, since the Gitea API
+// cannot filter them by head branch.
+func (g *Gitea) FindPullRequest(ctx context.Context, head string) (*PullRequest, error) {
+	for page := 1; ; page++ {
+		var prs []giteaPR
+		if err := g.api.do(ctx, http.MethodGet, g.repoPath("/pulls?state=open&limit=50&page=%d", page), nil, &prs); err != nil {
+			return nil, fmt.Errorf("listing pull requests: %w", err)
+		}
+		for i := range prs {
+			if prs[i].Head.Ref == head {
+				return prs[i].toPullRequest(), nil
+			}
+		}
+		if len(prs) < 50 {
+			return nil, ErrNotFound
+		}
+	}
+}
+
+func (g *Gitea) ReviewComments(ctx context.Context, number int) ([]ReviewComment, error) {
+	var reviews []struct {
+		ID          int64     `json:"id"`
+		Body        string    `json:"body"`
+		SubmittedAt time.Time `json:"submitted_at"`
+		User        struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	}
+	if err := g.api.do(ctx, http.MethodGet, g.repoPath("/pulls/%d/reviews", number), nil, &reviews); err != nil {
+		return nil, fmt.Errorf("fetching reviews: %w", err)
+	}
+
+	var comments []ReviewComment
+	for _, r := range reviews {
+		if r.Body != "" {
+			comments = append(comments, ReviewComment{Author: r.User.Login, Body: r.Body, CreatedAt: r.SubmittedAt})
+		}
+
+		var lineComments []struct {
+			Body      string    `json:"body"`
+			Path      string    `json:"path"`
+			Position  int       `json:"position"`
+			CreatedAt time.Time `json:"created_at"`
+			User      struct {
+				Login string `json:"login"`
+			} `json:"user"`
+		}
+		if err := g.api.do(ctx, http.MethodGet, g.repoPath("/pulls/%d/reviews/%d/comments", number, r.ID), nil, &lineComments); err != nil {
+			return nil, fmt.Errorf("fetching review comments: %w", err)
+		}
+		for _, c := range lineComments {
+			comments = append(comments, ReviewComment{
+				Author:    c.User.Login,
+				Body:      c.Body,
+				Path:      c.Path,
+				Line:      c.Position,
+				CreatedAt: c.CreatedAt,
+			})
+		}
+	}
+	return comments, nil
+}
+
+func (g *Gitea) CheckStatus(ctx context.Context, number int) (*CheckStatus, error) {
+	var pr giteaPR
+	if err := g.api.do(ctx, http.MethodGet, g.repoPath("/pulls/%d", number), nil, &pr); err != nil {
+		return nil, fmt.Errorf("fetching pull request #%d: %w", number, err)
+	}
+
+	var combined struct {
+		Statuses []struct {
+			Context   string `json:"context"`
+			Status    string `json:"status"`
+			TargetURL string `json:"target_url"`
+		} `json:"statuses"`
+	}
+	if err := g.api.do(ctx, http.MethodGet, g.repoPath("/commits/%s/status", pr.Head.SHA), nil, &combined); err != nil {
+		return nil, fmt.Errorf("fetching commit status: %w", err)
+	}
+
+	checks := make([]Check, 0, len(combined.Statuses))
+	for _, s := range combined.Statuses {
+		state := CheckPending
+		switch s.Status {
+		case "success", "warning":
+			state = CheckSuccess
+		case "failure", "error":
+			state = CheckFailure
+		}
+		checks = append(checks, Check{Name: s.Context, State: state, URL: s.TargetURL})
+	}
+	return combineChecks(checks), nil
+}
+
+func (g *Gitea) ClosePullRequest(ctx context.Context, number int) error {
+	if err := g.api.do(ctx, http.MethodPatch, g.repoPath("/pulls/%d", number), map[string]string{"state": "closed"}, nil); err != nil {
+		return fmt.Errorf("closing pull request #%d: %w", number, err)
+	}
+	return nil
+}
+
+func (g *Gitea) CompareURL(base, head string) string {
+	return fmt.Sprintf("%s/%s/compare/%s...%s", g.webURL, g.project, base, head)
+}
@@ -0,0 +1,89 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitea(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/org/app/pulls", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodPost {
+			w.Write([]byte(`{"number":5,"html_url":"https://gitea.example.com/org/app/pulls/5","state":"open","head":{"ref":"feature/1-x"},"base":{"ref":"main"}}`))
+			return
+		}
+
+		// First page is full of unrelated pull requests
+		var prs []map[string]interface{}
+		if r.URL.Query().Get("page") == "1" {
+			for i := 0; i < 50; i++ {
+				prs = append(prs, map[string]interface{}{"number": 100 + i, "head": map[string]string{"ref": fmt.Sprintf("other/%d", i)}})
+			}
+		} else {
+			prs = append(prs, map[string]interface{}{"number": 5, "head": map[string]string{"ref": "feature/1-x"}})
+		}
+		json.NewEncoder(w).Encode(prs)
+	})
+	mux.HandleFunc("/repos/org/app/pulls/5", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"number":5,"state":"open","head":{"ref":"feature/1-x","sha":"def456"},"base":{"ref":"main"}}`))
+	})
+	mux.HandleFunc("/repos/org/app/pulls/5/reviews", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id":11,"body":"Needs work","user":{"login":"dave"}}]`))
+	})
+	mux.HandleFunc("/repos/org/app/pulls/5/reviews/11/comments", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"body":"off by one","path":"loop.go","position":7,"user":{"login":"dave"}}]`))
+	})
+	mux.HandleFunc("/repos/org/app/commits/def456/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"statuses":[{"context":"ci/build","status":"success"},{"context":"ci/test","status":"success"}]}`))
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ctx := context.Background()
+	g := NewGitea(Config{URL: "https://gitea.example.com", APIURL: srv.URL, Project: "org/app", Token: "secret"})
+
+	pr, err := g.CreatePullRequest(ctx, PullRequestOptions{Head: "feature/1-x", Base: "main", Title: "Feature"})
+	if err != nil {
+		t.Fatalf("CreatePullRequest() error = %v", err)
+	}
+	if pr.Number != 5 || pr.URL != "https://gitea.example.com/org/app/pulls/5" {
+		t.Errorf("CreatePullRequest() = %+v", pr)
+	}
+
+	if found, err := g.FindPullRequest(ctx, "feature/1-x"); err != nil || found.Number != 5 {
+		t.Errorf("FindPullRequest() = %+v, %v, want #5 from the second page", found, err)
+	}
+	if _, err := g.FindPullRequest(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindPullRequest() for unknown head error = %v, want ErrNotFound", err)
+	}
+
+	comments, err := g.ReviewComments(ctx, 5)
+	if err != nil {
+		t.Fatalf("ReviewComments() error = %v", err)
+	}
+	if len(comments) != 2 || comments[1].Path != "loop.go" || comments[1].Line != 7 {
+		t.Errorf("ReviewComments() = %+v", comments)
+	}
+
+	status, err := g.CheckStatus(ctx, 5)
+	if err != nil {
+		t.Fatalf("CheckStatus() error = %v", err)
+	}
+	if status.State != CheckSuccess {
+		t.Errorf("CheckStatus() = %+v, want success", status)
+	}
+
+	if got := g.CompareURL("main", "feature/1-x"); got != "https://gitea.example.com/org/app/compare/main...feature/1-x" {
+		t.Errorf("CompareURL() = %q", got)
+	}
+}
@@ -0,0 +1,221 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GitHub implements Forge against the GitHub REST API, including GitHub
+// Enterprise Server.
+type GitHub struct {
+	webURL  string
+	owner   string
+	project string // "owner/repo"
+	api     *client
+}
+
+// NewGitHub creates a GitHub forge. The API URL defaults to api.github.com
+// for github.com and to <url>/api/v3 for Enterprise hosts.
+func NewGitHub(cfg Config) *GitHub {
+	apiURL := cfg.APIURL
+	if apiURL == "" {
+		if cfg.URL == "https://github.com" {
+			apiURL = "https://api.github.com"
+		} else {
+			apiURL = cfg.URL + "/api/v3"
+		}
+	}
+
+	token := cfg.Token
+	owner, _, _ := strings.Cut(cfg.Project, "/")
+	return &GitHub{
+		webURL:  cfg.URL,
+		owner:   owner,
+		project: cfg.Project,
+		api: newClient(strings.TrimSuffix(apiURL, "/"), func(req *http.Request) {
+			req.Header.Set("Accept", "application/vnd.github+json")
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+		}),
+	}
+}
+
+type githubPR struct {
+	Number   int    `json:"number"`
+	HTMLURL  string `json:"html_url"`
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+	State    string `json:"state"`
+	MergedAt string `json:"merged_at"`
+	Head     struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+func (p *githubPR) toPullRequest() *PullRequest {
+	state := p.State
+	if p.MergedAt != "" {
+		state = "merged"
+	}
+	return &PullRequest{
+		Number: p.Number,
+		URL:    p.HTMLURL,
+		Title:  p.Title,
+		Body:   p.Body,
+		Head:   p.Head.Ref,
+		Base:   p.Base.Ref,
+		State:  state,
+	}
+}
+
+func (g *GitHub) repoPath(format string, args ...interface{}) string {
+	return "/repos/" + g.project + fmt.Sprintf(format, args...)
+}
+
+func (g *GitHub) CreatePullRequest(ctx context.Context, opts PullRequestOptions) (*PullRequest, error) {
+	var pr githubPR
+	err := g.api.do(ctx, http.MethodPost, g.repoPath("/pulls"), map[string]string{
+		"title": opts.Title,
+		"body":  opts.Body,
+		"head":  opts.Head,
+		"base":  opts.Base,
+	}, &pr)
+	if err != nil {
+		return nil, fmt.Errorf("creating pull request: %w", err)
+	}
+	return pr.toPullRequest(), nil
+}
+
+func (g *GitHub) UpdatePullRequest(ctx context.Context, number int, opts PullRequestOptions) (*PullRequest, error) {
+	var pr githubPR
+	if err := g.api.do(ctx, http.MethodPatch, g.repoPath("/pulls/%d", number), updateFields(opts, "body", "base"), &pr); err != nil {
+		return nil, fmt.Errorf("updating pull request #%d: %w", number, err)
+	}
+	return pr.toPullRequest(), nil
+}
+
+func (g *GitHub) FindPullRequest(ctx context.Context, head string) (*PullRequest, error) {
+	var prs []githubPR
+	query := url.Values{"head": {g.owner + ":" + head}, "state": {"open"}}
+	if err := g.api.do(ctx, http.MethodGet, g.repoPath("/pulls?%s", query.Encode()), nil, &prs); err != nil {
+		return nil, fmt.Errorf("listing pull requests: %w", err)
+	}
+	if len(prs) == 0 {
+		return nil, ErrNotFound
+	}
+	return prs[0].toPullRequest(), nil
+}
+
+func (g *GitHub) ReviewComments(ctx context.Context, number int) ([]ReviewComment, error) {
+	var reviews []struct {
+		Body        string    `json:"body"`
+		SubmittedAt time.Time `json:"submitted_at"`
+		User        struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	}
+	if err := g.api.do(ctx, http.MethodGet, g.repoPath("/pulls/%d/reviews", number), nil, &reviews); err != nil {
+		return nil, fmt.Errorf("fetching reviews: %w", err)
+	}
+
+	var lineComments []struct {
+		Body      string    `json:"body"`
+		Path      string    `json:"path"`
+		Line      int       `json:"line"`
+		CreatedAt time.Time `json:"created_at"`
+		User      struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	}
+	if err := g.api.do(ctx, http.MethodGet, g.repoPath("/pulls/%d/comments", number), nil, &lineComments); err != nil {
+		return nil, fmt.Errorf("fetching review comments: %w", err)
+	}
+
+	var comments []ReviewComment
+	for _, r := range reviews {
+		if r.Body == "" {
+			continue
+		}
+		comments = append(comments, ReviewComment{Author: r.User.Login, Body: r.Body, CreatedAt: r.SubmittedAt})
+	}
+	for _, c := range lineComments {
+		comments = append(comments, ReviewComment{
+			Author:    c.User.Login,
+			Body:      c.Body,
+			Path:      c.Path,
+			Line:      c.Line,
+			CreatedAt: c.CreatedAt,
+		})
+	}
+	return comments, nil
+}
+
+func (g *GitHub) CheckStatus(ctx context.Context, number int) (*CheckStatus, error) {
+	var pr githubPR
+	if err := g.api.do(ctx, http.MethodGet, g.repoPath("/pulls/%d", number), nil, &pr); err != nil {
+		return nil, fmt.Errorf("fetching pull request #%d: %w", number, err)
+	}
+
+	var runs struct {
+		CheckRuns []struct {
+			Name       string `json:"name"`
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+			HTMLURL    string `json:"html_url"`
+		} `json:"check_runs"`
+	}
+	if err := g.api.do(ctx, http.MethodGet, g.repoPath("/commits/%s/check-runs", pr.Head.SHA), nil, &runs); err != nil {
+		return nil, fmt.Errorf("fetching check runs: %w", err)
+	}
+
+	checks := make([]Check, 0, len(runs.CheckRuns))
+	for _, run := range runs.CheckRuns {
+		state := CheckPending
+		if run.Status == "completed" {
+			switch run.Conclusion {
+			case "success", "neutral", "skipped":
+				state = CheckSuccess
+			default:
+				state = CheckFailure
+			}
+		}
+		checks = append(checks, Check{Name: run.Name, State: state, URL: run.HTMLURL})
+	}
+	return combineChecks(checks), nil
+}
+
+func (g *GitHub) ClosePullRequest(ctx context.Context, number int) error {
+	if err := g.api.do(ctx, http.MethodPatch, g.repoPath("/pulls/%d", number), map[string]string{"state": "closed"}, nil); err != nil {
+		return fmt.Errorf("closing pull request #%d: %w", number, err)
+	}
+	return nil
+}
+
+func (g *GitHub) CompareURL(base, head string) string {
+	return fmt.Sprintf("%s/%s/compare/%s...%s", g.webURL, g.project, base, head)
+}
+
+// updateFields builds a partial update body from the non-empty options,
+// using the forge's names for the body and base fields.
+func updateFields(opts PullRequestOptions, bodyField, baseField string) map[string]string {
+	fields := make(map[string]string)
+	if opts.Title != "" {
+		fields["title"] = opts.Title
+	}
+	if opts.Body != "" {
+		fields[bodyField] = opts.Body
+	}
+	if opts.Base != "" {
+		fields[baseField] = opts.Base
+	}
+	return fields
+}
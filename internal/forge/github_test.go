@@ -0,0 +1,145 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newGitHubServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+
+	pr := map[string]interface{}{
+		"number":   7,
+		"html_url": "https://github.com/acme/app/pull/7",
+		"title":    "Feature",
+		"body":     "body",
+		"state":    "open",
+		"head":     map[string]string{"ref": "feature/1-x", "sha": "abc123"},
+		"base":     map[string]string{"ref": "main"},
+	}
+
+	mux.HandleFunc("/repos/acme/app/pulls", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			var req map[string]string
+			json.NewDecoder(r.Body).Decode(&req)
+			if req["head"] != "feature/1-x" || req["base"] != "main" {
+				t.Errorf("unexpected create request %v", req)
+			}
+			json.NewEncoder(w).Encode(pr)
+		case http.MethodGet:
+			if r.URL.Query().Get("head") == "acme:feature/1-x" {
+				json.NewEncoder(w).Encode([]interface{}{pr})
+				return
+			}
+			json.NewEncoder(w).Encode([]interface{}{})
+		}
+	})
+	mux.HandleFunc("/repos/acme/app/pulls/7", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch {
+			var req map[string]string
+			json.NewDecoder(r.Body).Decode(&req)
+			if req["state"] == "closed" {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("{}"))
+				return
+			}
+			if _, ok := req["base"]; ok {
+				t.Errorf("empty base should not be sent: %v", req)
+			}
+		}
+		json.NewEncoder(w).Encode(pr)
+	})
+	mux.HandleFunc("/repos/acme/app/pulls/7/reviews", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"body":"Looks good overall","user":{"login":"alice"}},{"body":"","user":{"login":"bob"}}]`))
+	})
+	mux.HandleFunc("/repos/acme/app/pulls/7/comments", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"body":"rename this","path":"main.go","line":12,"user":{"login":"bob"}}]`))
+	})
+	mux.HandleFunc("/repos/acme/app/commits/abc123/check-runs", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"check_runs":[
+			{"name":"build","status":"completed","conclusion":"success"},
+			{"name":"test","status":"in_progress","conclusion":null}
+		]}`))
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestGitHub(t *testing.T) {
+	srv := newGitHubServer(t)
+	defer srv.Close()
+
+	ctx := context.Background()
+	g := NewGitHub(Config{URL: "https://github.com", APIURL: srv.URL, Project: "acme/app", Token: "secret"})
+
+	pr, err := g.CreatePullRequest(ctx, PullRequestOptions{Head: "feature/1-x", Base: "main", Title: "Feature", Body: "body"})
+	if err != nil {
+		t.Fatalf("CreatePullRequest() error = %v", err)
+	}
+	if pr.Number != 7 || pr.URL != "https://github.com/acme/app/pull/7" || pr.State != "open" {
+		t.Errorf("CreatePullRequest() = %+v", pr)
+	}
+
+	if _, err := g.UpdatePullRequest(ctx, 7, PullRequestOptions{Body: "updated"}); err != nil {
+		t.Errorf("UpdatePullRequest() error = %v", err)
+	}
+
+	found, err := g.FindPullRequest(ctx, "feature/1-x")
+	if err != nil || found.Number != 7 {
+		t.Errorf("FindPullRequest() = %+v, %v", found, err)
+	}
+	if _, err := g.FindPullRequest(ctx, "feature/2-y"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindPullRequest() for unknown head error = %v, want ErrNotFound", err)
+	}
+
+	comments, err := g.ReviewComments(ctx, 7)
+	if err != nil {
+		t.Fatalf("ReviewComments() error = %v", err)
+	}
+	if len(comments) != 2 || comments[1].Path != "main.go" || comments[1].Line != 12 {
+		t.Errorf("ReviewComments() = %+v", comments)
+	}
+
+	status, err := g.CheckStatus(ctx, 7)
+	if err != nil {
+		t.Fatalf("CheckStatus() error = %v", err)
+	}
+	if status.State != CheckPending || len(status.Checks) != 2 {
+		t.Errorf("CheckStatus() = %+v, want pending with 2 checks", status)
+	}
+
+	if err := g.ClosePullRequest(ctx, 7); err != nil {
+		t.Errorf("ClosePullRequest() error = %v", err)
+	}
+
+	if got := g.CompareURL("main", "task/abc"); got != "https://github.com/acme/app/compare/main...task/abc" {
+		t.Errorf("CompareURL() = %q", got)
+	}
+}
+
+func TestGitHubEnterpriseAPIURL(t *testing.T) {
+	g := NewGitHub(Config{URL: "https://github.example.com", Project: "acme/app"})
+	if g.api.baseURL != "https://github.example.com/api/v3" {
+		t.Errorf("API URL = %q, want enterprise /api/v3", g.api.baseURL)
+	}
+}
+
+func TestGitHubErrorStatus(t *testing.T) {
+	srv := newGitHubServer(t)
+	defer srv.Close()
+
+	g := NewGitHub(Config{URL: "https://github.com", APIURL: srv.URL, Project: "acme/app", Token: "wrong"})
+	if _, err := g.CreatePullRequest(context.Background(), PullRequestOptions{Head: "feature/1-x", Base: "main"}); err == nil {
+		t.Error("expected error for unauthorized request")
+	}
+}
@@ -0,0 +1,171 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GitLab implements Forge against the GitLab REST API (gitlab.com or
+// self-hosted). Merge requests are addressed by their project-scoped IID.
+type GitLab struct {
+	webURL  string
+	project string
+	api     *client
+}
+
+// NewGitLab creates a GitLab forge. The API URL defaults to <url>/api/v4.
+func NewGitLab(cfg Config) *GitLab {
+	apiURL := cfg.APIURL
+	if apiURL == "" {
+		apiURL = cfg.URL + "/api/v4"
+	}
+
+	token := cfg.Token
+	return &GitLab{
+		webURL:  cfg.URL,
+		project: cfg.Project,
+		api: newClient(strings.TrimSuffix(apiURL, "/"), func(req *http.Request) {
+			if token != "" {
+				req.Header.Set("PRIVATE-TOKEN", token)
+			}
+		}),
+	}
+}
+
+type gitlabMR struct {
+	IID          int    `json:"iid"`
+	WebURL       string `json:"web_url"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	State        string `json:"state"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+}
+
+func (m *gitlabMR) toPullRequest() *PullRequest {
+	state := m.State
+	switch state {
+	case "opened", "locked":
+		state = "open"
+	}
+	return &PullRequest{
+		Number: m.IID,
+		URL:    m.WebURL,
+		Title:  m.Title,
+		Body:   m.Description,
+		Head:   m.SourceBranch,
+		Base:   m.TargetBranch,
+		State:  state,
+	}
+}
+
+func (g *GitLab) projectPath(format string, args ...interface{}) string {
+	return "/projects/" + url.PathEscape(g.project) + fmt.Sprintf(format, args...)
+}
+
+func (g *GitLab) CreatePullRequest(ctx context.Context, opts PullRequestOptions) (*PullRequest, error) {
+	var mr gitlabMR
+	err := g.api.do(ctx, http.MethodPost, g.projectPath("/merge_requests"), map[string]string{
+		"title":         opts.Title,
+		"description":   opts.Body,
+		"source_branch": opts.Head,
+		"target_branch": opts.Base,
+	}, &mr)
+	if err != nil {
+		return nil, fmt.Errorf("creating merge request: %w", err)
+	}
+	return mr.toPullRequest(), nil
+}
+
+func (g *GitLab) UpdatePullRequest(ctx context.Context, number int, opts PullRequestOptions) (*PullRequest, error) {
+	var mr gitlabMR
+	fields := updateFields(opts, "description", "target_branch")
+	if err := g.api.do(ctx, http.MethodPut, g.projectPath("/merge_requests/%d", number), fields, &mr); err != nil {
+		return nil, fmt.Errorf("updating merge request !%d: %w", number, err)
+	}
+	return mr.toPullRequest(), nil
+}
+
+func (g *GitLab) FindPullRequest(ctx context.Context, head string) (*PullRequest, error) {
+	var mrs []gitlabMR
+	query := url.Values{"source_branch": {head}, "state": {"opened"}}
+	if err := g.api.do(ctx, http.MethodGet, g.projectPath("/merge_requests?%s", query.Encode()), nil, &mrs); err != nil {
+		return nil, fmt.Errorf("listing merge requests: %w", err)
+	}
+	if len(mrs) == 0 {
+		return nil, ErrNotFound
+	}
+	return mrs[0].toPullRequest(), nil
+}
+
+func (g *GitLab) ReviewComments(ctx context.Context, number int) ([]ReviewComment, error) {
+	var notes []struct {
+		Body      string    `json:"body"`
+		System    bool      `json:"system"`
+		CreatedAt time.Time `json:"created_at"`
+		Author    struct {
+			Username string `json:"username"`
+		} `json:"author"`
+		Position *struct {
+			NewPath string `json:"new_path"`
+			NewLine int    `json:"new_line"`
+		} `json:"position"`
+	}
+	if err := g.api.do(ctx, http.MethodGet, g.projectPath("/merge_requests/%d/notes?sort=asc", number), nil, &notes); err != nil {
+		return nil, fmt.Errorf("fetching merge request notes: %w", err)
+	}
+
+	var comments []ReviewComment
+	for _, n := range notes {
+		// System notes record events such as pushes, not reviews
+		if n.System {
+			continue
+		}
+		c := ReviewComment{Author: n.Author.Username, Body: n.Body, CreatedAt: n.CreatedAt}
+		if n.Position != nil {
+			c.Path, c.Line = n.Position.NewPath, n.Position.NewLine
+		}
+		comments = append(comments, c)
+	}
+	return comments, nil
+}
+
+func (g *GitLab) CheckStatus(ctx context.Context, number int) (*CheckStatus, error) {
+	var pipelines []struct {
+		ID     int    `json:"id"`
+		Status string `json:"status"`
+		WebURL string `json:"web_url"`
+	}
+	if err := g.api.do(ctx, http.MethodGet, g.projectPath("/merge_requests/%d/pipelines", number), nil, &pipelines); err != nil {
+		return nil, fmt.Errorf("fetching pipelines: %w", err)
+	}
+	if len(pipelines) == 0 {
+		return combineChecks(nil), nil
+	}
+
+	// Pipelines are returned newest first; only the latest one counts
+	p := pipelines[0]
+	state := CheckPending
+	switch p.Status {
+	case "success", "skipped":
+		state = CheckSuccess
+	case "failed", "canceled":
+		state = CheckFailure
+	}
+	return combineChecks([]Check{{Name: fmt.Sprintf("pipeline #%d", p.ID), State: state, URL: p.WebURL}}), nil
+}
+
+func (g *GitLab) ClosePullRequest(ctx context.Context, number int) error {
+	if err := g.api.do(ctx, http.MethodPut, g.projectPath("/merge_requests/%d", number), map[string]string{"state_event": "close"}, nil); err != nil {
+		return fmt.Errorf("closing merge request !%d: %w", number, err)
+	}
+	return nil
+}
+
+func (g *GitLab) CompareURL(base, head string) string {
+	return fmt.Sprintf("%s/%s/-/compare/%s...%s", g.webURL, g.project, base, head)
+}
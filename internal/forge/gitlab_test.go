@@ -0,0 +1,111 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitLab(t *testing.T) {
+	mr := map[string]interface{}{
+		"iid":           3,
+		"web_url":       "https://gitlab.example.com/group/sub/app/-/merge_requests/3",
+		"title":         "Feature",
+		"description":   "body",
+		"state":         "opened",
+		"source_branch": "feature/1-x",
+		"target_branch": "main",
+	}
+
+	routes := make(map[string]http.HandlerFunc)
+	// The project path is URL-escaped into a single segment
+	handle := func(path string, h http.HandlerFunc) { routes[path] = h }
+	handle("/projects/group%2Fsub%2Fapp/merge_requests", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodPost {
+			var req map[string]string
+			json.NewDecoder(r.Body).Decode(&req)
+			if req["source_branch"] != "feature/1-x" || req["description"] != "body" {
+				t.Errorf("unexpected create request %v", req)
+			}
+		} else {
+			if r.URL.Query().Get("source_branch") != "feature/1-x" {
+				w.Write([]byte("[]"))
+				return
+			}
+			json.NewEncoder(w).Encode([]interface{}{mr})
+			return
+		}
+		json.NewEncoder(w).Encode(mr)
+	})
+	handle("/projects/group%2Fsub%2Fapp/merge_requests/3", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["state_event"] == "close" {
+			mr["state"] = "closed"
+		}
+		json.NewEncoder(w).Encode(mr)
+	})
+	handle("/projects/group%2Fsub%2Fapp/merge_requests/3/notes", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"body":"added 1 commit","system":true,"author":{"username":"bot"}},
+			{"body":"please add a test","system":false,"author":{"username":"carol"},"position":{"new_path":"app.go","new_line":40}}
+		]`))
+	})
+	handle("/projects/group%2Fsub%2Fapp/merge_requests/3/pipelines", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id":99,"status":"failed","web_url":"https://gitlab.example.com/p/99"},{"id":98,"status":"success"}]`))
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, ok := routes[r.URL.EscapedPath()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		h(w, r)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	g := NewGitLab(Config{URL: "https://gitlab.example.com", APIURL: srv.URL, Project: "group/sub/app", Token: "secret"})
+
+	pr, err := g.CreatePullRequest(ctx, PullRequestOptions{Head: "feature/1-x", Base: "main", Title: "Feature", Body: "body"})
+	if err != nil {
+		t.Fatalf("CreatePullRequest() error = %v", err)
+	}
+	if pr.Number != 3 || pr.State != "open" || pr.Head != "feature/1-x" {
+		t.Errorf("CreatePullRequest() = %+v", pr)
+	}
+
+	if found, err := g.FindPullRequest(ctx, "feature/1-x"); err != nil || found.Number != 3 {
+		t.Errorf("FindPullRequest() = %+v, %v", found, err)
+	}
+
+	comments, err := g.ReviewComments(ctx, 3)
+	if err != nil {
+		t.Fatalf("ReviewComments() error = %v", err)
+	}
+	if len(comments) != 1 || comments[0].Author != "carol" || comments[0].Line != 40 {
+		t.Errorf("ReviewComments() = %+v, want only the non-system note", comments)
+	}
+
+	status, err := g.CheckStatus(ctx, 3)
+	if err != nil {
+		t.Fatalf("CheckStatus() error = %v", err)
+	}
+	if status.State != CheckFailure || len(status.Checks) != 1 {
+		t.Errorf("CheckStatus() = %+v, want latest pipeline failed", status)
+	}
+
+	if err := g.ClosePullRequest(ctx, 3); err != nil {
+		t.Fatalf("ClosePullRequest() error = %v", err)
+	}
+	if mr["state"] != "closed" {
+		t.Error("expected merge request to be closed")
+	}
+}
@@ -0,0 +1,159 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Memory is an in-process Forge for tests and local runs without a hosting
+// service. Review comments and check results are injected by the caller.
+type Memory struct {
+	webURL string
+
+	mu       sync.Mutex
+	prs      map[int]*PullRequest
+	comments map[int][]ReviewComment
+	checks   map[int][]Check
+	next     int
+}
+
+// NewMemory creates an empty in-memory forge. webURL only shapes the URLs it
+// hands out.
+func NewMemory(webURL string) *Memory {
+	if webURL == "" {
+		webURL = "http://forge.local/project"
+	}
+	return &Memory{
+		webURL:   webURL,
+		prs:      make(map[int]*PullRequest),
+		comments: make(map[int][]ReviewComment),
+		checks:   make(map[int][]Check),
+		next:     1,
+	}
+}
+
+func (m *Memory) CreatePullRequest(ctx context.Context, opts PullRequestOptions) (*PullRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, pr := range m.prs {
+		if pr.Head == opts.Head && pr.State == "open" {
+			return nil, fmt.Errorf("creating pull request: a pull request for %s already exists", opts.Head)
+		}
+	}
+
+	pr := &PullRequest{
+		Number: m.next,
+		URL:    fmt.Sprintf("%s/pulls/%d", m.webURL, m.next),
+		Title:  opts.Title,
+		Body:   opts.Body,
+		Head:   opts.Head,
+		Base:   opts.Base,
+		State:  "open",
+	}
+	m.prs[pr.Number] = pr
+	m.next++
+
+	copied := *pr
+	return &copied, nil
+}
+
+func (m *Memory) UpdatePullRequest(ctx context.Context, number int, opts PullRequestOptions) (*PullRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pr, ok := m.prs[number]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if opts.Title != "" {
+		pr.Title = opts.Title
+	}
+	if opts.Body != "" {
+		pr.Body = opts.Body
+	}
+	if opts.Base != "" {
+		pr.Base = opts.Base
+	}
+
+	copied := *pr
+	return &copied, nil
+}
+
+func (m *Memory) FindPullRequest(ctx context.Context, head string) (*PullRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, pr := range m.prs {
+		if pr.Head == head && pr.State == "open" {
+			copied := *pr
+			return &copied, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) ReviewComments(ctx context.Context, number int) ([]ReviewComment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.prs[number]; !ok {
+		return nil, ErrNotFound
+	}
+	return append([]ReviewComment(nil), m.comments[number]...), nil
+}
+
+func (m *Memory) CheckStatus(ctx context.Context, number int) (*CheckStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.prs[number]; !ok {
+		return nil, ErrNotFound
+	}
+	return combineChecks(append([]Check(nil), m.checks[number]...)), nil
+}
+
+func (m *Memory) ClosePullRequest(ctx context.Context, number int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pr, ok := m.prs[number]
+	if !ok {
+		return ErrNotFound
+	}
+	pr.State = "closed"
+	return nil
+}
+
+func (m *Memory) CompareURL(base, head string) string {
+	return fmt.Sprintf("%s/compare/%s...%s", m.webURL, base, head)
+}
+
+// AddReviewComment records a review comment on a pull request.
+func (m *Memory) AddReviewComment(number int, comment ReviewComment) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.comments[number] = append(m.comments[number], comment)
+}
+
+// SetChecks replaces the checks reported for a pull request.
+func (m *Memory) SetChecks(number int, checks ...Check) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checks[number] = checks
+}
+
+// PullRequests returns a snapshot of every pull request, open or not.
+func (m *Memory) PullRequests() []PullRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prs := make([]PullRequest, 0, len(m.prs))
+	for i := 1; i < m.next; i++ {
+		if pr, ok := m.prs[i]; ok {
+			prs = append(prs, *pr)
+		}
+	}
+	return prs
+}
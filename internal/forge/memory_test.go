@@ -0,0 +1,63 @@
+package forge
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMemoryPullRequestLifecycle(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory("http://forge.test/acme/app")
+
+	pr, err := m.CreatePullRequest(ctx, PullRequestOptions{Head: "feature/1-x", Base: "main", Title: "X", Body: "body"})
+	if err != nil {
+		t.Fatalf("CreatePullRequest() error = %v", err)
+	}
+	if pr.Number != 1 || pr.URL != "http://forge.test/acme/app/pulls/1" {
+		t.Errorf("unexpected pull request %+v", pr)
+	}
+
+	if _, err := m.CreatePullRequest(ctx, PullRequestOptions{Head: "feature/1-x", Base: "main"}); err == nil {
+		t.Error("expected error creating a second open pull request for the same head")
+	}
+
+	found, err := m.FindPullRequest(ctx, "feature/1-x")
+	if err != nil || found.Number != 1 {
+		t.Fatalf("FindPullRequest() = %+v, %v", found, err)
+	}
+
+	updated, err := m.UpdatePullRequest(ctx, 1, PullRequestOptions{Body: "new body"})
+	if err != nil {
+		t.Fatalf("UpdatePullRequest() error = %v", err)
+	}
+	if updated.Title != "X" || updated.Body != "new body" {
+		t.Errorf("UpdatePullRequest() = %+v, want title kept and body replaced", updated)
+	}
+
+	m.AddReviewComment(1, ReviewComment{Author: "alice", Body: "nit", Path: "a.go", Line: 3})
+	comments, err := m.ReviewComments(ctx, 1)
+	if err != nil || len(comments) != 1 || comments[0].Author != "alice" {
+		t.Errorf("ReviewComments() = %+v, %v", comments, err)
+	}
+
+	m.SetChecks(1, Check{Name: "ci", State: CheckFailure})
+	status, err := m.CheckStatus(ctx, 1)
+	if err != nil || status.State != CheckFailure {
+		t.Errorf("CheckStatus() = %+v, %v", status, err)
+	}
+
+	if err := m.ClosePullRequest(ctx, 1); err != nil {
+		t.Fatalf("ClosePullRequest() error = %v", err)
+	}
+	if _, err := m.FindPullRequest(ctx, "feature/1-x"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindPullRequest() after close error = %v, want ErrNotFound", err)
+	}
+	if prs := m.PullRequests(); len(prs) != 1 || prs[0].State != "closed" {
+		t.Errorf("PullRequests() = %+v", prs)
+	}
+
+	if _, err := m.ReviewComments(ctx, 42); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ReviewComments() for missing PR error = %v, want ErrNotFound", err)
+	}
+}
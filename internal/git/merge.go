@@ -0,0 +1,241 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bayological/foreman/internal/validation"
+)
+
+// MergeConflictError is returned when merging a branch stops on conflicts.
+type MergeConflictError struct {
+	Target string
+	Source string
+	Files  []string
+}
+
+func (e *MergeConflictError) Error() string {
+	return fmt.Sprintf("merging %s into %s conflicts in: %s", e.Source, e.Target, strings.Join(e.Files, ", "))
+}
+
+// IntegrateBranch merges source into target without touching the main
+// checkout. The merge runs in a temporary detached worktree; on success the
+// target branch is advanced to the merge commit, on conflict the merge is
+// aborted and a *MergeConflictError is returned.
+func (r *Repo) IntegrateBranch(target, source string) error {
+	if !validation.IsValidBranchName(target) || !validation.IsValidBranchName(source) {
+		return fmt.Errorf("invalid branch name: %s or %s", target, source)
+	}
+
+	r.mergeMu.Lock()
+	defer r.mergeMu.Unlock()
+
+	wtPath := filepath.Join(r.worktrees, ".integrate", source)
+	if err := r.addDetachedWorktree(wtPath, target); err != nil {
+		return fmt.Errorf("failed to create integration worktree: %w", err)
+	}
+	defer r.RemoveWorktreeAt(wtPath)
+
+	cmd := exec.Command("git", "merge", "--no-ff", "-m", fmt.Sprintf("Merge %s into %s", source, target), source)
+	cmd.Dir = wtPath
+	if out, err := cmd.CombinedOutput(); err != nil {
+		files := conflictedFiles(wtPath)
+		abort := exec.Command("git", "merge", "--abort")
+		abort.Dir = wtPath
+		abort.Run()
+
+		if len(files) > 0 {
+			return &MergeConflictError{Target: target, Source: source, Files: files}
+		}
+		return fmt.Errorf("merge failed: %s: %w", out, err)
+	}
+
+	head, err := gitIn(wtPath, "rev-parse", "HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to read merge commit: %w", err)
+	}
+
+	return r.advanceBranch(target, head)
+}
+
+// Conflict is a merge of Source into Target that stopped on conflicts. It is
+// left checked out, conflict markers and all, in its own detached worktree so
+// it can be resolved and then completed or aborted.
+type Conflict struct {
+	Target   string
+	Source   string
+	Files    []string
+	Worktree *Worktree
+
+	// base is the target commit the merge started from
+	base string
+}
+
+// PrepareConflict starts merging source into target in a dedicated worktree
+// and leaves it in the conflicted state. If the merge turns out to be clean,
+// the returned Conflict has no Files and only needs completing.
+func (r *Repo) PrepareConflict(target, source string) (*Conflict, error) {
+	if !validation.IsValidBranchName(target) || !validation.IsValidBranchName(source) {
+		return nil, fmt.Errorf("invalid branch name: %s or %s", target, source)
+	}
+
+	wtPath := filepath.Join(r.worktrees, ".resolve", source)
+	if err := r.addDetachedWorktree(wtPath, target); err != nil {
+		return nil, fmt.Errorf("failed to create resolution worktree: %w", err)
+	}
+
+	c := &Conflict{
+		Target:   target,
+		Source:   source,
+		Worktree: &Worktree{Path: wtPath, Branch: target},
+	}
+
+	base, err := gitIn(wtPath, "rev-parse", "HEAD")
+	if err != nil {
+		r.RemoveWorktreeAt(wtPath)
+		return nil, fmt.Errorf("failed to read %s: %w", target, err)
+	}
+	c.base = base
+
+	if out, err := gitIn(wtPath, "merge", "--no-ff", "--no-commit", source); err != nil {
+		c.Files = conflictedFiles(wtPath)
+		if len(c.Files) == 0 {
+			r.RemoveWorktreeAt(wtPath)
+			return nil, fmt.Errorf("merge failed: %s: %w", out, err)
+		}
+	}
+
+	return c, nil
+}
+
+// UnresolvedFiles returns the conflicted files that still contain conflict markers.
+func (c *Conflict) UnresolvedFiles() []string {
+	var unresolved []string
+	for _, file := range c.Files {
+		data, err := os.ReadFile(filepath.Join(c.Worktree.Path, file))
+		if err != nil {
+			continue
+		}
+		if HasConflictMarkers(string(data)) {
+			unresolved = append(unresolved, file)
+		}
+	}
+	return unresolved
+}
+
+// HasConflictMarkers reports whether content contains git conflict markers.
+func HasConflictMarkers(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "<<<<<<< ") || strings.HasPrefix(line, ">>>>>>> ") {
+			return true
+		}
+	}
+	return false
+}
+
+// CompleteMerge commits the resolved merge and advances the target branch.
+// It fails if conflict markers remain or if the target moved in the meantime.
+func (r *Repo) CompleteMerge(c *Conflict, message string) error {
+	if unresolved := c.UnresolvedFiles(); len(unresolved) > 0 {
+		return fmt.Errorf("unresolved conflicts remain in: %s", strings.Join(unresolved, ", "))
+	}
+
+	r.mergeMu.Lock()
+	defer r.mergeMu.Unlock()
+
+	current, err := r.git("rev-parse", "refs/heads/"+c.Target)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", c.Target, err)
+	}
+	if current != c.base {
+		return fmt.Errorf("%s moved since the merge was prepared; merge again", c.Target)
+	}
+
+	wtPath := c.Worktree.Path
+	if out, err := gitIn(wtPath, "add", "-A"); err != nil {
+		return fmt.Errorf("git add failed: %s: %w", out, err)
+	}
+	if out, err := gitIn(wtPath, "commit", "--no-edit", "-m", message); err != nil {
+		return fmt.Errorf("git commit failed: %s: %w", out, err)
+	}
+
+	head, err := gitIn(wtPath, "rev-parse", "HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to read merge commit: %w", err)
+	}
+
+	if err := r.advanceBranch(c.Target, head); err != nil {
+		return err
+	}
+
+	r.RemoveWorktreeAt(wtPath)
+	return nil
+}
+
+// AbortMerge throws away a prepared merge and its worktree.
+func (r *Repo) AbortMerge(c *Conflict) {
+	gitIn(c.Worktree.Path, "merge", "--abort")
+	r.RemoveWorktreeAt(c.Worktree.Path)
+}
+
+// addDetachedWorktree checks out commit at path, detached, replacing any
+// worktree left there
+func (r *Repo) addDetachedWorktree(path, commit string) error {
+	r.worktreeMu.Lock()
+	defer r.worktreeMu.Unlock()
+	r.git("worktree", "remove", path, "--force")
+	os.RemoveAll(path)
+	_, err := r.git("worktree", "add", "--detach", path, commit)
+	return err
+}
+
+// RemoveWorktreeAt removes the worktree at path, discarding any changes in it.
+func (r *Repo) RemoveWorktreeAt(path string) {
+	r.worktreeMu.Lock()
+	defer r.worktreeMu.Unlock()
+	r.git("worktree", "remove", path, "--force")
+	os.RemoveAll(path)
+}
+
+// advanceBranch moves branch to commit. If the branch is checked out in a
+// worktree the move is a fast-forward inside it, so the checkout stays in sync.
+func (r *Repo) advanceBranch(branch, commit string) error {
+	if wtPath := r.worktreePathFor(branch); wtPath != "" {
+		if out, err := gitIn(wtPath, "merge", "--ff-only", commit); err != nil {
+			return fmt.Errorf("fast-forward of %s failed: %s: %w", branch, out, err)
+		}
+		return nil
+	}
+
+	if out, err := r.git("branch", "-f", branch, commit); err != nil {
+		return fmt.Errorf("failed to update %s: %s: %w", branch, out, err)
+	}
+	return nil
+}
+
+// PushBranch pushes a local branch to the configured remote.
+func (r *Repo) PushBranch(branch string) error {
+	if out, err := r.git("push", r.remote, branch); err != nil {
+		return fmt.Errorf("git push failed: %s: %w", out, err)
+	}
+	return nil
+}
+
+// conflictedFiles lists files with unresolved conflicts in a worktree.
+func conflictedFiles(wtPath string) []string {
+	out, err := gitIn(wtPath, "diff", "--name-only", "--diff-filter=U")
+	if err != nil || out == "" {
+		return nil
+	}
+	return strings.Split(out, "\n")
+}
+
+func gitIn(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
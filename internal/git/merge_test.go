@@ -0,0 +1,298 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// commitFile writes a file in dir and commits it
+func commitFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	runGit(t, dir, "add", name)
+	runGit(t, dir, "commit", "-m", "Update "+name)
+}
+
+func TestCreateWorktreeFromLocalBase(t *testing.T) {
+	tmpDir := setupGitRepo(t)
+	defer os.RemoveAll(tmpDir)
+
+	repo, err := NewRepo(tmpDir, "origin", "main")
+	if err != nil {
+		t.Fatalf("NewRepo failed: %v", err)
+	}
+
+	runGit(t, tmpDir, "branch", "feature/1-demo")
+
+	wt, err := repo.CreateWorktreeFrom("feature/1/T-001", "feature/1-demo")
+	if err != nil {
+		t.Fatalf("CreateWorktreeFrom failed: %v", err)
+	}
+	defer repo.RemoveWorktree(wt.Branch)
+
+	if !repo.BranchExists("feature/1/T-001") {
+		t.Error("Expected task branch to be created")
+	}
+	if _, err := os.Stat(filepath.Join(wt.Path, "test.txt")); err != nil {
+		t.Errorf("Expected worktree to contain base files: %v", err)
+	}
+}
+
+func TestIntegrateBranch(t *testing.T) {
+	tmpDir := setupGitRepo(t)
+	defer os.RemoveAll(tmpDir)
+
+	repo, err := NewRepo(tmpDir, "origin", "main")
+	if err != nil {
+		t.Fatalf("NewRepo failed: %v", err)
+	}
+
+	runGit(t, tmpDir, "branch", "feature/1-demo")
+
+	wt, err := repo.CreateWorktreeFrom("feature/1/T-001", "feature/1-demo")
+	if err != nil {
+		t.Fatalf("CreateWorktreeFrom failed: %v", err)
+	}
+	commitFile(t, wt.Path, "a.txt", "from task")
+	repo.RemoveWorktree(wt.Branch)
+
+	if err := repo.IntegrateBranch("feature/1-demo", "feature/1/T-001"); err != nil {
+		t.Fatalf("IntegrateBranch failed: %v", err)
+	}
+
+	out := runGit(t, tmpDir, "show", "feature/1-demo:a.txt")
+	if out != "from task" {
+		t.Errorf("Expected merged content on feature branch, got %q", out)
+	}
+
+	// Main checkout must be untouched
+	if _, err := os.Stat(filepath.Join(tmpDir, "a.txt")); !os.IsNotExist(err) {
+		t.Error("IntegrateBranch should not modify the main checkout")
+	}
+}
+
+func TestIntegrateBranchIntoCheckedOutBranch(t *testing.T) {
+	tmpDir := setupGitRepo(t)
+	defer os.RemoveAll(tmpDir)
+
+	repo, err := NewRepo(tmpDir, "origin", "main")
+	if err != nil {
+		t.Fatalf("NewRepo failed: %v", err)
+	}
+
+	runGit(t, tmpDir, "branch", "feature/1-demo")
+
+	featureWT, err := repo.CreateWorktreeFrom("feature/1-demo", "")
+	if err != nil {
+		t.Fatalf("CreateWorktreeFrom failed: %v", err)
+	}
+	defer repo.RemoveWorktree(featureWT.Branch)
+
+	taskWT, err := repo.CreateWorktreeFrom("feature/1/T-001", "feature/1-demo")
+	if err != nil {
+		t.Fatalf("CreateWorktreeFrom failed: %v", err)
+	}
+	commitFile(t, taskWT.Path, "b.txt", "task b")
+	repo.RemoveWorktree(taskWT.Branch)
+
+	if err := repo.IntegrateBranch("feature/1-demo", "feature/1/T-001"); err != nil {
+		t.Fatalf("IntegrateBranch failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(featureWT.Path, "b.txt"))
+	if err != nil {
+		t.Fatalf("Expected feature worktree to be fast-forwarded: %v", err)
+	}
+	if string(data) != "task b" {
+		t.Errorf("Unexpected content %q", data)
+	}
+}
+
+func TestIntegrateBranchConflict(t *testing.T) {
+	tmpDir := setupGitRepo(t)
+	defer os.RemoveAll(tmpDir)
+
+	repo, err := NewRepo(tmpDir, "origin", "main")
+	if err != nil {
+		t.Fatalf("NewRepo failed: %v", err)
+	}
+
+	runGit(t, tmpDir, "branch", "feature/1-demo")
+
+	for _, task := range []string{"T-001", "T-002"} {
+		wt, err := repo.CreateWorktreeFrom("feature/1/"+task, "feature/1-demo")
+		if err != nil {
+			t.Fatalf("CreateWorktreeFrom failed: %v", err)
+		}
+		commitFile(t, wt.Path, "test.txt", "changed by "+task)
+		repo.RemoveWorktree(wt.Branch)
+	}
+
+	if err := repo.IntegrateBranch("feature/1-demo", "feature/1/T-001"); err != nil {
+		t.Fatalf("first IntegrateBranch failed: %v", err)
+	}
+
+	before := runGit(t, tmpDir, "rev-parse", "feature/1-demo")
+
+	err = repo.IntegrateBranch("feature/1-demo", "feature/1/T-002")
+	var conflict *MergeConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected MergeConflictError, got %v", err)
+	}
+	if len(conflict.Files) != 1 || conflict.Files[0] != "test.txt" {
+		t.Errorf("Expected conflict in test.txt, got %v", conflict.Files)
+	}
+
+	after := runGit(t, tmpDir, "rev-parse", "feature/1-demo")
+	if strings.TrimSpace(before) != strings.TrimSpace(after) {
+		t.Error("Feature branch should not move when the merge conflicts")
+	}
+}
+
+// setupConflict creates a feature branch with T-001 merged in and a T-002
+// branch that conflicts with it on test.txt.
+func setupConflict(t *testing.T) (string, *Repo) {
+	t.Helper()
+	tmpDir := setupGitRepo(t)
+
+	repo, err := NewRepo(tmpDir, "origin", "main")
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		t.Fatalf("NewRepo failed: %v", err)
+	}
+
+	runGit(t, tmpDir, "branch", "feature/1-demo")
+
+	for _, task := range []string{"T-001", "T-002"} {
+		wt, err := repo.CreateWorktreeFrom("feature/1/"+task, "feature/1-demo")
+		if err != nil {
+			os.RemoveAll(tmpDir)
+			t.Fatalf("CreateWorktreeFrom failed: %v", err)
+		}
+		commitFile(t, wt.Path, "test.txt", "changed by "+task)
+		repo.RemoveWorktree(wt.Branch)
+	}
+
+	if err := repo.IntegrateBranch("feature/1-demo", "feature/1/T-001"); err != nil {
+		os.RemoveAll(tmpDir)
+		t.Fatalf("IntegrateBranch failed: %v", err)
+	}
+
+	return tmpDir, repo
+}
+
+func TestPrepareAndCompleteConflict(t *testing.T) {
+	tmpDir, repo := setupConflict(t)
+	defer os.RemoveAll(tmpDir)
+
+	c, err := repo.PrepareConflict("feature/1-demo", "feature/1/T-002")
+	if err != nil {
+		t.Fatalf("PrepareConflict failed: %v", err)
+	}
+	if len(c.Files) != 1 || c.Files[0] != "test.txt" {
+		t.Fatalf("Expected conflict in test.txt, got %v", c.Files)
+	}
+	if got := c.UnresolvedFiles(); len(got) != 1 {
+		t.Errorf("Expected test.txt to contain conflict markers, got %v", got)
+	}
+
+	if err := repo.CompleteMerge(c, "Merge T-002"); err == nil {
+		t.Error("CompleteMerge should refuse to commit conflict markers")
+	}
+
+	// Resolve the conflict the way an agent would
+	resolved := "changed by T-001\nchanged by T-002\n"
+	if err := os.WriteFile(filepath.Join(c.Worktree.Path, "test.txt"), []byte(resolved), 0644); err != nil {
+		t.Fatalf("Failed to write resolution: %v", err)
+	}
+
+	if err := repo.CompleteMerge(c, "Merge T-002"); err != nil {
+		t.Fatalf("CompleteMerge failed: %v", err)
+	}
+
+	out := runGit(t, tmpDir, "show", "feature/1-demo:test.txt")
+	if out != resolved {
+		t.Errorf("Expected resolved content on feature branch, got %q", out)
+	}
+	parents := runGit(t, tmpDir, "rev-list", "--parents", "-n", "1", "feature/1-demo")
+	if len(strings.Fields(parents)) != 3 {
+		t.Errorf("Expected a merge commit, got parents %q", parents)
+	}
+	if _, err := os.Stat(c.Worktree.Path); !os.IsNotExist(err) {
+		t.Error("Expected resolution worktree to be removed")
+	}
+}
+
+func TestCompleteMergeTargetMoved(t *testing.T) {
+	tmpDir, repo := setupConflict(t)
+	defer os.RemoveAll(tmpDir)
+
+	c, err := repo.PrepareConflict("feature/1-demo", "feature/1/T-002")
+	if err != nil {
+		t.Fatalf("PrepareConflict failed: %v", err)
+	}
+	defer repo.AbortMerge(c)
+
+	if err := os.WriteFile(filepath.Join(c.Worktree.Path, "test.txt"), []byte("resolved"), 0644); err != nil {
+		t.Fatalf("Failed to write resolution: %v", err)
+	}
+
+	// Another task lands on the feature branch meanwhile
+	wt, err := repo.CreateWorktreeFrom("feature/1/T-003", "feature/1-demo")
+	if err != nil {
+		t.Fatalf("CreateWorktreeFrom failed: %v", err)
+	}
+	commitFile(t, wt.Path, "c.txt", "task c")
+	repo.RemoveWorktree(wt.Branch)
+	if err := repo.IntegrateBranch("feature/1-demo", "feature/1/T-003"); err != nil {
+		t.Fatalf("IntegrateBranch failed: %v", err)
+	}
+
+	if err := repo.CompleteMerge(c, "Merge T-002"); err == nil {
+		t.Error("CompleteMerge should fail when the target branch moved")
+	}
+}
+
+func TestAbortMerge(t *testing.T) {
+	tmpDir, repo := setupConflict(t)
+	defer os.RemoveAll(tmpDir)
+
+	before := runGit(t, tmpDir, "rev-parse", "feature/1-demo")
+
+	c, err := repo.PrepareConflict("feature/1-demo", "feature/1/T-002")
+	if err != nil {
+		t.Fatalf("PrepareConflict failed: %v", err)
+	}
+	repo.AbortMerge(c)
+
+	if _, err := os.Stat(c.Worktree.Path); !os.IsNotExist(err) {
+		t.Error("Expected resolution worktree to be removed")
+	}
+	if after := runGit(t, tmpDir, "rev-parse", "feature/1-demo"); after != before {
+		t.Error("Feature branch should not move when the merge is aborted")
+	}
+}
+
+func TestHasConflictMarkers(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"plain text", false},
+		{"<<<<<<< HEAD\na\n=======\nb\n>>>>>>> feature/1/T-002\n", true},
+		{"a\n>>>>>>> branch", true},
+		{"a <<<<<<< b", false},
+		{"=======\n", false},
+	}
+
+	for _, tc := range tests {
+		if got := HasConflictMarkers(tc.content); got != tc.want {
+			t.Errorf("HasConflictMarkers(%q) = %v, want %v", tc.content, got, tc.want)
+		}
+	}
+}
@@ -99,6 +99,29 @@ func TestRepoMainBranch(t *testing.T) {
 	}
 }
 
+func TestRemoteURL(t *testing.T) {
+	tmpDir := setupGitRepo(t)
+	defer os.RemoveAll(tmpDir)
+
+	repo, err := NewRepo(tmpDir, "origin", "main")
+	if err != nil {
+		t.Fatalf("NewRepo failed: %v", err)
+	}
+
+	if _, err := repo.RemoteURL(); err == nil {
+		t.Error("Expected error when the remote does not exist")
+	}
+
+	runGit(t, tmpDir, "remote", "add", "origin", "git@gitlab.example.com:group/app.git")
+	url, err := repo.RemoteURL()
+	if err != nil {
+		t.Fatalf("RemoteURL failed: %v", err)
+	}
+	if url != "git@gitlab.example.com:group/app.git" {
+		t.Errorf("Expected remote URL, got %s", url)
+	}
+}
+
 func TestGetCurrentBranch(t *testing.T) {
 	tmpDir := setupGitRepo(t)
 	defer os.RemoveAll(tmpDir)
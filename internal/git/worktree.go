@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/bayological/foreman/internal/validation"
 )
@@ -15,16 +16,33 @@ type Worktree struct {
 }
 
 func (r *Repo) CreateWorktree(branch string) (*Worktree, error) {
+	return r.CreateWorktreeFrom(branch, "")
+}
+
+// CreateWorktreeFrom creates a worktree for branch, forking the branch from
+// base if it doesn't exist yet. An empty base means the remote main branch.
+func (r *Repo) CreateWorktreeFrom(branch, base string) (*Worktree, error) {
 	// Validate branch name to prevent path traversal attacks
 	if !validation.IsValidBranchName(branch) {
 		return nil, fmt.Errorf("invalid branch name: %s", branch)
 	}
+	if base != "" && !validation.IsValidBranchName(base) {
+		return nil, fmt.Errorf("invalid base branch name: %s", base)
+	}
+
+	r.worktreeMu.Lock()
+	defer r.worktreeMu.Unlock()
+	return r.createWorktree(branch, base)
+}
 
+// createWorktree does the work of CreateWorktreeFrom; the caller holds
+// worktreeMu
+func (r *Repo) createWorktree(branch, base string) (*Worktree, error) {
 	wtPath := filepath.Join(r.worktrees, branch)
 
-	// Create branch from main if it doesn't exist
-	r.git("fetch", r.remote, r.mainBranch)
-	r.git("branch", branch, fmt.Sprintf("%s/%s", r.remote, r.mainBranch))
+	// Create branch from base if it doesn't exist
+	r.git("fetch", r.remote)
+	r.git("branch", branch, r.resolveBase(base))
 
 	// Remove existing worktree if present
 	r.git("worktree", "remove", wtPath, "--force")
@@ -40,9 +58,239 @@ func (r *Repo) CreateWorktree(branch string) (*Worktree, error) {
 	return &Worktree{Path: wtPath, Branch: branch}, nil
 }
 
+// EnsureWorktree returns the worktree for branch, creating it (and the
+// branch, forked from base) if needed. Unlike CreateWorktreeFrom, an existing
+// worktree is reused as is so that long-lived checkouts keep their state.
+func (r *Repo) EnsureWorktree(branch, base string) (*Worktree, error) {
+	if !validation.IsValidBranchName(branch) {
+		return nil, fmt.Errorf("invalid branch name: %s", branch)
+	}
+	if base != "" && !validation.IsValidBranchName(base) {
+		return nil, fmt.Errorf("invalid base branch name: %s", base)
+	}
+
+	r.worktreeMu.Lock()
+	defer r.worktreeMu.Unlock()
+
+	wtPath := filepath.Join(r.worktrees, branch)
+	if existing := r.worktreePathFor(branch); existing != "" {
+		if !samePath(existing, wtPath) {
+			return nil, fmt.Errorf("branch %s is already checked out at %s", branch, existing)
+		}
+		return &Worktree{Path: wtPath, Branch: branch}, nil
+	}
+
+	return r.createWorktree(branch, base)
+}
+
+// CommitWorktree commits all changes in wt to its branch. Tools that switch
+// the checkout to a branch of their own are undone first: the worktree goes
+// back to wt.Branch, carrying the uncommitted changes, and the stray branch is
+// deleted if it has no commits of its own.
+func (r *Repo) CommitWorktree(wt *Worktree, message string) error {
+	current, err := gitIn(wt.Path, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to read current branch: %s: %w", current, err)
+	}
+	if current != wt.Branch {
+		if out, err := gitIn(wt.Path, "checkout", wt.Branch); err != nil {
+			return fmt.Errorf("failed to switch back to %s: %s: %w", wt.Branch, out, err)
+		}
+		if current != "HEAD" {
+			if ahead, err := gitIn(wt.Path, "rev-list", wt.Branch+".."+current); err == nil && ahead == "" {
+				gitIn(wt.Path, "branch", "-D", current)
+			}
+		}
+	}
+
+	if out, err := gitIn(wt.Path, "add", "-A"); err != nil {
+		return fmt.Errorf("git add failed: %s: %w", out, err)
+	}
+	if _, err := gitIn(wt.Path, "diff", "--cached", "--quiet"); err == nil {
+		return nil
+	}
+	if out, err := gitIn(wt.Path, "commit", "-m", message); err != nil {
+		return fmt.Errorf("git commit failed: %s: %w", out, err)
+	}
+	return nil
+}
+
+func samePath(a, b string) bool {
+	resolve := func(p string) string {
+		if abs, err := filepath.Abs(p); err == nil {
+			p = abs
+		}
+		if real, err := filepath.EvalSymlinks(p); err == nil {
+			p = real
+		}
+		return p
+	}
+	return resolve(a) == resolve(b)
+}
+
+// resolveBase returns the ref a new branch should fork from. Local branches
+// win over their remote counterparts because feature branches are integrated
+// locally before being pushed. Main is taken from the remote when it has one.
+func (r *Repo) resolveBase(base string) string {
+	if base == "" {
+		remoteMain := fmt.Sprintf("%s/%s", r.remote, r.mainBranch)
+		if _, err := r.git("rev-parse", "--verify", "--quiet", "refs/remotes/"+remoteMain); err == nil {
+			return remoteMain
+		}
+		return r.mainBranch
+	}
+	if r.BranchExists(base) {
+		return base
+	}
+	return fmt.Sprintf("%s/%s", r.remote, base)
+}
+
+// BranchExists reports whether a local branch exists.
+func (r *Repo) BranchExists(branch string) bool {
+	_, err := r.git("rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
+
+// worktreePathFor returns the path of the worktree that has branch checked
+// out, or "" if the branch isn't checked out anywhere.
+func (r *Repo) worktreePathFor(branch string) string {
+	output, err := r.git("worktree", "list", "--porcelain")
+	if err != nil {
+		return ""
+	}
+
+	var path string
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "worktree "):
+			path = strings.TrimPrefix(line, "worktree ")
+		case line == "branch refs/heads/"+branch:
+			return path
+		}
+	}
+	return ""
+}
+
+// WorktreeFor returns the worktree that has branch checked out, or nil if
+// the branch isn't checked out anywhere.
+func (r *Repo) WorktreeFor(branch string) *Worktree {
+	path := r.worktreePathFor(branch)
+	if path == "" {
+		return nil
+	}
+	return &Worktree{Path: path, Branch: branch}
+}
+
+// ListWorktrees returns the worktrees Foreman manages under .worktrees.
+// Detached worktrees, such as those used to resolve conflicts, have an
+// empty Branch.
+func (r *Repo) ListWorktrees() ([]Worktree, error) {
+	output, err := r.git("worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktrees: %s: %w", output, err)
+	}
+
+	var worktrees []Worktree
+	for _, block := range strings.Split(output, "\n\n") {
+		var wt Worktree
+		for _, line := range strings.Split(block, "\n") {
+			switch {
+			case strings.HasPrefix(line, "worktree "):
+				wt.Path = strings.TrimPrefix(line, "worktree ")
+			case strings.HasPrefix(line, "branch refs/heads/"):
+				wt.Branch = strings.TrimPrefix(line, "branch refs/heads/")
+			}
+		}
+		if wt.Path != "" && r.isManaged(wt.Path) {
+			worktrees = append(worktrees, wt)
+		}
+	}
+	return worktrees, nil
+}
+
+// isManaged reports whether path lies under the .worktrees directory.
+func (r *Repo) isManaged(path string) bool {
+	root, err := filepath.EvalSymlinks(r.worktrees)
+	if err != nil {
+		root = r.worktrees
+	}
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		path = real
+	}
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..")
+}
+
+// HasChanges reports whether the worktree at path has uncommitted changes.
+func HasChanges(path string) (bool, error) {
+	output, err := gitIn(path, "status", "--porcelain")
+	if err != nil {
+		return false, fmt.Errorf("failed to read status of %s: %s: %w", path, output, err)
+	}
+	return output != "", nil
+}
+
+// PruneWorktrees drops git's records of worktrees whose directories no
+// longer exist.
+func (r *Repo) PruneWorktrees() error {
+	r.worktreeMu.Lock()
+	defer r.worktreeMu.Unlock()
+	if out, err := r.git("worktree", "prune"); err != nil {
+		return fmt.Errorf("failed to prune worktrees: %s: %w", out, err)
+	}
+	return nil
+}
+
+// CommitsAhead counts the commits on branch that are not on base. An empty
+// base means the remote main branch.
+func (r *Repo) CommitsAhead(branch, base string) (int, error) {
+	output, err := r.git("rev-list", "--count", r.resolveBase(base)+".."+branch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count commits on %s: %s: %w", branch, output, err)
+	}
+	var count int
+	if _, err := fmt.Sscanf(output, "%d", &count); err != nil {
+		return 0, fmt.Errorf("unexpected rev-list output %q: %w", output, err)
+	}
+	return count, nil
+}
+
+// DiffLines counts the lines added and removed on branch since it forked
+// from base. An empty base means the remote main branch.
+func (r *Repo) DiffLines(branch, base string) (int, error) {
+	output, err := r.git("diff", "--numstat", r.resolveBase(base)+"..."+branch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to diff %s: %s: %w", branch, output, err)
+	}
+	lines := 0
+	for _, line := range strings.Split(output, "\n") {
+		var added, removed int
+		// Binary files show "-" for both counts and are skipped
+		if _, err := fmt.Sscanf(line, "%d\t%d", &added, &removed); err == nil {
+			lines += added + removed
+		}
+	}
+	return lines, nil
+}
+
+// ResetBranch points branch at target, creating it if needed. The branch
+// must not be checked out in a worktree.
+func (r *Repo) ResetBranch(branch, target string) error {
+	if !validation.IsValidBranchName(branch) {
+		return fmt.Errorf("invalid branch name: %s", branch)
+	}
+	if out, err := r.git("branch", "-f", branch, target); err != nil {
+		return fmt.Errorf("failed to reset %s to %s: %s: %w", branch, target, out, err)
+	}
+	return nil
+}
+
 func (r *Repo) RemoveWorktree(branch string) error {
 	wtPath := filepath.Join(r.worktrees, branch)
 
+	r.worktreeMu.Lock()
+	defer r.worktreeMu.Unlock()
+
 	cmd := exec.Command("git", "worktree", "remove", wtPath, "--force")
 	cmd.Dir = r.path
 	cmd.Run() // Ignore errors
@@ -101,8 +349,13 @@ func (r *Repo) MergeBranch(branch string) error {
 	}
 
 	// Merge branch
-	if _, err := r.git("merge", branch, "--no-ff", "-m", fmt.Sprintf("Merge %s", branch)); err != nil {
-		return fmt.Errorf("merge failed: %w", err)
+	if out, err := r.git("merge", branch, "--no-ff", "-m", fmt.Sprintf("Merge %s", branch)); err != nil {
+		files := conflictedFiles(r.path)
+		r.git("merge", "--abort")
+		if len(files) > 0 {
+			return &MergeConflictError{Target: r.mainBranch, Source: branch, Files: files}
+		}
+		return fmt.Errorf("merge failed: %s: %w", out, err)
 	}
 
 	// Push
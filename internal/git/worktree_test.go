@@ -3,6 +3,7 @@ package git
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -96,3 +97,224 @@ func TestWorktreesDirectory(t *testing.T) {
 //
 // In production, CreateWorktree is designed to work with GitHub/GitLab remotes
 // where the repository already exists with proper branch structure.
+
+func TestEnsureWorktreeReusesExisting(t *testing.T) {
+	tmpDir := setupGitRepo(t)
+	defer os.RemoveAll(tmpDir)
+
+	// Fork from the local default branch, whatever git named it
+	mainBranch := strings.TrimSpace(runGit(t, tmpDir, "rev-parse", "--abbrev-ref", "HEAD"))
+	repo, err := NewRepo(tmpDir, "origin", mainBranch)
+	if err != nil {
+		t.Fatalf("NewRepo failed: %v", err)
+	}
+
+	wt, err := repo.EnsureWorktree("feature/1-demo", "")
+	if err != nil {
+		t.Fatalf("EnsureWorktree failed: %v", err)
+	}
+	defer repo.RemoveWorktree(wt.Branch)
+
+	scratch := filepath.Join(wt.Path, "scratch.md")
+	if err := os.WriteFile(scratch, []byte("work in progress"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	again, err := repo.EnsureWorktree("feature/1-demo", "")
+	if err != nil {
+		t.Fatalf("second EnsureWorktree failed: %v", err)
+	}
+	if again.Path != wt.Path {
+		t.Errorf("Expected same worktree path, got %s and %s", wt.Path, again.Path)
+	}
+	if _, err := os.Stat(scratch); err != nil {
+		t.Error("EnsureWorktree should keep the existing worktree's state")
+	}
+
+	// The main checkout must stay on its branch
+	if current := strings.TrimSpace(runGit(t, tmpDir, "rev-parse", "--abbrev-ref", "HEAD")); current != mainBranch {
+		t.Errorf("Main checkout switched to %s", current)
+	}
+}
+
+func TestEnsureWorktreeBranchCheckedOutElsewhere(t *testing.T) {
+	tmpDir := setupGitRepo(t)
+	defer os.RemoveAll(tmpDir)
+
+	mainBranch := strings.TrimSpace(runGit(t, tmpDir, "rev-parse", "--abbrev-ref", "HEAD"))
+	repo, err := NewRepo(tmpDir, "origin", mainBranch)
+	if err != nil {
+		t.Fatalf("NewRepo failed: %v", err)
+	}
+
+	if _, err := repo.EnsureWorktree(mainBranch, ""); err == nil {
+		t.Error("Expected error for a branch checked out in the main checkout")
+	}
+}
+
+func TestCommitWorktreeSwitchesBackFromStrayBranch(t *testing.T) {
+	tmpDir := setupGitRepo(t)
+	defer os.RemoveAll(tmpDir)
+
+	mainBranch := strings.TrimSpace(runGit(t, tmpDir, "rev-parse", "--abbrev-ref", "HEAD"))
+	repo, err := NewRepo(tmpDir, "origin", mainBranch)
+	if err != nil {
+		t.Fatalf("NewRepo failed: %v", err)
+	}
+
+	wt, err := repo.EnsureWorktree("feature/1-demo", "")
+	if err != nil {
+		t.Fatalf("EnsureWorktree failed: %v", err)
+	}
+	defer repo.RemoveWorktree(wt.Branch)
+
+	// A tool creates its own branch and writes files there
+	runGit(t, wt.Path, "checkout", "-b", "001-demo")
+	if err := os.MkdirAll(filepath.Join(wt.Path, "specs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(wt.Path, "specs", "spec.md"), []byte("# Spec"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.CommitWorktree(wt, "Add specification"); err != nil {
+		t.Fatalf("CommitWorktree failed: %v", err)
+	}
+
+	if current := strings.TrimSpace(runGit(t, wt.Path, "rev-parse", "--abbrev-ref", "HEAD")); current != "feature/1-demo" {
+		t.Errorf("Expected worktree back on feature/1-demo, got %s", current)
+	}
+	if out := runGit(t, tmpDir, "show", "feature/1-demo:specs/spec.md"); out != "# Spec" {
+		t.Errorf("Expected spec committed to feature branch, got %q", out)
+	}
+	if repo.BranchExists("001-demo") {
+		t.Error("Expected empty stray branch to be deleted")
+	}
+
+	// Nothing left to commit is not an error
+	if err := repo.CommitWorktree(wt, "Add specification"); err != nil {
+		t.Errorf("CommitWorktree with no changes failed: %v", err)
+	}
+}
+
+func TestListAndPruneWorktrees(t *testing.T) {
+	tmpDir := setupGitRepo(t)
+	defer os.RemoveAll(tmpDir)
+
+	mainBranch := strings.TrimSpace(runGit(t, tmpDir, "rev-parse", "--abbrev-ref", "HEAD"))
+	repo, err := NewRepo(tmpDir, "origin", mainBranch)
+	if err != nil {
+		t.Fatalf("NewRepo failed: %v", err)
+	}
+
+	kept, err := repo.CreateWorktree("feature/1/T001")
+	if err != nil {
+		t.Fatalf("CreateWorktree failed: %v", err)
+	}
+	defer repo.RemoveWorktree(kept.Branch)
+	lost, err := repo.CreateWorktree("feature/1/T002")
+	if err != nil {
+		t.Fatalf("CreateWorktree failed: %v", err)
+	}
+
+	worktrees, err := repo.ListWorktrees()
+	if err != nil {
+		t.Fatalf("ListWorktrees failed: %v", err)
+	}
+	if len(worktrees) != 2 {
+		t.Fatalf("Expected 2 managed worktrees, got %+v", worktrees)
+	}
+
+	// A worktree directory deleted behind git's back stays registered
+	// until pruned
+	os.RemoveAll(lost.Path)
+	if repo.WorktreeFor(lost.Branch) == nil {
+		t.Fatal("Expected the deleted worktree to still be registered")
+	}
+	if err := repo.PruneWorktrees(); err != nil {
+		t.Fatalf("PruneWorktrees failed: %v", err)
+	}
+	if repo.WorktreeFor(lost.Branch) != nil {
+		t.Error("Expected the deleted worktree to be pruned")
+	}
+
+	wt := repo.WorktreeFor(kept.Branch)
+	if wt == nil || !samePath(wt.Path, kept.Path) {
+		t.Errorf("WorktreeFor(%s) = %+v, want %s", kept.Branch, wt, kept.Path)
+	}
+}
+
+func TestCommitsAhead(t *testing.T) {
+	tmpDir := setupGitRepo(t)
+	defer os.RemoveAll(tmpDir)
+
+	mainBranch := strings.TrimSpace(runGit(t, tmpDir, "rev-parse", "--abbrev-ref", "HEAD"))
+	repo, err := NewRepo(tmpDir, "origin", mainBranch)
+	if err != nil {
+		t.Fatalf("NewRepo failed: %v", err)
+	}
+
+	runGit(t, tmpDir, "branch", "task/1")
+	ahead, err := repo.CommitsAhead("task/1", mainBranch)
+	if err != nil {
+		t.Fatalf("CommitsAhead failed: %v", err)
+	}
+	if ahead != 0 {
+		t.Errorf("Expected 0 commits ahead, got %d", ahead)
+	}
+
+	runGit(t, tmpDir, "checkout", "task/1")
+	commitFile(t, tmpDir, "a.txt", "a")
+	commitFile(t, tmpDir, "b.txt", "b")
+	runGit(t, tmpDir, "checkout", mainBranch)
+
+	ahead, err = repo.CommitsAhead("task/1", mainBranch)
+	if err != nil {
+		t.Fatalf("CommitsAhead failed: %v", err)
+	}
+	if ahead != 2 {
+		t.Errorf("Expected 2 commits ahead, got %d", ahead)
+	}
+
+	if _, err := repo.CommitsAhead("missing", mainBranch); err == nil {
+		t.Error("Expected error for a missing branch")
+	}
+}
+
+func TestDiffLinesAndResetBranch(t *testing.T) {
+	tmpDir := setupGitRepo(t)
+	defer os.RemoveAll(tmpDir)
+
+	mainBranch := strings.TrimSpace(runGit(t, tmpDir, "rev-parse", "--abbrev-ref", "HEAD"))
+	repo, err := NewRepo(tmpDir, "origin", mainBranch)
+	if err != nil {
+		t.Fatalf("NewRepo failed: %v", err)
+	}
+
+	runGit(t, tmpDir, "checkout", "-b", "task/1-c1")
+	commitFile(t, tmpDir, "a.txt", "one\ntwo\nthree\n")
+	commitFile(t, tmpDir, "test.txt", "changed")
+	runGit(t, tmpDir, "checkout", mainBranch)
+
+	// Three lines added, one replaced
+	lines, err := repo.DiffLines("task/1-c1", mainBranch)
+	if err != nil {
+		t.Fatalf("DiffLines failed: %v", err)
+	}
+	if lines != 5 {
+		t.Errorf("Expected 5 changed lines, got %d", lines)
+	}
+
+	if err := repo.ResetBranch("task/1", "task/1-c1"); err != nil {
+		t.Fatalf("ResetBranch failed: %v", err)
+	}
+	if ahead, _ := repo.CommitsAhead("task/1", mainBranch); ahead != 2 {
+		t.Errorf("Expected the reset branch 2 commits ahead, got %d", ahead)
+	}
+	if err := repo.ResetBranch("task/1", mainBranch); err != nil {
+		t.Fatalf("ResetBranch failed: %v", err)
+	}
+	if ahead, _ := repo.CommitsAhead("task/1", mainBranch); ahead != 0 {
+		t.Errorf("Expected the reset branch level with %s, got %d ahead", mainBranch, ahead)
+	}
+}
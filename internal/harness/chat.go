@@ -0,0 +1,293 @@
+package harness
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bayological/foreman/internal/telegram"
+)
+
+// Button is an inline button; pressing it sends Data as a callback
+type Button struct {
+	Label string
+	Data  string
+}
+
+// Message is what the chat received from Foreman
+type Message struct {
+	ID       int
+	Text     string
+	Buttons  []Button
+	Document string // name of the attached file, if any
+	Edits    int    // times the text was replaced
+}
+
+// Chat is an in-memory chat frontend. It records everything Foreman sends,
+// with the same buttons the Telegram bot would show, and lets tests send
+// commands, replies and button presses the way the bot delivers them.
+type Chat struct {
+	mu        sync.Mutex
+	messages  []Message
+	changed   chan struct{} // closed whenever messages change
+	commands  map[string]telegram.CommandHandler
+	callbacks map[string]telegram.CallbackHandler
+	onText    telegram.MessageHandler
+}
+
+func NewChat() *Chat {
+	return &Chat{
+		changed:   make(chan struct{}),
+		commands:  make(map[string]telegram.CommandHandler),
+		callbacks: make(map[string]telegram.CallbackHandler),
+	}
+}
+
+func (c *Chat) RegisterCommand(name string, handler telegram.CommandHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.commands[name] = handler
+}
+
+func (c *Chat) RegisterCallback(prefix string, handler telegram.CallbackHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.callbacks[prefix] = handler
+}
+
+func (c *Chat) RegisterMessageHandler(handler telegram.MessageHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onText = handler
+}
+
+// Listen does nothing: updates are delivered by Command, Reply and Press
+func (c *Chat) Listen(ctx context.Context) {
+	<-ctx.Done()
+}
+
+// add records a message and returns its ID
+func (c *Chat) add(msg Message) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	msg.ID = len(c.messages) + 1
+	c.messages = append(c.messages, msg)
+	c.notify()
+	return msg.ID
+}
+
+// notify wakes everything waiting for a change; mu must be held
+func (c *Chat) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func (c *Chat) Send(message string) error {
+	c.add(Message{Text: message})
+	return nil
+}
+
+func (c *Chat) SendTracked(message string) (int, error) {
+	return c.add(Message{Text: message}), nil
+}
+
+func (c *Chat) Edit(messageID int, message string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if messageID < 1 || messageID > len(c.messages) {
+		return fmt.Errorf("message %d not found", messageID)
+	}
+	c.messages[messageID-1].Text = message
+	c.messages[messageID-1].Edits++
+	c.notify()
+	return nil
+}
+
+func (c *Chat) SendDocument(name string, data []byte, caption string) error {
+	c.add(Message{Text: caption, Document: name})
+	return nil
+}
+
+func (c *Chat) RequestApproval(taskID, summary, prURL string) error {
+	text := fmt.Sprintf("🚦 *Approval Required*\n\nTask: `%s`\n\n%s", taskID, summary)
+	if prURL != "" {
+		text += fmt.Sprintf("\n\n[View Changes](%s)", prURL)
+	}
+	c.add(Message{Text: text, Buttons: []Button{
+		{"✅ Approve", "approve:" + taskID},
+		{"❌ Reject", "reject:" + taskID},
+		{"🔄 Request Changes", "changes:" + taskID},
+	}})
+	return nil
+}
+
+func (c *Chat) Escalate(taskID, reason, details string) error {
+	c.add(Message{
+		Text: fmt.Sprintf("🚨 *Escalation Required*\n\nTask: `%s`\nReason: %s\n\n%s", taskID, reason, details),
+		Buttons: []Button{
+			{"🔄 Retry", "retry:" + taskID},
+			{"🗑️ Abandon", "reject:" + taskID},
+		},
+	})
+	return nil
+}
+
+func (c *Chat) RequestPhaseApproval(featureID, phase, summary, extra string) error {
+	var buttons []Button
+	switch phase {
+	case "spec", "plan", "tasks":
+		title := strings.ToUpper(phase[:1]) + phase[1:]
+		buttons = []Button{
+			{"✅ Approve " + title, fmt.Sprintf("approve_%s:%s", phase, featureID)},
+			{"✏️ Request Changes", fmt.Sprintf("reject_%s:%s", phase, featureID)},
+		}
+	case "code":
+		buttons = codeButtons(featureID)
+	}
+
+	text := fmt.Sprintf("🚦 *Approval Required*\n\nFeature: `%s`\nPhase: %s\n\n%s", featureID, strings.ToUpper(phase), summary)
+	if extra != "" {
+		text += "\n\n" + extra
+	}
+	c.add(Message{Text: text, Buttons: buttons})
+	return nil
+}
+
+func (c *Chat) RequestCodeApproval(featureID, taskID, summary, extra string) error {
+	text := fmt.Sprintf("🚦 *Approval Required*\n\nFeature: `%s`\nTask: `%s`\nPhase: CODE\n\n%s", featureID, taskID, summary)
+	if extra != "" {
+		text += "\n\n" + extra
+	}
+	c.add(Message{Text: text, Buttons: codeButtons(featureID + ":" + taskID)})
+	return nil
+}
+
+func codeButtons(ref string) []Button {
+	return []Button{
+		{"✅ Approve & Merge", "approve_code:" + ref},
+		{"🔄 Request Changes", "request_changes:" + ref},
+		{"❌ Reject", "reject_code:" + ref},
+	}
+}
+
+func (c *Chat) RequestResolutionApproval(featureID, taskID, summary, extra string) error {
+	ref := featureID + ":" + taskID
+	text := fmt.Sprintf("🔀 *Conflict Resolution*\n\nTask: `%s`\n\n%s", taskID, summary)
+	if extra != "" {
+		text += "\n\n" + extra
+	}
+	c.add(Message{Text: text, Buttons: []Button{
+		{"✅ Commit Resolution", "approve_resolution:" + ref},
+		{"❌ Discard", "reject_resolution:" + ref},
+	}})
+	return nil
+}
+
+func (c *Chat) RequestCandidatePick(featureID, taskID, summary string, candidates []string) error {
+	ref := featureID + ":" + taskID
+	var buttons []Button
+	for i, candidate := range candidates {
+		buttons = append(buttons, Button{"🏆 Pick " + candidate, fmt.Sprintf("pick_candidate:%s:%d", ref, i+1)})
+	}
+	buttons = append(buttons, Button{"❌ Reject All", "reject_code:" + ref})
+	c.add(Message{
+		Text:    fmt.Sprintf("🏁 *Pick a Candidate*\n\nFeature: `%s`\nTask: `%s`\n\n%s", featureID, taskID, summary),
+		Buttons: buttons,
+	})
+	return nil
+}
+
+func (c *Chat) RequestBudgetApproval(scope, summary, raise string) error {
+	c.add(Message{Text: fmt.Sprintf("💸 *Budget Reached*\n\n%s", summary), Buttons: []Button{
+		{fmt.Sprintf("✅ Approve %s More", raise), "approve_budget:" + scope},
+		{"⛔ Stop", "stop_budget:" + scope},
+	}})
+	return nil
+}
+
+// Command sends "/name args". Unknown commands get the bot's reply.
+func (c *Chat) Command(name, args string) {
+	c.mu.Lock()
+	handler, ok := c.commands[name]
+	c.mu.Unlock()
+
+	if !ok {
+		c.Send(fmt.Sprintf("Unknown command: /%s\nUse /help to see available commands", name))
+		return
+	}
+	handler(args)
+}
+
+// Reply sends a plain text message, e.g. requested feedback
+func (c *Chat) Reply(text string) {
+	c.mu.Lock()
+	handler := c.onText
+	c.mu.Unlock()
+
+	if handler != nil {
+		handler(text)
+	}
+}
+
+// Press delivers a button's callback data to the handler registered for its
+// prefix
+func (c *Chat) Press(data string) error {
+	prefix, _, _ := strings.Cut(data, ":")
+
+	c.mu.Lock()
+	handler, ok := c.callbacks[prefix]
+	c.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("no handler for button %q", data)
+	}
+	handler(data)
+	return nil
+}
+
+// Messages returns a copy of everything received so far, oldest first
+func (c *Chat) Messages() []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	messages := make([]Message, len(c.messages))
+	for i, msg := range c.messages {
+		msg.Buttons = append([]Button(nil), msg.Buttons...)
+		messages[i] = msg
+	}
+	return messages
+}
+
+// Wait blocks until a message after the first from matches, and returns
+// its index. It fails when ctx is done first.
+func (c *Chat) Wait(ctx context.Context, from int, match func(Message) bool) (int, Message, error) {
+	for {
+		c.mu.Lock()
+		for i := from; i < len(c.messages); i++ {
+			if match(c.messages[i]) {
+				msg := c.messages[i]
+				c.mu.Unlock()
+				return i, msg, nil
+			}
+		}
+		changed := c.changed
+		c.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return 0, Message{}, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Button returns the data of the message's button whose data starts with
+// prefix
+func (m Message) Button(prefix string) (string, bool) {
+	for _, b := range m.Buttons {
+		if strings.HasPrefix(b.Data, prefix) {
+			return b.Data, true
+		}
+	}
+	return "", false
+}
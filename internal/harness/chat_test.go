@@ -0,0 +1,64 @@
+package harness
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChatDelivers(t *testing.T) {
+	chat := NewChat()
+
+	var pressed, args, replied string
+	chat.RegisterCallback("approve_code", func(data string) { pressed = data })
+	chat.RegisterCommand("feature", func(a string) { args = a })
+	chat.RegisterMessageHandler(func(text string) { replied = text })
+
+	chat.RequestCodeApproval("f1", "T-001", "All checks passed", "")
+	data, ok := chat.Messages()[0].Button("approve_code:")
+	if !ok || data != "approve_code:f1:T-001" {
+		t.Fatalf("approve button = %q, %v", data, ok)
+	}
+	if err := chat.Press(data); err != nil || pressed != data {
+		t.Errorf("Press() = %v, handler got %q", err, pressed)
+	}
+	if err := chat.Press("approve_spec:f1"); err == nil {
+		t.Error("Press() of a button without a handler should fail")
+	}
+
+	chat.Command("feature", "f1")
+	chat.Reply("looks good")
+	if args != "f1" || replied != "looks good" {
+		t.Errorf("command got %q, reply got %q", args, replied)
+	}
+
+	chat.Command("nope", "")
+	if last := chat.Messages()[1]; !strings.Contains(last.Text, "Unknown command: /nope") {
+		t.Errorf("unknown command reply = %q", last.Text)
+	}
+}
+
+func TestChatWait(t *testing.T) {
+	chat := NewChat()
+	chat.Send("first")
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		id, _ := chat.SendTracked("working")
+		chat.Edit(id, "done")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	i, msg, err := chat.Wait(ctx, 1, func(m Message) bool { return m.Text == "done" })
+	if err != nil || i != 1 || msg.Edits != 1 {
+		t.Fatalf("Wait() = %d, %+v, %v", i, msg, err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := chat.Wait(ctx, 0, func(m Message) bool { return m.Text == "never" }); err == nil {
+		t.Error("Wait() for a message that never comes should time out")
+	}
+}
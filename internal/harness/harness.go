@@ -0,0 +1,290 @@
+// Package harness runs Foreman end to end without any external service: a
+// temporary repo pushes to a local bare origin, the chat is in memory, and
+// SpecKit and the coding agent are scripted. Tests drive it the way a user
+// drives the Telegram bot.
+//
+// Foreman's workers run concurrently with the test, as they do with a real
+// chat, so run the harness under the race detector:
+//
+//	go test -race ./internal/harness
+package harness
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bayological/foreman/internal/agents"
+	"github.com/bayological/foreman/internal/foreman"
+	"github.com/bayological/foreman/internal/forge"
+)
+
+const (
+	// AgentName is the name of the scripted agent tasks are assigned to
+	AgentName = "scripted"
+
+	// waitTimeout is how long Expect and Press wait for a message
+	waitTimeout = 30 * time.Second
+)
+
+// Harness is one simulated Foreman deployment. The repo and its origin,
+// storage, chat, SpecKit runner, agent and forge all survive Restart.
+type Harness struct {
+	t testing.TB
+
+	Dir    string // holds everything below; removed when the test ends
+	Repo   string // the checkout Foreman works in
+	Origin string // the bare remote it pushes to
+
+	Config  *foreman.Config
+	Chat    *Chat
+	SpecKit *SpecKit
+	Agent   *agents.Scripted
+	Forge   *forge.Memory
+	Foreman *foreman.Foreman
+
+	others map[string]agents.Agent // agents added with AddAgent
+	cancel context.CancelFunc
+	done   chan error
+	seen   int // messages already matched by Expect and Press
+}
+
+// New sets up a repo with a bare origin and a Foreman whose agent follows
+// script. The review runs check.sh from the repo as its test command, so
+// an agent fails review by making it print FAILED. Foreman is not started.
+func New(t testing.TB, script agents.Script) *Harness {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "foreman-harness")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	h := &Harness{
+		t:       t,
+		Dir:     dir,
+		Repo:    filepath.Join(dir, "repo"),
+		Origin:  filepath.Join(dir, "origin.git"),
+		Chat:    NewChat(),
+		SpecKit: NewSpecKit(),
+		Agent:   agents.NewScripted(AgentName, script),
+		Forge:   forge.NewMemory(""),
+		others:  make(map[string]agents.Agent),
+	}
+
+	h.git(dir, "init", "--bare", "--initial-branch=main", h.Origin)
+	h.git(dir, "init", "--initial-branch=main", h.Repo)
+	h.git(h.Repo, "config", "user.email", "test@test.com")
+	h.git(h.Repo, "config", "user.name", "Test User")
+	h.git(h.Repo, "config", "commit.gpgsign", "false")
+	h.WriteFile("README.md", "# Demo\n")
+	h.WriteFile(".gitignore", ".worktrees/\n")
+	h.WriteFile("check.sh", "echo ok\n")
+	h.git(h.Repo, "add", ".")
+	h.git(h.Repo, "commit", "-m", "Initial commit")
+	h.git(h.Repo, "remote", "add", "origin", h.Origin)
+	h.git(h.Repo, "push", "-u", "origin", "main")
+
+	h.Config = &foreman.Config{
+		Repo: foreman.RepoConfig{Path: h.Repo, Remote: "origin", MainBranch: "main"},
+		Agents: foreman.AgentsConfig{
+			AgentName: {Enabled: true, Type: foreman.AgentTypeScripted},
+		},
+		Review: foreman.ReviewConfig{
+			MaxRetries: 2,
+			Tools: foreman.ReviewToolsConfig{
+				TestCommand: "sh check.sh",
+				// A linter that always passes; the defaults may not be installed
+				Linters: []string{"true"},
+			},
+		},
+		Concurrency:  foreman.ConcurrencyConfig{MaxTasks: 1, TaskTimeout: time.Minute},
+		Storage:      foreman.StorageConfig{Path: filepath.Join(dir, "features.json")},
+		DefaultAgent: AgentName,
+	}
+	return h
+}
+
+// WriteFile writes a file in the repo's main checkout
+func (h *Harness) WriteFile(name, content string) {
+	h.t.Helper()
+	path := filepath.Join(h.Repo, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		h.t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+func (h *Harness) git(dir string, args ...string) string {
+	h.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		h.t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// RemoteFile returns the content of a file on a branch of the origin, or
+// "" if it is not there
+func (h *Harness) RemoteFile(branch, path string) string {
+	cmd := exec.Command("git", "--git-dir", h.Origin, "show", branch+":"+path)
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+// AddAgent configures another scripted agent, e.g. as the target of a
+// routing rule, and returns it. Call it before Start.
+func (h *Harness) AddAgent(name string, script agents.Script) *agents.Scripted {
+	agent := agents.NewScripted(name, script)
+	h.Config.Agents[name] = foreman.AgentConfig{Enabled: true, Type: foreman.AgentTypeScripted}
+	h.others[name] = agent
+	return agent
+}
+
+// Start creates Foreman from the current config and runs it until Stop
+func (h *Harness) Start() {
+	h.t.Helper()
+	if h.cancel != nil {
+		h.t.Fatal("harness already started")
+	}
+
+	configured := map[string]agents.Agent{AgentName: h.Agent}
+	for name, agent := range h.others {
+		configured[name] = agent
+	}
+	f, err := foreman.NewWithDeps(h.Config, foreman.Deps{
+		Chat:      h.Chat,
+		SpecKit:   h.SpecKit,
+		Agents:    configured,
+		Forge:     h.Forge,
+		Preflight: preflight,
+	})
+	if err != nil {
+		h.t.Fatalf("creating foreman: %v", err)
+	}
+	h.Foreman = f
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	h.done = make(chan error, 1)
+	go func() { h.done <- f.Run(ctx) }()
+	h.t.Cleanup(h.Stop)
+
+	h.Expect("Ready!")
+}
+
+// preflight stands in for the host checks, which would run git, the agent
+// CLIs and gh on the test machine and wait on the network
+func preflight(ctx context.Context, cfg *foreman.Config) foreman.PreflightReport {
+	return foreman.PreflightReport{{Name: "harness", Status: foreman.CheckPass, Detail: "host checks skipped"}}
+}
+
+// Stop shuts Foreman down and waits for it to save its state. Work still in
+// flight is cancelled, so tests stop once Foreman is waiting on the user.
+func (h *Harness) Stop() {
+	if h.cancel == nil {
+		return
+	}
+	h.cancel()
+	select {
+	case <-h.done:
+	case <-time.After(waitTimeout):
+		h.t.Error("foreman did not shut down")
+	}
+	h.cancel = nil
+}
+
+// Restart stops Foreman and starts a new one on the same repo and storage,
+// as after a crash or redeploy
+func (h *Harness) Restart() {
+	h.t.Helper()
+	h.Stop()
+	h.Start()
+}
+
+// Expect waits for a message containing text that arrived after the last
+// one matched, and returns it
+func (h *Harness) Expect(text string) Message {
+	h.t.Helper()
+	return h.wait(fmt.Sprintf("a message containing %q", text), func(m Message) bool {
+		return strings.Contains(m.Text, text)
+	})
+}
+
+// Press waits for a button whose data starts with prefix, on a message
+// that arrived after the last one matched, and presses it
+func (h *Harness) Press(prefix string) Message {
+	h.t.Helper()
+	msg := h.wait(fmt.Sprintf("a button %q", prefix), func(m Message) bool {
+		_, ok := m.Button(prefix)
+		return ok
+	})
+	data, _ := msg.Button(prefix)
+	if err := h.Chat.Press(data); err != nil {
+		h.t.Fatal(err)
+	}
+	return msg
+}
+
+func (h *Harness) wait(what string, match func(Message) bool) Message {
+	h.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), waitTimeout)
+	defer cancel()
+
+	i, msg, err := h.Chat.Wait(ctx, h.seen, match)
+	if err != nil {
+		var b strings.Builder
+		for _, m := range h.Chat.Messages()[h.seen:] {
+			fmt.Fprintf(&b, "\n--- %d\n%s", m.ID, m.Text)
+		}
+		h.t.Fatalf("timed out waiting for %s; messages since the last match:%s", what, b.String())
+	}
+	h.seen = i + 1
+	return msg
+}
+
+// Command sends "/name args"
+func (h *Harness) Command(name, args string) {
+	h.Chat.Command(name, args)
+}
+
+// Reply sends a plain text message, e.g. feedback Foreman asked for
+func (h *Harness) Reply(text string) {
+	h.Chat.Reply(text)
+}
+
+var featureIDPattern = regexp.MustCompile("ID: `([^`]+)`")
+
+// NewFeature starts a feature with /newfeature and returns its ID
+func (h *Harness) NewFeature(name, description string) string {
+	h.t.Helper()
+	h.Command("newfeature", name+" | "+description)
+	m := featureIDPattern.FindStringSubmatch(h.Expect("*New Feature Started*").Text)
+	if m == nil {
+		h.t.Fatal("feature ID missing from the announcement")
+	}
+	return m[1]
+}
+
+// ApprovePhases approves the spec, plan and tasks of a feature, answering
+// no clarifications
+func (h *Harness) ApprovePhases(featureID string) {
+	h.t.Helper()
+	for _, phase := range []string{"spec", "plan", "tasks"} {
+		h.Press(fmt.Sprintf("approve_%s:%s", phase, featureID))
+	}
+}
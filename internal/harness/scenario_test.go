@@ -0,0 +1,399 @@
+package harness
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bayological/foreman/internal/agents"
+	"github.com/bayological/foreman/internal/foreman"
+)
+
+// greeting implements both default tasks in one attempt each
+var greeting = agents.Script{
+	"T-001": {{Files: map[string]string{"greet.go": "package greet\n"}, Summary: "Added greeting"}},
+	"T-002": {{Files: map[string]string{"greet_test.go": "package greet\n"}, Summary: "Added test"}},
+}
+
+func TestFeatureToCompletion(t *testing.T) {
+	h := New(t, greeting)
+	h.Start()
+
+	id := h.NewFeature("Greeting", "Say hello to the user")
+	h.ApprovePhases(id)
+
+	h.Press("approve_code:" + id + ":T-001")
+	h.Expect("Task `T-001` approved. Starting 1 more task(s)")
+	h.Press("approve_code:" + id + ":T-002")
+	done := h.Expect("*Feature Complete!*")
+
+	if !strings.Contains(done.Text, "View Pull Request") {
+		t.Errorf("completion should link the pull request:\n%s", done.Text)
+	}
+	branch := "feature/" + id + "-Greeting"
+	pr, err := h.Forge.FindPullRequest(context.Background(), branch)
+	if err != nil || pr == nil || pr.Base != "main" {
+		t.Fatalf("pull request for %s = %+v, %v", branch, pr, err)
+	}
+
+	for _, path := range []string{"greet.go", "greet_test.go"} {
+		if h.RemoteFile(branch, path) == "" {
+			t.Errorf("%s missing from %s on origin", path, branch)
+		}
+	}
+	if h.RemoteFile(branch, ".specify/specs/001-say-hello-to/tasks.md") == "" {
+		t.Errorf("tasks.md missing from %s on origin", branch)
+	}
+	if h.RemoteFile("main", "greet.go") != "" {
+		t.Error("main should be untouched until the pull request is merged")
+	}
+}
+
+func TestDoctor(t *testing.T) {
+	h := New(t, greeting)
+	h.Start()
+
+	h.Command("doctor", "")
+	h.Expect("Running preflight checks...")
+	h.Expect("host checks skipped")
+}
+
+func TestApproveBudgetWithNothingHeld(t *testing.T) {
+	h := New(t, greeting)
+	h.Start()
+
+	// A second press of the same button must not raise the budget again
+	if err := h.Chat.Press("approve_budget:feature:1"); err != nil {
+		t.Fatal(err)
+	}
+	h.Expect("No tasks are held by budget `feature:1`")
+}
+
+func TestSpecRejectionAndClarification(t *testing.T) {
+	h := New(t, greeting)
+	h.SpecKit.Script.Clarify = []SpecStep{{Output: "1. Should the greeting name the user?\n"}}
+	h.Start()
+
+	id := h.NewFeature("Greeting", "Say hello to the user")
+
+	h.Press("reject_spec:" + id)
+	h.Expect("Please type your feedback")
+	h.Reply("Greet in French")
+	h.Expect("Re-running specification")
+
+	h.Press("approve_spec:" + id)
+	h.Expect("Should the greeting name the user?")
+	h.Command("answer", id+" Q1: Yes")
+	h.Press("approve_plan:" + id)
+
+	if runs := h.SpecKit.Runs("specify"); len(runs) != 2 {
+		t.Errorf("specify ran %d times, want 2", len(runs))
+	}
+	if runs := h.SpecKit.Runs("plan"); len(runs) != 1 {
+		t.Errorf("plan ran %d times, want 1", len(runs))
+	}
+	h.Expect("*2 Tasks Generated*")
+}
+
+func TestCodeFeedbackLoop(t *testing.T) {
+	h := New(t, agents.Script{
+		"T-001": {
+			{Files: map[string]string{"greet.go": "package greet\n"}},
+			{Files: map[string]string{"greet.go": "package greet\n\n// Bonjour\n"}},
+		},
+		"T-002": greeting["T-002"],
+	})
+	h.Start()
+
+	id := h.NewFeature("Greeting", "Say hello to the user")
+	h.ApprovePhases(id)
+
+	h.Press("request_changes:" + id + ":T-001")
+	h.Expect("Please type your requested changes")
+	h.Reply("Say it in French")
+	h.Expect("Re-queuing with feedback")
+
+	h.Press("approve_code:" + id + ":T-001")
+	h.Press("approve_code:" + id + ":T-002")
+	h.Expect("*Feature Complete!*")
+
+	prompts := h.Agent.Prompts("T-001")
+	if len(prompts) != 2 || !strings.Contains(prompts[1], "Say it in French") {
+		t.Errorf("second attempt should get the feedback, prompts = %q", prompts)
+	}
+	if got := h.RemoteFile("feature/"+id+"-Greeting", "greet.go"); !strings.Contains(got, "Bonjour") {
+		t.Errorf("feature branch has greet.go = %q, want the revised version", got)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	h := New(t, agents.Script{
+		"T-001": {
+			// The agent fails outright, then breaks the tests, then fixes them
+			{Fail: "does not compile"},
+			{Files: map[string]string{"greet.go": "package greet\n", "check.sh": "echo FAILED\n"}},
+			{Files: map[string]string{"check.sh": "echo ok\n"}},
+		},
+		"T-002": greeting["T-002"],
+	})
+	h.Start()
+
+	id := h.NewFeature("Greeting", "Say hello to the user")
+	h.ApprovePhases(id)
+
+	h.Expect("*Agent Failed* - Retrying (1/2)")
+	escalation := h.Press("retry:T-001")
+	if !strings.Contains(escalation.Text, "Blocking issues found") {
+		t.Errorf("escalation should give the review's reason:\n%s", escalation.Text)
+	}
+	h.Press("approve_code:" + id + ":T-001")
+	h.Press("approve_code:" + id + ":T-002")
+	h.Expect("*Feature Complete!*")
+
+	if n := len(h.Agent.Prompts("T-001")); n != 3 {
+		t.Errorf("T-001 ran %d times, want 3", n)
+	}
+}
+
+func TestRejectCodeAndRetry(t *testing.T) {
+	h := New(t, agents.Script{
+		"T-001": {
+			{Files: map[string]string{"greet.go": "package greet\n"}},
+			{Files: map[string]string{"greet.go": "package greet\n\n// Bonjour\n"}},
+		},
+		"T-002": greeting["T-002"],
+	})
+	h.Start()
+
+	id := h.NewFeature("Greeting", "Say hello to the user")
+	h.ApprovePhases(id)
+
+	h.Press("reject_code:" + id + ":T-001")
+	h.Expect("Code rejected for `" + id + "`. Task cancelled.")
+
+	// The rejected task failed; retrying it lets the feature carry on
+	if err := h.Chat.Press("retry:T-001"); err != nil {
+		t.Fatal(err)
+	}
+	h.Press("approve_code:" + id + ":T-001")
+	h.Expect("Task `T-001` approved. Starting 1 more task(s)")
+	h.Press("approve_code:" + id + ":T-002")
+	h.Expect("*Feature Complete!*")
+
+	if n := len(h.Agent.Prompts("T-001")); n != 2 {
+		t.Errorf("T-001 ran %d times, want 2", n)
+	}
+}
+
+func TestParallelTasks(t *testing.T) {
+	h := New(t, agents.Script{
+		"T-001": greeting["T-001"],
+		"T-002": {{Files: map[string]string{"farewell.go": "package greet\n"}, Summary: "Added farewell"}},
+	})
+	h.SpecKit.Script.Tasks = []SpecStep{{Files: map[string]string{
+		"tasks.md": "## Phase: Core\n\n- [ ] T001 [P] Add greeting in `greet.go`\n- [ ] T002 [P] Add farewell in `farewell.go`\n",
+	}}}
+	h.Config.Concurrency.MaxTasks = 2
+	h.Start()
+
+	id := h.NewFeature("Greeting", "Say hello to the user")
+	h.ApprovePhases(id)
+
+	// Both tasks wait for approval at once; whichever is approved last
+	// completes the feature
+	var approvals []string
+	for i := 0; i < 2; i++ {
+		data, ok := h.Expect("Phase: CODE").Button("approve_code:" + id)
+		if !ok {
+			t.Fatal("code approval without an approve button")
+		}
+		approvals = append(approvals, data)
+	}
+	for _, data := range approvals {
+		if err := h.Chat.Press(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h.Expect("*Feature Complete!*")
+
+	h.Command("status", "")
+	h.Expect("[complete] Complete (2/2 tasks)")
+}
+
+func TestRestartAwaitingApproval(t *testing.T) {
+	h := New(t, greeting)
+	h.Start()
+
+	id := h.NewFeature("Greeting", "Say hello to the user")
+	h.ApprovePhases(id)
+	approval := h.Expect("Phase: CODE")
+
+	h.Restart()
+
+	// The button sent before the restart still works
+	data, _ := approval.Button("approve_code:")
+	if err := h.Chat.Press(data); err != nil {
+		t.Fatal(err)
+	}
+	h.Press("approve_code:" + id + ":T-002")
+	h.Expect("*Feature Complete!*")
+
+	if n := len(h.Agent.Prompts("T-001")); n != 1 {
+		t.Errorf("T-001 ran %d times, want it kept across the restart", n)
+	}
+}
+
+func TestRestartResumesPhase(t *testing.T) {
+	h := New(t, greeting)
+	h.Start()
+
+	id := h.NewFeature("Greeting", "Say hello to the user")
+	h.Expect("Phase: SPEC")
+
+	h.Restart()
+	h.Command("resume", id)
+	h.Press("approve_spec:" + id)
+	h.Press("approve_plan:" + id)
+	h.Expect("*2 Tasks Generated*")
+}
+
+func TestRoutingAndReassign(t *testing.T) {
+	h := New(t, greeting)
+	tester := h.AddAgent("tester", greeting)
+	yes := true
+	h.Config.Routing = []foreman.RouteConfig{{Agent: "tester", IsTest: &yes, Timeout: 5 * time.Minute}}
+	h.Start()
+
+	id := h.NewFeature("Greeting", "Say hello to the user")
+	h.Press("approve_spec:" + id)
+	h.Press("approve_plan:" + id)
+	tasks := h.Expect("*2 Tasks Generated*")
+	if !strings.Contains(tasks.Text, "→ tester") || strings.Count(tasks.Text, "→") != 1 {
+		t.Errorf("task list should show the routed agent of T-002 only:\n%s", tasks.Text)
+	}
+
+	h.Command("reassign", "T-001 nobody")
+	h.Expect("Unknown agent: nobody")
+	h.Command("reassign", "T-001 tester")
+	h.Expect("Task `T-001` reassigned from scripted to tester")
+
+	data, _ := tasks.Button("approve_tasks:")
+	if err := h.Chat.Press(data); err != nil {
+		t.Fatal(err)
+	}
+	h.Press("approve_code:" + id + ":T-001")
+	h.Press("approve_code:" + id + ":T-002")
+	h.Expect("*Feature Complete!*")
+
+	if n := len(h.Agent.Prompts("T-001")) + len(h.Agent.Prompts("T-002")); n != 0 {
+		t.Errorf("the default agent ran %d times, want none", n)
+	}
+	for _, task := range []string{"T-001", "T-002"} {
+		if n := len(tester.Prompts(task)); n != 1 {
+			t.Errorf("tester ran %s %d times, want 1", task, n)
+		}
+	}
+}
+
+func TestCompetition(t *testing.T) {
+	h := New(t, agents.Script{
+		"T-001": {{Files: map[string]string{"greet.go": "package greet\n\n// Hello\n// Hello again\n"}}},
+		"T-002": greeting["T-002"],
+	})
+	tester := h.AddAgent("tester", agents.Script{
+		"T-001": {{Files: map[string]string{"greet.go": "package greet\n"}}},
+	})
+	no := false
+	h.Config.Routing = []foreman.RouteConfig{
+		{Compete: []string{AgentName, "tester"}, IsTest: &no, UserStory: []string{"core"}},
+	}
+	// With one worker slot the candidates share it and run in turn
+	h.Start()
+
+	id := h.NewFeature("Greeting", "Say hello to the user")
+	h.Press("approve_spec:" + id)
+	h.Press("approve_plan:" + id)
+	tasks := h.Press("approve_tasks:" + id)
+	if !strings.Contains(tasks.Text, "→ scripted vs tester") {
+		t.Errorf("task list should show the competition:\n%s", tasks.Text)
+	}
+
+	h.Expect("Running 2 candidates: scripted, tester")
+	pick := h.Expect("*Pick a Candidate*")
+	if !strings.Contains(pick.Text, "1. `tester #2` APPROVE, tests pass, 1 lines") {
+		t.Errorf("the smaller diff should rank first:\n%s", pick.Text)
+	}
+	data, ok := pick.Button("pick_candidate:")
+	if !ok || data != "pick_candidate:"+id+":T-001:1" {
+		t.Fatalf("first pick button = %q", data)
+	}
+	if _, ok := pick.Button("pick_candidate:" + id + ":T-001:2"); !ok {
+		t.Error("the runner-up should be offered too")
+	}
+	if err := h.Chat.Press(data); err != nil {
+		t.Fatal(err)
+	}
+	h.Expect("Picked tester's implementation of `T-001`")
+
+	h.Press("approve_code:" + id + ":T-002")
+	h.Expect("*Feature Complete!*")
+
+	branch := "feature/" + id + "-Greeting"
+	if got := h.RemoteFile(branch, "greet.go"); got != "package greet\n" {
+		t.Errorf("feature branch has greet.go = %q, want the picked version", got)
+	}
+	for _, c := range []string{"-c1", "-c2"} {
+		if h.RemoteFile("feature/"+id+"/T-001"+c, "greet.go") != "" {
+			t.Errorf("candidate branch T-001%s should be deleted after the pick", c)
+		}
+	}
+	if len(h.Agent.Prompts("T-001")) != 1 || len(tester.Prompts("T-001")) != 1 {
+		t.Error("each competitor should run T-001 once")
+	}
+	if n := len(tester.Prompts("T-002")); n != 0 {
+		t.Errorf("T-002 does not match the route, but tester ran it %d times", n)
+	}
+}
+
+func TestCompeteCommandWhenEveryCandidateFails(t *testing.T) {
+	h := New(t, agents.Script{
+		"T-001": {
+			{Fail: "does not compile"},
+			{Error: "crashed"},
+			greeting["T-001"][0],
+		},
+		"T-002": greeting["T-002"],
+	})
+	h.Start()
+
+	id := h.NewFeature("Greeting", "Say hello to the user")
+	h.Press("approve_spec:" + id)
+	h.Press("approve_plan:" + id)
+	tasks := h.Expect("*2 Tasks Generated*")
+
+	h.Command("compete", "T-001 "+AgentName)
+	h.Expect("A competition needs two agents, or one agent twice")
+	h.Command("compete", id+" T-001 "+AgentName+" "+AgentName)
+	h.Expect("Task `T-001` will be run by scripted, scripted")
+
+	data, _ := tasks.Button("approve_tasks:")
+	if err := h.Chat.Press(data); err != nil {
+		t.Fatal(err)
+	}
+	failed := h.Expect("Every candidate failed")
+	if !strings.Contains(failed.Text, "does not compile") || !strings.Contains(failed.Text, "failed: crashed") {
+		t.Errorf("escalation should list each candidate's failure:\n%s", failed.Text)
+	}
+
+	// Without the competition the retry runs the agent alone
+	h.Command("compete", "T-001 off")
+	h.Expect("Task `T-001` runs with scripted alone")
+	data, _ = failed.Button("retry:")
+	if err := h.Chat.Press(data); err != nil {
+		t.Fatal(err)
+	}
+	h.Press("approve_code:" + id + ":T-001")
+	h.Press("approve_code:" + id + ":T-002")
+	h.Expect("*Feature Complete!*")
+}
@@ -0,0 +1,154 @@
+package harness
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bayological/foreman/internal/speckit"
+)
+
+// SpecStep is what one run of a scripted SpecKit command does
+type SpecStep struct {
+	Files  map[string]string // file name -> content, written to the feature directory
+	Output string            // e.g. the questions clarify asks
+	Fail   string            // the command reports failure with this error
+}
+
+// SpecScript holds the steps of each SpecKit command. Successive runs of a
+// command take successive steps; the last step repeats. A command without
+// steps succeeds without writing anything.
+type SpecScript struct {
+	Specify []SpecStep
+	Clarify []SpecStep
+	Plan    []SpecStep
+	Tasks   []SpecStep
+}
+
+// SpecKit is a scripted SpecKit runner that writes canned artifacts instead
+// of asking a model
+type SpecKit struct {
+	*speckit.SpecKit // finds feature directories like the real runner
+
+	Script SpecScript
+
+	mu   sync.Mutex
+	runs map[string][]string
+}
+
+// NewSpecKit returns a runner that gives every feature a one-story spec, a
+// Go plan and two tasks, T-002 depending on T-001
+func NewSpecKit() *SpecKit {
+	return &SpecKit{
+		SpecKit: speckit.New(""),
+		Script: SpecScript{
+			Specify: []SpecStep{{Files: map[string]string{
+				"spec.md": "# Greeting\n\n## User Story: Greet the user\n\nThe app says hello.\n",
+			}}},
+			Plan: []SpecStep{{Files: map[string]string{
+				"plan.md": "# Plan\n\n## Tech Stack\n- Go\n\n## Structure\nOne package.\n",
+			}}},
+			Tasks: []SpecStep{{Files: map[string]string{
+				"tasks.md": "## Phase: Core\n\n- [ ] T001 Add greeting in `greet.go`\n- [ ] T002 Add greeting test in `greet_test.go` (depends on T001)\n",
+			}}},
+		},
+		runs: make(map[string][]string),
+	}
+}
+
+// Runs returns the arguments of each run of a command, e.g. "specify"
+func (s *SpecKit) Runs(command string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.runs[command]...)
+}
+
+// next records a run of command and returns its step
+func (s *SpecKit) next(command, args string, steps []SpecStep) SpecStep {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run := len(s.runs[command])
+	s.runs[command] = append(s.runs[command], args)
+	if len(steps) == 0 {
+		return SpecStep{}
+	}
+	if run >= len(steps) {
+		run = len(steps) - 1
+	}
+	return steps[run]
+}
+
+// run writes the step's files to the feature directory and reports its result
+func (s *SpecKit) run(command, args, featureDir string, step SpecStep) (*speckit.CommandResult, error) {
+	result := &speckit.CommandResult{
+		Command:   command,
+		Args:      args,
+		Output:    step.Output,
+		Timestamp: time.Now(),
+	}
+	if step.Fail != "" {
+		result.Error = step.Fail
+		return result, nil
+	}
+
+	if len(step.Files) > 0 {
+		if err := os.MkdirAll(featureDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	for name, content := range step.Files {
+		if err := os.WriteFile(filepath.Join(featureDir, name), []byte(content), 0644); err != nil {
+			return nil, err
+		}
+	}
+	result.Success = true
+	return result, nil
+}
+
+func (s *SpecKit) Initialize(ctx context.Context, workDir string) error {
+	return os.MkdirAll(filepath.Join(workDir, ".specify", "specs"), 0755)
+}
+
+func (s *SpecKit) Constitution(ctx context.Context, principles string) (*speckit.CommandResult, error) {
+	s.next("constitution", principles, nil)
+	return &speckit.CommandResult{Command: "speckit.constitution", Args: principles, Success: true, Timestamp: time.Now()}, nil
+}
+
+// nonAlnum matches what is dropped from feature directory names
+var nonAlnum = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Specify writes to a new feature directory on every run, as the real
+// command does, named after the description: 001-build-a-greeting
+func (s *SpecKit) Specify(ctx context.Context, description string, ws *speckit.Workspace) (*speckit.CommandResult, error) {
+	step := s.next("specify", description, s.Script.Specify)
+
+	words := strings.Fields(nonAlnum.ReplaceAllString(strings.ToLower(description), " "))
+	if len(words) > 3 {
+		words = words[:3]
+	}
+	name := fmt.Sprintf("%03d-%s", len(s.ListFeatureDirs(ws.Dir))+1, strings.Join(words, "-"))
+
+	result, err := s.run("speckit.specify", description, s.FeatureDirIn(ws.Dir, name), step)
+	if result != nil && result.Output == "" {
+		result.Output = fmt.Sprintf("Created .specify/specs/%s/spec.md", name)
+	}
+	return result, err
+}
+
+func (s *SpecKit) Clarify(ctx context.Context, ws *speckit.Workspace) (*speckit.CommandResult, error) {
+	return s.run("speckit.clarify", "", s.FeatureDirIn(ws.Dir, ws.Feature), s.next("clarify", "", s.Script.Clarify))
+}
+
+func (s *SpecKit) Plan(ctx context.Context, techStack string, ws *speckit.Workspace) (*speckit.CommandResult, error) {
+	return s.run("speckit.plan", techStack, s.FeatureDirIn(ws.Dir, ws.Feature), s.next("plan", techStack, s.Script.Plan))
+}
+
+func (s *SpecKit) Tasks(ctx context.Context, ws *speckit.Workspace) (*speckit.CommandResult, error) {
+	return s.run("speckit.tasks", "", s.FeatureDirIn(ws.Dir, ws.Feature), s.next("tasks", "", s.Script.Tasks))
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -153,6 +154,7 @@ func ParseTasks(featureDir string) ([]TaskItem, error) {
 	parallelRegex := regexp.MustCompile(`\[P\]`)
 	userStoryRegex := regexp.MustCompile(`(?i)^##\s*(User Story|Phase|Story):?\s*(.+)$`)
 	filePathRegex := regexp.MustCompile("`([^`]+\\.[a-zA-Z]+)`")
+	explicitIDRegex := regexp.MustCompile(`^T-?(\d+)\b\s*`)
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -170,6 +172,14 @@ func ParseTasks(featureDir string) ([]TaskItem, error) {
 			taskTitle = parallelRegex.ReplaceAllString(taskTitle, "")
 			taskTitle = strings.TrimSpace(taskTitle)
 
+			// Honour explicit IDs ("T004 Create models") so that
+			// dependency references elsewhere in the file resolve.
+			taskID := fmt.Sprintf("T-%03d", taskOrder)
+			if idMatch := explicitIDRegex.FindStringSubmatch(taskTitle); len(idMatch) >= 2 {
+				taskID = normalizeTaskID(idMatch[1])
+				taskTitle = strings.TrimSpace(taskTitle[len(idMatch[0]):])
+			}
+
 			isTest := strings.Contains(strings.ToLower(taskTitle), "test")
 
 			var filePaths []string
@@ -182,9 +192,10 @@ func ParseTasks(featureDir string) ([]TaskItem, error) {
 			}
 
 			task := TaskItem{
-				ID:           fmt.Sprintf("T-%03d", taskOrder),
+				ID:           taskID,
 				Title:        taskTitle,
 				UserStoryRef: currentUserStory,
+				Dependencies: parseTaskDependencies(taskTitle, taskID),
 				FilePaths:    filePaths,
 				IsParallel:   isParallel,
 				IsTest:       isTest,
@@ -202,6 +213,41 @@ func ParseTasks(featureDir string) ([]TaskItem, error) {
 	return tasks, nil
 }
 
+var (
+	dependencyRegex = regexp.MustCompile(`(?i)\b(?:depends on|after|requires|blocked by)\s*:?\s*((?:T-?\d+[\s,]*(?:and\s+)?)+)`)
+	taskRefRegex    = regexp.MustCompile(`T-?(\d+)`)
+)
+
+// parseTaskDependencies extracts explicit task references such as
+// "depends on T001, T003" or "(after T-002)" from a task line.
+func parseTaskDependencies(title, selfID string) []string {
+	var deps []string
+	seen := make(map[string]bool)
+
+	for _, match := range dependencyRegex.FindAllStringSubmatch(title, -1) {
+		for _, ref := range taskRefRegex.FindAllStringSubmatch(match[1], -1) {
+			id := normalizeTaskID(ref[1])
+			if id == selfID || seen[id] {
+				continue
+			}
+			seen[id] = true
+			deps = append(deps, id)
+		}
+	}
+
+	return deps
+}
+
+// normalizeTaskID converts the numeric part of a task reference to the
+// canonical T-NNN form used for generated task IDs.
+func normalizeTaskID(num string) string {
+	n, err := strconv.Atoi(num)
+	if err != nil {
+		return "T-" + num
+	}
+	return fmt.Sprintf("T-%03d", n)
+}
+
 // ParseClarifications extracts questions from clarify output
 func ParseClarifications(output string) []Question {
 	var questions []Question
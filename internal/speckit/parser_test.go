@@ -239,6 +239,74 @@ func TestParseTasks(t *testing.T) {
 	}
 }
 
+func TestParseTasks_Dependencies(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "speckit-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tasksContent := `# Tasks
+
+## Phase 1: Setup
+
+- [ ] T001 Create project structure
+- [ ] T002 [P] Add models in ` + "`src/models.go`" + `
+
+## Phase 2: Core
+
+- [ ] T003 Implement service (depends on T001, T002)
+- [ ] T004 Add handlers after T-003 and T1
+- [ ] Wire everything together
+`
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "tasks.md"), []byte(tasksContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := ParseTasks(tmpDir)
+	if err != nil {
+		t.Fatalf("ParseTasks() error = %v", err)
+	}
+
+	if len(tasks) != 5 {
+		t.Fatalf("ParseTasks() count = %d, want 5", len(tasks))
+	}
+
+	if tasks[0].ID != "T-001" || tasks[0].Title != "Create project structure" {
+		t.Errorf("explicit ID not parsed: got %q %q", tasks[0].ID, tasks[0].Title)
+	}
+	if !tasks[1].IsParallel || tasks[1].ID != "T-002" {
+		t.Errorf("parallel task parsed as %+v", tasks[1])
+	}
+	if len(tasks[1].FilePaths) != 1 || tasks[1].FilePaths[0] != "src/models.go" {
+		t.Errorf("FilePaths = %v, want [src/models.go]", tasks[1].FilePaths)
+	}
+
+	wantDeps := map[string][]string{
+		"T-001": nil,
+		"T-003": {"T-001", "T-002"},
+		"T-004": {"T-003", "T-001"},
+		"T-005": nil,
+	}
+	for _, task := range tasks {
+		want, ok := wantDeps[task.ID]
+		if !ok {
+			continue
+		}
+		if len(task.Dependencies) != len(want) {
+			t.Errorf("%s Dependencies = %v, want %v", task.ID, task.Dependencies, want)
+			continue
+		}
+		for i := range want {
+			if task.Dependencies[i] != want[i] {
+				t.Errorf("%s Dependencies = %v, want %v", task.ID, task.Dependencies, want)
+				break
+			}
+		}
+	}
+}
+
 func TestSpecSummary(t *testing.T) {
 	spec := &Spec{
 		Title: "Test Feature",
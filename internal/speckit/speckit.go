@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -24,6 +25,21 @@ func New(repoPath string) *SpecKit {
 	}
 }
 
+// Workspace is the checkout SpecKit commands for one feature run in.
+type Workspace struct {
+	Dir string
+	// Feature names the feature's directory under .specify/specs. SpecKit
+	// scripts derive it from the branch name unless SPECIFY_FEATURE is set.
+	Feature string
+}
+
+func (w *Workspace) env() []string {
+	if w.Feature == "" {
+		return nil
+	}
+	return []string{"SPECIFY_FEATURE=" + w.Feature}
+}
+
 type CommandResult struct {
 	Command   string
 	Args      string
@@ -33,14 +49,14 @@ type CommandResult struct {
 	Timestamp time.Time
 }
 
-// Initialize runs 'specify init' if not already initialized
-func (s *SpecKit) Initialize(ctx context.Context) error {
-	if _, err := os.Stat(s.specifyPath); err == nil {
+// Initialize runs 'specify init' in workDir if it is not already initialized
+func (s *SpecKit) Initialize(ctx context.Context, workDir string) error {
+	if _, err := os.Stat(filepath.Join(workDir, ".specify")); err == nil {
 		return nil
 	}
 
 	cmd := exec.CommandContext(ctx, "specify", "init", ".", "--ai", "claude", "--force")
-	cmd.Dir = s.repoPath
+	cmd.Dir = workDir
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("specify init failed: %w\noutput: %s", err, output)
@@ -57,6 +73,10 @@ func (s *SpecKit) IsInitialized() bool {
 
 // RunClaudeCommand executes a SpecKit slash command via Claude Code
 func (s *SpecKit) RunClaudeCommand(ctx context.Context, command string, args string, workDir string) (*CommandResult, error) {
+	return s.runClaudeCommand(ctx, command, args, workDir, nil)
+}
+
+func (s *SpecKit) runClaudeCommand(ctx context.Context, command, args, workDir string, env []string) (*CommandResult, error) {
 	prompt := fmt.Sprintf("/%s %s", command, args)
 
 	claudeArgs := []string{
@@ -67,6 +87,9 @@ func (s *SpecKit) RunClaudeCommand(ctx context.Context, command string, args str
 
 	cmd := exec.CommandContext(ctx, "claude", claudeArgs...)
 	cmd.Dir = workDir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -123,29 +146,24 @@ func (s *SpecKit) Constitution(ctx context.Context, principles string) (*Command
 	return s.RunClaudeCommand(ctx, "speckit.constitution", principles, s.repoPath)
 }
 
-// Specify creates feature specification
-func (s *SpecKit) Specify(ctx context.Context, description string, featureBranch string) (*CommandResult, error) {
-	// Checkout the feature branch first
-	if err := exec.CommandContext(ctx, "git", "-C", s.repoPath, "checkout", "-B", featureBranch).Run(); err != nil {
-		return nil, fmt.Errorf("failed to checkout branch %s: %w", featureBranch, err)
-	}
-
-	return s.RunClaudeCommand(ctx, "speckit.specify", description, s.repoPath)
+// Specify creates feature specification in the workspace
+func (s *SpecKit) Specify(ctx context.Context, description string, ws *Workspace) (*CommandResult, error) {
+	return s.runClaudeCommand(ctx, "speckit.specify", description, ws.Dir, ws.env())
 }
 
-// Clarify runs the clarification workflow
-func (s *SpecKit) Clarify(ctx context.Context) (*CommandResult, error) {
-	return s.RunClaudeCommand(ctx, "speckit.clarify", "", s.repoPath)
+// Clarify runs the clarification workflow in the workspace
+func (s *SpecKit) Clarify(ctx context.Context, ws *Workspace) (*CommandResult, error) {
+	return s.runClaudeCommand(ctx, "speckit.clarify", "", ws.Dir, ws.env())
 }
 
-// Plan creates implementation plan with tech stack
-func (s *SpecKit) Plan(ctx context.Context, techStack string) (*CommandResult, error) {
-	return s.RunClaudeCommand(ctx, "speckit.plan", techStack, s.repoPath)
+// Plan creates implementation plan with tech stack in the workspace
+func (s *SpecKit) Plan(ctx context.Context, techStack string, ws *Workspace) (*CommandResult, error) {
+	return s.runClaudeCommand(ctx, "speckit.plan", techStack, ws.Dir, ws.env())
 }
 
-// Tasks generates task breakdown
-func (s *SpecKit) Tasks(ctx context.Context) (*CommandResult, error) {
-	return s.RunClaudeCommand(ctx, "speckit.tasks", "", s.repoPath)
+// Tasks generates task breakdown in the workspace
+func (s *SpecKit) Tasks(ctx context.Context, ws *Workspace) (*CommandResult, error) {
+	return s.runClaudeCommand(ctx, "speckit.tasks", "", ws.Dir, ws.env())
 }
 
 // GetSpecsDir returns the specs directory path
@@ -156,8 +174,10 @@ func (s *SpecKit) GetSpecsDir() string {
 // GetLatestFeatureDir returns the most recent feature directory
 // Directories are sorted by modification time, most recent first
 func (s *SpecKit) GetLatestFeatureDir() string {
-	specsDir := s.GetSpecsDir()
+	return latestDir(s.GetSpecsDir())
+}
 
+func latestDir(specsDir string) string {
 	entries, err := os.ReadDir(specsDir)
 	if err != nil {
 		return ""
@@ -184,6 +204,74 @@ func (s *SpecKit) GetLatestFeatureDir() string {
 	return latestDir
 }
 
+// FeatureDirIn returns the path of the named feature directory in the
+// checkout at workDir
+func (s *SpecKit) FeatureDirIn(workDir, name string) string {
+	return filepath.Join(workDir, ".specify", "specs", name)
+}
+
+// ListFeatureDirs returns the names of the feature directories in the
+// checkout at workDir
+func (s *SpecKit) ListFeatureDirs(workDir string) []string {
+	entries, err := os.ReadDir(filepath.Join(workDir, ".specify", "specs"))
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+// specDirRef matches a feature directory mentioned in command output, e.g.
+// ".specify/specs/001-user-auth/spec.md"
+var specDirRef = regexp.MustCompile(`specs/(\d{3}-[A-Za-z0-9._-]+)`)
+
+// DetectFeatureDir works out which feature directory a specify run created,
+// given the directory names present before it ran and its output. A single
+// new directory wins; otherwise the output must name exactly one existing
+// directory.
+func (s *SpecKit) DetectFeatureDir(workDir string, before []string, output string) (string, error) {
+	existed := make(map[string]bool, len(before))
+	for _, name := range before {
+		existed[name] = true
+	}
+
+	present := make(map[string]bool)
+	var created []string
+	for _, name := range s.ListFeatureDirs(workDir) {
+		present[name] = true
+		if !existed[name] {
+			created = append(created, name)
+		}
+	}
+
+	if len(created) == 1 {
+		return created[0], nil
+	}
+
+	mentioned := make(map[string]bool)
+	for _, m := range specDirRef.FindAllStringSubmatch(output, -1) {
+		if present[m[1]] {
+			mentioned[m[1]] = true
+		}
+	}
+	if len(mentioned) == 1 {
+		for name := range mentioned {
+			return name, nil
+		}
+	}
+
+	if len(created) > 1 {
+		return "", fmt.Errorf("specify created several feature directories: %s", strings.Join(created, ", "))
+	}
+	return "", fmt.Errorf("could not find the feature directory created by specify")
+}
+
 // GetFeatureDir returns the directory for a specific feature ID prefix
 func (s *SpecKit) GetFeatureDir(featurePrefix string) string {
 	specsDir := s.GetSpecsDir()
@@ -228,3 +228,67 @@ func TestCommandInfo_NeedsArgs(t *testing.T) {
 		}
 	}
 }
+
+func TestWorkspaceEnv(t *testing.T) {
+	ws := &Workspace{Dir: "/wt"}
+	if env := ws.env(); env != nil {
+		t.Errorf("env() without feature = %v, want nil", env)
+	}
+
+	ws.Feature = "001-auth"
+	env := ws.env()
+	if len(env) != 1 || env[0] != "SPECIFY_FEATURE=001-auth" {
+		t.Errorf("env() = %v, want SPECIFY_FEATURE=001-auth", env)
+	}
+}
+
+func TestDetectFeatureDir(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "speckit-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sk := New(tmpDir)
+	mkdir := func(name string) {
+		if err := os.MkdirAll(sk.FeatureDirIn(tmpDir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mkdir("001-auth")
+	before := sk.ListFeatureDirs(tmpDir)
+
+	// Nothing new and nothing mentioned
+	if _, err := sk.DetectFeatureDir(tmpDir, before, "done"); err == nil {
+		t.Error("expected error when no directory was created")
+	}
+
+	// One new directory wins regardless of output
+	mkdir("002-billing")
+	got, err := sk.DetectFeatureDir(tmpDir, before, "Created .specify/specs/001-auth/spec.md")
+	if err != nil || got != "002-billing" {
+		t.Errorf("DetectFeatureDir() = %q, %v, want 002-billing", got, err)
+	}
+
+	// Two new directories: the output decides
+	mkdir("003-search")
+	got, err = sk.DetectFeatureDir(tmpDir, before, "SPEC_FILE: /wt/.specify/specs/003-search/spec.md")
+	if err != nil || got != "003-search" {
+		t.Errorf("DetectFeatureDir() = %q, %v, want 003-search", got, err)
+	}
+	if _, err := sk.DetectFeatureDir(tmpDir, before, "no paths here"); err == nil {
+		t.Error("expected error when several directories were created and none is named")
+	}
+
+	// Re-running specify on an existing directory is found through the output
+	got, err = sk.DetectFeatureDir(tmpDir, sk.ListFeatureDirs(tmpDir), "Updated specs/001-auth/spec.md")
+	if err != nil || got != "001-auth" {
+		t.Errorf("DetectFeatureDir() = %q, %v, want 001-auth", got, err)
+	}
+
+	// Mentions of directories that don't exist are ignored
+	if _, err := sk.DetectFeatureDir(tmpDir, sk.ListFeatureDirs(tmpDir), "specs/999-ghost"); err == nil {
+		t.Error("expected error for a mention of a missing directory")
+	}
+}
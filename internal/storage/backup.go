@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBackups is the number of backups kept when none is configured
+	DefaultBackups = 5
+
+	// backupInterval is the minimum time between backups taken on save
+	backupInterval = time.Hour
+
+	// backupTimeFormat is fixed-width so backups sort by name
+	backupTimeFormat = "20060102T150405.000000000Z"
+	backupSuffix     = ".bak"
+)
+
+// ErrLocked is returned when another process holds the storage lock
+var ErrLocked = errors.New("storage is in use by another Foreman process")
+
+// Backup is a timestamped copy of a storage file
+type Backup struct {
+	Path string
+	Time time.Time
+}
+
+// lockPath returns the path of the lock file guarding a storage file
+func lockPath(path string) string {
+	return path + ".lock"
+}
+
+// backupPath returns the path of a backup of path taken at t
+func backupPath(path string, t time.Time) string {
+	return path + "." + t.UTC().Format(backupTimeFormat) + backupSuffix
+}
+
+// ListBackups returns the backups of the storage file at path, newest first
+func ListBackups(path string) ([]Backup, error) {
+	matches, err := filepath.Glob(path + ".*" + backupSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := path + "."
+	var backups []Backup
+	for _, match := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(match, prefix), backupSuffix)
+		t, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{Path: match, Time: t})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+
+	return backups, nil
+}
+
+// Restore replaces the storage file at path with a backup. An empty backup
+// restores the newest one. The current file is backed up first, so a
+// restore can be undone. Restore fails if Foreman is running on the file.
+func Restore(path, backup string) (string, error) {
+	lock, err := lockFile(lockPath(path))
+	if err != nil {
+		return "", err
+	}
+	defer unlockFile(lock)
+
+	if backup == "" {
+		backups, err := ListBackups(path)
+		if err != nil {
+			return "", err
+		}
+		if len(backups) == 0 {
+			return "", fmt.Errorf("no backups of %s", path)
+		}
+		backup = backups[0].Path
+	}
+
+	data, err := os.ReadFile(backup)
+	if err != nil {
+		return "", fmt.Errorf("reading backup: %w", err)
+	}
+	if _, _, err := decodeStore(data); err != nil {
+		return "", fmt.Errorf("backup %s is not a valid storage file: %w", backup, err)
+	}
+
+	if _, err := createBackup(path, DefaultBackups); err != nil {
+		return "", fmt.Errorf("backing up current storage: %w", err)
+	}
+
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return "", fmt.Errorf("writing storage file: %w", err)
+	}
+
+	return backup, nil
+}
+
+// keepPreMigrationCopy saves data, a storage file at the given schema
+// version, next to path unless a copy of that version already exists
+func keepPreMigrationCopy(path string, version int, data []byte) error {
+	copyPath := fmt.Sprintf("%s.v%d", path, version)
+	if _, err := os.Stat(copyPath); err == nil {
+		return nil
+	}
+	return writeFileAtomic(copyPath, data, 0644)
+}
+
+// createBackup copies the storage file at path to a new timestamped backup
+// and removes all but the newest keep backups. It returns false when there
+// is no storage file to back up yet.
+func createBackup(path string, keep int) (bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := writeFileAtomic(backupPath(path, time.Now()), data, 0644); err != nil {
+		return false, err
+	}
+
+	backups, err := ListBackups(path)
+	if err != nil {
+		return true, err
+	}
+	for i := keep; i < len(backups); i++ {
+		if err := os.Remove(backups[i].Path); err != nil && !os.IsNotExist(err) {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers and crashes only ever see the old or the new
+// contents in full.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	// Clean up the temporary file unless it was renamed into place
+	renamed := false
+	defer func() {
+		if !renamed {
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	renamed = true
+
+	// Persist the rename itself. Not every platform can sync a directory,
+	// so failures here are ignored.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
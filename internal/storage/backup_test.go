@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveLeavesNoTemporaryFiles(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "storage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	store, err := NewWithBackups(filepath.Join(tmpDir, "features.json"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	for _, id := range []string{"a", "b", "c"} {
+		if err := store.SaveFeature(&FeatureState{ID: id}); err != nil {
+			t.Fatalf("SaveFeature() error = %v", err)
+		}
+	}
+
+	files, _ := os.ReadDir(tmpDir)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	if len(names) != 2 || names[0] != "features.json" || names[1] != "features.json.lock" {
+		t.Errorf("unexpected files in storage dir: %v", names)
+	}
+}
+
+func TestSecondInstanceIsRefused(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "storage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "features.json")
+	first, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New(path); !errors.Is(err, ErrLocked) {
+		t.Errorf("second New() error = %v, want ErrLocked", err)
+	}
+
+	first.Close()
+	second, err := New(path)
+	if err != nil {
+		t.Fatalf("New() after Close() error = %v", err)
+	}
+	second.Close()
+}
+
+func TestBackupsRotate(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "storage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "features.json")
+	if created, err := createBackup(path, 2); err != nil || created {
+		t.Errorf("createBackup() without a storage file = %v, %v; want false, nil", created, err)
+	}
+
+	for _, content := range []string{"1", "2", "3"} {
+		os.WriteFile(path, []byte(content), 0644)
+		if _, err := createBackup(path, 2); err != nil {
+			t.Fatalf("createBackup() error = %v", err)
+		}
+	}
+
+	backups, err := ListBackups(path)
+	if err != nil {
+		t.Fatalf("ListBackups() error = %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups, got %d", len(backups))
+	}
+	if data, _ := os.ReadFile(backups[0].Path); string(data) != "3" {
+		t.Errorf("newest backup = %q, want %q", data, "3")
+	}
+	if !backups[0].Time.After(backups[1].Time) {
+		t.Error("expected backups newest first")
+	}
+}
+
+func TestRestore(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "storage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "features.json")
+
+	store, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.SaveFeature(&FeatureState{ID: "good"})
+	store.Close()
+
+	// The next run backs up the good state before losing it
+	store, err = New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.DeleteFeature("good")
+
+	if _, err := Restore(path, ""); !errors.Is(err, ErrLocked) {
+		t.Errorf("Restore() while in use error = %v, want ErrLocked", err)
+	}
+	store.Close()
+
+	restored, err := Restore(path, "")
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if !strings.HasSuffix(restored, backupSuffix) {
+		t.Errorf("Restore() = %q, want a backup path", restored)
+	}
+
+	store, err = New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if _, err := store.LoadFeature("good"); err != nil {
+		t.Errorf("expected restored feature, got %v", err)
+	}
+
+	// The state replaced by the restore was kept as a backup too
+	if backups, _ := ListBackups(path); len(backups) < 2 {
+		t.Errorf("expected the pre-restore state to be backed up, got %d backups", len(backups))
+	}
+
+	os.WriteFile(filepath.Join(tmpDir, "broken.json"), []byte("{"), 0644)
+	store.Close()
+	if _, err := Restore(path, filepath.Join(tmpDir, "broken.json")); err == nil {
+		t.Error("Restore() of an invalid backup should fail")
+	}
+}
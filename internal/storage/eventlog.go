@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxLogLine bounds a single event log line. Events carrying a feature or
+// task snapshot can be large.
+const maxLogLine = 16 << 20
+
+// LogEvent is one entry of the event log. Which fields are set depends on
+// the event type.
+type LogEvent struct {
+	Seq       int64     `json:"seq"`
+	Timestamp time.Time `json:"timestamp"`
+	Type      string    `json:"type"`
+	FeatureID string    `json:"feature_id,omitempty"`
+	TaskID    string    `json:"task_id,omitempty"`
+	Actor     string    `json:"actor,omitempty"`
+	Message   string    `json:"message,omitempty"`
+
+	// Phase transitions
+	FromPhase string `json:"from_phase,omitempty"`
+	ToPhase   string `json:"to_phase,omitempty"`
+
+	// Review verdicts
+	Verdict string `json:"verdict,omitempty"`
+
+	// Snapshots: the feature when it is created, its tasks when they are
+	// generated, and a task as it was right after a task event
+	Feature *FeatureState `json:"feature,omitempty"`
+	Tasks   []TaskState   `json:"tasks,omitempty"`
+	Task    *TaskState    `json:"task,omitempty"`
+
+	Data map[string]string `json:"data,omitempty"`
+}
+
+// EventLog is an append-only log of events, stored as one JSON object per
+// line. Entries are never rewritten, so the log doubles as an audit trail.
+type EventLog struct {
+	path string
+	mu   sync.Mutex
+	file *os.File
+	seq  int64
+}
+
+// OpenEventLog opens the event log at path for appending, creating it if
+// needed. Damaged lines are skipped with a warning rather than keeping
+// Foreman from starting; numbering carries on from the last good event.
+func OpenEventLog(path string) (*EventLog, error) {
+	events, err := readEventLog(path, true)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening event log: %w", err)
+	}
+
+	// A crash may have cut the last line short. Drop it so the next event
+	// starts on a line of its own.
+	if err := truncatePartialLine(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("repairing event log: %w", err)
+	}
+
+	log := &EventLog{path: path, file: file}
+	if len(events) > 0 {
+		log.seq = events[len(events)-1].Seq
+	}
+	return log, nil
+}
+
+// Append stamps the event with the next sequence number and, if unset, the
+// current time, and writes it to the log
+func (l *EventLog) Append(event LogEvent) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	event.Seq = l.seq + 1
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshaling event: %w", err)
+	}
+
+	// One write per line keeps concurrent readers from seeing half an event
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("writing event log: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("syncing event log: %w", err)
+	}
+
+	l.seq = event.Seq
+	return nil
+}
+
+// Events returns every event in the log, oldest first
+func (l *EventLog) Events() ([]LogEvent, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return ReadEventLog(l.path)
+}
+
+// Close closes the log file
+func (l *EventLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// ReadEventLog reads every event from the log at path, oldest first. A
+// final line cut short by a crash is skipped; damage anywhere else is an
+// error.
+func ReadEventLog(path string) ([]LogEvent, error) {
+	return readEventLog(path, false)
+}
+
+// readEventLog reads the log at path; with skipDamaged, damaged lines are
+// logged and skipped wherever they are
+func readEventLog(path string, skipDamaged bool) ([]LogEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []LogEvent
+	var broken error
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxLogLine)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if broken != nil {
+			return nil, broken
+		}
+
+		var event LogEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			broken = fmt.Errorf("event log %s line %d: %w", path, line, err)
+			if skipDamaged {
+				log.Printf("Warning: Skipping %v", broken)
+				broken = nil
+			}
+			continue
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading event log: %w", err)
+	}
+
+	return events, nil
+}
+
+// FeatureEvents returns the events of one feature, oldest first
+func FeatureEvents(events []LogEvent, featureID string) []LogEvent {
+	var matched []LogEvent
+	for _, e := range events {
+		if e.FeatureID == featureID {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+// truncatePartialLine cuts file back to the end of its last complete line
+func truncatePartialLine(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 4096)
+	end := info.Size()
+	for pos := end; pos > 0; {
+		n := int64(len(buf))
+		if pos < n {
+			n = pos
+		}
+		pos -= n
+		if _, err := file.ReadAt(buf[:n], pos); err != nil && err != io.EOF {
+			return err
+		}
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			if pos+int64(i)+1 == end {
+				return nil
+			}
+			return file.Truncate(pos + int64(i) + 1)
+		}
+	}
+
+	// No complete line at all
+	if end == 0 {
+		return nil
+	}
+	return file.Truncate(0)
+}
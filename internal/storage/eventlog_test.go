@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestEventLog(t *testing.T) (*EventLog, string) {
+	t.Helper()
+
+	tmpDir, err := os.MkdirTemp("", "eventlog-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	path := filepath.Join(tmpDir, "events.jsonl")
+	log, err := OpenEventLog(path)
+	if err != nil {
+		t.Fatalf("OpenEventLog() error = %v", err)
+	}
+	return log, path
+}
+
+func TestEventLogAppendAndRead(t *testing.T) {
+	log, path := newTestEventLog(t)
+
+	log.Append(LogEvent{Type: "feature_created", FeatureID: "f1", Feature: &FeatureState{ID: "f1", Name: "Auth"}})
+	log.Append(LogEvent{Type: "transition", FeatureID: "f1", FromPhase: "idle", ToPhase: "specifying"})
+	log.Append(LogEvent{Type: "feature_created", FeatureID: "f2"})
+	log.Close()
+
+	// Reopening continues the sequence
+	log, err := OpenEventLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	log.Append(LogEvent{Type: "review", FeatureID: "f1", TaskID: "T001", Verdict: "approve"})
+
+	events, err := log.Events()
+	if err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events, got %d", len(events))
+	}
+	for i, e := range events {
+		if e.Seq != int64(i+1) {
+			t.Errorf("event %d seq = %d, want %d", i, e.Seq, i+1)
+		}
+		if e.Timestamp.IsZero() {
+			t.Errorf("event %d has no timestamp", i)
+		}
+	}
+	if events[0].Feature == nil || events[0].Feature.Name != "Auth" {
+		t.Errorf("feature snapshot not stored: %+v", events[0].Feature)
+	}
+
+	f1 := FeatureEvents(events, "f1")
+	if len(f1) != 3 || f1[2].Verdict != "approve" {
+		t.Errorf("FeatureEvents() = %+v", f1)
+	}
+}
+
+func TestEventLogDropsPartialLine(t *testing.T) {
+	log, path := newTestEventLog(t)
+	log.Append(LogEvent{Type: "transition", FeatureID: "f1"})
+	log.Close()
+
+	// Simulate a crash halfway through writing an event
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	file.WriteString(`{"seq":2,"type":"trans`)
+	file.Close()
+
+	if events, err := ReadEventLog(path); err != nil || len(events) != 1 {
+		t.Errorf("ReadEventLog() = %d events, %v; want the complete event only", len(events), err)
+	}
+
+	log, err := OpenEventLog(path)
+	if err != nil {
+		t.Fatalf("OpenEventLog() error = %v", err)
+	}
+	defer log.Close()
+	if err := log.Append(LogEvent{Type: "review", FeatureID: "f1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := ReadEventLog(path)
+	if err != nil {
+		t.Fatalf("ReadEventLog() error = %v", err)
+	}
+	if len(events) != 2 || events[1].Type != "review" || events[1].Seq != 2 {
+		t.Errorf("events after repair = %+v", events)
+	}
+}
+
+func TestReadEventLogRejectsDamage(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "eventlog-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "events.jsonl")
+	os.WriteFile(path, []byte("{\"seq\":1}\nnot json\n{\"seq\":3}\n"), 0644)
+
+	if _, err := ReadEventLog(path); err == nil {
+		t.Error("ReadEventLog() should fail on a damaged line in the middle of the log")
+	}
+}
+
+func TestOpenEventLogSkipsDamage(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "eventlog-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "events.jsonl")
+	os.WriteFile(path, []byte("{\"seq\":1}\nnot json\n{\"seq\":3}\n"), 0644)
+
+	log, err := OpenEventLog(path)
+	if err != nil {
+		t.Fatalf("OpenEventLog() should skip a damaged line: %v", err)
+	}
+	defer log.Close()
+
+	if err := log.Append(LogEvent{Type: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "\"seq\":4") {
+		t.Errorf("numbering should carry on from the last good event:\n%s", data)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !unix && !windows
+
+package storage
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+// lockFile opens the lock file without locking it: this platform has no
+// advisory locks, so nothing stops a second process from using the same
+// storage.
+func lockFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file: %w", err)
+	}
+	log.Printf("Warning: Storage locks are not supported on this platform; run a single Foreman per %s", path)
+	return f, nil
+}
+
+// unlockFile releases a lock taken by lockFile
+func unlockFile(f *os.File) error {
+	return f.Close()
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// SchemaVersion is the version of the storage document written by this
+// build. Files written before versioning was introduced are version 0.
+const SchemaVersion = 2
+
+// migration upgrades a raw storage document by one schema version
+type migration struct {
+	description string
+	apply       func(doc map[string]any) error
+}
+
+// migrations[i] upgrades a document from version i to version i+1. To change
+// the schema, append a migration, bump SchemaVersion and add a fixture for
+// the new version to testdata. Never edit a migration that has shipped.
+var migrations = []migration{
+	{"give feature tasks their own branches", migrateTaskBranches},
+	{"key queue entries by feature and task", migrateQueueKeys},
+}
+
+// decodeStore parses a storage document of any known version, upgrading it
+// to SchemaVersion. It returns the store and the version it was written at.
+func decodeStore(data []byte) (*Store, int, error) {
+	// Decode numbers as json.Number so durations and lease counters survive
+	// the round trip through map[string]any unchanged
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, 0, err
+	}
+
+	version, err := documentVersion(doc)
+	if err != nil {
+		return nil, 0, err
+	}
+	if version > SchemaVersion {
+		return nil, version, fmt.Errorf("schema version %d is newer than this build supports (%d)", version, SchemaVersion)
+	}
+
+	if version < SchemaVersion {
+		for v := version; v < SchemaVersion; v++ {
+			if err := migrations[v].apply(doc); err != nil {
+				return nil, version, fmt.Errorf("migrating to version %d (%s): %w", v+1, migrations[v].description, err)
+			}
+		}
+		doc["version"] = SchemaVersion
+
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, version, err
+		}
+	}
+
+	store := &Store{}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, version, err
+	}
+	return store, version, nil
+}
+
+// documentVersion returns the schema version of a raw storage document
+func documentVersion(doc map[string]any) (int, error) {
+	raw, ok := doc["version"]
+	if !ok {
+		return 0, nil
+	}
+
+	n, ok := raw.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("invalid schema version %v", raw)
+	}
+	version, err := n.Int64()
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid schema version %v", raw)
+	}
+	return int(version), nil
+}
+
+// migrateTaskBranches upgrades version 0 documents. Feature tasks used to
+// run directly on the feature branch; they now each get a branch of their
+// own that is merged back into the feature branch.
+func migrateTaskBranches(doc map[string]any) error {
+	features, _ := doc["features"].(map[string]any)
+	for _, f := range features {
+		feature, ok := f.(map[string]any)
+		if !ok {
+			return fmt.Errorf("invalid feature %v", f)
+		}
+		featureID, _ := feature["id"].(string)
+		featureBranch, _ := feature["branch"].(string)
+
+		tasks, _ := feature["tasks"].([]any)
+		for _, t := range tasks {
+			task, ok := t.(map[string]any)
+			if !ok {
+				return fmt.Errorf("invalid task in feature %s", featureID)
+			}
+			taskID, _ := task["id"].(string)
+
+			if base, _ := task["base_branch"].(string); base == "" {
+				task["base_branch"] = featureBranch
+			}
+			if branch, _ := task["branch"].(string); branch == "" || branch == featureBranch {
+				task["branch"] = fmt.Sprintf("feature/%s/%s", featureID, taskID)
+			}
+		}
+	}
+	return nil
+}
+
+// migrateQueueKeys upgrades version 1 documents. Queue entries were keyed by
+// task ID alone, which collides when features share task IDs; they are now
+// keyed by feature and task.
+func migrateQueueKeys(doc map[string]any) error {
+	queue, _ := doc["queue"].(map[string]any)
+	rekeyed := make(map[string]any, len(queue))
+	for id, e := range queue {
+		entry, ok := e.(map[string]any)
+		if !ok {
+			return fmt.Errorf("invalid queue entry %s", id)
+		}
+		key := QueueKey{TaskID: id}
+		key.FeatureID, _ = entry["feature_id"].(string)
+		if taskID, _ := entry["task_id"].(string); taskID != "" {
+			key.TaskID = taskID
+		}
+		rekeyed[key.String()] = entry
+	}
+	if queue != nil {
+		doc["queue"] = rekeyed
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrationsMatchSchemaVersion(t *testing.T) {
+	if len(migrations) != SchemaVersion {
+		t.Errorf("%d migrations for schema version %d; every version needs a migration from the one before", len(migrations), SchemaVersion)
+	}
+}
+
+// Every schema version that has shipped has a fixture in testdata, all
+// describing the same feature. Each must load into the current schema.
+func TestLoadFixtures(t *testing.T) {
+	for version := 0; version <= SchemaVersion; version++ {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			fixture, err := os.ReadFile(filepath.Join("testdata", fmt.Sprintf("store-v%d.json", version)))
+			if err != nil {
+				t.Fatalf("missing fixture for schema version %d: %v", version, err)
+			}
+
+			tmpDir, err := os.MkdirTemp("", "storage-test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tmpDir)
+
+			path := filepath.Join(tmpDir, "features.json")
+			os.WriteFile(path, fixture, 0644)
+
+			store, err := NewWithBackups(path, 0)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			defer store.Close()
+
+			feature, err := store.LoadFeature("feat-1")
+			if err != nil {
+				t.Fatalf("LoadFeature() error = %v", err)
+			}
+			if feature.Name != "Auth" || feature.Phase != "implementing" || feature.Answers["Q1"] != "No" {
+				t.Errorf("feature not loaded in full: %+v", feature)
+			}
+			if len(feature.Tasks) != 2 {
+				t.Fatalf("expected 2 tasks, got %d", len(feature.Tasks))
+			}
+			for _, task := range feature.Tasks {
+				if want := "feature/feat-1/" + task.ID; task.Branch != want {
+					t.Errorf("task %s branch = %q, want %q", task.ID, task.Branch, want)
+				}
+				if task.BaseBranch != "feature/feat-1-auth" {
+					t.Errorf("task %s base branch = %q, want the feature branch", task.ID, task.BaseBranch)
+				}
+			}
+
+			// The version 0 fixture predates the queue
+			if version > 0 {
+				key := QueueKey{FeatureID: "feat-1", TaskID: "T002"}
+				queue, err := store.QueueEntries()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(queue) != 1 || queue[0].Key() != key || queue[0].Lease != 3 {
+					t.Errorf("queue not loaded in full: %+v", queue)
+				}
+				if err := store.MarkDone(key, 3); err != nil {
+					t.Errorf("queue entry not found by its key: %v", err)
+				}
+			}
+
+			// Older files are kept as they were before migrating
+			_, err = os.Stat(path + fmt.Sprintf(".v%d", version))
+			if version < SchemaVersion && err != nil {
+				t.Errorf("expected a copy of the version %d file: %v", version, err)
+			}
+			if version == SchemaVersion && err == nil {
+				t.Error("current files should not be copied")
+			}
+
+			// The next save writes the current version
+			if err := store.SaveFeature(feature); err != nil {
+				t.Fatal(err)
+			}
+			data, _ := os.ReadFile(path)
+			if want := fmt.Sprintf(`"version": %d`, SchemaVersion); !strings.Contains(string(data), want) {
+				t.Errorf("saved file missing %s", want)
+			}
+		})
+	}
+}
+
+func TestMigrationKeepsOwnTaskBranches(t *testing.T) {
+	// Files written after tasks got their own branches, but before the
+	// schema was versioned, are version 0 too
+	data := `{"features": {"f1": {"id": "f1", "branch": "feature/f1-x", "tasks": [
+		{"id": "T001", "branch": "feature/f1/T001", "timeout": 9007199254740993}
+	]}}}`
+
+	store, version, err := decodeStore([]byte(data))
+	if err != nil {
+		t.Fatalf("decodeStore() error = %v", err)
+	}
+	if version != 0 || store.Version != SchemaVersion {
+		t.Errorf("versions = %d -> %d, want 0 -> %d", version, store.Version, SchemaVersion)
+	}
+
+	task := store.Features["f1"].Tasks[0]
+	if task.Branch != "feature/f1/T001" || task.BaseBranch != "feature/f1-x" {
+		t.Errorf("task branches = %q from %q", task.Branch, task.BaseBranch)
+	}
+	if task.Timeout != 9007199254740993 {
+		t.Errorf("timeout = %d, numbers must survive migration exactly", task.Timeout)
+	}
+}
+
+func TestDecodeStoreRejectsNewerVersions(t *testing.T) {
+	data := fmt.Sprintf(`{"version": %d, "features": {}}`, SchemaVersion+1)
+	if _, _, err := decodeStore([]byte(data)); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("decodeStore() error = %v, want a newer-version error", err)
+	}
+
+	if _, _, err := decodeStore([]byte(`{"version": "one"}`)); err == nil {
+		t.Error("decodeStore() should reject an invalid version")
+	}
+}
@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// QueueState is the lifecycle state of a queue entry
+type QueueState string
+
+const (
+	QueueQueued  QueueState = "queued"  // waiting for a worker
+	QueueLeased  QueueState = "leased"  // claimed by a worker, not yet started
+	QueueRunning QueueState = "running" // being executed
+	QueueDone    QueueState = "done"    // finished, kept as a record of the run
+)
+
+// QueueKey identifies a queue entry. SpecKit task IDs repeat across
+// features, so entries are keyed by feature as well as task.
+type QueueKey struct {
+	FeatureID string
+	TaskID    string
+}
+
+// String returns "feature/task", or the task ID alone for tasks that do not
+// belong to a feature
+func (k QueueKey) String() string {
+	if k.FeatureID == "" {
+		return k.TaskID
+	}
+	return k.FeatureID + "/" + k.TaskID
+}
+
+// QueueEntry tracks one task through the persistent task queue
+type QueueEntry struct {
+	TaskID     string     `json:"task_id"`
+	FeatureID  string     `json:"feature_id,omitempty"`
+	State      QueueState `json:"state"`
+	Lease      int        `json:"lease"` // incremented each time the entry is leased
+	LeaseUntil time.Time  `json:"lease_until,omitempty"`
+	EnqueuedAt time.Time  `json:"enqueued_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// Task holds the full state of tasks that do not belong to a feature,
+	// since nothing else persists them
+	Task *TaskState `json:"task,omitempty"`
+}
+
+// Key returns the key identifying the entry
+func (e QueueEntry) Key() QueueKey {
+	return QueueKey{FeatureID: e.FeatureID, TaskID: e.TaskID}
+}
+
+// Enqueue adds a task to the queue. Enqueuing a task that is already queued
+// or leased is a no-op and reports false; a running or finished task is
+// queued again.
+func (fs *FileStorage) Enqueue(entry QueueEntry) (bool, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.store.Queue == nil {
+		fs.store.Queue = make(map[string]*QueueEntry)
+	}
+
+	now := time.Now()
+	key := entry.Key().String()
+	existing, ok := fs.store.Queue[key]
+	if ok && (existing.State == QueueQueued || existing.State == QueueLeased) {
+		return false, nil
+	}
+
+	if ok {
+		entry.Lease = existing.Lease
+	}
+	entry.State = QueueQueued
+	entry.LeaseUntil = time.Time{}
+	entry.EnqueuedAt = now
+	entry.UpdatedAt = now
+	fs.store.Queue[key] = &entry
+
+	return true, fs.save()
+}
+
+// Lease claims the longest-waiting queued entry for d. It returns nil when
+// nothing is queued. The returned entry's Lease identifies this claim in
+// later calls.
+func (fs *FileStorage) Lease(d time.Duration) (*QueueEntry, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	var next *QueueEntry
+	for _, entry := range fs.store.Queue {
+		if entry.State != QueueQueued {
+			continue
+		}
+		if next == nil || entry.EnqueuedAt.Before(next.EnqueuedAt) {
+			next = entry
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+
+	now := time.Now()
+	next.State = QueueLeased
+	next.Lease++
+	next.LeaseUntil = now.Add(d)
+	next.UpdatedAt = now
+
+	leased := *next
+	return &leased, fs.save()
+}
+
+// MarkRunning records that the worker holding the lease started the task,
+// extending the lease by d.
+func (fs *FileStorage) MarkRunning(key QueueKey, lease int, d time.Duration) error {
+	return fs.updateLease(key, lease, func(entry *QueueEntry, now time.Time) {
+		entry.State = QueueRunning
+		entry.LeaseUntil = now.Add(d)
+	})
+}
+
+// RenewLease extends a held lease by d. Workers call it periodically so
+// that long-running tasks are not mistaken for crashed ones.
+func (fs *FileStorage) RenewLease(key QueueKey, lease int, d time.Duration) error {
+	return fs.updateLease(key, lease, func(entry *QueueEntry, now time.Time) {
+		entry.LeaseUntil = now.Add(d)
+	})
+}
+
+// MarkDone finishes the run held by lease. It does nothing if the task was
+// queued again in the meantime, for example to retry it.
+func (fs *FileStorage) MarkDone(key QueueKey, lease int) error {
+	return fs.updateLease(key, lease, func(entry *QueueEntry, now time.Time) {
+		entry.State = QueueDone
+		entry.LeaseUntil = time.Time{}
+	})
+}
+
+// updateLease applies update to the entry if lease is still the current,
+// held lease on it.
+func (fs *FileStorage) updateLease(key QueueKey, lease int, update func(*QueueEntry, time.Time)) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	entry, ok := fs.store.Queue[key.String()]
+	if !ok {
+		return fmt.Errorf("task %s is not queued", key)
+	}
+	if entry.Lease != lease || (entry.State != QueueLeased && entry.State != QueueRunning) {
+		return nil
+	}
+
+	now := time.Now()
+	update(entry, now)
+	entry.UpdatedAt = now
+
+	return fs.save()
+}
+
+// ExpireLeases returns entries whose lease ran out before now to the queue,
+// so tasks held by crashed workers run again. It returns their keys.
+func (fs *FileStorage) ExpireLeases(now time.Time) ([]QueueKey, error) {
+	return fs.requeue(func(entry *QueueEntry) bool {
+		return entry.LeaseUntil.Before(now)
+	})
+}
+
+// RequeueLeased returns every leased or running entry to the queue. It is
+// meant for startup, when no worker can still hold a lease.
+func (fs *FileStorage) RequeueLeased() ([]QueueKey, error) {
+	return fs.requeue(func(*QueueEntry) bool { return true })
+}
+
+func (fs *FileStorage) requeue(match func(*QueueEntry) bool) ([]QueueKey, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	var keys []QueueKey
+	now := time.Now()
+	for _, entry := range fs.store.Queue {
+		if entry.State != QueueLeased && entry.State != QueueRunning {
+			continue
+		}
+		if !match(entry) {
+			continue
+		}
+		entry.State = QueueQueued
+		entry.LeaseUntil = time.Time{}
+		entry.UpdatedAt = now
+		keys = append(keys, entry.Key())
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	return keys, fs.save()
+}
+
+// QueueEntries returns a snapshot of every queue entry, finished or not
+func (fs *FileStorage) QueueEntries() ([]QueueEntry, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	entries := make([]QueueEntry, 0, len(fs.store.Queue))
+	for _, entry := range fs.store.Queue {
+		entries = append(entries, *entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// forEachBackend runs fn against every storage backend
+func forEachBackend(t *testing.T, fn func(t *testing.T, store Storage)) {
+	tmpDir, err := os.MkdirTemp("", "storage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sqlite, err := NewSQLite(filepath.Join(tmpDir, "foreman.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer sqlite.Close()
+
+	t.Run("file", func(t *testing.T) { fn(t, NewMemory()) })
+	t.Run("sqlite", func(t *testing.T) { fn(t, sqlite) })
+}
+
+func entries(t *testing.T, store Storage) []QueueEntry {
+	t.Helper()
+	entries, err := store.QueueEntries()
+	if err != nil {
+		t.Fatalf("QueueEntries() error = %v", err)
+	}
+	return entries
+}
+
+func TestEnqueueIsIdempotent(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Storage) {
+		queued, err := store.Enqueue(QueueEntry{TaskID: "t1", FeatureID: "f1"})
+		if err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+		if !queued {
+			t.Error("first Enqueue() should queue the task")
+		}
+
+		queued, err = store.Enqueue(QueueEntry{TaskID: "t1", FeatureID: "f1"})
+		if err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+		if queued {
+			t.Error("second Enqueue() should be a no-op")
+		}
+
+		if got := entries(t, store); len(got) != 1 {
+			t.Errorf("expected 1 queue entry, got %d", len(got))
+		}
+	})
+}
+
+func TestLeaseOrder(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Storage) {
+		for _, id := range []string{"t1", "t2"} {
+			if _, err := store.Enqueue(QueueEntry{TaskID: id}); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		for _, want := range []string{"t1", "t2"} {
+			entry, err := store.Lease(time.Minute)
+			if err != nil {
+				t.Fatalf("Lease() error = %v", err)
+			}
+			if entry == nil || entry.TaskID != want {
+				t.Fatalf("Lease() = %+v, want task %s", entry, want)
+			}
+			if entry.State != QueueLeased {
+				t.Errorf("leased entry state = %s, want %s", entry.State, QueueLeased)
+			}
+		}
+
+		entry, err := store.Lease(time.Minute)
+		if err != nil {
+			t.Fatalf("Lease() error = %v", err)
+		}
+		if entry != nil {
+			t.Errorf("Lease() on an empty queue = %+v, want nil", entry)
+		}
+	})
+}
+
+func TestQueueLifecycle(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Storage) {
+		if _, err := store.Enqueue(QueueEntry{TaskID: "t1"}); err != nil {
+			t.Fatal(err)
+		}
+		entry, err := store.Lease(time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// A leased task is still waiting to run
+		if queued, _ := store.Enqueue(QueueEntry{TaskID: "t1"}); queued {
+			t.Error("Enqueue() of a leased task should be a no-op")
+		}
+
+		if err := store.MarkRunning(entry.Key(), entry.Lease, time.Minute); err != nil {
+			t.Fatalf("MarkRunning() error = %v", err)
+		}
+		if got := entries(t, store)[0].State; got != QueueRunning {
+			t.Errorf("state = %s, want %s", got, QueueRunning)
+		}
+
+		if err := store.MarkDone(entry.Key(), entry.Lease); err != nil {
+			t.Fatalf("MarkDone() error = %v", err)
+		}
+		if got := entries(t, store)[0].State; got != QueueDone {
+			t.Errorf("state = %s, want %s", got, QueueDone)
+		}
+
+		// A finished task can be queued again
+		if queued, _ := store.Enqueue(QueueEntry{TaskID: "t1"}); !queued {
+			t.Error("Enqueue() of a finished task should queue it again")
+		}
+	})
+}
+
+func TestMarkDoneAfterRequeue(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Storage) {
+		store.Enqueue(QueueEntry{TaskID: "t1"})
+		first, _ := store.Lease(time.Minute)
+		store.MarkRunning(first.Key(), first.Lease, time.Minute)
+
+		// The worker retries the task, and another worker picks it up
+		store.Enqueue(QueueEntry{TaskID: "t1"})
+		second, _ := store.Lease(time.Minute)
+		if second == nil || second.Lease == first.Lease {
+			t.Fatalf("expected a new lease, got %+v", second)
+		}
+
+		// The first worker finishing must not end the second run
+		if err := store.MarkDone(first.Key(), first.Lease); err != nil {
+			t.Fatalf("MarkDone() error = %v", err)
+		}
+		if got := entries(t, store)[0].State; got != QueueLeased {
+			t.Errorf("state = %s, want %s", got, QueueLeased)
+		}
+	})
+}
+
+func TestQueueKeysByFeature(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Storage) {
+		// SpecKit numbers tasks per feature, so two features share T-001
+		for _, featureID := range []string{"f1", "f2"} {
+			if queued, err := store.Enqueue(QueueEntry{TaskID: "T-001", FeatureID: featureID}); err != nil || !queued {
+				t.Fatalf("Enqueue() for %s = %v, %v", featureID, queued, err)
+			}
+		}
+		if got := entries(t, store); len(got) != 2 {
+			t.Fatalf("expected 2 queue entries, got %d", len(got))
+		}
+
+		first, _ := store.Lease(time.Minute)
+		second, _ := store.Lease(time.Minute)
+		if first == nil || second == nil || first.FeatureID == second.FeatureID {
+			t.Fatalf("expected one lease per feature, got %+v and %+v", first, second)
+		}
+
+		// Finishing one feature's task leaves the other's running
+		if err := store.MarkRunning(second.Key(), second.Lease, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.MarkDone(first.Key(), first.Lease); err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range entries(t, store) {
+			want := QueueRunning
+			if entry.FeatureID == first.FeatureID {
+				want = QueueDone
+			}
+			if entry.State != want {
+				t.Errorf("%s state = %s, want %s", entry.Key(), entry.State, want)
+			}
+		}
+	})
+}
+
+func TestExpireLeases(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Storage) {
+		store.Enqueue(QueueEntry{TaskID: "t1"})
+		store.Enqueue(QueueEntry{TaskID: "t2"})
+		short, _ := store.Lease(time.Millisecond)
+		long, _ := store.Lease(time.Hour)
+
+		ids, err := store.ExpireLeases(time.Now().Add(time.Second))
+		if err != nil {
+			t.Fatalf("ExpireLeases() error = %v", err)
+		}
+		if len(ids) != 1 || ids[0] != short.Key() {
+			t.Errorf("ExpireLeases() = %v, want [%s]", ids, short.TaskID)
+		}
+
+		for _, entry := range entries(t, store) {
+			want := QueueLeased
+			if entry.TaskID == short.TaskID {
+				want = QueueQueued
+			}
+			if entry.State != want {
+				t.Errorf("task %s state = %s, want %s", entry.TaskID, entry.State, want)
+			}
+		}
+
+		// Renewing keeps a lease alive
+		if err := store.RenewLease(long.Key(), long.Lease, time.Hour); err != nil {
+			t.Fatalf("RenewLease() error = %v", err)
+		}
+		if ids, _ := store.ExpireLeases(time.Now().Add(30 * time.Minute)); len(ids) != 0 {
+			t.Errorf("renewed lease expired: %v", ids)
+		}
+	})
+}
+
+func TestRequeueLeasedAfterRestart(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "storage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	backends := map[string]func() (Storage, error){
+		"file":   func() (Storage, error) { return New(filepath.Join(tmpDir, "features.json")) },
+		"sqlite": func() (Storage, error) { return NewSQLite(filepath.Join(tmpDir, "foreman.db")) },
+	}
+
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			store1, err := open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			store1.Enqueue(QueueEntry{TaskID: "running"})
+			store1.Enqueue(QueueEntry{TaskID: "waiting", Task: &TaskState{ID: "waiting", Spec: "Do it"}})
+			entry, _ := store1.Lease(time.Hour)
+			store1.MarkRunning(entry.Key(), entry.Lease, time.Hour)
+			store1.Close()
+
+			store2, err := open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store2.Close()
+
+			ids, err := store2.RequeueLeased()
+			if err != nil {
+				t.Fatalf("RequeueLeased() error = %v", err)
+			}
+			if len(ids) != 1 || ids[0].TaskID != "running" {
+				t.Errorf("RequeueLeased() = %v, want [running]", ids)
+			}
+
+			for _, entry := range entries(t, store2) {
+				if entry.State != QueueQueued {
+					t.Errorf("task %s state = %s, want %s", entry.TaskID, entry.State, QueueQueued)
+				}
+				if entry.TaskID == "waiting" && (entry.Task == nil || entry.Task.Spec != "Do it") {
+					t.Errorf("standalone task state not persisted: %+v", entry.Task)
+				}
+			}
+		})
+	}
+}
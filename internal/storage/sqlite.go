@@ -0,0 +1,555 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the pure-Go "sqlite" driver
+)
+
+// sqliteSchema is the first version of the schema. Databases created
+// before the schema was versioned already have these tables, hence IF NOT
+// EXISTS.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS features (
+	id                TEXT PRIMARY KEY,
+	name              TEXT NOT NULL,
+	description       TEXT NOT NULL,
+	branch            TEXT NOT NULL,
+	pr_number         INTEGER NOT NULL DEFAULT 0,
+	pr_url            TEXT NOT NULL DEFAULT '',
+	spec_dir          TEXT NOT NULL DEFAULT '',
+	phase             TEXT NOT NULL,
+	current_task      TEXT NOT NULL DEFAULT '',
+	spec              TEXT NOT NULL DEFAULT 'null',
+	plan              TEXT NOT NULL DEFAULT 'null',
+	task_index        INTEGER NOT NULL DEFAULT 0,
+	pending_questions TEXT NOT NULL DEFAULT 'null',
+	answers           TEXT NOT NULL DEFAULT 'null',
+	tech_stack        TEXT NOT NULL DEFAULT '',
+	constraints       TEXT NOT NULL DEFAULT '',
+	created_at        TEXT NOT NULL,
+	updated_at        TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS tasks (
+	feature_id    TEXT NOT NULL REFERENCES features(id) ON DELETE CASCADE,
+	id            TEXT NOT NULL,
+	position      INTEGER NOT NULL,
+	spec          TEXT NOT NULL,
+	context       TEXT NOT NULL DEFAULT '',
+	status        TEXT NOT NULL,
+	branch        TEXT NOT NULL,
+	base_branch   TEXT NOT NULL DEFAULT '',
+	worktree_path TEXT NOT NULL DEFAULT '',
+	agent_name    TEXT NOT NULL,
+	timeout       INTEGER NOT NULL DEFAULT 0,
+	is_parallel   INTEGER NOT NULL DEFAULT 0,
+	attempt       INTEGER NOT NULL DEFAULT 0,
+	created_at    TEXT NOT NULL,
+	dependencies  TEXT NOT NULL DEFAULT 'null',
+	file_paths    TEXT NOT NULL DEFAULT 'null',
+	metadata      TEXT NOT NULL DEFAULT 'null',
+	PRIMARY KEY (feature_id, id)
+);
+
+CREATE TABLE IF NOT EXISTS events (
+	feature_id TEXT NOT NULL REFERENCES features(id) ON DELETE CASCADE,
+	seq        INTEGER NOT NULL,
+	timestamp  TEXT NOT NULL,
+	from_phase TEXT NOT NULL,
+	to_phase   TEXT NOT NULL,
+	message    TEXT NOT NULL DEFAULT '',
+	actor      TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (feature_id, seq)
+);
+
+CREATE TABLE IF NOT EXISTS reviews (
+	id              INTEGER PRIMARY KEY AUTOINCREMENT,
+	feature_id      TEXT NOT NULL DEFAULT '',
+	task_id         TEXT NOT NULL,
+	attempt         INTEGER NOT NULL DEFAULT 0,
+	verdict         TEXT NOT NULL,
+	summary         TEXT NOT NULL DEFAULT '',
+	blocking_issues TEXT NOT NULL DEFAULT 'null',
+	suggestions     TEXT NOT NULL DEFAULT 'null',
+	created_at      TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS reviews_by_task ON reviews (feature_id, task_id);
+
+CREATE TABLE IF NOT EXISTS usage (
+	id            INTEGER PRIMARY KEY AUTOINCREMENT,
+	feature_id    TEXT NOT NULL DEFAULT '',
+	task_id       TEXT NOT NULL,
+	agent         TEXT NOT NULL,
+	attempt       INTEGER NOT NULL DEFAULT 0,
+	input_tokens  INTEGER NOT NULL DEFAULT 0,
+	output_tokens INTEGER NOT NULL DEFAULT 0,
+	cost_usd      REAL NOT NULL DEFAULT 0,
+	turns         INTEGER NOT NULL DEFAULT 0,
+	created_at    TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS usage_by_time ON usage (created_at);
+
+CREATE TABLE IF NOT EXISTS queue (
+	task_id     TEXT PRIMARY KEY,
+	feature_id  TEXT NOT NULL DEFAULT '',
+	state       TEXT NOT NULL,
+	lease       INTEGER NOT NULL DEFAULT 0,
+	lease_until TEXT NOT NULL DEFAULT '',
+	enqueued_at TEXT NOT NULL,
+	updated_at  TEXT NOT NULL,
+	task        TEXT NOT NULL DEFAULT 'null'
+);
+`
+
+// SQLiteSchemaVersion is the user_version of databases written by this
+// build. Databases created before the schema was versioned are version 0.
+const SQLiteSchemaVersion = 3
+
+// sqliteMigration upgrades a database by one schema version
+type sqliteMigration struct {
+	description string
+	apply       func(tx *sql.Tx) error
+}
+
+// sqliteMigrations[i] upgrades a database from version i to version i+1, as
+// migrations does for the JSON store. To change the schema, append a
+// migration and bump SQLiteSchemaVersion. Never edit a migration that has
+// shipped.
+var sqliteMigrations = []sqliteMigration{
+	{"create the initial schema", execSQL(sqliteSchema)},
+	{"key queue entries by feature and task", execSQL(sqliteQueueKeys)},
+	{"record budget approvals", execSQL(sqliteBudgetApprovals)},
+}
+
+// sqliteQueueKeys re-creates the queue keyed by feature as well as task,
+// since SpecKit task IDs repeat across features. SQLite cannot change a
+// primary key in place.
+const sqliteQueueKeys = `
+CREATE TABLE queue_v2 (
+	feature_id  TEXT NOT NULL DEFAULT '',
+	task_id     TEXT NOT NULL,
+	state       TEXT NOT NULL,
+	lease       INTEGER NOT NULL DEFAULT 0,
+	lease_until TEXT NOT NULL DEFAULT '',
+	enqueued_at TEXT NOT NULL,
+	updated_at  TEXT NOT NULL,
+	task        TEXT NOT NULL DEFAULT 'null',
+	PRIMARY KEY (feature_id, task_id)
+);
+INSERT INTO queue_v2 (feature_id, task_id, state, lease, lease_until, enqueued_at, updated_at, task)
+	SELECT feature_id, task_id, state, lease, lease_until, enqueued_at, updated_at, task FROM queue ORDER BY rowid;
+DROP TABLE queue;
+ALTER TABLE queue_v2 RENAME TO queue;
+`
+
+// sqliteBudgetApprovals keeps the approvals to go over a budget across
+// restarts
+const sqliteBudgetApprovals = `
+CREATE TABLE budget_approvals (
+	scope     TEXT PRIMARY KEY,
+	approvals INTEGER NOT NULL DEFAULT 0
+);
+`
+
+func execSQL(query string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(query)
+		return err
+	}
+}
+
+// migrateSQLite upgrades db to SQLiteSchemaVersion, one version per
+// transaction, stamping each version in PRAGMA user_version
+func migrateSQLite(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+	if version > SQLiteSchemaVersion {
+		return fmt.Errorf("schema version %d is newer than this build supports (%d)", version, SQLiteSchemaVersion)
+	}
+
+	for v := version; v < SQLiteSchemaVersion; v++ {
+		m := sqliteMigrations[v]
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("starting transaction: %w", err)
+		}
+		if err := m.apply(tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migrating to version %d (%s): %w", v+1, m.description, err)
+		}
+		// PRAGMA does not take bound parameters
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, v+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("stamping version %d: %w", v+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migrating to version %d: %w", v+1, err)
+		}
+	}
+	return nil
+}
+
+// SQLiteStorage persists state in an embedded SQLite database. Saving a
+// feature only appends its new events instead of rewriting its history.
+type SQLiteStorage struct {
+	db *sql.DB
+}
+
+// NewSQLite opens (creating if needed) the SQLite database at path
+func NewSQLite(path string) (*SQLiteStorage, error) {
+	dsn, err := sqliteDSN(path)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+
+	// A single connection serialises writers, which SQLite requires anyway
+	db.SetMaxOpenConns(1)
+
+	if err := migrateSQLite(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteStorage{db: db}, nil
+}
+
+// sqliteDSN builds the URI the database at path is opened with. The path
+// is escaped, so names containing ?, # or % are taken literally.
+func sqliteDSN(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("resolving database path: %w", err)
+	}
+	// Windows paths become /C:/..., as file URIs expect
+	abs = filepath.ToSlash(abs)
+	if !strings.HasPrefix(abs, "/") {
+		abs = "/" + abs
+	}
+
+	query := url.Values{"_pragma": {"foreign_keys(1)", "busy_timeout(5000)", "journal_mode(WAL)"}}
+	u := url.URL{Scheme: "file", Path: abs, RawQuery: query.Encode()}
+	return u.String(), nil
+}
+
+// Close closes the database
+func (s *SQLiteStorage) Close() error {
+	return s.db.Close()
+}
+
+// SaveFeature persists a feature state
+func (s *SQLiteStorage) SaveFeature(state *FeatureState) error {
+	state.UpdatedAt = time.Now()
+
+	return s.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO features (id, name, description, branch, pr_number, pr_url, spec_dir, phase,
+				current_task, spec, plan, task_index, pending_questions, answers, tech_stack, constraints,
+				created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET
+				name = excluded.name, description = excluded.description, branch = excluded.branch,
+				pr_number = excluded.pr_number, pr_url = excluded.pr_url, spec_dir = excluded.spec_dir,
+				phase = excluded.phase, current_task = excluded.current_task, spec = excluded.spec,
+				plan = excluded.plan, task_index = excluded.task_index,
+				pending_questions = excluded.pending_questions, answers = excluded.answers,
+				tech_stack = excluded.tech_stack, constraints = excluded.constraints,
+				created_at = excluded.created_at, updated_at = excluded.updated_at`,
+			state.ID, state.Name, state.Description, state.Branch, state.PRNumber, state.PRURL,
+			state.SpecDir, state.Phase, state.CurrentTask, toJSON(state.Spec), toJSON(state.Plan),
+			state.TaskIndex, toJSON(state.PendingQuestions), toJSON(state.Answers), state.TechStack,
+			state.Constraints, formatTime(state.CreatedAt), formatTime(state.UpdatedAt))
+		if err != nil {
+			return fmt.Errorf("saving feature %s: %w", state.ID, err)
+		}
+
+		if _, err := tx.Exec(`DELETE FROM tasks WHERE feature_id = ?`, state.ID); err != nil {
+			return fmt.Errorf("saving tasks of %s: %w", state.ID, err)
+		}
+		for i, t := range state.Tasks {
+			_, err := tx.Exec(`
+				INSERT INTO tasks (feature_id, id, position, spec, context, status, branch, base_branch,
+					worktree_path, agent_name, timeout, is_parallel, attempt, created_at, dependencies,
+					file_paths, metadata)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				state.ID, t.ID, i, t.Spec, t.Context, t.Status, t.Branch, t.BaseBranch, t.WorktreePath,
+				t.AgentName, int64(t.Timeout), t.IsParallel, t.Attempt, formatTime(t.CreatedAt),
+				toJSON(t.Dependencies), toJSON(t.FilePaths), toJSON(t.Metadata))
+			if err != nil {
+				return fmt.Errorf("saving task %s: %w", t.ID, err)
+			}
+		}
+
+		return saveEvents(tx, state.ID, state.Events)
+	})
+}
+
+// saveEvents appends the events not yet stored. Events are only ever
+// appended, so if the stored history is longer than the given one it was
+// replaced and is rewritten.
+func saveEvents(tx *sql.Tx, featureID string, events []EventState) error {
+	var stored int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM events WHERE feature_id = ?`, featureID).Scan(&stored); err != nil {
+		return fmt.Errorf("counting events of %s: %w", featureID, err)
+	}
+	if stored > len(events) {
+		if _, err := tx.Exec(`DELETE FROM events WHERE feature_id = ?`, featureID); err != nil {
+			return fmt.Errorf("clearing events of %s: %w", featureID, err)
+		}
+		stored = 0
+	}
+
+	for i := stored; i < len(events); i++ {
+		e := events[i]
+		_, err := tx.Exec(`
+			INSERT INTO events (feature_id, seq, timestamp, from_phase, to_phase, message, actor)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			featureID, i, formatTime(e.Timestamp), e.FromPhase, e.ToPhase, e.Message, e.Actor)
+		if err != nil {
+			return fmt.Errorf("saving event of %s: %w", featureID, err)
+		}
+	}
+	return nil
+}
+
+// LoadFeature retrieves a feature state
+func (s *SQLiteStorage) LoadFeature(id string) (*FeatureState, error) {
+	states, err := s.loadFeatures(`WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, fmt.Errorf("feature %s not found", id)
+	}
+	return states[0], nil
+}
+
+// LoadAllFeatures retrieves all feature states
+func (s *SQLiteStorage) LoadAllFeatures() ([]*FeatureState, error) {
+	return s.loadFeatures("")
+}
+
+func (s *SQLiteStorage) loadFeatures(where string, args ...interface{}) ([]*FeatureState, error) {
+	rows, err := s.db.Query(`
+		SELECT id, name, description, branch, pr_number, pr_url, spec_dir, phase, current_task, spec,
+			plan, task_index, pending_questions, answers, tech_stack, constraints, created_at, updated_at
+		FROM features `+where+` ORDER BY created_at`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("loading features: %w", err)
+	}
+	defer rows.Close()
+
+	var states []*FeatureState
+	for rows.Next() {
+		var st FeatureState
+		var spec, plan, questions, answers, created, updated string
+		err := rows.Scan(&st.ID, &st.Name, &st.Description, &st.Branch, &st.PRNumber, &st.PRURL,
+			&st.SpecDir, &st.Phase, &st.CurrentTask, &spec, &plan, &st.TaskIndex, &questions, &answers,
+			&st.TechStack, &st.Constraints, &created, &updated)
+		if err != nil {
+			return nil, fmt.Errorf("reading feature: %w", err)
+		}
+		if err := fromJSON(spec, &st.Spec, plan, &st.Plan, questions, &st.PendingQuestions, answers, &st.Answers); err != nil {
+			return nil, fmt.Errorf("reading feature %s: %w", st.ID, err)
+		}
+		st.CreatedAt, st.UpdatedAt = parseTime(created), parseTime(updated)
+		states = append(states, &st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("loading features: %w", err)
+	}
+	// The single connection must be free for the queries below
+	rows.Close()
+
+	for _, st := range states {
+		if st.Tasks, err = s.loadTasks(st.ID); err != nil {
+			return nil, err
+		}
+		if st.Events, err = s.loadEvents(st.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return states, nil
+}
+
+func (s *SQLiteStorage) loadTasks(featureID string) ([]TaskState, error) {
+	rows, err := s.db.Query(`
+		SELECT id, spec, context, status, branch, base_branch, worktree_path, agent_name, timeout,
+			is_parallel, attempt, created_at, dependencies, file_paths, metadata
+		FROM tasks WHERE feature_id = ? ORDER BY position`, featureID)
+	if err != nil {
+		return nil, fmt.Errorf("loading tasks of %s: %w", featureID, err)
+	}
+	defer rows.Close()
+
+	var tasks []TaskState
+	for rows.Next() {
+		t := TaskState{FeatureID: featureID}
+		var timeout int64
+		var created, deps, paths, metadata string
+		err := rows.Scan(&t.ID, &t.Spec, &t.Context, &t.Status, &t.Branch, &t.BaseBranch,
+			&t.WorktreePath, &t.AgentName, &timeout, &t.IsParallel, &t.Attempt, &created, &deps,
+			&paths, &metadata)
+		if err != nil {
+			return nil, fmt.Errorf("reading task of %s: %w", featureID, err)
+		}
+		if err := fromJSON(deps, &t.Dependencies, paths, &t.FilePaths, metadata, &t.Metadata); err != nil {
+			return nil, fmt.Errorf("reading task %s: %w", t.ID, err)
+		}
+		t.Timeout = time.Duration(timeout)
+		t.CreatedAt = parseTime(created)
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+func (s *SQLiteStorage) loadEvents(featureID string) ([]EventState, error) {
+	rows, err := s.db.Query(`
+		SELECT timestamp, from_phase, to_phase, message, actor
+		FROM events WHERE feature_id = ? ORDER BY seq`, featureID)
+	if err != nil {
+		return nil, fmt.Errorf("loading events of %s: %w", featureID, err)
+	}
+	defer rows.Close()
+
+	var events []EventState
+	for rows.Next() {
+		var e EventState
+		var timestamp string
+		if err := rows.Scan(&timestamp, &e.FromPhase, &e.ToPhase, &e.Message, &e.Actor); err != nil {
+			return nil, fmt.Errorf("reading event of %s: %w", featureID, err)
+		}
+		e.Timestamp = parseTime(timestamp)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// DeleteFeature removes a feature, its tasks, events and reviews
+func (s *SQLiteStorage) DeleteFeature(id string) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM reviews WHERE feature_id = ?`, id); err != nil {
+			return fmt.Errorf("deleting reviews of %s: %w", id, err)
+		}
+		if _, err := tx.Exec(`DELETE FROM features WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("deleting feature %s: %w", id, err)
+		}
+		return nil
+	})
+}
+
+// SaveReview records the result of one review of a task
+func (s *SQLiteStorage) SaveReview(review *ReviewState) error {
+	if review.CreatedAt.IsZero() {
+		review.CreatedAt = time.Now()
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO reviews (feature_id, task_id, attempt, verdict, summary, blocking_issues,
+			suggestions, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		review.FeatureID, review.TaskID, review.Attempt, review.Verdict, review.Summary,
+		toJSON(review.BlockingIssues), toJSON(review.Suggestions), formatTime(review.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("saving review of %s: %w", review.TaskID, err)
+	}
+	return nil
+}
+
+// LoadReviews returns a task's reviews, oldest first
+func (s *SQLiteStorage) LoadReviews(featureID, taskID string) ([]*ReviewState, error) {
+	rows, err := s.db.Query(`
+		SELECT attempt, verdict, summary, blocking_issues, suggestions, created_at
+		FROM reviews WHERE feature_id = ? AND task_id = ? ORDER BY id`, featureID, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("loading reviews of %s: %w", taskID, err)
+	}
+	defer rows.Close()
+
+	var reviews []*ReviewState
+	for rows.Next() {
+		r := &ReviewState{FeatureID: featureID, TaskID: taskID}
+		var blocking, suggestions, created string
+		if err := rows.Scan(&r.Attempt, &r.Verdict, &r.Summary, &blocking, &suggestions, &created); err != nil {
+			return nil, fmt.Errorf("reading review of %s: %w", taskID, err)
+		}
+		if err := fromJSON(blocking, &r.BlockingIssues, suggestions, &r.Suggestions); err != nil {
+			return nil, fmt.Errorf("reading review of %s: %w", taskID, err)
+		}
+		r.CreatedAt = parseTime(created)
+		reviews = append(reviews, r)
+	}
+	return reviews, rows.Err()
+}
+
+func (s *SQLiteStorage) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
+
+// toJSON encodes a column value. Nil values encode as null so that they
+// decode back to nil.
+func toJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "null"
+	}
+	return string(data)
+}
+
+// fromJSON decodes pairs of column values and destinations
+func fromJSON(pairs ...interface{}) error {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		data, ok := pairs[i].(string)
+		if !ok {
+			return errors.New("fromJSON: column value is not a string")
+		}
+		if err := json.Unmarshal([]byte(data), pairs[i+1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqliteTime is a fixed-width UTC layout, so stored times sort and compare
+// correctly as text
+const sqliteTime = "2006-01-02T15:04:05.000000000Z"
+
+// formatTime stores the zero time as ""
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(sqliteTime)
+}
+
+func parseTime(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+	t, _ := time.Parse(sqliteTime, s)
+	return t
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Enqueue adds a task to the queue. Enqueuing a task that is already queued
+// or leased is a no-op and reports false; a running or finished task is
+// queued again.
+func (s *SQLiteStorage) Enqueue(entry QueueEntry) (bool, error) {
+	queued := false
+	err := s.withTx(func(tx *sql.Tx) error {
+		var state string
+		var lease int
+		err := tx.QueryRow(`SELECT state, lease FROM queue WHERE feature_id = ? AND task_id = ?`,
+			entry.FeatureID, entry.TaskID).Scan(&state, &lease)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+		case err != nil:
+			return fmt.Errorf("reading queue entry %s: %w", entry.Key(), err)
+		case QueueState(state) == QueueQueued || QueueState(state) == QueueLeased:
+			return nil
+		}
+
+		now := formatTime(time.Now())
+		_, err = tx.Exec(`
+			INSERT INTO queue (feature_id, task_id, state, lease, lease_until, enqueued_at, updated_at, task)
+			VALUES (?, ?, ?, ?, '', ?, ?, ?)
+			ON CONFLICT (feature_id, task_id) DO UPDATE SET
+				state = excluded.state, lease_until = '',
+				enqueued_at = excluded.enqueued_at, updated_at = excluded.updated_at, task = excluded.task`,
+			entry.FeatureID, entry.TaskID, QueueQueued, lease, now, now, toJSON(entry.Task))
+		if err != nil {
+			return fmt.Errorf("queueing task %s: %w", entry.Key(), err)
+		}
+		queued = true
+		return nil
+	})
+	return queued, err
+}
+
+// Lease claims the longest-waiting queued entry for d. It returns nil when
+// nothing is queued. The returned entry's Lease identifies this claim in
+// later calls.
+func (s *SQLiteStorage) Lease(d time.Duration) (*QueueEntry, error) {
+	var leased *QueueEntry
+	err := s.withTx(func(tx *sql.Tx) error {
+		entries, err := queryQueue(tx, `WHERE state = ? ORDER BY enqueued_at, rowid LIMIT 1`, QueueQueued)
+		if err != nil || len(entries) == 0 {
+			return err
+		}
+
+		entry := entries[0]
+		now := time.Now()
+		entry.State = QueueLeased
+		entry.Lease++
+		entry.LeaseUntil = now.Add(d)
+		entry.UpdatedAt = now
+
+		_, err = tx.Exec(`UPDATE queue SET state = ?, lease = ?, lease_until = ?, updated_at = ?
+			WHERE feature_id = ? AND task_id = ?`,
+			entry.State, entry.Lease, formatTime(entry.LeaseUntil), formatTime(now), entry.FeatureID, entry.TaskID)
+		if err != nil {
+			return fmt.Errorf("leasing task %s: %w", entry.Key(), err)
+		}
+		leased = &entry
+		return nil
+	})
+	return leased, err
+}
+
+// MarkRunning records that the worker holding the lease started the task,
+// extending the lease by d.
+func (s *SQLiteStorage) MarkRunning(key QueueKey, lease int, d time.Duration) error {
+	return s.updateLease(key, lease, `state = ?, lease_until = ?`, QueueRunning, formatTime(time.Now().Add(d)))
+}
+
+// RenewLease extends a held lease by d
+func (s *SQLiteStorage) RenewLease(key QueueKey, lease int, d time.Duration) error {
+	return s.updateLease(key, lease, `lease_until = ?`, formatTime(time.Now().Add(d)))
+}
+
+// MarkDone finishes the run held by lease. It does nothing if the task was
+// queued again in the meantime.
+func (s *SQLiteStorage) MarkDone(key QueueKey, lease int) error {
+	return s.updateLease(key, lease, `state = ?, lease_until = ''`, QueueDone)
+}
+
+// updateLease applies set to the entry if lease is still the current, held
+// lease on it.
+func (s *SQLiteStorage) updateLease(key QueueKey, lease int, set string, args ...interface{}) error {
+	args = append(args, formatTime(time.Now()), key.FeatureID, key.TaskID, lease, QueueLeased, QueueRunning)
+	res, err := s.db.Exec(`UPDATE queue SET `+set+`, updated_at = ?
+		WHERE feature_id = ? AND task_id = ? AND lease = ? AND state IN (?, ?)`, args...)
+	if err != nil {
+		return fmt.Errorf("updating queue entry %s: %w", key, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var exists int
+		s.db.QueryRow(`SELECT COUNT(*) FROM queue WHERE feature_id = ? AND task_id = ?`, key.FeatureID, key.TaskID).Scan(&exists)
+		if exists == 0 {
+			return fmt.Errorf("task %s is not queued", key)
+		}
+	}
+	return nil
+}
+
+// ExpireLeases returns entries whose lease ran out before now to the queue.
+// It returns their keys.
+func (s *SQLiteStorage) ExpireLeases(now time.Time) ([]QueueKey, error) {
+	return s.requeue(`AND lease_until < ?`, formatTime(now))
+}
+
+// RequeueLeased returns every leased or running entry to the queue
+func (s *SQLiteStorage) RequeueLeased() ([]QueueKey, error) {
+	return s.requeue("")
+}
+
+func (s *SQLiteStorage) requeue(cond string, args ...interface{}) ([]QueueKey, error) {
+	var keys []QueueKey
+	err := s.withTx(func(tx *sql.Tx) error {
+		where := `WHERE state IN (?, ?) ` + cond
+		entries, err := queryQueue(tx, where, append([]interface{}{QueueLeased, QueueRunning}, args...)...)
+		if err != nil {
+			return err
+		}
+		now := formatTime(time.Now())
+		for _, entry := range entries {
+			_, err := tx.Exec(`UPDATE queue SET state = ?, lease_until = '', updated_at = ?
+				WHERE feature_id = ? AND task_id = ?`,
+				QueueQueued, now, entry.FeatureID, entry.TaskID)
+			if err != nil {
+				return fmt.Errorf("requeueing task %s: %w", entry.Key(), err)
+			}
+			keys = append(keys, entry.Key())
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// QueueEntries returns a snapshot of every queue entry, finished or not
+func (s *SQLiteStorage) QueueEntries() ([]QueueEntry, error) {
+	return queryQueue(s.db, `ORDER BY enqueued_at`)
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func queryQueue(q querier, where string, args ...interface{}) ([]QueueEntry, error) {
+	rows, err := q.Query(`
+		SELECT task_id, feature_id, state, lease, lease_until, enqueued_at, updated_at, task
+		FROM queue `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("reading queue: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []QueueEntry
+	for rows.Next() {
+		var e QueueEntry
+		var state, leaseUntil, enqueued, updated, task string
+		if err := rows.Scan(&e.TaskID, &e.FeatureID, &state, &e.Lease, &leaseUntil, &enqueued, &updated, &task); err != nil {
+			return nil, fmt.Errorf("reading queue entry: %w", err)
+		}
+		if err := fromJSON(task, &e.Task); err != nil {
+			return nil, fmt.Errorf("reading queue entry %s: %w", e.TaskID, err)
+		}
+		e.State = QueueState(state)
+		e.LeaseUntil, e.EnqueuedAt, e.UpdatedAt = parseTime(leaseUntil), parseTime(enqueued), parseTime(updated)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
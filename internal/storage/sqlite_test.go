@@ -0,0 +1,339 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestSQLite(t *testing.T) (*SQLiteStorage, string) {
+	t.Helper()
+
+	tmpDir, err := os.MkdirTemp("", "storage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	path := filepath.Join(tmpDir, "foreman.db")
+	store, err := NewSQLite(path)
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store, path
+}
+
+func sqliteFeature() *FeatureState {
+	created := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	return &FeatureState{
+		ID:          "feat-1",
+		Name:        "Auth",
+		Description: "Login with email",
+		Branch:      "feature/feat-1-auth",
+		PRNumber:    7,
+		PRURL:       "https://github.com/o/r/pull/7",
+		SpecDir:     "004-auth",
+		Phase:       "implementing",
+		CurrentTask: "T001",
+		Spec: &SpecState{
+			Title:       "Auth",
+			UserStories: []UserStoryState{{ID: "US1", Title: "Log in", Acceptance: []string{"Sees dashboard"}}},
+		},
+		Plan:             &PlanState{Overview: "Sessions", Phases: []PlanPhaseState{{Name: "Setup", Steps: []string{"Routes"}}}},
+		TaskIndex:        1,
+		PendingQuestions: []QuestionState{{ID: "Q1", Question: "SSO?"}},
+		Events: []EventState{
+			{Timestamp: created, FromPhase: "idle", ToPhase: "specifying", Message: "Starting", Actor: "foreman"},
+		},
+		TechStack:   "Go",
+		Constraints: "No new dependencies",
+		CreatedAt:   created,
+		Tasks: []TaskState{
+			{
+				ID:         "T001",
+				Spec:       "Add the login form",
+				Context:    "Review feedback",
+				Status:     "running",
+				Branch:     "feature/feat-1-auth/T001",
+				AgentName:  "claude-code",
+				Timeout:    30 * time.Minute,
+				Attempt:    1,
+				CreatedAt:  created,
+				FeatureID:  "feat-1",
+				FilePaths:  []string{"web/login.tsx"},
+				Metadata:   map[string]string{"user_story": "US1"},
+				IsParallel: true,
+			},
+			{ID: "T002", Spec: "Session API", Status: "pending", Branch: "feature/feat-1-auth/T002", AgentName: "codex", CreatedAt: created, FeatureID: "feat-1", Dependencies: []string{"T001"}},
+		},
+		Answers: map[string]string{"Q1": "No"},
+	}
+}
+
+func TestSQLiteSaveAndLoadFeature(t *testing.T) {
+	store, path := newTestSQLite(t)
+
+	feature := sqliteFeature()
+	if err := store.SaveFeature(feature); err != nil {
+		t.Fatalf("SaveFeature() error = %v", err)
+	}
+
+	// Reopen to make sure everything came from the database
+	store.Close()
+	reopened, err := NewSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	loaded, err := reopened.LoadFeature("feat-1")
+	if err != nil {
+		t.Fatalf("LoadFeature() error = %v", err)
+	}
+
+	// SaveFeature stamps UpdatedAt itself
+	if !loaded.UpdatedAt.Equal(feature.UpdatedAt) {
+		t.Errorf("UpdatedAt = %v, want %v", loaded.UpdatedAt, feature.UpdatedAt)
+	}
+	loaded.UpdatedAt = time.Time{}
+
+	want := sqliteFeature()
+	if !reflect.DeepEqual(loaded, want) {
+		t.Errorf("loaded feature differs:\n got %+v\nwant %+v", loaded, want)
+	}
+
+	if _, err := reopened.LoadFeature("missing"); err == nil {
+		t.Error("LoadFeature() of a missing feature should fail")
+	}
+}
+
+func TestSQLiteEventsAreAppended(t *testing.T) {
+	store, _ := newTestSQLite(t)
+
+	feature := sqliteFeature()
+	store.SaveFeature(feature)
+
+	feature.Events = append(feature.Events, EventState{
+		Timestamp: time.Now(), FromPhase: "specifying", ToPhase: "awaiting_spec_approval",
+	})
+	feature.Tasks = feature.Tasks[:1]
+	if err := store.SaveFeature(feature); err != nil {
+		t.Fatalf("SaveFeature() error = %v", err)
+	}
+
+	loaded, err := store.LoadFeature(feature.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Events) != 2 || loaded.Events[1].ToPhase != "awaiting_spec_approval" {
+		t.Errorf("events = %+v, want the appended event last", loaded.Events)
+	}
+	if len(loaded.Tasks) != 1 {
+		t.Errorf("expected removed tasks to be dropped, got %d tasks", len(loaded.Tasks))
+	}
+}
+
+func TestSQLiteLoadAllAndDelete(t *testing.T) {
+	store, _ := newTestSQLite(t)
+
+	for _, id := range []string{"a", "b"} {
+		feature := sqliteFeature()
+		feature.ID = id
+		if err := store.SaveFeature(feature); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.SaveReview(&ReviewState{FeatureID: "a", TaskID: "T001", Verdict: "approve"})
+
+	all, err := store.LoadAllFeatures()
+	if err != nil {
+		t.Fatalf("LoadAllFeatures() error = %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected 2 features, got %d", len(all))
+	}
+
+	if err := store.DeleteFeature("a"); err != nil {
+		t.Fatalf("DeleteFeature() error = %v", err)
+	}
+	if _, err := store.LoadFeature("a"); err == nil {
+		t.Error("expected deleted feature to be gone")
+	}
+	if reviews, _ := store.LoadReviews("a", "T001"); len(reviews) != 0 {
+		t.Errorf("expected reviews of deleted feature to be gone, got %d", len(reviews))
+	}
+
+	var tasks int
+	store.db.QueryRow(`SELECT COUNT(*) FROM tasks WHERE feature_id = 'a'`).Scan(&tasks)
+	if tasks != 0 {
+		t.Errorf("expected tasks of deleted feature to be gone, got %d", tasks)
+	}
+}
+
+func TestReviews(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Storage) {
+		reviews := []*ReviewState{
+			{FeatureID: "f1", TaskID: "T001", Attempt: 0, Verdict: "request_changes", Summary: "Add tests", BlockingIssues: []string{"No tests"}},
+			{FeatureID: "f1", TaskID: "T001", Attempt: 1, Verdict: "approve", Suggestions: []string{"Rename x"}},
+			{FeatureID: "f2", TaskID: "T001", Verdict: "block"},
+		}
+		for _, r := range reviews {
+			if err := store.SaveReview(r); err != nil {
+				t.Fatalf("SaveReview() error = %v", err)
+			}
+		}
+
+		loaded, err := store.LoadReviews("f1", "T001")
+		if err != nil {
+			t.Fatalf("LoadReviews() error = %v", err)
+		}
+		if len(loaded) != 2 {
+			t.Fatalf("expected 2 reviews, got %d", len(loaded))
+		}
+		if loaded[0].Verdict != "request_changes" || loaded[1].Verdict != "approve" {
+			t.Errorf("reviews out of order: %s, %s", loaded[0].Verdict, loaded[1].Verdict)
+		}
+		if len(loaded[0].BlockingIssues) != 1 || loaded[0].CreatedAt.IsZero() {
+			t.Errorf("review not stored in full: %+v", loaded[0])
+		}
+	})
+}
+
+func TestSQLiteMigrationsMatchSchemaVersion(t *testing.T) {
+	if len(sqliteMigrations) != SQLiteSchemaVersion {
+		t.Errorf("%d migrations for schema version %d; every version needs a migration from the one before", len(sqliteMigrations), SQLiteSchemaVersion)
+	}
+}
+
+func schemaVersion(t *testing.T, path string) int {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	return version
+}
+
+func TestSQLiteSchemaVersion(t *testing.T) {
+	store, path := newTestSQLite(t)
+	store.Close()
+	if got := schemaVersion(t, path); got != SQLiteSchemaVersion {
+		t.Errorf("new database version = %d, want %d", got, SQLiteSchemaVersion)
+	}
+
+	// Databases created before versioning have the tables but no version
+	unversioned := filepath.Join(filepath.Dir(path), "unversioned.db")
+	db, err := sql.Open("sqlite", "file:"+unversioned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`INSERT INTO features (id, name, description, branch, phase, created_at, updated_at)
+		VALUES ('feat-1', 'Auth', '', 'feature/feat-1-auth', 'implementing', '', '');
+		INSERT INTO queue (task_id, feature_id, state, lease, enqueued_at, updated_at)
+		VALUES ('T001', 'feat-1', 'queued', 3, '', '')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	upgraded, err := NewSQLite(unversioned)
+	if err != nil {
+		t.Fatalf("NewSQLite() on an unversioned database error = %v", err)
+	}
+	if feature, err := upgraded.LoadFeature("feat-1"); err != nil || feature.Name != "Auth" {
+		t.Errorf("LoadFeature() after upgrade = %+v, %v", feature, err)
+	}
+	// Queue entries survive re-keying
+	if err := upgraded.MarkDone(QueueKey{FeatureID: "feat-1", TaskID: "T001"}, 3); err != nil {
+		t.Errorf("queue entry lost in upgrade: %v", err)
+	}
+	if _, err := upgraded.Enqueue(QueueEntry{FeatureID: "feat-2", TaskID: "T001"}); err != nil {
+		t.Errorf("Enqueue() of another feature's T001 after upgrade error = %v", err)
+	}
+	if got := entries(t, upgraded); len(got) != 2 {
+		t.Errorf("expected 2 queue entries after upgrade, got %d", len(got))
+	}
+	upgraded.Close()
+	if got := schemaVersion(t, unversioned); got != SQLiteSchemaVersion {
+		t.Errorf("upgraded database version = %d, want %d", got, SQLiteSchemaVersion)
+	}
+
+	// A database from a newer build is left alone
+	db, err = sql.Open("sqlite", "file:"+unversioned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, SQLiteSchemaVersion+1))
+	db.Close()
+	if _, err := NewSQLite(unversioned); err == nil {
+		t.Error("NewSQLite() should refuse a database with a newer schema")
+	}
+}
+
+func TestSQLitePathIsEscaped(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "storage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "state?v=1#x %41.db")
+	store, err := NewSQLite(path)
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	if err := store.SaveFeature(sqliteFeature()); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("database not created at %q: %v", path, err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "storage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tests := []struct {
+		backend string
+		want    string
+	}{
+		{"", "*storage.FileStorage"},
+		{BackendFile, "*storage.FileStorage"},
+		{BackendSQLite, "*storage.SQLiteStorage"},
+	}
+	for _, tt := range tests {
+		store, err := Open(Options{Backend: tt.backend, Path: filepath.Join(tmpDir, "store-"+tt.backend)})
+		if err != nil {
+			t.Fatalf("Open(%q) error = %v", tt.backend, err)
+		}
+		if got := reflect.TypeOf(store).String(); got != tt.want {
+			t.Errorf("Open(%q) = %s, want %s", tt.backend, got, tt.want)
+		}
+		store.Close()
+	}
+
+	if _, err := Open(Options{Backend: "postgres", Path: filepath.Join(tmpDir, "x")}); err == nil {
+		t.Error("Open() with an unknown backend should fail")
+	}
+}
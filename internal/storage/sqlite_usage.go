@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// SaveUsage records one agent run
+func (s *SQLiteStorage) SaveUsage(usage *UsageState) error {
+	if usage.CreatedAt.IsZero() {
+		usage.CreatedAt = time.Now()
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO usage (feature_id, task_id, agent, attempt, input_tokens, output_tokens,
+			cost_usd, turns, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		usage.FeatureID, usage.TaskID, usage.Agent, usage.Attempt, usage.InputTokens,
+		usage.OutputTokens, usage.CostUSD, usage.Turns, formatTime(usage.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("saving usage of %s: %w", usage.TaskID, err)
+	}
+	return nil
+}
+
+// LoadUsage returns the runs recorded at or after since, oldest first
+func (s *SQLiteStorage) LoadUsage(since time.Time) ([]*UsageState, error) {
+	rows, err := s.db.Query(`
+		SELECT feature_id, task_id, agent, attempt, input_tokens, output_tokens, cost_usd,
+			turns, created_at
+		FROM usage WHERE created_at >= ? ORDER BY id`, formatTime(since))
+	if err != nil {
+		return nil, fmt.Errorf("loading usage: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []*UsageState
+	for rows.Next() {
+		u := &UsageState{}
+		var created string
+		if err := rows.Scan(&u.FeatureID, &u.TaskID, &u.Agent, &u.Attempt, &u.InputTokens,
+			&u.OutputTokens, &u.CostUSD, &u.Turns, &created); err != nil {
+			return nil, fmt.Errorf("reading usage: %w", err)
+		}
+		u.CreatedAt = parseTime(created)
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+// ApproveBudget records one more approval to spend past the budget of scope
+func (s *SQLiteStorage) ApproveBudget(scope string) error {
+	_, err := s.db.Exec(`
+		INSERT INTO budget_approvals (scope, approvals) VALUES (?, 1)
+		ON CONFLICT (scope) DO UPDATE SET approvals = approvals + 1`, scope)
+	if err != nil {
+		return fmt.Errorf("approving budget %s: %w", scope, err)
+	}
+	return nil
+}
+
+// LoadBudgetApprovals returns how many times each budget scope has been
+// approved
+func (s *SQLiteStorage) LoadBudgetApprovals() (map[string]int, error) {
+	rows, err := s.db.Query(`SELECT scope, approvals FROM budget_approvals`)
+	if err != nil {
+		return nil, fmt.Errorf("loading budget approvals: %w", err)
+	}
+	defer rows.Close()
+
+	approvals := make(map[string]int)
+	for rows.Next() {
+		var scope string
+		var n int
+		if err := rows.Scan(&scope, &n); err != nil {
+			return nil, fmt.Errorf("reading budget approvals: %w", err)
+		}
+		approvals[scope] = n
+	}
+	return approvals, rows.Err()
+}
@@ -8,63 +8,254 @@ import (
 	"time"
 )
 
+// Storage persists features, review results, agent usage and the task
+// queue
+type Storage interface {
+	Queue
+	UsageLog
+
+	SaveFeature(state *FeatureState) error
+	LoadFeature(id string) (*FeatureState, error)
+	LoadAllFeatures() ([]*FeatureState, error)
+	DeleteFeature(id string) error
+
+	// SaveReview records the result of one review of a task
+	SaveReview(review *ReviewState) error
+	// LoadReviews returns a task's reviews, oldest first
+	LoadReviews(featureID, taskID string) ([]*ReviewState, error)
+
+	Close() error
+}
+
+// Queue persists tasks waiting for a worker. Entries are leased by workers
+// and handed out again if the lease runs out.
+type Queue interface {
+	Enqueue(entry QueueEntry) (bool, error)
+	Lease(d time.Duration) (*QueueEntry, error)
+	MarkRunning(key QueueKey, lease int, d time.Duration) error
+	RenewLease(key QueueKey, lease int, d time.Duration) error
+	MarkDone(key QueueKey, lease int) error
+	ExpireLeases(now time.Time) ([]QueueKey, error)
+	RequeueLeased() ([]QueueKey, error)
+	QueueEntries() ([]QueueEntry, error)
+}
+
+// Backends accepted by Open
+const (
+	BackendFile   = "file"
+	BackendSQLite = "sqlite"
+)
+
+// Options selects and configures a storage backend
+type Options struct {
+	Backend string // file (default) or sqlite
+	Path    string
+	Backups int // backups kept by the file backend; 0 means DefaultBackups, negative disables them
+}
+
+// Open opens the configured storage backend
+func Open(opts Options) (Storage, error) {
+	switch opts.Backend {
+	case "", BackendFile:
+		backups := opts.Backups
+		if backups == 0 {
+			backups = DefaultBackups
+		}
+		return NewWithBackups(opts.Path, backups)
+	case BackendSQLite:
+		return NewSQLite(opts.Path)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", opts.Backend)
+	}
+}
+
 // FeatureState represents a feature's persisted state
 type FeatureState struct {
-	ID          string            `json:"id"`
-	Name        string            `json:"name"`
-	Description string            `json:"description"`
-	Branch      string            `json:"branch"`
-	Phase       string            `json:"phase"`
-	TechStack   string            `json:"tech_stack,omitempty"`
-	Constraints string            `json:"constraints,omitempty"`
-	CreatedAt   time.Time         `json:"created_at"`
-	UpdatedAt   time.Time         `json:"updated_at"`
-	Tasks       []TaskState       `json:"tasks,omitempty"`
-	Answers     map[string]string `json:"answers,omitempty"`
+	ID               string            `json:"id"`
+	Name             string            `json:"name"`
+	Description      string            `json:"description"`
+	Branch           string            `json:"branch"`
+	PRNumber         int               `json:"pr_number,omitempty"`
+	PRURL            string            `json:"pr_url,omitempty"`
+	SpecDir          string            `json:"spec_dir,omitempty"`
+	Phase            string            `json:"phase"`
+	CurrentTask      string            `json:"current_task,omitempty"`
+	Spec             *SpecState        `json:"spec,omitempty"`
+	Plan             *PlanState        `json:"plan,omitempty"`
+	TaskIndex        int               `json:"task_index,omitempty"`
+	PendingQuestions []QuestionState   `json:"pending_questions,omitempty"`
+	Events           []EventState      `json:"events,omitempty"`
+	TechStack        string            `json:"tech_stack,omitempty"`
+	Constraints      string            `json:"constraints,omitempty"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+	Tasks            []TaskState       `json:"tasks,omitempty"`
+	Answers          map[string]string `json:"answers,omitempty"`
 }
 
 // TaskState represents a task's persisted state
 type TaskState struct {
-	ID         string `json:"id"`
-	Spec       string `json:"spec"`
-	Status     string `json:"status"`
-	Branch     string `json:"branch"`
-	AgentName  string `json:"agent_name"`
-	IsParallel bool   `json:"is_parallel"`
-	Attempt    int    `json:"attempt"`
-	FeatureID  string `json:"feature_id"`
+	ID           string            `json:"id"`
+	Spec         string            `json:"spec"`
+	Context      string            `json:"context,omitempty"`
+	Status       string            `json:"status"`
+	Branch       string            `json:"branch"`
+	BaseBranch   string            `json:"base_branch,omitempty"`
+	WorktreePath string            `json:"worktree_path,omitempty"`
+	AgentName    string            `json:"agent_name"`
+	Timeout      time.Duration     `json:"timeout,omitempty"`
+	IsParallel   bool              `json:"is_parallel"`
+	Attempt      int               `json:"attempt"`
+	CreatedAt    time.Time         `json:"created_at,omitempty"`
+	FeatureID    string            `json:"feature_id"`
+	Dependencies []string          `json:"dependencies,omitempty"`
+	FilePaths    []string          `json:"file_paths,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+// SpecState represents a parsed specification's persisted state
+type SpecState struct {
+	Title        string           `json:"title"`
+	Description  string           `json:"description,omitempty"`
+	UserStories  []UserStoryState `json:"user_stories,omitempty"`
+	Requirements []string         `json:"requirements,omitempty"`
+	RawContent   string           `json:"raw_content,omitempty"`
+	FilePath     string           `json:"file_path,omitempty"`
+}
+
+// UserStoryState represents a user story's persisted state
+type UserStoryState struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Acceptance  []string `json:"acceptance,omitempty"`
+}
+
+// PlanState represents a parsed plan's persisted state
+type PlanState struct {
+	Overview     string           `json:"overview,omitempty"`
+	TechStack    []string         `json:"tech_stack,omitempty"`
+	Architecture string           `json:"architecture,omitempty"`
+	Phases       []PlanPhaseState `json:"phases,omitempty"`
+	RawContent   string           `json:"raw_content,omitempty"`
+	FilePath     string           `json:"file_path,omitempty"`
+}
+
+// PlanPhaseState represents a plan phase's persisted state
+type PlanPhaseState struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Steps       []string `json:"steps,omitempty"`
+}
+
+// QuestionState represents a clarification question's persisted state
+type QuestionState struct {
+	ID       string `json:"id"`
+	Question string `json:"question"`
+	Context  string `json:"context,omitempty"`
+	Answered bool   `json:"answered,omitempty"`
+	Answer   string `json:"answer,omitempty"`
+}
+
+// EventState represents a workflow event's persisted state
+type EventState struct {
+	Timestamp time.Time `json:"timestamp"`
+	FromPhase string    `json:"from_phase"`
+	ToPhase   string    `json:"to_phase"`
+	Message   string    `json:"message,omitempty"`
+	Actor     string    `json:"actor,omitempty"`
+}
+
+// ReviewState represents the persisted result of a task review
+type ReviewState struct {
+	FeatureID      string    `json:"feature_id,omitempty"`
+	TaskID         string    `json:"task_id"`
+	Attempt        int       `json:"attempt"`
+	Verdict        string    `json:"verdict"`
+	Summary        string    `json:"summary,omitempty"`
+	BlockingIssues []string  `json:"blocking_issues,omitempty"`
+	Suggestions    []string  `json:"suggestions,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
 }
 
 // Store represents the persistence store data
 type Store struct {
+	Version   int                      `json:"version"`
 	Features  map[string]*FeatureState `json:"features"`
+	Reviews   []*ReviewState           `json:"reviews,omitempty"`
+	Usage     []*UsageState            `json:"usage,omitempty"`
+	Queue     map[string]*QueueEntry   `json:"queue,omitempty"` // keyed by QueueKey.String()
 	UpdatedAt time.Time                `json:"updated_at"`
+
+	// BudgetApprovals counts the approvals to go over each budget scope
+	BudgetApprovals map[string]int `json:"budget_approvals,omitempty"`
 }
 
-// FileStorage provides JSON file-based persistence
+// FileStorage provides JSON file-based persistence. The whole store is
+// rewritten on every change, atomically, and an advisory lock keeps a second
+// process from using the same file. Timestamped backups are taken when the
+// file is opened and at most hourly after that.
 type FileStorage struct {
 	path  string
 	mu    sync.RWMutex
 	store *Store
+
+	lock       *os.File
+	backups    int
+	lastBackup time.Time
 }
 
-// New creates a new file storage instance
+// New creates a new file storage instance keeping DefaultBackups backups
 func New(path string) (*FileStorage, error) {
+	return NewWithBackups(path, DefaultBackups)
+}
+
+// NewWithBackups creates a new file storage instance keeping the given
+// number of backups. A count of zero or less disables backups.
+func NewWithBackups(path string, backups int) (*FileStorage, error) {
+	lock, err := lockFile(lockPath(path))
+	if err == ErrLocked {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	fs := &FileStorage{
 		path: path,
 		store: &Store{
 			Features: make(map[string]*FeatureState),
+			Queue:    make(map[string]*QueueEntry),
 		},
+		lock:    lock,
+		backups: backups,
 	}
 
 	// Try to load existing data
 	if err := fs.load(); err != nil && !os.IsNotExist(err) {
+		unlockFile(lock)
 		return nil, fmt.Errorf("loading storage: %w", err)
 	}
 
+	// Keep a copy of the state as it was before this run changes it
+	if err := fs.backup(); err != nil {
+		unlockFile(lock)
+		return nil, fmt.Errorf("backing up storage: %w", err)
+	}
+
 	return fs, nil
 }
 
+// NewMemory creates a storage instance that is never written to disk
+func NewMemory() *FileStorage {
+	return &FileStorage{
+		store: &Store{
+			Features: make(map[string]*FeatureState),
+			Queue:    make(map[string]*QueueEntry),
+		},
+	}
+}
+
 func (fs *FileStorage) load() error {
 	data, err := os.ReadFile(fs.path)
 	if err != nil {
@@ -74,28 +265,70 @@ func (fs *FileStorage) load() error {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
 
-	if err := json.Unmarshal(data, &fs.store); err != nil {
+	store, version, err := decodeStore(data)
+	if err != nil {
 		return fmt.Errorf("parsing storage file: %w", err)
 	}
 
+	// Keep the file as it was before its first migration. Unlike backups,
+	// this copy is never rotated away.
+	if version < SchemaVersion {
+		if err := keepPreMigrationCopy(fs.path, version, data); err != nil {
+			return fmt.Errorf("saving copy of version %d storage: %w", version, err)
+		}
+	}
+
+	if store.Features == nil {
+		store.Features = make(map[string]*FeatureState)
+	}
+	if store.Queue == nil {
+		store.Queue = make(map[string]*QueueEntry)
+	}
+	fs.store = store
+
 	return nil
 }
 
 func (fs *FileStorage) save() error {
+	fs.store.Version = SchemaVersion
 	fs.store.UpdatedAt = time.Now()
+	if fs.path == "" {
+		return nil
+	}
 
 	data, err := json.MarshalIndent(fs.store, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshaling storage: %w", err)
 	}
 
-	if err := os.WriteFile(fs.path, data, 0644); err != nil {
+	// A failed backup must not keep the new state from being written
+	var backupErr error
+	if time.Since(fs.lastBackup) >= backupInterval {
+		backupErr = fs.backup()
+	}
+
+	if err := writeFileAtomic(fs.path, data, 0644); err != nil {
 		return fmt.Errorf("writing storage file: %w", err)
 	}
 
+	if backupErr != nil {
+		return fmt.Errorf("backing up storage: %w", backupErr)
+	}
+
 	return nil
 }
 
+// backup copies the storage file to a new backup if backups are enabled
+func (fs *FileStorage) backup() error {
+	if fs.backups <= 0 {
+		return nil
+	}
+
+	fs.lastBackup = time.Now()
+	_, err := createBackup(fs.path, fs.backups)
+	return err
+}
+
 // SaveFeature persists a feature state
 func (fs *FileStorage) SaveFeature(state *FeatureState) error {
 	fs.mu.Lock()
@@ -140,5 +373,55 @@ func (fs *FileStorage) DeleteFeature(id string) error {
 
 	delete(fs.store.Features, id)
 
+	reviews := fs.store.Reviews[:0]
+	for _, r := range fs.store.Reviews {
+		if r.FeatureID != id {
+			reviews = append(reviews, r)
+		}
+	}
+	fs.store.Reviews = reviews
+
 	return fs.save()
 }
+
+// SaveReview records the result of one review of a task
+func (fs *FileStorage) SaveReview(review *ReviewState) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if review.CreatedAt.IsZero() {
+		review.CreatedAt = time.Now()
+	}
+	fs.store.Reviews = append(fs.store.Reviews, review)
+
+	return fs.save()
+}
+
+// LoadReviews returns a task's reviews, oldest first
+func (fs *FileStorage) LoadReviews(featureID, taskID string) ([]*ReviewState, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	var reviews []*ReviewState
+	for _, r := range fs.store.Reviews {
+		if r.FeatureID == featureID && r.TaskID == taskID {
+			reviews = append(reviews, r)
+		}
+	}
+
+	return reviews, nil
+}
+
+// Close releases the storage lock so another process can use the file
+func (fs *FileStorage) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.lock == nil {
+		return nil
+	}
+
+	err := unlockFile(fs.lock)
+	fs.lock = nil
+	return err
+}
@@ -24,6 +24,7 @@ func TestNewStorage(t *testing.T) {
 	if store == nil {
 		t.Error("New() should return non-nil storage")
 	}
+	store.Close()
 }
 
 func TestNewStorage_LoadExisting(t *testing.T) {
@@ -52,6 +53,7 @@ func TestNewStorage_LoadExisting(t *testing.T) {
 	if err := store1.SaveFeature(feature); err != nil {
 		t.Fatalf("SaveFeature() error = %v", err)
 	}
+	store1.Close()
 
 	// Create new storage instance and verify data is loaded
 	store2, err := New(storagePath)
@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTranscriptRetention is how long transcripts are kept when no
+	// retention is configured
+	DefaultTranscriptRetention = 30 * 24 * time.Hour
+
+	// standaloneTranscripts files the attempts of tasks outside a feature
+	standaloneTranscripts = "tasks"
+
+	// attemptPrefix names attempt directories: attempt-1, attempt-2, ...
+	attemptPrefix = "attempt-"
+)
+
+// Transcripts keeps the raw output of every agent run and review tool on
+// disk, one directory per task attempt:
+//
+//	<dir>/<feature>/<task>/attempt-<n>/<name>
+//
+// Tasks outside a feature are filed under "tasks". Attempts are numbered in
+// the order they ran and are never overwritten.
+type Transcripts struct {
+	dir       string
+	retention time.Duration // zero or less keeps transcripts forever
+	mu        sync.Mutex
+}
+
+// TranscriptFile is one file recorded for an attempt
+type TranscriptFile struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// TranscriptAttempt records the files of one attempt as they are produced
+type TranscriptAttempt struct {
+	Number int
+	dir    string
+}
+
+// OpenTranscripts opens the transcript directory, creating it if needed
+func OpenTranscripts(dir string, retention time.Duration) (*Transcripts, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating transcript directory: %w", err)
+	}
+	return &Transcripts{dir: dir, retention: retention}, nil
+}
+
+// taskDir returns the directory holding a task's attempts
+func (t *Transcripts) taskDir(featureID, taskID string) (string, error) {
+	if featureID == "" {
+		featureID = standaloneTranscripts
+	}
+	for _, name := range []string{featureID, taskID} {
+		if !validTranscriptName(name) {
+			return "", fmt.Errorf("invalid transcript path component %q", name)
+		}
+	}
+	return filepath.Join(t.dir, featureID, taskID), nil
+}
+
+// Begin starts recording the next attempt of a task
+func (t *Transcripts) Begin(featureID, taskID string) (*TranscriptAttempt, error) {
+	dir, err := t.taskDir(featureID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	attempts, err := listAttempts(dir)
+	if err != nil {
+		return nil, err
+	}
+	next := 1
+	if len(attempts) > 0 {
+		next = attempts[len(attempts)-1] + 1
+	}
+
+	attempt := &TranscriptAttempt{Number: next, dir: filepath.Join(dir, attemptPrefix+strconv.Itoa(next))}
+	if err := os.MkdirAll(attempt.dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating transcript directory: %w", err)
+	}
+	return attempt, nil
+}
+
+// Save writes one file of the attempt. Empty content is not recorded.
+func (a *TranscriptAttempt) Save(name, content string) error {
+	if !validTranscriptName(name) {
+		return fmt.Errorf("invalid transcript name %q", name)
+	}
+	if content == "" {
+		return nil
+	}
+	return os.WriteFile(filepath.Join(a.dir, name), []byte(content), 0644)
+}
+
+// Attempts returns the recorded attempt numbers of a task, oldest first
+func (t *Transcripts) Attempts(featureID, taskID string) ([]int, error) {
+	dir, err := t.taskDir(featureID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return listAttempts(dir)
+}
+
+// Files returns the files recorded for an attempt, sorted by name
+func (t *Transcripts) Files(featureID, taskID string, attempt int) ([]TranscriptFile, error) {
+	dir, err := t.taskDir(featureID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, attemptPrefix+strconv.Itoa(attempt)))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []TranscriptFile
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, TranscriptFile{Name: entry.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	return files, nil
+}
+
+// Read returns the content of one file of an attempt
+func (t *Transcripts) Read(featureID, taskID string, attempt int, name string) ([]byte, error) {
+	dir, err := t.taskDir(featureID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if !validTranscriptName(name) {
+		return nil, fmt.Errorf("invalid transcript name %q", name)
+	}
+	return os.ReadFile(filepath.Join(dir, attemptPrefix+strconv.Itoa(attempt), name))
+}
+
+// Prune removes the attempts last written before the retention period and
+// the task and feature directories left empty. It returns the number of
+// attempts removed.
+func (t *Transcripts) Prune(now time.Time) (int, error) {
+	if t.retention <= 0 {
+		return 0, nil
+	}
+	cutoff := now.Add(-t.retention)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	attemptDirs, err := filepath.Glob(filepath.Join(t.dir, "*", "*", attemptPrefix+"*"))
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, dir := range attemptDirs {
+		if lastModified(dir).After(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return removed, fmt.Errorf("removing %s: %w", dir, err)
+		}
+		removed++
+
+		// Remove the task and feature directories once they are empty
+		taskDir := filepath.Dir(dir)
+		if os.Remove(taskDir) == nil {
+			os.Remove(filepath.Dir(taskDir))
+		}
+	}
+	return removed, nil
+}
+
+// lastModified returns when anything in dir was last written
+func lastModified(dir string) time.Time {
+	var latest time.Time
+	if info, err := os.Stat(dir); err == nil {
+		latest = info.ModTime()
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+// listAttempts returns the attempt numbers recorded in a task directory
+func listAttempts(dir string) ([]int, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var attempts []int
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), attemptPrefix) {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimPrefix(entry.Name(), attemptPrefix)); err == nil && n > 0 {
+			attempts = append(attempts, n)
+		}
+	}
+	sort.Ints(attempts)
+	return attempts, nil
+}
+
+// validTranscriptName reports whether name is safe to use as a single path
+// component
+func validTranscriptName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
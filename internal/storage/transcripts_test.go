@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTranscriptAttempts(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "transcripts-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	transcripts, err := OpenTranscripts(filepath.Join(tmpDir, "transcripts"), DefaultTranscriptRetention)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := transcripts.Begin("F001", "T001")
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err := first.Save("agent.log", "stream"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := first.Save("stderr.log", ""); err != nil {
+		t.Fatalf("Save() of empty content error = %v", err)
+	}
+
+	second, err := transcripts.Begin("F001", "T001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Number != 1 || second.Number != 2 {
+		t.Errorf("attempt numbers = %d, %d; want 1, 2", first.Number, second.Number)
+	}
+	second.Save("review-tests.log", "ok")
+
+	// The same task ID in another feature, and a standalone task, are kept apart
+	other, _ := transcripts.Begin("F002", "T001")
+	standalone, _ := transcripts.Begin("", "T001")
+	if other.Number != 1 || standalone.Number != 1 {
+		t.Errorf("attempt numbers of other tasks = %d, %d; want 1, 1", other.Number, standalone.Number)
+	}
+
+	attempts, err := transcripts.Attempts("F001", "T001")
+	if err != nil || !reflect.DeepEqual(attempts, []int{1, 2}) {
+		t.Errorf("Attempts() = %v, %v", attempts, err)
+	}
+	if attempts, err := transcripts.Attempts("F001", "T999"); err != nil || len(attempts) != 0 {
+		t.Errorf("Attempts() of an unknown task = %v, %v", attempts, err)
+	}
+
+	files, err := transcripts.Files("F001", "T001", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name != "agent.log" || files[0].Size != 6 {
+		t.Errorf("Files() = %+v, want only agent.log", files)
+	}
+
+	data, err := transcripts.Read("F001", "T001", 2, "review-tests.log")
+	if err != nil || string(data) != "ok" {
+		t.Errorf("Read() = %q, %v", data, err)
+	}
+}
+
+func TestTranscriptNamesStayInside(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "transcripts-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	transcripts, err := OpenTranscripts(tmpDir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := transcripts.Begin("..", "T001"); err == nil {
+		t.Error("Begin() should reject a feature ID of ..")
+	}
+	if _, err := transcripts.Begin("F001", "a/b"); err == nil {
+		t.Error("Begin() should reject a task ID with a slash")
+	}
+
+	attempt, err := transcripts.Begin("F001", "T001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := attempt.Save("../escape.log", "x"); err == nil {
+		t.Error("Save() should reject names outside the attempt")
+	}
+	if _, err := transcripts.Read("F001", "T001", 1, "../../../etc/passwd"); err == nil {
+		t.Error("Read() should reject names outside the attempt")
+	}
+}
+
+func TestTranscriptPrune(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "transcripts-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	transcripts, err := OpenTranscripts(tmpDir, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old, _ := transcripts.Begin("F001", "T001")
+	old.Save("agent.log", "old")
+	recent, _ := transcripts.Begin("F001", "T002")
+	recent.Save("agent.log", "recent")
+
+	// Age the first attempt past the retention period
+	past := time.Now().Add(-48 * time.Hour)
+	for _, path := range []string{filepath.Join(old.dir, "agent.log"), old.dir} {
+		if err := os.Chtimes(path, past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := transcripts.Prune(time.Now())
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Prune() removed %d attempts, want 1", removed)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "F001", "T001")); !os.IsNotExist(err) {
+		t.Error("the emptied task directory should be removed")
+	}
+	if attempts, _ := transcripts.Attempts("F001", "T002"); len(attempts) != 1 {
+		t.Errorf("recent attempt was pruned: %v", attempts)
+	}
+
+	// Without a retention period nothing is pruned
+	keep, _ := OpenTranscripts(tmpDir, 0)
+	if removed, _ := keep.Prune(time.Now().Add(365 * 24 * time.Hour)); removed != 0 {
+		t.Errorf("Prune() without retention removed %d attempts", removed)
+	}
+}
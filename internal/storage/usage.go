@@ -0,0 +1,88 @@
+package storage
+
+import "time"
+
+// UsageLog records what agent runs consumed, so spending can be totalled
+// per task, feature and agent. Usage outlives the features it belongs to:
+// deleting a feature does not lower what was spent.
+type UsageLog interface {
+	// SaveUsage records one agent run
+	SaveUsage(usage *UsageState) error
+	// LoadUsage returns the runs recorded at or after since, oldest first.
+	// A zero since returns every run.
+	LoadUsage(since time.Time) ([]*UsageState, error)
+	// ApproveBudget records one more approval to spend past the budget of
+	// scope
+	ApproveBudget(scope string) error
+	// LoadBudgetApprovals returns how many times each budget scope has
+	// been approved
+	LoadBudgetApprovals() (map[string]int, error)
+}
+
+// UsageState is the persisted usage of one agent run
+type UsageState struct {
+	FeatureID    string    `json:"feature_id,omitempty"`
+	TaskID       string    `json:"task_id"`
+	Agent        string    `json:"agent"`
+	Attempt      int       `json:"attempt"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	CostUSD      float64   `json:"cost_usd"`
+	Turns        int       `json:"turns"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SaveUsage records one agent run
+func (fs *FileStorage) SaveUsage(usage *UsageState) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if usage.CreatedAt.IsZero() {
+		usage.CreatedAt = time.Now()
+	}
+	fs.store.Usage = append(fs.store.Usage, usage)
+
+	return fs.save()
+}
+
+// LoadUsage returns the runs recorded at or after since, oldest first
+func (fs *FileStorage) LoadUsage(since time.Time) ([]*UsageState, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	var usage []*UsageState
+	for _, u := range fs.store.Usage {
+		if !u.CreatedAt.Before(since) {
+			usage = append(usage, u)
+		}
+	}
+
+	return usage, nil
+}
+
+// ApproveBudget records one more approval to spend past the budget of scope
+func (fs *FileStorage) ApproveBudget(scope string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.store.BudgetApprovals == nil {
+		fs.store.BudgetApprovals = make(map[string]int)
+	}
+	fs.store.BudgetApprovals[scope]++
+
+	return fs.save()
+}
+
+// LoadBudgetApprovals returns how many times each budget scope has been
+// approved
+func (fs *FileStorage) LoadBudgetApprovals() (map[string]int, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	approvals := make(map[string]int, len(fs.store.BudgetApprovals))
+	for scope, n := range fs.store.BudgetApprovals {
+		approvals[scope] = n
+	}
+
+	return approvals, nil
+}
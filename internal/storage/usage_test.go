@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUsage(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Storage) {
+		yesterday := time.Now().Add(-24 * time.Hour)
+		runs := []*UsageState{
+			{FeatureID: "f1", TaskID: "T001", Agent: "claude-code", InputTokens: 1000, OutputTokens: 200, CostUSD: 0.25, Turns: 3, CreatedAt: yesterday},
+			{FeatureID: "f1", TaskID: "T001", Agent: "claude-code", Attempt: 1, InputTokens: 500, OutputTokens: 100, CostUSD: 0.125, Turns: 2},
+			{TaskID: "42", Agent: "local", InputTokens: 80, OutputTokens: 10, Turns: 1},
+		}
+		for _, u := range runs {
+			if err := store.SaveUsage(u); err != nil {
+				t.Fatalf("SaveUsage() error = %v", err)
+			}
+		}
+
+		all, err := store.LoadUsage(time.Time{})
+		if err != nil {
+			t.Fatalf("LoadUsage() error = %v", err)
+		}
+		if len(all) != 3 {
+			t.Fatalf("expected 3 runs, got %d", len(all))
+		}
+		first := *all[0]
+		first.CreatedAt = time.Time{}
+		want := *runs[0]
+		want.CreatedAt = time.Time{}
+		if first != want {
+			t.Errorf("first run = %+v, want %+v", first, want)
+		}
+		if all[2].CreatedAt.IsZero() {
+			t.Error("SaveUsage() should stamp the time")
+		}
+
+		today, err := store.LoadUsage(time.Now().Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("LoadUsage() error = %v", err)
+		}
+		if len(today) != 2 || today[0].Attempt != 1 || today[1].Agent != "local" {
+			t.Errorf("recent runs = %+v", today)
+		}
+
+		// Deleting a feature keeps what it spent
+		store.SaveFeature(&FeatureState{ID: "f1", Name: "F", Branch: "feature/f1", Phase: "implementing"})
+		if err := store.DeleteFeature("f1"); err != nil {
+			t.Fatal(err)
+		}
+		if all, _ := store.LoadUsage(time.Time{}); len(all) != 3 {
+			t.Errorf("expected usage to survive feature deletion, got %d runs", len(all))
+		}
+	})
+}
+
+func TestBudgetApprovals(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Storage) {
+		for _, scope := range []string{"daily:2026-10-17", "feature:f1", "daily:2026-10-17"} {
+			if err := store.ApproveBudget(scope); err != nil {
+				t.Fatalf("ApproveBudget(%s) error = %v", scope, err)
+			}
+		}
+
+		approvals, err := store.LoadBudgetApprovals()
+		if err != nil {
+			t.Fatalf("LoadBudgetApprovals() error = %v", err)
+		}
+		if len(approvals) != 2 || approvals["daily:2026-10-17"] != 2 || approvals["feature:f1"] != 1 {
+			t.Errorf("approvals = %v", approvals)
+		}
+	})
+}
@@ -128,6 +128,41 @@ func (b *Bot) Send(message string) error {
 	return err
 }
 
+// SendTracked sends a message and returns its ID, so it can be edited later
+func (b *Bot) SendTracked(message string) (int, error) {
+	msg := tgbotapi.NewMessage(b.chatID, message)
+	msg.ParseMode = "Markdown"
+	sent, err := b.api.Send(msg)
+	if err != nil {
+		log.Printf("Failed to send message: %v", err)
+		return 0, err
+	}
+	return sent.MessageID, nil
+}
+
+// Edit replaces the text of a message sent earlier
+func (b *Bot) Edit(messageID int, message string) error {
+	edit := tgbotapi.NewEditMessageText(b.chatID, messageID, message)
+	edit.ParseMode = "Markdown"
+	_, err := b.api.Send(edit)
+	if err != nil {
+		log.Printf("Failed to edit message: %v", err)
+	}
+	return err
+}
+
+// SendDocument sends data as a file attachment with a caption
+func (b *Bot) SendDocument(name string, data []byte, caption string) error {
+	doc := tgbotapi.NewDocument(b.chatID, tgbotapi.FileBytes{Name: name, Bytes: data})
+	doc.Caption = caption
+	doc.ParseMode = "Markdown"
+	_, err := b.api.Send(doc)
+	if err != nil {
+		log.Printf("Failed to send document: %v", err)
+	}
+	return err
+}
+
 func (b *Bot) RequestApproval(taskID, summary, prURL string) error {
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
@@ -140,11 +175,13 @@ func (b *Bot) RequestApproval(taskID, summary, prURL string) error {
 	)
 
 	text := fmt.Sprintf(
-		"🚦 *Approval Required*\n\nTask: `%s`\n\n%s\n\n[View Changes](%s)",
+		"🚦 *Approval Required*\n\nTask: `%s`\n\n%s",
 		taskID,
 		truncate(summary, 500),
-		prURL,
 	)
+	if prURL != "" {
+		text += fmt.Sprintf("\n\n[View Changes](%s)", prURL)
+	}
 
 	msg := tgbotapi.NewMessage(b.chatID, text)
 	msg.ParseMode = "Markdown"
@@ -233,6 +270,106 @@ func (b *Bot) RequestPhaseApproval(featureID, phase, summary, extra string) erro
 	return err
 }
 
+// RequestCodeApproval sends a code approval request for a single task within
+// a feature. The callback data carries both IDs so that several tasks can be
+// awaiting approval at the same time.
+func (b *Bot) RequestCodeApproval(featureID, taskID, summary, extra string) error {
+	ref := fmt.Sprintf("%s:%s", featureID, taskID)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Approve & Merge", fmt.Sprintf("approve_code:%s", ref)),
+			tgbotapi.NewInlineKeyboardButtonData("🔄 Request Changes", fmt.Sprintf("request_changes:%s", ref)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("❌ Reject", fmt.Sprintf("reject_code:%s", ref)),
+		),
+	)
+
+	text := fmt.Sprintf("🚦 *Approval Required*\n\nFeature: `%s`\nTask: `%s`\nPhase: CODE\n\n%s",
+		featureID,
+		taskID,
+		truncate(summary, 3000),
+	)
+
+	if extra != "" {
+		text += "\n\n" + extra
+	}
+
+	msg := tgbotapi.NewMessage(b.chatID, text)
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = keyboard
+
+	_, err := b.api.Send(msg)
+	return err
+}
+
+// RequestResolutionApproval asks for approval of an agent-produced merge
+// conflict resolution before it is committed.
+func (b *Bot) RequestResolutionApproval(featureID, taskID, summary, extra string) error {
+	ref := fmt.Sprintf("%s:%s", featureID, taskID)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Commit Resolution", fmt.Sprintf("approve_resolution:%s", ref)),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Discard", fmt.Sprintf("reject_resolution:%s", ref)),
+		),
+	)
+
+	text := fmt.Sprintf("🔀 *Conflict Resolution*\n\nTask: `%s`\n\n%s", taskID, truncate(summary, 3000))
+	if extra != "" {
+		text += "\n\n" + extra
+	}
+
+	msg := tgbotapi.NewMessage(b.chatID, text)
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = keyboard
+
+	_, err := b.api.Send(msg)
+	return err
+}
+
+// RequestCandidatePick asks which of several competing implementations of
+// a task to merge. Each candidate gets a button carrying its position in
+// candidates, counting from 1.
+func (b *Bot) RequestCandidatePick(featureID, taskID, summary string, candidates []string) error {
+	ref := fmt.Sprintf("%s:%s", featureID, taskID)
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, candidate := range candidates {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🏆 Pick %s", candidate), fmt.Sprintf("pick_candidate:%s:%d", ref, i+1)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("❌ Reject All", fmt.Sprintf("reject_code:%s", ref)),
+	))
+
+	text := fmt.Sprintf("🏁 *Pick a Candidate*\n\nFeature: `%s`\nTask: `%s`\n\n%s", featureID, taskID, truncate(summary, 3000))
+
+	msg := tgbotapi.NewMessage(b.chatID, text)
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+
+	_, err := b.api.Send(msg)
+	return err
+}
+
+// RequestBudgetApproval asks whether to raise a budget that held up work.
+// The scope identifies the budget, e.g. "feature:123" or "daily:2024-03-01".
+func (b *Bot) RequestBudgetApproval(scope, summary, raise string) error {
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("✅ Approve %s More", raise), fmt.Sprintf("approve_budget:%s", scope)),
+			tgbotapi.NewInlineKeyboardButtonData("⛔ Stop", fmt.Sprintf("stop_budget:%s", scope)),
+		),
+	)
+
+	msg := tgbotapi.NewMessage(b.chatID, fmt.Sprintf("💸 *Budget Reached*\n\n%s", summary))
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = keyboard
+
+	_, err := b.api.Send(msg)
+	return err
+}
+
 func truncate(s string, max int) string {
 	if len(s) <= max {
 		return s
@@ -52,6 +52,19 @@ var linterConfigs = map[string]linterConfig{
 	},
 }
 
+// Names returns the linters l runs
+func (l *Linter) Names() []string {
+	return l.linters
+}
+
+// LinterCommand returns the program linter needs on the PATH
+func LinterCommand(linter string) string {
+	if cfg, known := linterConfigs[linter]; known {
+		return cfg.check
+	}
+	return linter
+}
+
 func (l *Linter) Run(ctx context.Context, workDir string) (string, error) {
 	var results []string
 
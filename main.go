@@ -3,16 +3,20 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/bayological/foreman/internal/foreman"
+	"github.com/bayological/foreman/internal/storage"
 )
 
 func main() {
 	configPath := flag.String("config", "configs/foreman.yaml", "path to config file")
+	flag.Usage = usage
 	flag.Parse()
 
 	cfg, err := foreman.LoadConfig(*configPath)
@@ -20,6 +24,13 @@ func main() {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
+	if flag.NArg() > 0 {
+		if err := runCommand(cfg, flag.Args()); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	f, err := foreman.New(cfg)
 	if err != nil {
 		log.Fatalf("failed to create foreman: %v", err)
@@ -42,4 +53,133 @@ func main() {
 	if err := f.Run(ctx); err != nil && err != context.Canceled {
 		log.Fatalf("foreman exited with error: %v", err)
 	}
-}
\ No newline at end of file
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: foreman [-config path] [command]\n\n")
+	fmt.Fprintf(out, "Without a command Foreman starts and runs until interrupted.\n\n")
+	fmt.Fprintf(out, "Commands:\n")
+	fmt.Fprintf(out, "  storage backups           list backups of the storage file\n")
+	fmt.Fprintf(out, "  storage restore [backup]  restore a backup, the newest by default\n")
+	fmt.Fprintf(out, "  events <feature_id>       show a feature's event history and replayed state\n")
+	fmt.Fprintf(out, "  doctor                    check agents, tools and the repo are ready\n\n")
+	flag.PrintDefaults()
+}
+
+// runCommand runs a maintenance command instead of starting Foreman
+func runCommand(cfg *foreman.Config, args []string) error {
+	switch {
+	case args[0] == "storage" && len(args) >= 2:
+		return storageCommand(cfg, args)
+	case args[0] == "events" && len(args) == 2:
+		return eventsCommand(cfg, args[1])
+	case args[0] == "doctor" && len(args) == 1:
+		return doctorCommand(cfg)
+	default:
+		flag.Usage()
+		return fmt.Errorf("unknown command %q", strings.Join(args, " "))
+	}
+}
+
+// doctorCommand runs the preflight checks and fails when any of them does
+func doctorCommand(cfg *foreman.Config) error {
+	report := foreman.Preflight(context.Background(), cfg)
+	fmt.Print(report.Table())
+	fmt.Printf("\n%s\n", report.Summary())
+	if report.Failed() {
+		return fmt.Errorf("%d preflight checks failed", report.Count(foreman.CheckFail))
+	}
+	return nil
+}
+
+// eventsCommand prints a feature's event log and the state it replays to
+func eventsCommand(cfg *foreman.Config, featureID string) error {
+	path := cfg.Storage.EventLogPath()
+	if path == "" {
+		return fmt.Errorf("storage is not configured")
+	}
+
+	events, err := storage.ReadEventLog(path)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range storage.FeatureEvents(events, featureID) {
+		line := fmt.Sprintf("%s  %-16s", e.Timestamp.Local().Format("2006-01-02 15:04:05"), e.Type)
+		if e.TaskID != "" {
+			line += " " + e.TaskID
+		}
+		if e.FromPhase != "" || e.ToPhase != "" {
+			line += fmt.Sprintf(" %s -> %s", e.FromPhase, e.ToPhase)
+		}
+		if e.Task != nil {
+			line += fmt.Sprintf(" [%s, attempt %d]", e.Task.Status, e.Task.Attempt)
+		}
+		if e.Verdict != "" {
+			line += " " + e.Verdict
+		}
+		if stage := e.Data["stage"]; stage != "" {
+			line += " " + stage
+		}
+		if e.Actor != "" {
+			line += " by " + e.Actor
+		}
+		if e.Message != "" {
+			line += ": " + e.Message
+		}
+		fmt.Println(line)
+	}
+
+	feature, err := foreman.ReplayFeature(events, featureID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nReplayed state:\n%s", feature.StatusReport())
+	for _, task := range feature.Tasks {
+		fmt.Printf("  %s  %-17s attempt %d  %s\n", task.ID, task.Status, task.Attempt, task.Spec)
+	}
+	return nil
+}
+
+// storageCommand restores or lists backups of the storage file
+func storageCommand(cfg *foreman.Config, args []string) error {
+	if cfg.Storage.Backend != "" && cfg.Storage.Backend != storage.BackendFile {
+		return fmt.Errorf("backups are only kept by the file storage backend")
+	}
+	if cfg.Storage.Path == "" {
+		return fmt.Errorf("storage is not configured")
+	}
+
+	switch args[1] {
+	case "backups":
+		backups, err := storage.ListBackups(cfg.Storage.Path)
+		if err != nil {
+			return err
+		}
+		if len(backups) == 0 {
+			fmt.Println("No backups")
+		}
+		for _, b := range backups {
+			fmt.Printf("%s  %s\n", b.Time.Local().Format("2006-01-02 15:04:05"), b.Path)
+		}
+		return nil
+
+	case "restore":
+		var backup string
+		if len(args) > 2 {
+			backup = args[2]
+		}
+		restored, err := storage.Restore(cfg.Storage.Path, backup)
+		if err != nil {
+			return fmt.Errorf("restore failed: %w", err)
+		}
+		fmt.Printf("Restored %s from %s\n", cfg.Storage.Path, restored)
+		return nil
+
+	default:
+		flag.Usage()
+		return fmt.Errorf("unknown command %q", strings.Join(args, " "))
+	}
+}